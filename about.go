@@ -0,0 +1,141 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// The about overlay is opened from the options screen. It shows the game
+// credits, version information, and the open source licenses. The same
+// overlay is used to show crash reports.
+
+import (
+	_ "embed" // for the licenses.
+	"runtime/debug"
+	"strings"
+
+	"github.com/gazed/vu"
+)
+
+// licenses are the open source licenses for the game and the engine.
+//go:embed licenses.txt
+var licenses string
+
+// about is an overlay that scrolls through lines of text.
+type about struct {
+	ui    *vu.Ent   // Overlay parent.
+	bg    *vu.Ent   // Hides the options screen.
+	lines []*vu.Ent // Text line labels.
+	rows  int       // Number of text lines that fit on the screen.
+	text  []string  // All the text lines.
+	top   int       // First text line shown.
+}
+
+// aboutLine is the height of one text line in pixels.
+const aboutLine = 20
+
+// newAbout creates the hidden about overlay.
+func newAbout(root *vu.Ent, credits []string) *about {
+	text := append(aboutInfo(), "")
+	text = append(text, credits...)
+	text = append(text, "")
+	text = append(text, textLines(licenses)...)
+	return newTextView(root, text)
+}
+
+// newTextView creates a hidden overlay for the given lines of text.
+func newTextView(root *vu.Ent, text []string) *about {
+	a := &about{text: text}
+	a.ui = root.AddPart()
+	a.bg = a.ui.AddPart()
+	a.bg.MakeModel("colored", "msh:square", "mat:tblack")
+	a.ui.Cull(true)
+	return a
+}
+
+// textLines splits text into lines.
+func textLines(text string) []string {
+	return strings.Split(strings.Replace(text, "\r", "", -1), "\n")
+}
+
+// aboutInfo returns the game version information.
+func aboutInfo() []string {
+	built := buildDate
+	if built == "" {
+		built = "unknown"
+	}
+	return []string{"Bampf " + version, "Built " + built, "Engine " + engineVersion()}
+}
+
+// engineVersion returns the version of the vu engine that was built
+// into the game.
+func engineVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == "github.com/gazed/vu" {
+				return "vu " + dep.Version
+			}
+		}
+	}
+	return "vu"
+}
+
+// resize fits the overlay, and the number of text lines shown, to the screen.
+func (a *about) resize(width, height int) {
+	a.bg.SetAt(float64(width/2), float64(height/2), 0)
+	a.bg.SetScale(float64(width), float64(height), 1)
+	a.rows = (height - 2*aboutLine) / aboutLine
+	for len(a.lines) < a.rows {
+		line := a.ui.AddPart()
+		line.MakeLabel("labeled", "lucidiaSu18")
+		a.lines = append(a.lines, line)
+	}
+	for cnt, line := range a.lines {
+		line.SetAt(40, float64(height-2*aboutLine-cnt*aboutLine), 0)
+		line.Cull(cnt >= a.rows)
+	}
+	a.scroll(0)
+}
+
+// visible returns true if the overlay is showing.
+func (a *about) visible() bool { return !a.ui.Culled() }
+
+// toggle shows the overlay from the top or hides the overlay.
+func (a *about) toggle() {
+	a.ui.Cull(a.visible())
+	a.top = 0
+	a.scroll(0)
+}
+
+// handleInput scrolls the overlay using the mouse wheel or arrow keys.
+// Returns true if the player clicked or pressed escape to close it.
+func (a *about) handleInput(in *vu.Input, ip *inputState) (closed bool) {
+	a.scroll(-in.Scroll)
+	for _, press := range ip.pressedKeys() {
+		switch press {
+		case vu.KUa:
+			a.scroll(-1)
+		case vu.KDa:
+			a.scroll(1)
+		case vu.KEsc, vu.KLm:
+			closed = true
+		}
+	}
+	return closed
+}
+
+// scroll moves the shown text by the given number of lines.
+func (a *about) scroll(lines int) {
+	a.top += lines
+	if max := len(a.text) - a.rows; a.top > max {
+		a.top = max
+	}
+	if a.top < 0 {
+		a.top = 0
+	}
+	for cnt := 0; cnt < a.rows && cnt < len(a.lines); cnt++ {
+		text := ""
+		if a.top+cnt < len(a.text) {
+			text = a.text[a.top+cnt]
+		}
+		a.lines[cnt].SetStr(text)
+	}
+}
@@ -72,6 +72,9 @@ func (a *animator) skip() {
 	a.animations = []animation{}
 }
 
+// running returns true while there are active animations.
+func (a *animator) running() bool { return len(a.animations) > 0 }
+
 // animator
 // ===========================================================================
 // transitionAnimation
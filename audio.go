@@ -0,0 +1,82 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/gazed/vu"
+)
+
+// audio plays the game sounds and tells an optional listener, ie: the
+// HUD captions, a short description of each sound that was played.
+type audio struct {
+	captions map[uint32]string    // Sound descriptions by sound identifier.
+	listener func(caption string) // Called each time a sound is played.
+	loop     *loop                // Repeating sound, nil when nothing loops.
+}
+
+// sounds is the game audio manager.
+var sounds = &audio{captions: map[uint32]string{}}
+
+// add loads a sound and remembers its caption.
+func (a *audio) add(eng vu.Eng, name, caption string) uint32 {
+	sound := eng.AddSound(name)
+	a.captions[sound] = caption
+	return sound
+}
+
+// play plays the sound at the given location and passes
+// the sound caption to the listener.
+func (a *audio) play(pov *vu.Ent, sound uint32) {
+	pov.PlaySound(sound)
+	if a.listener != nil {
+		a.listener(a.captions[sound])
+	}
+}
+
+// loop repeats a short sound while it is on. The engine can't loop or
+// pitch shift sounds, so the sound is replayed each time it finishes
+// and the pitch is picked from recorded variants. Looping sounds go
+// through the engine like other sounds so they follow the mute and
+// volume settings.
+type loop struct {
+	pov    *vu.Ent  // Where the sound is played.
+	sounds []uint32 // Sound variants from lowest to highest pitch.
+	ticks  int      // Game ticks until the sound is replayed.
+}
+
+// loopTicks is the number of game ticks between loop replays.
+// Looping sounds are a little over half a second long.
+const loopTicks = 24
+
+// startLoop starts repeating the given sound variants at the given
+// location, replacing any current loop. Loops are not captioned since
+// they accompany a sound that already was.
+func (a *audio) startLoop(pov *vu.Ent, sounds []uint32) {
+	if len(sounds) > 0 {
+		a.loop = &loop{pov: pov, sounds: sounds}
+	}
+}
+
+// stopLoop stops the current loop once the playing sound finishes.
+func (a *audio) stopLoop() { a.loop = nil }
+
+// tickLoop is called each game tick to keep the loop playing. Pitch is
+// from 0 for the lowest variant to 1 for the highest. A loop stops when
+// it is no longer ticked, so it can't outlast the game screen.
+func (a *audio) tickLoop(pitch float64) {
+	if a.loop == nil {
+		return
+	}
+	if a.loop.ticks--; a.loop.ticks <= 0 {
+		a.loop.ticks = loopTicks
+		index := int(pitch * float64(len(a.loop.sounds)))
+		switch {
+		case index < 0:
+			index = 0
+		case index >= len(a.loop.sounds):
+			index = len(a.loop.sounds) - 1
+		}
+		a.loop.pov.PlaySound(a.loop.sounds[index])
+	}
+}
@@ -12,6 +12,8 @@ package main
 
 import (
 	"container/list"
+	"fmt"
+	"math"
 	"math/rand"
 	"runtime/debug"
 	"time"
@@ -35,6 +37,10 @@ func main() {
 //    go build -ldflags "-X main.version `git describe`"
 var version string
 
+// buildDate is set by the build using ld flags. Eg.
+//    go build -ldflags "-X main.buildDate `date +%Y-%m-%d`"
+var buildDate string
+
 // catchErrors is for debugging developer loads.
 func catchErrors() {
 	if r := recover(); r != nil {
@@ -51,20 +57,39 @@ func catchErrors() {
 //   1. Prepare and share the initial state and data structures.
 //   2. Ensure orderly switching between game states.
 type bampf struct {
-	eng         vu.Eng     // Engine.
-	state       gameState  // Which main screen is active.
-	launch      *launch    // Initial choosing screen.
-	game        *game      // Main game play screen.
-	end         *end       // Final "you won" screen.
-	config      *config    // Options screen.
-	active      screen     // Currently drawn screen (state).
-	eventq      *list.List // Game event queue.
-	mute        bool       // Track if the sound is on or off.
-	fullScreen  bool       // Track if the app is full screen.
-	ww, wh      int        // Application window size.
-	ani         *animator  // Handles short animations.
-	launchLevel int        // Choosen by the user on the launch screen.
-	keys        []int      // Restored key bindings.
+	eng         vu.Eng          // Engine.
+	state       gameState       // Which main screen is active.
+	launch      *launch         // Initial choosing screen.
+	game        *game           // Main game play screen.
+	end         *end            // Final "you won" screen.
+	config      *config         // Options screen.
+	active      screen          // Currently drawn screen (state).
+	eventq      *list.List      // Game event queue.
+	mute        bool            // Track if the sound is on or off.
+	fullScreen  bool            // Track if the app is full screen.
+	ww, wh      int             // Application window size.
+	ani         *animator       // Handles short animations.
+	launchLevel int             // Choosen by the user on the launch screen.
+	launchMaze  string          // Custom maze choosen on the launch screen.
+	launchDaily bool            // True if the daily challenge was choosen.
+	practice    bool            // True if practice mode was choosen.
+	mutators    map[string]bool // Mutators choosen on the launch screen.
+	resume      *RunSave        // Saved run to continue, nil for a new run.
+	replay      *shareCode      // Share code to replay, nil for a new run.
+	shared      string          // Share code of the last run, if it could be shared.
+	keys        []int           // Restored key bindings.
+	opts        map[string]bool // Restored optional feature settings.
+	backdrop    string          // Restored launch screen backdrop theme.
+	colours     string          // Restored trooper colour scheme.
+	crosshair   []string        // Restored crosshair shape, size, and colour.
+	rumble      string          // Restored controller rumble intensity.
+	presence    *presence       // Exports game status to other programs.
+	speech      *speech         // Reads out important game events.
+	clip        clipboard       // Copies and pastes share codes.
+	haptics     *haptics        // Game controller rumble.
+	input       *inputState     // Pressed, held, and released keys.
+	focused     bool            // True if the window had focus on the previous update.
+	idler       idler           // Slows down idle menu screens.
 }
 
 // Game state transition constants are passed to game state methods which
@@ -81,7 +106,11 @@ type gameState func(int) gameState
 
 // create the game screens before the main action/update loop is started.
 func (mp *bampf) Create(eng vu.Eng, s *vu.State) {
+	defer mp.reportCrash()
 	var x, y int
+	profiles := newProfiles()
+	profiles.restore()
+	activeProfile = profiles.active()
 	x, y, mp.ww, mp.wh, mp.mute, mp.fullScreen = mp.prefs()
 	eng.Set(vu.Title("Bampf"), vu.Size(x, y, mp.ww, mp.wh))
 	if mp.fullScreen {
@@ -92,6 +121,19 @@ func (mp *bampf) Create(eng vu.Eng, s *vu.State) {
 	mp.ani = &animator{}
 	mp.setMute(mp.mute)
 	mp.eventq = list.New()
+	mp.input = newInputState()
+	mp.mutators = map[string]bool{}
+	mp.presence = newPresence()
+	mp.presence.addPresenter(newStatusFile())
+	mp.presence.setEnabled(mp.opts[presenceOption])
+	mp.speech = newSpeech()
+	mp.clip = newClipboard()
+	mp.speech.on = mp.opts[speechOption]
+	mp.haptics = newHaptics()
+	mp.haptics.setIntensity(mp.rumble)
+	loadThemes()
+	loadLevels()
+	loadFlavor()
 	mp.createScreens(s.W, s.H)
 	mp.state = mp.choosing
 	mp.active = mp.launch
@@ -99,33 +141,54 @@ func (mp *bampf) Create(eng vu.Eng, s *vu.State) {
 	eng.Set(vu.Color(1, 1, 1, 1)) // White as default background.
 
 	// create the noises needed by the trooper.
-	teleportSound = eng.AddSound("teleport")
-	fetchSound = eng.AddSound("fetch")
-	cloakSound = eng.AddSound("cloak")
-	decloakSound = eng.AddSound("decloak")
-	collideSound = eng.AddSound("collide")
+	teleportSound = sounds.add(eng, "teleport", "teleport")
+	fetchSound = sounds.add(eng, "fetch", "core collected")
+	fetchSounds = []uint32{fetchSound}
+	for _, name := range []string{"fetch1", "fetch2"} {
+		fetchSounds = append(fetchSounds, sounds.add(eng, name, "core collected"))
+	}
+	cloakSound = sounds.add(eng, "cloak", "cloak on")
+	decloakSound = sounds.add(eng, "decloak", "cloak off")
+	collideSound = sounds.add(eng, "collide", "sentinel hit")
+	dropSound = sounds.add(eng, "drop", "core dropped")
+	pingSound = sounds.add(eng, "ping", "sentinel nearby")
+	lowCloakSound = sounds.add(eng, "lowcloak", "cloak low")
+	freezeSound = sounds.add(eng, "freeze", "sentinels frozen")
+	markSound = sounds.add(eng, "mark", "partner ping")
+	for _, name := range []string{"hum0", "hum1", "hum2"} {
+		humSounds = append(humSounds, sounds.add(eng, name, "cloak hum"))
+	}
 }
 
 // Update is a regular engine callback and is passed onto the currently
 // active screen. Update will run many times a second and should return
 // promptly.
 func (mp *bampf) Update(eng vu.Eng, in *vu.Input, s *vu.State) {
+	defer mp.reportCrash()
 	if in.Resized {
 		mp.resize(s.X, s.Y, s.W, s.H, s.Full)
 	}
+	mp.haptics.hold(!in.Focus || mp.active != mp.game)
+	if mp.focused && !in.Focus {
+		mp.loseFocus()
+	}
+	mp.focused = in.Focus
 	if in.Focus {
 		mp.ani.animate(in.Dt)                 // run active animations
+		mp.input.update(in)                   // track key presses.
 		mp.active.processInput(in, mp.eventq) // user input to game events.
 		for mp.eventq.Len() > 0 {
 			transition := mp.active.processEvents(mp.eventq)
 			mp.state = mp.state(transition)
 		}
 	}
+	mp.throttle(in)
 }
 
 // createScreens creates the different application screens and anything
 // else needed before the render loop takes over.
 func (mp *bampf) createScreens(ww, wh int) *bampf {
+	setTextScale(wh)
 	mp.launch = newLaunchScreen(mp)
 	mp.game = newGameScreen(mp)
 	mp.end = newEndScreen(mp, ww, wh)
@@ -169,6 +232,7 @@ func (mp *bampf) configuring(event int) gameState {
 	case playGame:
 		mp.active = mp.game
 		mp.active.activate(screenActive)
+		mp.game.startResume()
 		return mp.playing
 	case finishGame:
 		mp.active = mp.end
@@ -186,6 +250,7 @@ func (mp *bampf) configuring(event int) gameState {
 func (mp *bampf) playing(event int) gameState {
 	switch event {
 	case configGame:
+		mp.game.saveRun()
 		mp.active.activate(screenPaused)
 		mp.config.setExitTransition(playGame)
 		mp.active = mp.config
@@ -230,7 +295,20 @@ func (mp *bampf) transitionToGameScreen() {
 	fadeIn := mp.game.fadeIn()
 	mid := func() {
 		mp.active = mp.game
-		mp.game.setLevel(mp.launchLevel)
+		switch {
+		case mp.resume != nil:
+			mp.game.continueRun(mp.resume)
+			mp.resume = nil
+		case mp.replay != nil:
+			mp.game.replayRun(mp.replay)
+			mp.replay = nil
+		case mp.launchDaily:
+			mp.game.newGame(true, nil)
+			mp.game.setLevel(0) // daily challenges start at the beginning.
+		default:
+			mp.game.newGame(false, mp.mutators)
+			mp.game.setLevel(mp.launchLevel)
+		}
 		mp.active.activate(screenEvolving)
 	}
 	mp.ani.addAnimation(newTransitionAnimation(fadeOut, fadeIn, mid))
@@ -255,6 +333,8 @@ func (mp *bampf) returnToMenu() {
 	mp.config.activate(screenDeactive)
 	mp.game.activate(screenDeactive)
 	mp.end.activate(screenDeactive)
+	mp.game.stopCoop()
+	mp.game.evictLevels(mp.launchLevel) // the next game starts here.
 	mp.active = mp.launch
 	mp.active.activate(screenActive)
 }
@@ -267,9 +347,21 @@ func (mp *bampf) skipAnimation() {
 	mp.ani.skip()
 }
 
+// loseFocus stops everything the player was doing when the game window
+// loses focus, ie: alt-tab. A game in progress is paused so that the
+// player can resume from the options screen once they come back.
+func (mp *bampf) loseFocus() {
+	mp.input.blur()
+	mp.game.releaseMouse() // captured again once the game resumes.
+	if mp.active == mp.game && mp.game.blur() {
+		mp.state = mp.state(configGame)
+	}
+}
+
 // resize adjusts all the screens to the current game window size.
 func (mp *bampf) resize(wx, wy, ww, wh int, fullScreen bool) {
 	mp.ww, mp.wh = ww, wh
+	setTextScale(wh)
 	mp.launch.resize(ww, wh)
 	mp.game.resize(ww, wh)
 	mp.end.resize(ww, wh)
@@ -298,6 +390,15 @@ func (mp *bampf) prefs() (x, y, w, h int, mute, full bool) {
 		h = saver.H
 	}
 	mp.keys = append(mp.keys, saver.Kbinds...)
+	mp.opts = map[string]bool{}
+	for id, on := range saver.Opts {
+		mp.opts[id] = on
+	}
+	mp.backdrop = saver.Backdrop
+	mp.colours = saver.Colours
+	trooperColours = getColours(mp.colours)
+	mp.crosshair = crosshairStyle(saver.Crosshair)
+	mp.rumble = saver.Rumble
 	return
 }
 
@@ -346,23 +447,109 @@ const (
 	screenPaused          // Screen is visible behind config screen.
 )
 
+// optional feature identifiers. These are also the names used
+// to save the feature settings.
+const (
+	presenceOption    = "presence"    // Export game status to other programs.
+	coreExpiryOption  = "coreExpiry"  // Dropped cores disappear over time.
+	safeFlashOption   = "safeFlash"   // Replace screen flashes with vignettes.
+	captionOption     = "captions"    // Show captions for game sounds.
+	holdCloakOption   = "holdCloak"   // Cloak only while the cloak key is held.
+	autoRunOption     = "autoRun"     // Enable the auto-run toggle key.
+	progressOption    = "progress"    // Completing levels unlocks starting levels.
+	quickEvolveOption = "quickEvolve" // Evolve without the countdown.
+	timerOption       = "timer"       // Show the run timer and level splits.
+	streamOption      = "stream"      // Use the streaming HUD layout.
+	speechOption      = "speech"      // Read out important game events.
+	kinematicOption   = "kinematic"   // Move the player without physics.
+	mirrorOption      = "mirror"      // Show a rear-view mirror on the HUD.
+	freeMouseOption   = "freeMouse"   // Don't capture the mouse pointer.
+	telemetryOption   = "telemetry"   // Record level outcomes to a local file.
+	reachOption       = "reach"       // Show the core pickup reach around the player.
+	keepCoresOption   = "keepCores"   // Keep dropped cores when returning to a level.
+	quickResumeOption = "quickResume" // Resume a paused game without the countdown.
+	liveLevelsOption  = "liveLevels"  // Keep sentinels moving on the other cached levels.
+)
+
+// setOption turns an optional feature on or off.
+func (mp *bampf) setOption(id string, on bool) {
+	mp.opts[id] = on
+	switch id {
+	case presenceOption:
+		mp.presence.setEnabled(on)
+	case safeFlashOption:
+		mp.game.setSafeMode(on)
+	case streamOption:
+		mp.game.setHudLayout()
+	case speechOption:
+		mp.speech.on = on
+	case mirrorOption:
+		mp.game.setMirror(on)
+	}
+	if mp.game.rules != nil {
+		mp.game.rules.useAssists(mp.opts)
+	}
+}
+
 // screen
 // ===========================================================================
 // utilities
 
-// logf does nothing by default so that log messages are discarded
-// during production builds.
-var logf = func(format string, v ...interface{}) {}
+// logf only keeps recent log messages for crash reports by default
+// so that log messages are discarded during production builds.
+var logf = func(format string, v ...interface{}) {
+	recentLogs.add(fmt.Sprintf(format, v...))
+}
 
 // setLogger turns logging on in debug loads.
 func (mp *bampf) setLogger(gi interface{}) {
 	if fn, ok := gi.(interface {
 		logger(string, ...interface{})
 	}); ok {
-		logf = fn.logger
+		logf = func(format string, v ...interface{}) {
+			recentLogs.add(fmt.Sprintf(format, v...))
+			fn.logger(format, v...)
+		}
 	}
 }
 
+// trackAsset notes the models built from a texture or material so
+// that debug builds can reload changed art while the game runs.
+// It does nothing in production builds.
+var trackAsset = func(e *vu.Ent, asset string) {}
+
+// timeStage starts timing one stage of the per-tick level update and
+// returns the function that stops the timer. Debug builds use it to
+// find bottlenecks. It does nothing in production builds.
+var timeStage = func(stage string) (stop func()) { return func() {} }
+
+// textScale enlarges the HUD and menu text so that it stays legible
+// on large windows. The bitmap fonts are only scaled in half steps
+// which keeps the letters crisp.
+var textScale = 1.0
+
+// textHeight is the window height where text is shown at its natural size.
+const textHeight = 1080
+
+// setTextScale picks the text scale for the given window height.
+// Text is never shrunk below its natural size.
+func setTextScale(height int) {
+	textScale = math.Max(1, math.Floor(float64(height)*2/textHeight)/2)
+}
+
+// scaleLabel sizes a label, drawn at the given scale, for the current
+// text scale. Expected to be called whenever the window is resized.
+func scaleLabel(label *vu.Ent, scale float64) *vu.Ent {
+	return label.SetScale(scale*textScale, scale*textScale, 1)
+}
+
+// labelSize returns the label width and height in pixels at the
+// current text scale.
+func labelSize(label *vu.Ent) (w, h int) {
+	w, h = label.Size()
+	return int(float64(w) * textScale), int(float64(h) * textScale)
+}
+
 // utilities
 // ===========================================================================
 // area
@@ -393,29 +580,65 @@ var fetchSound uint32
 var cloakSound uint32
 var decloakSound uint32
 var collideSound uint32
+var dropSound uint32
+var pingSound uint32
+var lowCloakSound uint32
+var freezeSound uint32
+var markSound uint32
+var humSounds []uint32   // Cloak hum from low to high pitch.
+var fetchSounds []uint32 // Core pickup from high to low pitch.
 
 // ===========================================================================
 // game events
 
 // Game events.
 const (
-	_             = iota // start at 1.
-	goForward            // Move the player forward.
-	goBack               // Move the player back.
-	goLeft               // Move the player left.
-	goRight              // Move the player right.
-	cloak                // Toggle cloaking.
-	teleport             // Trigger teleport.
-	skipAnim             // Skip any playing animation.
-	rollCredits          // Toggle the game developer list.
-	toggleMute           // Toggle sound.
-	toggleOptions        // Toggle the config screen.
-	pickLevel            // expects int data.
-	rebindKey            // expects rebindKeyEvent data.
-	keysRebound          // expects []string data.
-	startGame            // Transition to the game level.
-	wonGame              // Transition to the end screen.
-	quitLevel            // Transition to the launch screen.
+	_               = iota // start at 1.
+	goForward              // Move the player forward.
+	goBack                 // Move the player back.
+	goLeft                 // Move the player left.
+	goRight                // Move the player right.
+	cloak                  // Toggle cloaking.
+	teleport               // Trigger teleport.
+	skipAnim               // Skip any playing animation.
+	toggleAbout            // Toggle the credits, version, and licenses.
+	toggleMute             // Toggle sound.
+	toggleOptions          // Toggle the config screen.
+	pickLevel              // expects int data.
+	rebindKey              // expects rebindKeyEvent data.
+	keysRebound            // expects []string data.
+	startGame              // Transition to the game level.
+	continueGame           // Continue the saved run.
+	wonGame                // Transition to the end screen.
+	quitLevel              // Transition to the launch screen.
+	toggleOption           // expects option id string data.
+	statusChanged          // expects Status data.
+	pickMaze               // Choose the next custom maze.
+	pickDaily              // Toggle the daily challenge.
+	pickPractice           // Toggle practice mode.
+	pickShare              // Start typing a share code.
+	replayShare            // expects share code string data.
+	copyShare              // Copy the last share code to the clipboard.
+	pasteShare             // expects clipping data.
+	copiedShare            // expects clipping data.
+	toggleMutator          // expects mutator id string data.
+	previewTeleport        // expects bool data.
+	descend                // Voluntarily drop down a level.
+	pickBackdrop           // Choose the next launch screen theme.
+	viewCrash              // Toggle the last crash report.
+	viewChanges            // Close the changes in a new version.
+	pickProfile            // Choose the next local player profile.
+	editProfile            // expects profile link string data.
+	renameProfile          // expects new profile name string data.
+	toggleHud              // Hide or show the HUD.
+	exportMap              // Save a picture of the level map.
+	findPath               // Show the way to the maze center.
+	placePing              // expects pingEvent data.
+	speak                  // expects text string data.
+	pickRumble             // Choose the next rumble intensity.
+	pickColours            // Choose the next trooper colour scheme.
+	pickCrosshair          // expects crosshair setting index int data.
+	quickTurn              // Spin the camera around to look behind.
 )
 
 // event is the standard structure for all game events.
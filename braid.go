@@ -0,0 +1,88 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Braided mazes are Prim's mazes with some of the dead-ends removed by
+// knocking out the wall at the end of the dead-end. They sit between the
+// classic maze, where every side corridor is a dead-end, and the dense
+// skirmish grid, where no corridor is.
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/gazed/bampf/gridmath"
+	"github.com/gazed/vu/grid"
+)
+
+// braidMaze is the floorplan generator number for braided mazes.
+// It follows the vu grid generators so it can be used like them.
+const braidMaze = grid.Dungeon + 1
+
+// braidShare is the share of the dead-ends that are opened up.
+const braidShare = 0.5
+
+// newGrid returns an empty floorplan for the given generator.
+func newGrid(gridType int) grid.Grid {
+	if gridType == braidMaze {
+		return &braidPlan{Grid: grid.New(grid.PrimMaze)}
+	}
+	return grid.New(gridType)
+}
+
+// braidPlan is a braided maze. It implements grid.Grid so that it can
+// be used in place of the vu floorplans.
+type braidPlan struct {
+	grid.Grid                        // Prim's maze that is braided.
+	seed      int64                  // Picks the opened dead-ends, zero for random.
+	opened    map[gridmath.Spot]bool // Walls removed from the maze.
+}
+
+// Seed is used to generate the same maze each time.
+func (bp *braidPlan) Seed(seed int64) {
+	bp.seed = seed
+	bp.Grid.Seed(seed)
+}
+
+// Generate creates the maze and then opens up some of its dead-ends.
+func (bp *braidPlan) Generate(width, depth int) grid.Grid {
+	bp.Grid.Generate(width, depth)
+	seed := bp.seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	bp.braid(rand.New(rand.NewSource(seed)))
+	return bp
+}
+
+// IsOpen returns true for the maze floors and the opened walls.
+func (bp *braidPlan) IsOpen(x, y int) bool {
+	return bp.opened[gridmath.Spot{X: x, Y: y}] || bp.Grid.IsOpen(x, y)
+}
+
+// braid opens braidShare of the dead-ends by removing a wall at the end
+// of the dead-end that has floor behind it. The wall straight ahead is
+// preferred over the side walls. The maze boundary is never opened.
+func (bp *braidPlan) braid(rng *rand.Rand) {
+	bp.opened = map[gridmath.Spot]bool{}
+	width, height := bp.Size()
+	for x := 1; x < width-1; x++ {
+		for y := 1; y < height-1; y++ {
+			exit, ok := deadEnd(bp, x, y)
+			if !ok || rng.Float64() >= braidShare {
+				continue
+			}
+			ahead := gridmath.Spot{X: -exit.X, Y: -exit.Y}
+			sides := []gridmath.Spot{{X: exit.Y, Y: exit.X}, {X: -exit.Y, Y: -exit.X}}
+			for _, dir := range append([]gridmath.Spot{ahead}, sides...) {
+				wx, wy := x+dir.X, y+dir.Y
+				inside := wx > 0 && wy > 0 && wx < width-1 && wy < height-1
+				if inside && bp.IsOpen(wx+dir.X, wy+dir.Y) {
+					bp.opened[gridmath.Spot{X: wx, Y: wy}] = true
+					break
+				}
+			}
+		}
+	}
+}
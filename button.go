@@ -4,6 +4,8 @@
 package main
 
 import (
+	"image"
+
 	"github.com/gazed/vu"
 )
 
@@ -20,6 +22,10 @@ type button struct {
 	banner    *vu.Ent     // Label for the action associated with the button.
 	cx, cy    float64     // Button center location.
 	model     *vu.Ent     // Holds button 3D model. Used for transforms.
+	note      *vu.Ent     // Optional text shown below the button.
+	badge     *vu.Ent     // Optional status icon in the button corner.
+	disabled  bool        // Disabled buttons are greyed out and can't be clicked.
+	thumb     *vu.Ent     // Optional picture shown above the button on hover.
 }
 
 // newButton creates a button. Buttons are initialized with a size and repositioned later.
@@ -53,9 +59,19 @@ func (b *button) setVisible(visible bool) { b.model.Cull(!visible) }
 // setIcon changes the buttons icon.
 func (b *button) setIcon(icon string) { b.icon.SetFirst(icon) }
 
+// setEnabled greys out and disables the button, or restores it.
+func (b *button) setEnabled(enabled bool) {
+	b.disabled = !enabled
+	alpha := 0.5
+	if b.disabled {
+		alpha = 0.15
+	}
+	b.icon.SetAlpha(alpha)
+}
+
 // clicked returns true if the button was clicked.
 func (b *button) clicked(mx, my int) bool {
-	return !b.model.Culled() && mx >= b.x && mx <= b.x+b.w && my >= b.y && my <= b.y+b.h
+	return !b.disabled && !b.model.Culled() && mx >= b.x && mx <= b.x+b.w && my >= b.y && my <= b.y+b.h
 }
 
 // label adds a banner to a button or updates the banner if there is
@@ -74,6 +90,33 @@ func (b *button) label(part *vu.Ent, keyCode int) {
 	}
 }
 
+// setNote shows the given text below the button. An empty note
+// hides any existing note.
+func (b *button) setNote(text string) {
+	if b.note == nil {
+		b.note = b.model.AddPart().SetAt(float64(-b.w/2), float64(-b.h/2)-20*textScale, 0)
+		scaleLabel(b.note.MakeLabel("labeled", "lucidiaSu18"), 1)
+	}
+	b.note.SetStr(text)
+	b.note.Cull(text == "")
+}
+
+// setBadge shows the given icon in the top right corner of the button.
+// An empty icon name hides any existing badge.
+func (b *button) setBadge(icon string) {
+	if b.badge == nil && icon != "" {
+		b.badge = b.model.AddPart().SetAt(float64(b.w/2-8), float64(b.h/2-8), 0)
+		b.badge.SetScale(8, 8, 1)
+		b.badge.MakeModel("textured", "msh:icon", "tex:"+icon)
+	}
+	if b.badge != nil {
+		if icon != "" {
+			b.badge.SetFirst(icon)
+		}
+		b.badge.Cull(icon == "")
+	}
+}
+
 // position specifies the new center location for the button. This ensures the
 // button remains properly located after a screen resize.
 func (b *button) position(cx, cy float64) {
@@ -83,16 +126,146 @@ func (b *button) position(cx, cy float64) {
 	b.y = int(cy) - b.h/2
 	b.model.SetAt(b.cx, b.cy, 0)
 	if b.banner != nil {
-		b.banner.SetAt(float64(b.x), float64(b.y), 0)
+		scaleLabel(b.banner, 1).SetAt(float64(b.x), float64(b.y), 0)
 	}
+	if b.note != nil {
+		scaleLabel(b.note, 1).SetAt(float64(-b.w/2), float64(-b.h/2)-20*textScale, 0)
+	}
+}
+
+// setThumb adds a picture that is shown above the button while the
+// mouse is over the button. The texture name must be unique.
+func (b *button) setThumb(img image.Image, name string) {
+	size := img.Bounds().Size()
+	w := float64(b.w) * 2
+	h := w * float64(size.Y) / float64(size.X)
+	b.thumb = b.model.AddPart().SetAt(0, float64(b.h/2)+h*0.5+8, 0)
+	b.thumb.SetScale(w*0.5, h*0.5, 1)
+	b.thumb.MakeModel("textured", "msh:icon").GenTex(name).Set(img)
 }
 
-// hover hilights the button when the mouse is over it.
+// hover hilights the button, and shows any thumbnail, when the mouse
+// is over it.
 func (b *button) hover(mx, my int) bool {
-	b.hilite.Cull(true)
-	if mx >= b.x && mx <= b.x+b.w && my >= b.y && my <= b.y+b.h {
-		b.hilite.Cull(false)
-		return true
+	over := mx >= b.x && mx <= b.x+b.w && my >= b.y && my <= b.y+b.h
+	b.hilite.Cull(!over)
+	if b.thumb != nil {
+		b.thumb.Cull(!over)
+	}
+	return over
+}
+
+// button
+// ===========================================================================
+// toggle
+
+// toggle is a clickable on/off option. The option description and its
+// current value are shown as a single text label.
+type toggle struct {
+	area           // Toggle is rectangular.
+	id     string  // Option identifier, also used to save the option.
+	text   string  // Option description.
+	on     bool    // Current option value.
+	banner *vu.Ent // Label showing the description and value.
+}
+
+// newToggle creates a toggle. Toggles are initialized with their
+// current value and positioned later.
+func newToggle(root *vu.Ent, id, text string, on bool) *toggle {
+	t := &toggle{id: id, text: text}
+	t.w, t.h = 200, 18
+	t.banner = root.AddPart()
+	t.banner.MakeLabel("labeled", "lucidiaSu18")
+	t.set(on)
+	return t
+}
+
+// set updates the toggle value and its label.
+func (t *toggle) set(on bool) {
+	t.on = on
+	value := "off"
+	if t.on {
+		value = "on"
 	}
-	return false
+	t.banner.SetStr(t.text + ": " + value)
+}
+
+// position specifies the new bottom left location for the toggle.
+func (t *toggle) position(x, y float64) {
+	t.x, t.y = int(x), int(y)
+	scaleLabel(t.banner, 1).SetAt(x, y, 0)
+}
+
+// clicked returns true if the toggle was clicked.
+func (t *toggle) clicked(mx, my int) bool {
+	w, h := int(float64(t.w)*textScale), int(float64(t.h)*textScale)
+	return mx >= t.x && mx <= t.x+w && my >= t.y && my <= t.y+h
+}
+
+// toggle
+// ===========================================================================
+// chooser
+
+// chooser is a clickable text label that cycles through a list of choices.
+type chooser struct {
+	area             // Chooser is rectangular.
+	text    string   // Chooser description.
+	choices []string // Available choices.
+	index   int      // Current choice.
+	banner  *vu.Ent  // Label showing the description and choice.
+}
+
+// newChooser creates a chooser showing the first of the given choices.
+func newChooser(root *vu.Ent, text string, choices []string) *chooser {
+	c := &chooser{text: text}
+	c.w, c.h = 200, 18
+	c.banner = root.AddPart()
+	c.banner.MakeLabel("labeled", "lucidiaSu18")
+	c.setChoices(choices)
+	return c
+}
+
+// setChoices replaces the available choices, keeping the current
+// choice if it is still available.
+func (c *chooser) setChoices(choices []string) {
+	current := c.choice()
+	c.choices, c.index = choices, 0
+	for cnt, choice := range c.choices {
+		if choice == current {
+			c.index = cnt
+		}
+	}
+	c.show()
+}
+
+// next moves to, and returns, the next choice.
+func (c *chooser) next() string {
+	if len(c.choices) > 0 {
+		c.index = (c.index + 1) % len(c.choices)
+	}
+	c.show()
+	return c.choice()
+}
+
+// choice returns the current choice.
+func (c *chooser) choice() string {
+	if c.index < len(c.choices) {
+		return c.choices[c.index]
+	}
+	return ""
+}
+
+// show updates the label to display the current choice.
+func (c *chooser) show() { c.banner.SetStr(c.text + ": " + c.choice()) }
+
+// position specifies the new bottom left location for the chooser.
+func (c *chooser) position(x, y float64) {
+	c.x, c.y = int(x), int(y)
+	scaleLabel(c.banner, 1).SetAt(x, y, 0)
+}
+
+// clicked returns true if the chooser was clicked.
+func (c *chooser) clicked(mx, my int) bool {
+	w, h := int(float64(c.w)*textScale), int(float64(c.h)*textScale)
+	return mx >= c.x && mx <= c.x+w && my >= c.y && my <= c.y+h
 }
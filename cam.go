@@ -14,6 +14,9 @@ import (
 type cam struct {
 	pitch float64 // used to smooth camera.
 	yaw   float64 // used to smooth camera.
+
+	// deflect, if set, adjusts pushes that run into walls.
+	deflect func(dx, dz float64) (float64, float64)
 }
 
 // implement the rest of the lens interface.
@@ -35,20 +38,20 @@ func (c *cam) move(bod *vu.Ent, x, y, z float64, dir *lin.Q) {
 			case sx == 0.0:
 				// apply push in the current direction.
 				dx, dy, dz := lin.MultSQ(x*boost, 0, 0, dir)
-				body.Push(dx, dy, dz)
+				c.push(body, dx, dy, dz)
 			case math.Abs(sx) < maxAccel && math.Abs(sz) < maxAccel:
 				dx, dy, dz := lin.MultSQ(x, 0, 0, dir)
-				body.Push(dx, dy, dz)
+				c.push(body, dx, dy, dz)
 			}
 		}
 		if z != 0 {
 			switch {
 			case sz == 0.0:
 				dx, dy, dz := lin.MultSQ(0, 0, z*boost, dir)
-				body.Push(dx, dy, dz)
+				c.push(body, dx, dy, dz)
 			case math.Abs(sx) < maxAccel && math.Abs(sz) < maxAccel:
 				dx, dy, dz := lin.MultSQ(0, 0, z, dir)
-				body.Push(dx, dy, dz)
+				c.push(body, dx, dy, dz)
 			}
 		}
 	} else {
@@ -56,6 +59,14 @@ func (c *cam) move(bod *vu.Ent, x, y, z float64, dir *lin.Q) {
 	}
 }
 
+// push moves the body, first turning any push into a wall along the wall.
+func (c *cam) push(body vu.Body, dx, dy, dz float64) {
+	if c.deflect != nil {
+		dx, dz = c.deflect(dx, dz)
+	}
+	body.Push(dx, dy, dz)
+}
+
 // look changes the view left/right for changes in the x direction
 // and up/down for changes in the y direction.
 func (c *cam) look(spin, dt, xdiff, ydiff float64) {
@@ -100,6 +111,10 @@ func (c *cam) reset(camera *vu.Camera) {
 	camera.SetYaw(0)
 }
 
+// turn swings the target yaw around to face backwards. The camera
+// smoothing in update eases the view around over a few ticks.
+func (c *cam) turn() { c.yaw += 180 }
+
 func (c *cam) update(camera *vu.Camera) {
 	fraction := 0.25
 	pitch := camera.Pitch
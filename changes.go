@@ -0,0 +1,85 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// The changes overlay is shown on the launch screen the first time a new
+// version of the game is played. It lists what is new in the version and
+// notes any saved settings that were updated to work with the new version.
+// Developer builds don't have a version and never show the changes.
+
+import (
+	_ "embed" // for the change log.
+	"fmt"
+	"os"
+	"strings"
+)
+
+// changeLog lists what is new in each version. Each version starts with
+// a "# version" heading line.
+//go:embed changes.txt
+var changeLog string
+
+// release returns the release part of a version from git describe,
+// dropping the commit count and hash of builds made after the release.
+func release(version string) string {
+	parts := strings.Split(version, "-")
+	if n := len(parts); n >= 3 && strings.HasPrefix(parts[n-1], "g") {
+		return strings.Join(parts[:n-2], "-")
+	}
+	return version
+}
+
+// changeNotes returns the change log lines for the given version.
+// Nothing is returned for versions that are not in the change log.
+func changeNotes(text, version string) (notes []string) {
+	found := false
+	for _, line := range textLines(text) {
+		if strings.HasPrefix(line, "# ") {
+			found = strings.TrimSpace(line[2:]) == release(version)
+			continue
+		}
+		if found && strings.TrimSpace(line) != "" {
+			notes = append(notes, line)
+		}
+	}
+	return notes
+}
+
+// migrationNotes describes how settings saved by older versions
+// are updated when they are loaded.
+func migrationNotes(s *Saver) (notes []string) {
+	if saved, all := len(s.Kbinds), len(defaultKeys()); saved > 0 && saved < all {
+		notes = append(notes, fmt.Sprintf("%d new rebindable keys were given their default keys.", all-saved))
+	}
+	return notes
+}
+
+// changesToShow returns the lines of the changes overlay for the current
+// version, or nothing if the changes were already seen. The changes are
+// marked as seen when the overlay is closed. New players have nothing
+// to compare against, so they never see the changes.
+func changesToShow(s *Saver) []string {
+	if version == "" || s.Seen == version {
+		return nil
+	}
+	if _, err := os.Stat(s.File); os.IsNotExist(err) {
+		s.persistSeen(version)
+		return nil
+	}
+	notes := changeNotes(changeLog, version)
+	migrated := migrationNotes(s)
+	if len(notes) == 0 && len(migrated) == 0 {
+		s.persistSeen(version)
+		return nil
+	}
+	lines := []string{"What's new in Bampf " + release(version), ""}
+	lines = append(lines, notes...)
+	if len(migrated) > 0 {
+		lines = append(lines, "", "Updated settings")
+		for _, note := range migrated {
+			lines = append(lines, "  "+note)
+		}
+	}
+	return append(lines, "", "Click or press escape to continue.")
+}
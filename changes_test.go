@@ -0,0 +1,21 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestChangeNotes(t *testing.T) {
+	text := "# v1.0\nold\n# v1.1\nnew one\n\n  new two\n# v1.2\nnext\n"
+	if notes := changeNotes(text, "v1.1-4-g1a2b3c"); len(notes) != 2 || notes[1] != "  new two" {
+		t.Errorf("Expected the v1.1 notes, got %v", notes)
+	}
+	if notes := changeNotes(text, "v2.0"); len(notes) != 0 {
+		t.Errorf("Expected no notes for an unknown version, got %v", notes)
+	}
+	if got := release("v1.1-rc1"); got != "v1.1-rc1" {
+		t.Errorf("Expected a release version to be unchanged, got %s", got)
+	}
+}
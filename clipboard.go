@@ -0,0 +1,62 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// The clipboard lets share codes be copied from the end and launch
+// screens, and pasted into the launch screen, instead of retyped by hand.
+// Platforms without a clipboard adapter use a clipboard that can't copy
+// or paste, which leaves the share file as the only way to copy a code.
+//    osx  : see clipboard_darwin.go
+//    win  : see clipboard_windows.go
+
+import "errors"
+
+// clipboard is implemented by the platform clipboard adapters.
+type clipboard interface {
+	copy(text string) error // Return nil if the text was copied.
+	paste() (string, error) // Return the clipboard text, empty if there is none.
+}
+
+// errNoClipboard is returned when copying on platforms without a clipboard.
+var errNoClipboard = errors.New("no clipboard")
+
+// clipping is the clipboard text, or the error reading or writing it,
+// delivered by pasteLater and copyLater.
+type clipping struct {
+	text string // Clipboard text.
+	err  error  // Non-nil if the clipboard couldn't be read or written.
+}
+
+// copyLater writes the clipboard on its own goroutine, like pasteLater,
+// so that slow platform commands don't hold up the game loop. The copied
+// text, or the copy error, is sent on the returned channel once done.
+// Errors are left for the game loop to log.
+func copyLater(clip clipboard, text string) <-chan clipping {
+	copied := make(chan clipping, 1)
+	go func() {
+		copied <- clipping{text: text, err: clip.copy(text)}
+	}()
+	return copied
+}
+
+// pasteLater reads the clipboard on its own goroutine so that slow
+// platform commands don't hold up the game loop. The clipboard text
+// is sent on the returned channel once it has been read.
+func pasteLater(clip clipboard) <-chan clipping {
+	pasted := make(chan clipping, 1)
+	go func() {
+		text, err := clip.paste()
+		pasted <- clipping{text: text, err: err}
+	}()
+	return pasted
+}
+
+// noClipboard is the clipboard for platforms without a clipboard adapter.
+type noClipboard struct{}
+
+// copy implements clipboard by refusing to copy.
+func (nc noClipboard) copy(text string) error { return errNoClipboard }
+
+// paste implements clipboard by having nothing to paste.
+func (nc noClipboard) paste() (string, error) { return "", nil }
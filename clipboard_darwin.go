@@ -0,0 +1,29 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// pasteboard is the clipboard for OSX using the built in pbcopy
+// and pbpaste commands.
+type pasteboard struct{}
+
+// newClipboard returns the clipboard adapter for OSX.
+func newClipboard() clipboard { return pasteboard{} }
+
+// copy implements clipboard.
+func (pb pasteboard) copy(text string) error {
+	cmd := exec.Command("pbcopy")
+	cmd.Stdin = strings.NewReader(text)
+	return cmd.Run()
+}
+
+// paste implements clipboard.
+func (pb pasteboard) paste() (string, error) {
+	text, err := exec.Command("pbpaste").Output()
+	return strings.TrimSpace(string(text)), err
+}
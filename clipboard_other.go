@@ -0,0 +1,9 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+// +build !darwin,!windows
+
+package main
+
+// newClipboard returns the clipboard for platforms without an adapter.
+func newClipboard() clipboard { return noClipboard{} }
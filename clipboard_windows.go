@@ -0,0 +1,33 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// systemClipboard is the clipboard for Windows using the built in clip
+// command and PowerShell.
+type systemClipboard struct{}
+
+// newClipboard returns the clipboard adapter for Windows.
+func newClipboard() clipboard { return systemClipboard{} }
+
+// copy implements clipboard without showing a console window.
+func (sc systemClipboard) copy(text string) error {
+	cmd := exec.Command("clip")
+	cmd.Stdin = strings.NewReader(text)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	return cmd.Run()
+}
+
+// paste implements clipboard without showing a console window.
+func (sc systemClipboard) paste() (string, error) {
+	cmd := exec.Command("powershell", "-NoProfile", "-Command", "Get-Clipboard")
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	text, err := cmd.Output()
+	return strings.TrimSpace(string(text)), err
+}
@@ -0,0 +1,58 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Collecting cores in quick succession builds a combo. The combo multiplier
+// is shown as a coloured trail behind the player on the minimap and as a
+// tint on the health bar. The combo ends when the player is caught by a
+// sentinel or waits too long between cores.
+
+// Combo tuning.
+const (
+	comboWindow = 150 // Game ticks allowed between cores to keep a combo.
+	comboMax    = 5   // Largest combo multiplier.
+)
+
+// combo tracks the cores collected in quick succession.
+type combo struct {
+	chain int // Cores collected in the current combo.
+	ticks int // Game ticks left to collect the next core.
+}
+
+// fetch adds a collected core to the combo.
+func (c *combo) fetch() {
+	c.chain++
+	c.ticks = comboWindow
+}
+
+// tick ends the combo once the time to collect the next core runs out.
+func (c *combo) tick() {
+	if c.ticks > 0 {
+		c.ticks--
+		if c.ticks == 0 {
+			c.chain = 0
+		}
+	}
+}
+
+// hit ends the combo.
+func (c *combo) hit() { c.chain, c.ticks = 0, 0 }
+
+// multiplier returns the current combo multiplier. The combo is
+// active when the multiplier is more than 1.
+func (c *combo) multiplier() int {
+	switch {
+	case c.chain < 1:
+		return 1
+	case c.chain > comboMax:
+		return comboMax
+	}
+	return c.chain
+}
+
+// strength returns the combo intensity from 0, for no combo,
+// to 1 for the largest multiplier.
+func (c *combo) strength() float64 {
+	return float64(c.multiplier()-1) / float64(comboMax-1)
+}
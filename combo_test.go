@@ -0,0 +1,33 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestCombo(t *testing.T) {
+	c := &combo{}
+	if c.multiplier() != 1 || c.strength() != 0 {
+		t.Errorf("expected no combo, got %d", c.multiplier())
+	}
+	for cnt := 0; cnt < comboMax+2; cnt++ {
+		c.fetch()
+	}
+	if c.multiplier() != comboMax || c.strength() != 1 {
+		t.Errorf("expected the largest combo, got %d", c.multiplier())
+	}
+	for cnt := 0; cnt < comboWindow; cnt++ {
+		c.tick()
+	}
+	if c.multiplier() != 1 {
+		t.Errorf("expected the combo to time out, got %d", c.multiplier())
+	}
+	c.fetch()
+	c.fetch()
+	c.hit()
+	if c.multiplier() != 1 {
+		t.Errorf("expected a hit to end the combo, got %d", c.multiplier())
+	}
+}
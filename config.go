@@ -16,21 +16,28 @@ import (
 //     game screen  : allows the user to map keys or quit the level.
 //     end screen   : allows the user to map keys or return to the start screen.
 type config struct {
-	ui             *vu.Ent   // UI scene created at init.
-	area                     // Options fills up the full screen.
-	keys           []int     // Rebindable keys.
-	keysRebound    bool      // True if keys were changed.
-	mp             *bampf    // Main program.
-	bg             *vu.Ent   // Gray out the screen when options are up.
-	buttonGroup    *vu.Ent   // Part to group buttons.
-	buttons        []*button // Option buttons.
-	buttonSize     int       // Width and height of each button.
-	restart        *button   // Quit level button.
-	back           *button   // Back to game button.
-	info           *button   // Info/credits button.
-	mute           *button   // Mute toggle.
-	creditList     []*vu.Ent // The info model.
-	exitTransition int       // Transition to use when exiting config.
+	ui             *vu.Ent    // UI scene created at init.
+	area                      // Options fills up the full screen.
+	keys           []int      // Rebindable keys.
+	keysRebound    bool       // True if keys were changed.
+	mp             *bampf     // Main program.
+	bg             *vu.Ent    // Gray out the screen when options are up.
+	buttonGroup    *vu.Ent    // Part to group buttons.
+	buttons        []*button  // Option buttons.
+	buttonSize     int        // Width and height of each button.
+	restart        *button    // Quit level button.
+	back           *button    // Back to game button.
+	info           *button    // Info/credits button.
+	mute           *button    // Mute toggle.
+	toggles        []*toggle  // Optional feature settings.
+	rumble         *chooser   // Controller rumble intensity.
+	crosshair      []*chooser // Crosshair shape, size, and colour.
+	rules          *runStrip  // Conditions of the current run.
+	loss           *vu.Ent    // Sentinel hit cost on the current level.
+	overview       *vu.Ent    // Cores, sentinels, and maze size of the current level.
+	tip            *vu.Ent    // Cloak or teleport usage for the bar under the mouse.
+	about          *about     // Credits, version, and licenses overlay.
+	exitTransition int        // Transition to use when exiting config.
 }
 
 // options implements the screen interface.
@@ -41,8 +48,14 @@ func (c *config) activate(state int) {
 	switch state {
 	case screenActive:
 		c.keysRebound = false
+		if c.about.visible() {
+			c.about.toggle()
+		}
+		tint := getBackdrop(c.mp.backdrop).tint
+		c.bg.SetColor(float64(tint[0])*0.2, float64(tint[1])*0.2, float64(tint[2])*0.2)
 		c.ui.Cull(false)
 		c.ui.SetOver(2) // Draw the config screen over other overlays.
+		c.showRules()
 	case screenDeactive:
 		c.ui.Cull(true)
 	default:
@@ -52,19 +65,37 @@ func (c *config) activate(state int) {
 
 // User input to game events. Implements screen interface.
 func (c *config) processInput(in *vu.Input, eventq *list.List) {
+	if c.about.visible() {
+		c.aboutInput(in, eventq)
+		return
+	}
 	overIndex := c.hover(in.Mx, in.My) // per tick processing.
-	for press, down := range in.Down {
+	c.showTip(in.Mx, in.My)
+	for _, press := range c.mp.input.pressedKeys() {
 		switch {
-		case press == vu.KEsc && down == 1:
+		case press == vu.KEsc:
 			publish(eventq, toggleOptions, nil)
-		case overIndex >= 0 && down == 1:
+		case overIndex >= 0:
 			publish(eventq, rebindKey, rebindKeyEvent{index: overIndex, key: press})
-		case press == vu.KLm && down == 1:
+		case press == vu.KLm:
 			for _, btn := range c.buttons {
 				if btn.clicked(in.Mx, in.My) {
 					publish(eventq, btn.eventID, btn.eventData)
 				}
 			}
+			for _, t := range c.toggles {
+				if t.clicked(in.Mx, in.My) {
+					publish(eventq, toggleOption, t.id)
+				}
+			}
+			if c.rumble.clicked(in.Mx, in.My) {
+				publish(eventq, pickRumble, nil)
+			}
+			for cnt, ch := range c.crosshair {
+				if ch.clicked(in.Mx, in.My) {
+					publish(eventq, pickCrosshair, cnt)
+				}
+			}
 			switch {
 			case c.mute.clicked(in.Mx, in.My):
 				publish(eventq, c.mute.eventID, c.mute.eventData)
@@ -79,6 +110,13 @@ func (c *config) processInput(in *vu.Input, eventq *list.List) {
 	}
 }
 
+// aboutInput scrolls the about overlay. Any click or escape closes it.
+func (c *config) aboutInput(in *vu.Input, eventq *list.List) {
+	if c.about.handleInput(in, c.mp.input) {
+		publish(eventq, toggleAbout, nil)
+	}
+}
+
 // Process game events. Implements screen interface.
 func (c *config) processEvents(eventq *list.List) (transition int) {
 	for e := eventq.Front(); e != nil; e = e.Next() {
@@ -100,12 +138,37 @@ func (c *config) processEvents(eventq *list.List) (transition int) {
 				logf("options.processEvents: did not receive rebindKeyEvent")
 			}
 		case quitLevel:
+			c.mp.game.logOutcome(quitOutcome)
+			c.mp.game.shareRun()
+			c.mp.game.endRun()
 			c.mp.returnToMenu()
 			return chooseGame
-		case rollCredits:
-			c.rollCredits()
+		case toggleAbout:
+			c.about.toggle()
 		case toggleMute:
 			c.toggleMute()
+		case pickRumble:
+			c.mp.rumble = c.rumble.next()
+			c.mp.haptics.setIntensity(c.mp.rumble)
+			newSaver().persistRumble(c.mp.rumble)
+		case pickCrosshair:
+			if index, ok := event.data.(int); ok && index < len(c.crosshair) {
+				c.crosshair[index].next()
+				c.mp.crosshair = []string{}
+				for _, ch := range c.crosshair {
+					c.mp.crosshair = append(c.mp.crosshair, ch.choice())
+				}
+				c.mp.game.setCrosshair(c.mp.crosshair)
+				newSaver().persistCrosshair(c.mp.crosshair)
+			} else {
+				logf("options.processEvents: did not receive pickCrosshair index")
+			}
+		case toggleOption:
+			if id, ok := event.data.(string); ok {
+				c.toggleOption(id)
+			} else {
+				logf("options.processEvents: did not receive toggleOption id")
+			}
 		}
 
 	}
@@ -124,17 +187,7 @@ func newConfigScreen(mp *bampf, keys []int, ww, wh int) *config {
 	c.bg = c.ui.AddPart().SetAt(float64(c.cx), float64(c.cy), 0)
 	c.bg.SetScale(float64(c.w), float64(c.h), 1)
 	c.bg.MakeModel("colored", "msh:square", "mat:tblack")
-	c.keys = []int{ // rebindable key defaults.
-		vu.KW, // forwards
-		vu.KS, // backwards
-		vu.KA, // left
-		vu.KD, // right
-		vu.KC, // cloak
-		vu.KT, // teleport
-	}
-	if len(keys) == len(c.keys) { // override with saved keys.
-		c.keys = keys
-	}
+	c.keys = savedKeys(keys) // override defaults with saved keys.
 
 	// ensure that the game buttons always appear in the same location
 	// by mapping reaction ids to button positions.
@@ -144,7 +197,7 @@ func newConfigScreen(mp *bampf, keys []int, ww, wh int) *config {
 
 	// create the non-mappable buttons.
 	sz := c.buttonSize
-	c.info = newButton(c.buttonGroup, sz/2, "info", rollCredits, nil)
+	c.info = newButton(c.buttonGroup, sz/2, "info", toggleAbout, nil)
 	c.mute = newButton(c.buttonGroup, sz/2, "muteoff", toggleMute, nil)
 	c.mute.icon.Load("tex:muteon") // add second texture to button.
 	if c.mp.mute {
@@ -155,10 +208,86 @@ func newConfigScreen(mp *bampf, keys []int, ww, wh int) *config {
 	c.back.position(float64(c.w-20-c.back.w/2), 20) // bottom right corner
 	c.restart = newButton(c.buttonGroup, sz/2, "quit", quitLevel, nil)
 	c.restart.position(float64(c.cx), 20) // bottom center of screen.
+
+	// create the optional feature settings.
+	c.toggles = []*toggle{
+		newToggle(c.buttonGroup, presenceOption, "status export", mp.opts[presenceOption]),
+		newToggle(c.buttonGroup, coreExpiryOption, "core expiry", mp.opts[coreExpiryOption]),
+		newToggle(c.buttonGroup, safeFlashOption, "photo-sensitive mode", mp.opts[safeFlashOption]),
+		newToggle(c.buttonGroup, captionOption, "sound captions", mp.opts[captionOption]),
+		newToggle(c.buttonGroup, holdCloakOption, "hold to cloak", mp.opts[holdCloakOption]),
+		newToggle(c.buttonGroup, autoRunOption, "auto-run (R key)", mp.opts[autoRunOption]),
+		newToggle(c.buttonGroup, progressOption, "level progression", mp.opts[progressOption]),
+		newToggle(c.buttonGroup, quickEvolveOption, "skip evolve countdown", mp.opts[quickEvolveOption]),
+		newToggle(c.buttonGroup, timerOption, "speedrun timer", mp.opts[timerOption]),
+		newToggle(c.buttonGroup, streamOption, "stream HUD layout", mp.opts[streamOption]),
+		newToggle(c.buttonGroup, speechOption, "spoken events", mp.opts[speechOption]),
+		newToggle(c.buttonGroup, kinematicOption, "kinematic movement", mp.opts[kinematicOption]),
+		newToggle(c.buttonGroup, mirrorOption, "rear-view mirror", mp.opts[mirrorOption]),
+		newToggle(c.buttonGroup, freeMouseOption, "free mouse pointer", mp.opts[freeMouseOption]),
+		newToggle(c.buttonGroup, telemetryOption, "local telemetry", mp.opts[telemetryOption]),
+		newToggle(c.buttonGroup, reachOption, "core reach ring", mp.opts[reachOption]),
+		newToggle(c.buttonGroup, keepCoresOption, "keep dropped cores", mp.opts[keepCoresOption]),
+		newToggle(c.buttonGroup, quickResumeOption, "skip resume countdown", mp.opts[quickResumeOption]),
+		newToggle(c.buttonGroup, liveLevelsOption, "live nearby levels", mp.opts[liveLevelsOption]),
+	}
+	c.rumble = newChooser(c.buttonGroup, "rumble", rumbleSettings)
+	c.setRumble(mp.rumble)
+	c.crosshair = []*chooser{
+		newChooser(c.buttonGroup, "crosshair", crossShapes),
+		newChooser(c.buttonGroup, "crosshair size", crossSizes),
+		newChooser(c.buttonGroup, "crosshair colour", crossColours),
+	}
+	c.setCrosshair(mp.crosshair)
+	c.rules = newRunStrip(c.buttonGroup)
+	c.loss = c.buttonGroup.AddPart().MakeLabel("labeled", "lucidiaSu18")
+	c.overview = c.buttonGroup.AddPart().MakeLabel("labeled", "lucidiaSu18")
+	c.tip = c.buttonGroup.AddPart().MakeLabel("labeled", "lucidiaSu18")
+	c.tip.Cull(true)
+	c.about = newAbout(c.ui, credits)
+	c.about.resize(c.w, c.h)
+	c.layout()
 	c.ui.Cull(true)
 	return c
 }
 
+// defaultKeys returns the rebindable key defaults.
+func defaultKeys() []int {
+	return []int{
+		vu.KW, // forwards
+		vu.KS, // backwards
+		vu.KA, // left
+		vu.KD, // right
+		vu.KC, // cloak
+		vu.KT, // teleport
+		vu.KQ, // quick turn
+		vu.KX, // descend
+	}
+}
+
+// savedKeys returns the saved key bindings. Bindings saved before more
+// rebindable keys were added keep their keys and use the defaults for
+// the new keys. Unexpected bindings are replaced by the defaults.
+func savedKeys(keys []int) []int {
+	defaults := defaultKeys()
+	if len(keys) == 0 || len(keys) > len(defaults) {
+		return defaults
+	}
+	for cnt, key := range keys {
+		defaults[cnt] = key
+	}
+	return defaults
+}
+
+// setProfile shows the key bindings and options of a newly chosen profile.
+func (c *config) setProfile(keys []int) {
+	c.keys = savedKeys(keys)
+	c.labelButtons()
+	for _, t := range c.toggles {
+		t.set(c.mp.opts[t.id])
+	}
+}
+
 // handleResize repositions the visible elements when the user resizes the screen.
 func (c *config) handleResize(width, height int) {
 	c.x, c.y, c.w, c.h = 0, 0, width, height
@@ -167,6 +296,9 @@ func (c *config) handleResize(width, height int) {
 		c.bg.SetScale(float64(c.w), float64(c.h), 1)
 		c.bg.SetAt(float64(c.cx), float64(c.cy), 0)
 	}
+	if c.about != nil {
+		c.about.resize(c.w, c.h)
+	}
 	c.layout()
 }
 
@@ -179,6 +311,8 @@ func (c *config) createButtons() {
 	c.buttons[3] = newButton(c.buttonGroup, sz, "mRight", 0, nil)
 	c.buttons[4] = newButton(c.buttonGroup, sz, "cloak", 0, nil)
 	c.buttons[5] = newButton(c.buttonGroup, sz, "teleport", 0, nil)
+	c.buttons[6] = newButton(c.buttonGroup, sz, "turn", 0, nil)
+	c.buttons[7] = newButton(c.buttonGroup, sz, "descend", 0, nil)
 	c.labelButtons()
 	c.layout()
 }
@@ -191,6 +325,8 @@ func (c *config) labelButtons() {
 	c.buttons[3].label(c.buttonGroup, c.keys[3])
 	c.buttons[4].label(c.buttonGroup, c.keys[4])
 	c.buttons[5].label(c.buttonGroup, c.keys[5])
+	c.buttons[6].label(c.buttonGroup, c.keys[6])
+	c.buttons[7].label(c.buttonGroup, c.keys[7])
 }
 
 // layout positions the option screen buttons.
@@ -207,6 +343,8 @@ func (c *config) layout() {
 		c.buttons[3].position(cx1+dy, cy-dy)   // right
 		c.buttons[4].position(cx1-dy, cy-2*dy) // cloak
 		c.buttons[5].position(cx1+dy, cy-2*dy) // teleport
+		c.buttons[6].position(cx1, cy-2*dy)    // quick turn
+		c.buttons[7].position(cx1+dy, cy)      // descend
 	}
 	if c.restart != nil {
 		// top center of screen.
@@ -218,6 +356,15 @@ func (c *config) layout() {
 		c.info.position(30, float64(c.h)-20) // top left corner
 		c.mute.position(70, float64(c.h)-20) // top left corner
 	}
+	for cnt, t := range c.toggles {
+		t.position(20, float64(c.h)-float64(60+cnt*25)*textScale) // down the left side.
+	}
+	if c.rumble != nil {
+		c.rumble.position(20, float64(c.h)-float64(60+len(c.toggles)*25)*textScale) // below the toggles.
+	}
+	for cnt, ch := range c.crosshair {
+		ch.position(20, float64(c.h)-float64(60+(len(c.toggles)+1+cnt)*25)*textScale) // below the rumble.
+	}
 }
 
 // setExitTransition is called by lost so that closing the options
@@ -227,6 +374,67 @@ func (c *config) setExitTransition(transition int) {
 	c.restart.setVisible(c.exitTransition != chooseGame)
 }
 
+// showRules shows the conditions of the current run, the sentinel hit
+// cost, and an overview of the current level, in the bottom left corner.
+// There is no run when the options are opened from the launch screen.
+func (c *config) showRules() {
+	var rc *runConfig
+	inGame := c.exitTransition != chooseGame && c.mp.game.cl != nil
+	if inGame {
+		rc = c.mp.game.rules
+		scaleLabel(c.loss, 1).SetStr(c.mp.game.cl.describeLoss())
+		c.loss.SetAt(20, 20+(badgeHeight+badgeGap)*textScale, 0)
+		scaleLabel(c.overview, 1).SetStr(c.mp.game.cl.describeOverview())
+		c.overview.SetAt(20, 20+2*(badgeHeight+badgeGap)*textScale, 0)
+	}
+	c.loss.Cull(!inGame)
+	c.overview.Cull(!inGame)
+	c.rules.show(rc, 20, 20)
+}
+
+// showTip shows the cloak or teleport usage for the current run when
+// the mouse is over the matching energy bar of the paused game.
+func (c *config) showTip(mx, my int) {
+	text := ""
+	if c.exitTransition != chooseGame && c.mp.game.cl != nil && !c.mp.game.hudHidden {
+		xp, used := c.mp.game.cl.hd.xp, &c.mp.game.used
+		switch {
+		case xp.cbar.over(mx, my):
+			text = used.describeCloak()
+		case xp.tbar.over(mx, my):
+			text = used.describeTeleport()
+		}
+	}
+	if text != "" {
+		scaleLabel(c.tip, 1).SetStr(text)
+		w, _ := labelSize(c.tip)
+		c.tip.SetAt(clamp(float64(mx-w/2), 10, float64(c.w-w-10)), float64(my)+30, 0)
+	}
+	c.tip.Cull(text == "")
+}
+
+// setRumble shows the given rumble intensity. Unknown settings,
+// including no setting, show full intensity.
+func (c *config) setRumble(setting string) {
+	c.rumble.index = 0
+	for cnt := 0; cnt < len(rumbleSettings) && c.rumble.choice() != setting; cnt++ {
+		c.rumble.next()
+	}
+	c.rumble.show()
+}
+
+// setCrosshair shows the given crosshair shape, size, and colour.
+func (c *config) setCrosshair(style []string) {
+	style = crosshairStyle(style)
+	for cnt, ch := range c.crosshair {
+		ch.index = 0
+		for tries := 0; tries < len(ch.choices) && ch.choice() != style[cnt]; tries++ {
+			ch.next()
+		}
+		ch.show()
+	}
+}
+
 // rebindKey changes the key for a given reaction. If the newKey is already used,
 // then it's reaction is bound to the oldKey. Otherwise the oldKey is dropped.
 func (c *config) rebindKey(index int, key int) {
@@ -262,31 +470,13 @@ func (c *config) hover(mx, my int) int {
 	return -1
 }
 
-// hide or display game credits.
-func (c *config) rollCredits() {
-	credits := []string{
-		"@galvanizedlogic.com",
-		"rust",
-		"hymn",
-		"jazz",
-		"soap",
-	}
-	info := "Bampf " + version
-	credits = append(credits, info)
-	if c.creditList == nil {
-		c.creditList = []*vu.Ent{}
-		height := float64(45)
-		for _, credit := range credits {
-			banner := c.ui.AddPart().SetAt(20, height, 0)
-			banner.MakeLabel("labeled", "lucidiaSu18").SetStr(credit)
-			height += 18
-			c.creditList = append(c.creditList, banner)
-		}
-	} else {
-		for _, banner := range c.creditList {
-			banner.Cull(!banner.Culled())
-		}
-	}
+// credits lists the game developers.
+var credits = []string{
+	"@galvanizedlogic.com",
+	"rust",
+	"hymn",
+	"jazz",
+	"soap",
 }
 
 // toggleMute turns the game sound off or on.
@@ -298,3 +488,16 @@ func (c *config) toggleMute() {
 		c.mute.setIcon("muteoff")
 	}
 }
+
+// toggleOption flips the indicated optional feature and saves the
+// new setting.
+func (c *config) toggleOption(id string) {
+	for _, t := range c.toggles {
+		if t.id == id {
+			t.set(!t.on)
+			saver := newSaver()
+			saver.persistOption(t.id, t.on)
+			c.mp.setOption(t.id, t.on)
+		}
+	}
+}
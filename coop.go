@@ -0,0 +1,374 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Co-op is an experimental two player mode. It is turned on by a coop.json
+// data file that gives the local and partner network addresses and a maze
+// seed. Both games send their player state over UDP so that each player
+// sees the partner in the maze and on the minimap. Mazes are generated
+// from the shared seed and cores collected by either player count for both.
+// Partner cores are credited, not shared: only the partner core count is
+// sent, so a core the partner collects stays in the local maze.
+//
+// The connection is opened when a game starts and closed when the game is
+// quit, so removing coop.json between games turns co-op off.
+//
+// Race mode uses the same connection, but each player collects their own
+// cores. The HUD shows the rival progress and the first player to finish
+// the last level wins. A rival that stops responding is treated as having
+// left the race and the game carries on as a single player game.
+//
+// Either player can click the minimap, when the mouse pointer is free,
+// to ping a spot for the partner. Pings are sent as their own message
+// type and show for a few seconds on both minimaps and in both mazes.
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gazed/vu"
+)
+
+// CoopConfig is the co-op session read from coop.json. CoopConfig needs
+// to be public and visible for the encoding package.
+type CoopConfig struct {
+	Listen string `json:"listen"` // Local UDP address, eg: ":7777".
+	Peer   string `json:"peer"`   // Partner UDP address, eg: "10.0.0.2:7777".
+	Seed   int64  `json:"seed"`   // Maze seed shared by both players.
+	Mode   string `json:"mode"`   // Either "coop", the default, or "race".
+}
+
+// CoopState is the player state sent to the partner. CoopState needs
+// to be public and visible for the encoding package.
+type CoopState struct {
+	Level   int     `json:"level"`   // Current game level.
+	X       float64 `json:"x"`       // Player game location.
+	Z       float64 `json:"z"`       //   "
+	Cloaked bool    `json:"cloaked"` // True if the player is cloaked.
+	Cores   int     `json:"cores"`   // Cores collected on the current level.
+	Health  int     `json:"health"`  // Percent of the cells needed to evolve.
+	Won     bool    `json:"won"`     // True once the player finished the game.
+	Kind    string  `json:"kind"`    // Message type, empty for player state.
+}
+
+// coopPing is the message type for a minimap ping. Pings only use the
+// level and location fields.
+const coopPing = "ping"
+
+// coop sends the player state to the partner and keeps the latest
+// partner state. Partner states are received on a separate goroutine.
+type coop struct {
+	conn     *net.UDPConn  // Local connection.
+	peer     *net.UDPAddr  // Partner address.
+	seed     int64         // Shared maze seed.
+	race     bool          // True when racing instead of co-operating.
+	lost     bool          // True if the rival finished the race first.
+	last     time.Time     // Last time the player state was sent.
+	holdoff  time.Duration // Minimum time between sends.
+	credited map[int]int   // Partner cores already counted for each level.
+
+	mu      sync.Mutex  // Guards the partner state.
+	partner CoopState   // Latest partner state.
+	heard   time.Time   // When the latest partner state arrived.
+	pings   []CoopState // Partner pings not yet shown.
+}
+
+// newCoop starts a co-op session if there is a co-op data file.
+// Returns nil if co-op is not configured or the network is unavailable.
+func newCoop() *coop {
+	cfg := CoopConfig{}
+	if !loadData("coop.json", &cfg) {
+		return nil
+	}
+	local, err := net.ResolveUDPAddr("udp", cfg.Listen)
+	if err != nil {
+		logf("coop.json: bad listen address %s: %s", cfg.Listen, err)
+		return nil
+	}
+	peer, err := net.ResolveUDPAddr("udp", cfg.Peer)
+	if err != nil {
+		logf("coop.json: bad peer address %s: %s", cfg.Peer, err)
+		return nil
+	}
+	conn, err := net.ListenUDP("udp", local)
+	if err != nil {
+		logf("coop: failed to listen on %s: %s", cfg.Listen, err)
+		return nil
+	}
+	c := &coop{conn: conn, peer: peer, seed: cfg.Seed, race: cfg.Mode == "race"}
+	if c.seed == 0 {
+		c.seed = 1 // a zero seed generates random mazes.
+	}
+	c.holdoff = 50 * time.Millisecond
+	c.credited = map[int]int{}
+	go c.receive()
+	return c
+}
+
+// receive keeps the most recent partner state until the connection closes.
+func (c *coop) receive() {
+	buff := make([]byte, 1024)
+	for {
+		n, addr, err := c.conn.ReadFromUDP(buff)
+		if err != nil {
+			return // connection closed.
+		}
+		st := CoopState{}
+		if !addr.IP.Equal(c.peer.IP) || json.Unmarshal(buff[:n], &st) != nil {
+			continue // ignore strangers and garbage.
+		}
+		c.mu.Lock()
+		if st.Kind == coopPing {
+			c.keepPing(st)
+		} else {
+			c.partner, c.heard = st, time.Now()
+		}
+		c.mu.Unlock()
+	}
+}
+
+// close ends the session. The receive goroutine returns once
+// the connection is closed.
+func (c *coop) close() {
+	if err := c.conn.Close(); err != nil {
+		logf("coop: failed to close the connection: %s", err)
+	}
+}
+
+// send passes the player state to the partner. Sends are throttled
+// and failures are ignored since the next send replaces the state.
+func (c *coop) send(st CoopState) {
+	if time.Now().Before(c.last.Add(c.holdoff)) {
+		return
+	}
+	c.last = time.Now()
+	if bites, err := json.Marshal(st); err == nil {
+		c.conn.WriteToUDP(bites, c.peer)
+	}
+}
+
+// finish tells the partner that the player has finished the game.
+// The state is sent more than once, unthrottled, since the game stops
+// sending states once it is over.
+func (c *coop) finish(st CoopState) {
+	st.Won = true
+	if bites, err := json.Marshal(st); err == nil {
+		for cnt := 0; cnt < 3; cnt++ {
+			c.conn.WriteToUDP(bites, c.peer)
+		}
+	}
+}
+
+// ping sends a minimap ping to the partner. The ping is sent more than
+// once, unthrottled, since a lost ping isn't replaced by the next send.
+func (c *coop) ping(level int, x, z float64) {
+	if bites, err := json.Marshal(CoopState{Kind: coopPing, Level: level, X: x, Z: z}); err == nil {
+		for cnt := 0; cnt < 2; cnt++ {
+			c.conn.WriteToUDP(bites, c.peer)
+		}
+	}
+}
+
+// keepPing remembers a partner ping until it is taken. Only the latest
+// pingLimit pings are kept so that pings arriving while they are not
+// being taken can't pile up. Expected to be called with the lock held.
+func (c *coop) keepPing(st CoopState) {
+	if len(c.pings) >= pingLimit {
+		copy(c.pings, c.pings[1:])
+		c.pings = c.pings[:pingLimit-1]
+	}
+	c.pings = append(c.pings, st)
+}
+
+// takePings returns, and forgets, the partner pings received since the
+// last call. A ping sent twice is only returned once.
+func (c *coop) takePings() (pings []CoopState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, p := range c.pings {
+		if len(pings) == 0 || pings[len(pings)-1] != p {
+			pings = append(pings, p)
+		}
+	}
+	c.pings = nil
+	return pings
+}
+
+// latest returns the partner state. Returns false if nothing has been
+// heard from the partner recently.
+func (c *coop) latest() (st CoopState, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.partner, time.Since(c.heard) < coopTimeout
+}
+
+// coopTimeout is how long the partner is shown after the last update.
+const coopTimeout = 2 * time.Second
+
+// newCores returns the number of cores the partner has collected on the
+// given level that have not yet been counted. A partner count lower than
+// the counted cores means the partner has restarted the level.
+func (c *coop) newCores(st CoopState) int {
+	if st.Cores < c.credited[st.Level] {
+		c.credited[st.Level] = 0
+	}
+	cores := st.Cores - c.credited[st.Level]
+	c.credited[st.Level] = st.Cores
+	return cores
+}
+
+// resetCores forgets the counted partner cores when the player
+// restarts a level.
+func (c *coop) resetCores(level int) { c.credited[level] = 0 }
+
+// coop
+// ===========================================================================
+// game co-op handling.
+
+// syncCoop sends the player state to the partner, shows where the partner
+// is, and counts any cores collected by the partner on the same level.
+// Racing players keep their own cores and see the rival progress instead.
+func (g *game) syncCoop() {
+	if g.coop == nil {
+		return
+	}
+	g.coop.send(g.coopState())
+	st, ok := g.coop.latest()
+	sameLevel := ok && st.Level == g.cl.num
+	g.cl.showPartner(st.X, st.Z, st.Cloaked, sameLevel)
+	switch {
+	case g.coop.race:
+		g.cl.hd.showRival(g.coop.rivalStatus(st, ok))
+	case sameLevel:
+		for cnt := g.coop.newCores(st); cnt > 0; cnt-- {
+			g.cl.gainCore()
+		}
+	}
+	for _, p := range g.coop.takePings() {
+		if p.Level == g.cl.num {
+			g.cl.addPing(p.X, p.Z)
+		}
+	}
+}
+
+// pingEvent is the game location of a minimap ping placed by the player.
+type pingEvent struct {
+	x, z float64 // Game location.
+}
+
+// pingInput turns a click on the minimap into a ping for the partner.
+// The minimap can only be clicked when the mouse pointer is free.
+func (g *game) pingInput(in *vu.Input, eventq *list.List) {
+	if g.coop == nil || g.captured || !g.mp.input.pressed(vu.KLm) {
+		return
+	}
+	px, _, pz := g.cl.cam.At()
+	if x, z, ok := g.cl.hd.mm.gameAt(in.Mx, in.My, px, pz); ok {
+		publish(eventq, placePing, pingEvent{x, z})
+	}
+}
+
+// placePing shows the player ping and sends it to the partner.
+func (g *game) placePing(pe pingEvent) {
+	if g.coop != nil {
+		g.cl.addPing(pe.x, pe.z)
+		g.coop.ping(g.cl.num, pe.x, pe.z)
+	}
+}
+
+// coop pings
+// ===========================================================================
+// level ping handling.
+
+// Ping tuning.
+const (
+	pingLife  = 200  // Game ticks that a ping is shown.
+	pingAlpha = 0.6  // Transparency of a fresh ping beacon.
+	pingWidth = 0.06 // Ping beacon width in game units.
+	pingLimit = 4    // Most pings kept, or shown in the maze, at once.
+)
+
+// ping is a temporary beacon placed by either co-op player.
+type ping struct {
+	beacon *vu.Ent // Beam of light in the maze.
+	ticks  int     // Game ticks until the ping expires.
+}
+
+// addPing puts a beacon in the maze and a ring on the minimap
+// at the given game location. The oldest beacon is removed once
+// there are pingLimit beacons.
+func (lvl *level) addPing(x, z float64) {
+	if len(lvl.pings) >= pingLimit {
+		lvl.pings[0].beacon.Dispose()
+		lvl.pings = lvl.pings[1:]
+	}
+	beacon := lvl.scene.AddPart().SetAt(x, 1, z).SetScale(pingWidth, 1, pingWidth)
+	m := beacon.MakeModel("flata", "msh:cube", "mat:tgreen")
+	trackAsset(m, "mat:tgreen")
+	m.SetAlpha(pingAlpha).SetUniform("fd", lvl.fade)
+	lvl.pings = append(lvl.pings, &ping{beacon: beacon, ticks: pingLife})
+	lvl.mp.ani.addAnimation(lvl.hd.mm.newMarkAnimation(x, z))
+	lvl.player.play(markSound)
+}
+
+// updatePings fades the ping beacons and removes the expired pings.
+func (lvl *level) updatePings() {
+	for cnt := len(lvl.pings) - 1; cnt >= 0; cnt-- {
+		p := lvl.pings[cnt]
+		if p.ticks--; p.ticks <= 0 {
+			p.beacon.Dispose()
+			lvl.pings = append(lvl.pings[:cnt], lvl.pings[cnt+1:]...)
+			continue
+		}
+		p.beacon.SetAlpha(pingAlpha * float64(p.ticks) / pingLife)
+	}
+}
+
+// coopState is the current player state for the partner.
+func (g *game) coopState() CoopState {
+	x, _, z := g.cl.cam.At()
+	health, _, max := g.cl.player.health()
+	return CoopState{Level: g.cl.num, X: x, Z: z, Cloaked: g.cl.player.cloaked,
+		Cores: g.cl.fetched, Health: health * 100 / max}
+}
+
+// startCoop starts a new co-op session for a new game, closing any
+// session left over from the previous game.
+func (g *game) startCoop() {
+	g.stopCoop()
+	g.coop = newCoop()
+}
+
+// stopCoop closes the co-op session once the game is over.
+func (g *game) stopCoop() {
+	if g.coop != nil {
+		g.coop.close()
+		g.coop = nil
+	}
+}
+
+// finishCoop tells the partner the player has finished the game.
+func (g *game) finishCoop() {
+	if g.coop != nil {
+		g.coop.finish(g.coopState())
+	}
+}
+
+// rivalStatus describes the rival progress for the HUD. A rival that
+// finishes first wins the race, after which the player can still finish
+// the game alone.
+func (c *coop) rivalStatus(st CoopState, ok bool) string {
+	switch {
+	case c.lost || ok && st.Won:
+		c.lost = true
+		return "Rival won the race"
+	case !ok:
+		return "Rival disconnected, playing solo"
+	}
+	return fmt.Sprintf("Rival: level %d, %d%%", st.Level, st.Health)
+}
@@ -0,0 +1,74 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestCoopNewCores(t *testing.T) {
+	c := &coop{credited: map[int]int{}}
+	if cores := c.newCores(CoopState{Level: 1, Cores: 3}); cores != 3 {
+		t.Errorf("Expected 3 new cores, got %d", cores)
+	}
+	if cores := c.newCores(CoopState{Level: 1, Cores: 3}); cores != 0 {
+		t.Errorf("Expected cores to be counted once, got %d", cores)
+	}
+	if cores := c.newCores(CoopState{Level: 1, Cores: 1}); cores != 1 {
+		t.Errorf("Expected a restarted level to count again, got %d", cores)
+	}
+}
+
+func TestRivalStatus(t *testing.T) {
+	c := &coop{race: true}
+	if status := c.rivalStatus(CoopState{Level: 2, Health: 45}, true); status != "Rival: level 2, 45%" {
+		t.Errorf("Expected rival progress, got %q", status)
+	}
+	if status := c.rivalStatus(CoopState{}, false); status != "Rival disconnected, playing solo" {
+		t.Errorf("Expected disconnect, got %q", status)
+	}
+	c.rivalStatus(CoopState{Level: 4, Won: true}, true)
+	if status := c.rivalStatus(CoopState{}, false); status != "Rival won the race" {
+		t.Errorf("Expected the race to stay lost, got %q", status)
+	}
+}
+
+func TestCoopPings(t *testing.T) {
+	c := &coop{}
+	p := CoopState{Kind: coopPing, Level: 2, X: 4, Z: -6}
+	c.pings = []CoopState{p, p, {Kind: coopPing, Level: 2, X: 8}}
+	if pings := c.takePings(); len(pings) != 2 || pings[0] != p {
+		t.Errorf("Expected repeated pings to be shown once, got %v", pings)
+	}
+	if pings := c.takePings(); len(pings) != 0 {
+		t.Errorf("Expected pings to be taken once, got %v", pings)
+	}
+	for cnt := 0; cnt < pingLimit*3; cnt++ {
+		c.keepPing(CoopState{Kind: coopPing, X: float64(cnt)})
+	}
+	if pings := c.takePings(); len(pings) != pingLimit || pings[pingLimit-1].X != pingLimit*3-1 {
+		t.Errorf("Expected only the latest pings to be kept, got %v", pings)
+	}
+}
+
+func TestCoopClose(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Skipf("No local network: %s", err)
+	}
+	c := &coop{conn: conn, peer: conn.LocalAddr().(*net.UDPAddr)}
+	done := make(chan bool)
+	go func() {
+		c.receive()
+		done <- true
+	}()
+	c.close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("Expected receive to stop once the connection is closed")
+	}
+}
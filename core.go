@@ -6,23 +6,28 @@ package main
 // Energy core related code is grouped here.
 
 import (
+	"math"
 	"math/rand"
 	"time"
 
+	"github.com/gazed/bampf/gridmath"
 	"github.com/gazed/vu"
 )
 
 // coreControl tracks available core drop locations and regulates how fast
 // new cores appear.
 type coreControl struct {
-	cores   []*vu.Ent     // cores available to be collected.
-	tiles   []gridSpot    // core drop locations.
-	saved   []gridSpot    // remember the core drop locations for resets.
-	last    time.Time     // last time a core was dropped.
-	holdoff time.Duration // time delay between core drops.
-	units   float64       // eng.Units injected on creation is...
-	spot    *gridSpot     // ...used to translate between grid and game coordinates.
-	ani     *animator     // Handles short animations.
+	cores  []*vu.Ent       // cores available to be collected.
+	born   []int           // drop tick for each of the cores.
+	now    int             // game ticks counted while the level is played.
+	tiles  []gridmath.Spot // core drop locations.
+	saved  []gridmath.Spot // remember the core drop locations for resets.
+	next   int             // game tick of the next core drop.
+	units  float64         // eng.Units injected on creation is...
+	spot   *gridmath.Spot  // ...used to translate between grid and game coordinates.
+	ani    *animator       // Handles short animations.
+	freeze *vu.Ent         // Rare freeze pickup, nil when not dropped.
+	rng    *rand.Rand      // Drop randomness, seeded per level so replays match.
 }
 
 // newCoreControl returns an initialized coreControl structure.
@@ -31,20 +36,44 @@ func newCoreControl(units int, ani *animator) *coreControl {
 	cc.ani = ani
 	cc.units = float64(units)
 	cc.cores = []*vu.Ent{}
-	cc.saved = []gridSpot{}
-	cc.tiles = []gridSpot{}
-	cc.spot = &gridSpot{}
-	cc.holdoff, _ = time.ParseDuration("200ms")
+	cc.born = []int{}
+	cc.saved = []gridmath.Spot{}
+	cc.tiles = []gridmath.Spot{}
+	cc.spot = &gridmath.Spot{}
+	cc.next = coreHoldoff
+	cc.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
 	return cc
 }
 
-// timeToDrop regulates how fast the new cores appear.
-func (cc *coreControl) timeToDrop() bool {
-	if time.Now().After(cc.last.Add(cc.holdoff)) {
-		cc.last = time.Now()
-		return true
+// coreHoldoff is the number of game ticks before the first core drop.
+const coreHoldoff = 10
+
+// reseed restarts the drop randomness from the given seed so that runs
+// on the same mazes see the same drop spots. Sentinels use their own
+// level randomness so that they don't change the drop sequence.
+func (cc *coreControl) reseed(seed int64) {
+	cc.rng = rand.New(rand.NewSource(seed))
+}
+
+// timeToDrop regulates how fast the new cores appear. The given delay
+// is used as the game ticks until the following drop. Drops are counted
+// in game ticks so that they pause with the game.
+func (cc *coreControl) timeToDrop(delay int) bool {
+	if cc.now < cc.next {
+		return false
 	}
-	return false
+	cc.next = cc.now + delay
+	return true
+}
+
+// dropDelay is the game ticks between core drops for the given pacing.
+// The delay shrinks as more cores are needed and grows as the player
+// moves out from the maze center. Reach is the player distance from the
+// center as a fraction of the distance to the maze edge.
+func dropDelay(p PacingDef, coresNeeded int, reach float64) int {
+	ms := float64(p.Delay) / (1 + p.Deficit*float64(coresNeeded))
+	ms *= 1 + p.Distance*math.Min(reach, 1)
+	return int(ms * gameTickRate / 1000)
 }
 
 // canDrop is called to determine if a new core could/should be dropped.
@@ -54,12 +83,19 @@ func (cc *coreControl) canDrop(coresNeeded int) bool {
 	return len(cc.cores) < coresNeeded && len(cc.tiles) > 0
 }
 
-// dropSpot picks a random free core drop location. Return the potential
-// gridx, gridy drop location
-func (cc *coreControl) dropSpot() (gridx, gridy int) {
-	index := rand.Intn(len(cc.tiles))
-	spot := cc.tiles[index]
-	return spot.x, spot.y
+// dropSpot picks a random free core drop location. The farthest from
+// the player of the given number of random locations is used so that
+// drops cluster away from the player. Return the potential gridx, gridy
+// drop location
+func (cc *coreControl) dropSpot(px, py, picks int) (gridx, gridy int) {
+	far := -1
+	for cnt := 0; cnt < picks || far < 0; cnt++ {
+		spot := cc.tiles[cc.rng.Intn(len(cc.tiles))]
+		if dist := (spot.X-px)*(spot.X-px) + (spot.Y-py)*(spot.Y-py); dist > far {
+			far, gridx, gridy = dist, spot.X, spot.Y
+		}
+	}
+	return gridx, gridy
 }
 
 // dropCore creates a new core. Create it high so that it drops.
@@ -67,15 +103,7 @@ func (cc *coreControl) dropSpot() (gridx, gridy int) {
 func (cc *coreControl) dropCore(pov *vu.Ent, fade float64, gridx, gridy int) (gamex, gamez float64) {
 
 	// remove the dropped spot from the list of available spots.
-	removed := false // sanity check.
-	for index, xy := range cc.tiles {
-		if gridx == xy.x && gridy == xy.y {
-			cc.tiles = append(cc.tiles[:index], cc.tiles[index+1:]...)
-			removed = true
-			break
-		}
-	}
-	if !removed {
+	if !cc.takeTile(gridx, gridy) {
 		logf("core.dropCore: failed to locate what should be a valid drop location")
 		return 0, 0
 	}
@@ -83,37 +111,95 @@ func (cc *coreControl) dropCore(pov *vu.Ent, fade float64, gridx, gridy int) (ga
 
 	// add the core to the list of dropped cores.
 	cc.cores = append(cc.cores, core)
-	gamex, gamez = toGame(gridx, gridy, cc.units)
+	cc.born = append(cc.born, cc.now)
+	gamex, gamez = gridmath.ToGame(gridx, gridy, cc.units)
 	core.SetAt(gamex, 10, gamez) // start high and animate drop to floor level.
 	cc.ani.addAnimation(&coreDropAnimation{core: core})
 	return gamex, gamez
 }
 
+// takeTile removes the given grid location from the available drop spots.
+// Return false if the location was not available.
+func (cc *coreControl) takeTile(gridx, gridy int) bool {
+	for index, xy := range cc.tiles {
+		if gridx == xy.X && gridy == xy.Y {
+			cc.tiles = append(cc.tiles[:index], cc.tiles[index+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
 // remCore destroys the indicated core. The drop spot is now available for new
 // cores. Return the game location of the removed core.
 func (cc *coreControl) remCore(index int) (gamex, gamez float64) {
-	core := cc.cores[index]
+	core, gamex, gamez := cc.takeCore(index)
+	core.Dispose()
+	return gamex, gamez
+}
+
+// takeCore removes the indicated core from the list of dropped cores
+// without disposing it. Return the core and its game location.
+func (cc *coreControl) takeCore(index int) (core *vu.Ent, gamex, gamez float64) {
+	core = cc.cores[index]
 	cc.cores = append(cc.cores[:index], cc.cores[index+1:]...)
+	cc.born = append(cc.born[:index], cc.born[index+1:]...)
 
-	// remove the core from the display and minimap.
+	// make the tile available for a new drop. Use the old core location.
 	gamex, _, gamez = core.At()
-	gridx, gridy := toGrid(gamex, 0, gamez, cc.units)
-	core.Dispose()
+	gridx, gridy := gridmath.ToGrid(gamex, 0, gamez, cc.units)
+	cc.tiles = append(cc.tiles, gridmath.Spot{X: gridx, Y: gridy})
+	return core, gamex, gamez
+}
 
-	// make the tile available for a new drop. Use the old core location.
-	cc.tiles = append(cc.tiles, gridSpot{gridx, gridy})
+// tick ages the dropped cores by one game tick. Expected to be called
+// each level update so that cores don't age while the game is paused.
+func (cc *coreControl) tick() { cc.now++ }
+
+// expired returns the index of a core that has been dropped for longer
+// than the given lifetime in game ticks. Cores in the last quarter of
+// their lifetime blink as a warning. Return -1 if no cores have expired
+// or if the lifetime is 0, meaning cores last forever.
+func (cc *coreControl) expired(life int) (coreIndex int) {
+	if life <= 0 {
+		return -1
+	}
+	warn := life - life/4
+	for index, born := range cc.born {
+		age := cc.now - born
+		switch {
+		case age > life:
+			cc.cores[index].Cull(false)
+			return index
+		case age > warn:
+			blink := age/coreBlink%2 == 0
+			cc.cores[index].Cull(blink)
+		}
+	}
+	return -1
+}
+
+// coreBlink is the game ticks for each on or off blink of an expiring core.
+const coreBlink = 12
+
+// expireCore removes the indicated core, returning its drop spot to the
+// drop pool, and fades it away. Return the game location of the core.
+func (cc *coreControl) expireCore(index int) (gamex, gamez float64) {
+	core, gamex, gamez := cc.takeCore(index)
+	cc.ani.addAnimation(&coreExpireAnimation{core: core})
 	return gamex, gamez
 }
 
 // hitCore returns the core index if the given location is in the same grid location
-// as a core. Return -1 if no core was hit.
-func (cc *coreControl) hitCore(gamex, gamez float64) (coreIndex int) {
+// as a core, or within reach of a core. Return -1 if no core was hit.
+func (cc *coreControl) hitCore(gamex, gamez, reach float64) (coreIndex int) {
 	coreIndex = -1
-	gridx, gridy := toGrid(gamex, 0, gamez, cc.units)
+	gridx, gridy := gridmath.ToGrid(gamex, 0, gamez, cc.units)
 	for index, core := range cc.cores {
 		x, y, z := core.At()
-		corex, corey := toGrid(x, y, z, cc.units)
-		if gridx == corex && gridy == corey {
+		corex, corey := gridmath.ToGrid(x, y, z, cc.units)
+		near := (x-gamex)*(x-gamex)+(z-gamez)*(z-gamez) <= reach*reach
+		if near || gridx == corex && gridy == corey {
 			coreIndex = index
 			break
 		}
@@ -121,11 +207,59 @@ func (cc *coreControl) hitCore(gamex, gamez float64) (coreIndex int) {
 	return coreIndex
 }
 
+// nearest returns the x, z game offset from the given game location to
+// the closest core. Returns false if there are no cores.
+func (cc *coreControl) nearest(gamex, gamez float64) (dx, dz float64, ok bool) {
+	best := math.MaxFloat64
+	for _, core := range cc.cores {
+		x, _, z := core.At()
+		if dist := (x-gamex)*(x-gamex) + (z-gamez)*(z-gamez); dist < best {
+			best, dx, dz, ok = dist, x-gamex, z-gamez, true
+		}
+	}
+	return dx, dz, ok
+}
+
 // addDropAt adds a spot where cores are allowed to be dropped.
 // The coordinates are specified in grid coordinates.
 func (cc *coreControl) addDropAt(gridx, gridy int) {
-	cc.saved = append(cc.saved, gridSpot{gridx, gridy})
-	cc.tiles = append(cc.tiles, gridSpot{gridx, gridy})
+	cc.saved = append(cc.saved, gridmath.Spot{X: gridx, Y: gridy})
+	cc.tiles = append(cc.tiles, gridmath.Spot{X: gridx, Y: gridy})
+}
+
+// dropped returns the grid locations of the cores that are on the ground.
+func (cc *coreControl) dropped() (spots []gridmath.Spot) {
+	for _, core := range cc.cores {
+		x, y, z := core.At()
+		gridx, gridy := gridmath.ToGrid(x, y, z, cc.units)
+		spots = append(spots, gridmath.Spot{X: gridx, Y: gridy})
+	}
+	return spots
+}
+
+// isTile returns true if cores can be dropped at the given grid location.
+func (cc *coreControl) isTile(gridx, gridy int) bool {
+	for _, xy := range cc.tiles {
+		if xy.X == gridx && xy.Y == gridy {
+			return true
+		}
+	}
+	return false
+}
+
+// remDropAt stops cores from being dropped at the given grid spot.
+func (cc *coreControl) remDropAt(gridx, gridy int) {
+	at := gridmath.Spot{X: gridx, Y: gridy}
+	for cnt := len(cc.saved) - 1; cnt >= 0; cnt-- {
+		if cc.saved[cnt] == at {
+			cc.saved = append(cc.saved[:cnt], cc.saved[cnt+1:]...)
+		}
+	}
+	for cnt := len(cc.tiles) - 1; cnt >= 0; cnt-- {
+		if cc.tiles[cnt] == at {
+			cc.tiles = append(cc.tiles[:cnt], cc.tiles[cnt+1:]...)
+		}
+	}
 }
 
 // reset puts the core control back to the initial conditions before cores
@@ -135,10 +269,15 @@ func (cc *coreControl) reset() {
 	for _, core := range cc.cores {
 		core.Dispose()
 	}
+	if cc.freeze != nil {
+		cc.freeze.Dispose()
+		cc.freeze = nil
+	}
 	cc.cores = []*vu.Ent{}
-	cc.tiles = []gridSpot{}
+	cc.born = []int{}
+	cc.tiles = []gridmath.Spot{}
 	for _, spot := range cc.saved {
-		cc.tiles = append(cc.tiles, gridSpot{spot.x, spot.y})
+		cc.tiles = append(cc.tiles, gridmath.Spot{X: spot.X, Y: spot.Y})
 	}
 }
 
@@ -152,36 +291,45 @@ func (cc *coreControl) createCore(core *vu.Ent, fade float64) *vu.Ent {
 	return core
 }
 
-// coreControl
-// ===========================================================================
-// gridSpot is used by coreControl and sentinel.
-
-// gridSpot is used to track grid locations. It can be used to store grid
-// locations and to convert back and forth between grid and game locations.
-type gridSpot struct{ x, y int }
-
-// toGame takes a grid location and translates into a game location.
-// Game locations are where models of cores, walls, and tiles are placed.
-func toGame(gridx, gridy int, units float64) (gamex, gamez float64) {
-	return float64(gridx) * units, float64(-gridy) * units
+// dropFreeze drops a freeze pickup, with the given chance, at a free drop
+// location. Only one freeze pickup is dropped at a time. Return true and
+// the game location of the pickup if one was dropped.
+func (cc *coreControl) dropFreeze(scene *vu.Ent, fade, chance float64) (gamex, gamez float64, ok bool) {
+	if cc.freeze != nil || len(cc.tiles) == 0 || cc.rng.Float64() >= chance {
+		return 0, 0, false
+	}
+	gridx, gridy := cc.dropSpot(0, 0, 1)
+	cc.takeTile(gridx, gridy)
+	cc.freeze = scene.AddPart().SetScale(0.2, 0.2, 0.2)
+	cc.freeze.MakeModel("flata", "msh:cube", "mat:white").SetUniform("fd", fade)
+	gamex, gamez = gridmath.ToGame(gridx, gridy, cc.units)
+	cc.freeze.SetAt(gamex, 10, gamez) // drops like a core.
+	cc.ani.addAnimation(&coreDropAnimation{core: cc.freeze})
+	return gamex, gamez, true
 }
 
-// toGrid takes the current game location and translates into a grid location.
-// Grid locations are where cores are dropped or fetched.
-func toGrid(gamex, gamey, gamez, units float64) (gridx, gridy int) {
-	inv := 1.0 / units
-	adj := units * 0.5
-	xadj := adj
-	if gamex < 0 {
-		xadj = -xadj
-	}
-	yadj := adj
-	if gamez > 0 {
-		yadj = -yadj
+// hitFreeze returns true if the given location is in the same grid
+// location as the freeze pickup.
+func (cc *coreControl) hitFreeze(gamex, gamez float64) bool {
+	if cc.freeze == nil {
+		return false
 	}
-	return int((gamex + xadj) * inv), int((-gamez + yadj) * inv)
+	gridx, gridy := gridmath.ToGrid(gamex, 0, gamez, cc.units)
+	x, y, z := cc.freeze.At()
+	fx, fy := gridmath.ToGrid(x, y, z, cc.units)
+	return gridx == fx && gridy == fy
 }
 
+// remFreeze destroys the freeze pickup and makes its drop spot available.
+func (cc *coreControl) remFreeze() {
+	x, y, z := cc.freeze.At()
+	gridx, gridy := gridmath.ToGrid(x, y, z, cc.units)
+	cc.tiles = append(cc.tiles, gridmath.Spot{X: gridx, Y: gridy})
+	cc.freeze.Dispose()
+	cc.freeze = nil
+}
+
+// coreControl
 // ===========================================================================
 // coreDropAnimation
 
@@ -222,10 +370,55 @@ func (ca *coreDropAnimation) Animate(dt float64) bool {
 }
 
 // Wrap finishes the core drop by ensuring the core is at its
-// final location.
+// final location. The landing sound is played from the core so that
+// players can hear where it landed.
 func (ca *coreDropAnimation) Wrap() {
-	if ca.core.Exists() {
+	if ca.state != 2 && ca.core.Exists() {
 		ca.core.SetAt(ca.x, ca.rest, ca.z)
+		sounds.play(ca.core, dropSound)
+	}
+	ca.state = 2
+}
+
+// ===========================================================================
+// coreExpireAnimation
+
+// coreExpireAnimation shrinks and fades expired cores before they are
+// disposed.
+type coreExpireAnimation struct {
+	core  *vu.Ent // core to animate.
+	ticks int     // how many game ticks to animate.
+	tkcnt int     // current step.
+	state int     // track progress 0:start, 1:run, 2:done.
+}
+
+// Animate implements animation. Shrink the core.
+func (ca *coreExpireAnimation) Animate(dt float64) bool {
+	switch ca.state {
+	case 0:
+		ca.ticks = 25
+		ca.state = 1
+		return true
+	case 1:
+		if ca.tkcnt < ca.ticks && ca.core.Exists() {
+			ca.tkcnt++
+			ratio := 1 - float64(ca.tkcnt)/float64(ca.ticks)
+			scale := 0.25 * ratio
+			ca.core.SetScale(scale, scale, scale)
+			ca.core.SetAlpha(0.6 * ratio)
+			return true // animation not done.
+		}
+		ca.Wrap()
+		return false // animation done.
+	default:
+		return false // animation done.
+	}
+}
+
+// Wrap finishes the animation by disposing the core.
+func (ca *coreExpireAnimation) Wrap() {
+	if ca.state != 2 && ca.core.Exists() {
+		ca.core.Dispose()
 	}
 	ca.state = 2
 }
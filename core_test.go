@@ -5,58 +5,58 @@ package main
 
 import (
 	"testing"
+
+	"github.com/gazed/bampf/gridmath"
 )
 
-func TestToGrid(t *testing.T) {
-	units := 2.0
-	gridx, gridy := toGrid(-0.9, 0, -0.9, units)
-	if gridx != 0 || gridy != 0 {
-		t.Errorf("Expected 0,0 got %d,%d", gridx, gridy)
-	}
-	gridx, gridy = toGrid(0.9, 0, 0.9, units)
-	if gridx != 0 || gridy != 0 {
-		t.Errorf("Expected 0,0 got %d,%d", gridx, gridy)
+func TestDropDelay(t *testing.T) {
+	p := PacingDef{Delay: 200, Deficit: 0.5, Distance: 1}
+	if d := dropDelay(p, 0, 0); d != 10 {
+		t.Errorf("Expected 10 ticks got %d", d)
 	}
-	gridx, gridy = toGrid(-0.9, 0, 0.9, units)
-	if gridx != 0 || gridy != 0 {
-		t.Errorf("Expected 0,0 got %d,%d", gridx, gridy)
+	if d := dropDelay(p, 2, 0); d != 5 {
+		t.Errorf("Expected 5 ticks got %d", d)
 	}
-	gridx, gridy = toGrid(0.9, 0, -0.9, units)
-	if gridx != 0 || gridy != 0 {
-		t.Errorf("Expected 0,0 got %d,%d", gridx, gridy)
+	if d := dropDelay(p, 0, 2); d != 20 { // reach is capped.
+		t.Errorf("Expected 20 ticks got %d", d)
 	}
+}
 
-	gridx, gridy = toGrid(1.01, 0, -1.01, units)
-	if gridx != 1 || gridy != 1 {
-		t.Errorf("Expected 1,1 got %d,%d", gridx, gridy)
-	}
-	gridx, gridy = toGrid(2.99, 0, -2.99, units)
-	if gridx != 1 || gridy != 1 {
-		t.Errorf("Expected 1,1 got %d,%d", gridx, gridy)
-	}
-	gridx, gridy = toGrid(1.01, 0, -2.99, units)
-	if gridx != 1 || gridy != 1 {
-		t.Errorf("Expected 1,1 got %d,%d", gridx, gridy)
-	}
-	gridx, gridy = toGrid(2.99, 0, -1.01, units)
-	if gridx != 1 || gridy != 1 {
-		t.Errorf("Expected 1,1 got %d,%d", gridx, gridy)
+func TestDropSpot(t *testing.T) {
+	cc := newCoreControl(2, nil)
+	cc.tiles = []gridmath.Spot{{X: 1, Y: 1}, {X: 9, Y: 9}}
+	for cnt := 0; cnt < 10; cnt++ {
+		if x, y := cc.dropSpot(0, 0, 20); x != 9 || y != 9 {
+			t.Errorf("Expected 9,9 got %d,%d", x, y)
+		}
 	}
+}
 
-	gridx, gridy = toGrid(-1.01, 0, 1.01, units)
-	if gridx != -1 || gridy != -1 {
-		t.Errorf("Expected -1,-1 got %d,%d", gridx, gridy)
-	}
-	gridx, gridy = toGrid(-2.99, 0, 2.99, units)
-	if gridx != -1 || gridy != -1 {
-		t.Errorf("Expected -1,-1 got %d,%d", gridx, gridy)
-	}
-	gridx, gridy = toGrid(-1.01, 0, 2.99, units)
-	if gridx != -1 || gridy != -1 {
-		t.Errorf("Expected -1,-1 got %d,%d", gridx, gridy)
+func TestDropSeed(t *testing.T) {
+	first, second := newCoreControl(2, nil), newCoreControl(2, nil)
+	for x := 0; x < 20; x++ {
+		first.addDropAt(x, x)
+		second.addDropAt(x, x)
+	}
+	first.reseed(42)
+	second.reseed(42)
+	for cnt := 0; cnt < 10; cnt++ {
+		fx, fy := first.dropSpot(0, 0, 3)
+		sx, sy := second.dropSpot(0, 0, 3)
+		if fx != sx || fy != sy {
+			t.Fatalf("Expected the same seed to pick the same drop spots")
+		}
 	}
-	gridx, gridy = toGrid(-2.99, 0, 1.01, units)
-	if gridx != -1 || gridy != -1 {
-		t.Errorf("Expected -1,-1 got %d,%d", gridx, gridy)
+}
+
+func TestIsTile(t *testing.T) {
+	cc := newCoreControl(2, nil)
+	cc.addDropAt(1, 2)
+	if !cc.isTile(1, 2) || cc.isTile(2, 1) {
+		t.Errorf("Expected only 1,2 to be a drop spot")
+	}
+	cc.takeTile(1, 2)
+	if cc.isTile(1, 2) {
+		t.Errorf("Expected taken spot to be unavailable")
 	}
 }
@@ -0,0 +1,200 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Cracked walls are a few special maze walls that separate two corridors.
+// Teleporting away from beside a cracked wall weakens it, and the second
+// teleport breaks it open to make a shortcut. Broken walls lose their
+// physics body, disappear from the minimap, and burst into debris.
+// Cracked walls are only placed with the hazards mutator.
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/gazed/bampf/gridmath"
+	"github.com/gazed/vu"
+	"github.com/gazed/vu/grid"
+)
+
+// crackHits is the number of adjacent teleports that break a cracked wall.
+const crackHits = 2
+
+// crack is a cracked wall along with the number of times it was hit.
+type crack struct {
+	wall *vu.Ent // Wall model and physics body.
+	hits int     // Adjacent teleports so far.
+}
+
+// crackSpots picks up to count walls that separate two corridors,
+// spread evenly through the maze. The same plan always gives the same
+// walls so co-op and daily challenge players see the same cracks.
+func crackSpots(plan grid.Grid, count int) (spots []gridmath.Spot) {
+	width, height := plan.Size()
+	candidates := []gridmath.Spot{}
+	for x := 1; x < width-1; x++ {
+		for y := 1; y < height-1; y++ {
+			if plan.IsOpen(x, y) {
+				continue
+			}
+			across := plan.IsOpen(x-1, y) && plan.IsOpen(x+1, y) && !plan.IsOpen(x, y-1) && !plan.IsOpen(x, y+1)
+			down := plan.IsOpen(x, y-1) && plan.IsOpen(x, y+1) && !plan.IsOpen(x-1, y) && !plan.IsOpen(x+1, y)
+			if across || down {
+				candidates = append(candidates, gridmath.Spot{X: x, Y: y})
+			}
+		}
+	}
+	if count > len(candidates) {
+		count = len(candidates)
+	}
+	for cnt := 0; cnt < count; cnt++ {
+		spots = append(spots, candidates[(cnt+1)*len(candidates)/(count+1)])
+	}
+	return spots
+}
+
+// crackedPlan is a floorplan where broken walls are open.
+// It implements grid.Grid so that sentinels, movement, and
+// the map export all see the shortcuts.
+type crackedPlan struct {
+	grid.Grid                        // Original floorplan.
+	broken    map[gridmath.Spot]bool // Broken cracked walls.
+}
+
+// IsOpen returns true for floors and broken walls.
+func (cp *crackedPlan) IsOpen(x, y int) bool {
+	return cp.broken[gridmath.Spot{X: x, Y: y}] || cp.Grid.IsOpen(x, y)
+}
+
+// center passes on the custom maze center, if any, so that
+// layoutPlan puts the center in the same place.
+func (cp *crackedPlan) center() (x, y int) {
+	if c, ok := cp.Grid.(interface {
+		center() (x, y int)
+	}); ok {
+		return c.center()
+	}
+	width, height := cp.Size()
+	return width / 2, height / 2
+}
+
+// crackedPlan
+// ===========================================================================
+// level cracked wall handling.
+
+// hitCracks weakens any cracked walls beside the player. Expected to
+// be called as the player teleports away.
+func (lvl *level) hitCracks() {
+	x, y, z := lvl.body.At()
+	gx, gy := gridmath.ToGrid(x, y, z, float64(lvl.units))
+	for _, spot := range []gridmath.Spot{{X: gx - 1, Y: gy}, {X: gx + 1, Y: gy}, {X: gx, Y: gy - 1}, {X: gx, Y: gy + 1}} {
+		if c, ok := lvl.cracks[spot]; ok {
+			if c.hits++; c.hits >= crackHits {
+				lvl.breakWall(spot, c)
+			} else {
+				c.wall.SetAlpha(0.6) // show the wall is weakened.
+			}
+		}
+	}
+}
+
+// breakWall removes a cracked wall from the level.
+func (lvl *level) breakWall(spot gridmath.Spot, c *crack) {
+	x, _, z := c.wall.At()
+	c.wall.DisposeBody()
+	c.wall.Dispose()
+	for index, wall := range lvl.walls {
+		if wall == c.wall {
+			lvl.walls = append(lvl.walls[:index], lvl.walls[index+1:]...)
+			break
+		}
+	}
+	delete(lvl.cracks, spot)
+	if plan, ok := lvl.plan.(*crackedPlan); ok {
+		plan.broken[spot] = true
+	}
+	lvl.hd.remWall(x, z)
+	if lvl.mirror != nil {
+		lvl.mirror.breakWall(spot)
+	}
+	lvl.mp.ani.addAnimation(newDebrisAnimation(lvl.scene, x, z))
+}
+
+// level cracked wall handling.
+// ===========================================================================
+// debrisAnimation
+
+// debrisAnimation is a short burst of wall fragments thrown out from
+// a broken wall. The fragments are particles that fall and fade.
+type debrisAnimation struct {
+	scene *vu.Ent      // Level scene.
+	burst *vu.Ent      // Particle effect.
+	x, z  float64      // Broken wall location.
+	vel   [][3]float64 // Fragment velocities by particle index.
+	age   float64      // Seconds since the wall broke.
+	state int          // Track progress 0:start, 1:run, 2:done.
+}
+
+// Debris tuning.
+const (
+	debrisCount = 40  // Wall fragments.
+	debrisLife  = 1.0 // Seconds until the fragments are gone.
+)
+
+// newDebrisAnimation creates a debris burst at the given game location.
+func newDebrisAnimation(scene *vu.Ent, x, z float64) animation {
+	return &debrisAnimation{scene: scene, x: x, z: z}
+}
+
+// Animate is called each game loop while the animation is active.
+func (da *debrisAnimation) Animate(dt float64) bool {
+	switch da.state {
+	case 0:
+		for cnt := 0; cnt < debrisCount; cnt++ {
+			da.vel = append(da.vel, [3]float64{rand.Float64()*4 - 2, rand.Float64() * 4, rand.Float64()*4 - 2})
+		}
+		da.burst = da.scene.AddPart().SetAt(da.x, 0, da.z)
+		da.burst.MakeEffect("debris", "crack").SetMover(da.move, debrisCount)
+		da.state = 1
+		return true
+	case 1:
+		if da.age += dt; da.age >= debrisLife {
+			da.Wrap()
+			return false // animation done.
+		}
+		return true
+	default:
+		return false // animation done.
+	}
+}
+
+// move is the particle mover. Fragments start spread through the wall
+// and are thrown outwards and upwards before falling to the floor.
+func (da *debrisAnimation) move(all []*vu.Particle, dt float64) (live []*vu.Particle) {
+	for cnt, p := range all {
+		if p.Alive == 0 {
+			p.Index = float32(cnt)
+			p.X, p.Y, p.Z = da.vel[cnt][0]*0.2, 0.2+da.vel[cnt][1]*0.2, da.vel[cnt][2]*0.2
+		}
+		da.vel[cnt][1] -= 9.8 * dt // gravity.
+		p.X += da.vel[cnt][0] * dt
+		p.Y += da.vel[cnt][1] * dt
+		p.Z += da.vel[cnt][2] * dt
+		if p.Y < 0 {
+			p.Y, da.vel[cnt][1] = 0, 0 // come to rest on the floor.
+		}
+		p.Alive = float32(math.Max(1-da.age/debrisLife, 0.01)) // 0 is a new particle.
+		live = append(live, p)
+	}
+	return live
+}
+
+// Wrap removes the debris.
+func (da *debrisAnimation) Wrap() {
+	if da.burst != nil {
+		da.burst.Dispose()
+		da.burst = nil
+	}
+	da.state = 2
+}
@@ -0,0 +1,94 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Crash reports are written to the save directory whenever the game
+// panics. The launch screen offers to show the report the next time
+// the game is started.
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// reportCrash is deferred by the engine callbacks. It saves a crash
+// report for any panic and then passes the panic on to the engine.
+func (mp *bampf) reportCrash() {
+	if r := recover(); r != nil {
+		if file := writeCrash(r, debug.Stack(), mp.crashSummary()); file != "" {
+			newSaver().persistCrash(file)
+		}
+		panic(r)
+	}
+}
+
+// crashSummary describes the game state for a crash report. The game
+// state may be broken, so a failed summary is noted instead of panicking.
+func (mp *bampf) crashSummary() (lines []string) {
+	defer func() {
+		if r := recover(); r != nil {
+			lines = append(lines, fmt.Sprintf("Summary failed: %v", r))
+		}
+	}()
+	lines = append(lines, fmt.Sprintf("Screen: %T", mp.active))
+	lines = append(lines, fmt.Sprintf("Launch level: %d maze: %q daily: %t", mp.launchLevel, mp.launchMaze, mp.launchDaily))
+	if g := mp.game; g != nil && g.cl != nil {
+		lvl, active := g.cl, 0
+		for _, sentry := range lvl.sentries {
+			if sentry.active {
+				active++
+			}
+		}
+		lines = append(lines, fmt.Sprintf("Level: %d of %d cached", lvl.num, len(g.levels)))
+		lines = append(lines, fmt.Sprintf("Walls: %d cores: %d sentinels: %d/%d",
+			len(lvl.walls), len(lvl.cc.cores), active, len(lvl.sentries)))
+	}
+	return lines
+}
+
+// writeCrash saves a crash report to a new timestamped file in the save
+// directory. Returns the crash file name or the empty string if the
+// report could not be written.
+func writeCrash(r interface{}, stack []byte, summary []string) string {
+	lines := []string{fmt.Sprintf("Panic: %v", r), "Version: " + version, ""}
+	lines = append(lines, summary...)
+	lines = append(lines, "", "Recent log:")
+	lines = append(lines, recentLogs.lines()...)
+	lines = append(lines, "", "Stack:")
+	lines = append(lines, textLines(string(stack))...)
+	dir := path.Dir(newSaver().File)
+	file := path.Join(dir, time.Now().Format("crash-20060102-150405.txt"))
+	if err := ioutil.WriteFile(file, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		return ""
+	}
+	return file
+}
+
+// crash
+// ===========================================================================
+// logRing
+
+// recentLogs keeps the most recent log lines for crash reports.
+var recentLogs = &logRing{max: 50}
+
+// logRing keeps the last max log lines.
+type logRing struct {
+	max  int      // Number of lines kept.
+	logs []string // Kept lines, oldest first.
+}
+
+// add keeps a log line, dropping the oldest line when full.
+func (lr *logRing) add(line string) {
+	lr.logs = append(lr.logs, line)
+	if len(lr.logs) > lr.max {
+		lr.logs = lr.logs[len(lr.logs)-lr.max:]
+	}
+}
+
+// lines returns the kept log lines, oldest first.
+func (lr *logRing) lines() []string { return append([]string{}, lr.logs...) }
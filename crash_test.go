@@ -0,0 +1,18 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestLogRing(t *testing.T) {
+	lr := &logRing{max: 2}
+	lr.add("one")
+	lr.add("two")
+	lr.add("three")
+	if lines := lr.lines(); len(lines) != 2 || lines[0] != "two" || lines[1] != "three" {
+		t.Errorf("Expected [two three] got %v", lines)
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// The crosshair marks the center of the screen. Players pick its shape,
+// size, and colour from the options screen. It blooms outwards when a
+// core is collected, flashes red when the player is hit, and turns
+// hollow while the player is cloaked.
+
+import (
+	"math"
+
+	"github.com/gazed/vu"
+)
+
+// Crosshair settings. These are also the saved setting names. The first
+// of each is the default.
+var (
+	crossShapes  = []string{"dot", "cross", "both", "off"}
+	crossSizes   = []string{"medium", "large", "small"}
+	crossColours = []string{"white", "green", "yellow", "cyan"}
+)
+
+// crossPixels is the crosshair size, in pixels, for each size setting.
+var crossPixels = map[string]float64{"small": 3, "medium": 5, "large": 8}
+
+// crossRGB is the crosshair colour for each colour setting.
+var crossRGB = map[string][3]float64{
+	"white":  {1, 1, 1},
+	"green":  {0.3, 1, 0.3},
+	"yellow": {1, 0.9, 0.2},
+	"cyan":   {0.2, 0.9, 1},
+}
+
+// Crosshair feedback tuning.
+const (
+	crossBloomTicks = 20  // Game ticks for a core pickup bloom to settle.
+	crossFlashTicks = 30  // Game ticks for a hit flash to fade.
+	crossBloom      = 0.8 // Extra spread at the start of a bloom.
+	crossBurst      = 0.5 // Extra bloom for each doubling of the cells gained.
+	crossHollow     = 0.4 // Arm transparency while cloaked.
+)
+
+// crosshairStyle returns the shape, size, and colour from the saved
+// crosshair settings. Missing or unknown settings use the defaults.
+func crosshairStyle(saved []string) []string {
+	style := []string{crossShapes[0], crossSizes[0], crossColours[0]}
+	for cnt, choices := range [][]string{crossShapes, crossSizes, crossColours} {
+		if cnt < len(saved) {
+			for _, choice := range choices {
+				if choice == saved[cnt] {
+					style[cnt] = choice
+				}
+			}
+		}
+	}
+	return style
+}
+
+// crosshair is a center dot, a dot outline, and four arms. The shape
+// decides which parts are shown.
+type crosshair struct {
+	root   *vu.Ent    // Groups the crosshair parts.
+	dot    *vu.Ent    // Center dot.
+	rim    []*vu.Ent  // Outline shown instead of the dot while cloaked.
+	arms   []*vu.Ent  // Right, left, up, and down arms.
+	shape  string     // One of crossShapes.
+	size   float64    // Size in pixels before text scaling.
+	rgb    [3]float64 // Normal colour.
+	cx, cy float64    // Screen center in pixels.
+	bloom  int        // Game ticks left in the core pickup bloom.
+	burst  float64    // Bloom spread multiplier for the last core pickup.
+	flash  int        // Game ticks left in the hit flash.
+	hollow bool       // True while the player is cloaked.
+}
+
+// newCrosshair creates a crosshair with the default style.
+func newCrosshair(root *vu.Ent) *crosshair {
+	ch := &crosshair{root: root}
+	ch.dot = root.AddPart()
+	ch.dot.MakeModel("colored", "msh:square", "mat:white")
+	for cnt := 0; cnt < 4; cnt++ {
+		rim := root.AddPart()
+		rim.MakeModel("colored", "msh:square", "mat:white")
+		arm := root.AddPart()
+		arm.MakeModel("colored", "msh:square", "mat:white")
+		ch.rim = append(ch.rim, rim)
+		ch.arms = append(ch.arms, arm)
+	}
+	ch.setStyle(nil)
+	return ch
+}
+
+// setStyle changes the crosshair shape, size, and colour.
+func (ch *crosshair) setStyle(saved []string) {
+	style := crosshairStyle(saved)
+	ch.shape, ch.size, ch.rgb = style[0], crossPixels[style[1]], crossRGB[style[2]]
+	ch.draw()
+}
+
+// place centers the crosshair at the given pixel location.
+func (ch *crosshair) place(cx, cy float64) {
+	ch.cx, ch.cy = cx, cy
+	ch.draw()
+}
+
+// bloomOut briefly spreads the crosshair when a core is collected.
+// Cores worth more cells spread the crosshair further.
+func (ch *crosshair) bloomOut(cells int) {
+	ch.bloom = crossBloomTicks
+	ch.burst = 1 + crossBurst*math.Log2(clamp(float64(cells), 1, 16))
+	ch.draw()
+}
+
+// flashRed briefly turns the crosshair red when the player is hit.
+func (ch *crosshair) flashRed() {
+	ch.flash = crossFlashTicks
+	ch.draw()
+}
+
+// setHollow shows the outline instead of the dot while cloaked.
+func (ch *crosshair) setHollow(hollow bool) {
+	if hollow != ch.hollow {
+		ch.hollow = hollow
+		ch.draw()
+	}
+}
+
+// update is called each game tick to settle the bloom and flash.
+func (ch *crosshair) update() {
+	if ch.bloom > 0 || ch.flash > 0 {
+		if ch.bloom > 0 {
+			ch.bloom--
+		}
+		if ch.flash > 0 {
+			ch.flash--
+		}
+		ch.draw()
+	}
+}
+
+// draw sizes, places, and colours the crosshair parts.
+func (ch *crosshair) draw() {
+	showDot := ch.shape == "dot" || ch.shape == "both"
+	showArms := ch.shape == "cross" || ch.shape == "both"
+	s := ch.size * textScale
+	thick := s * 0.4
+	if thick < 1 {
+		thick = 1
+	}
+	spread := 1 + crossBloom*ch.burst*float64(ch.bloom)/crossBloomTicks
+	red := float64(ch.flash) / crossFlashTicks
+	r := ch.rgb[0] + (1-ch.rgb[0])*red
+	g := ch.rgb[1] * (1 - red)
+	b := ch.rgb[2] * (1 - red)
+
+	// the dot, or its outline while cloaked.
+	half := s * 0.5 * spread
+	ch.dot.Cull(!showDot || ch.hollow)
+	ch.dot.SetAt(ch.cx, ch.cy, 0).SetScale(half, half, 1)
+	ch.dot.SetColor(r, g, b)
+	edge := half + thick*0.5
+	rims := [][4]float64{
+		{ch.cx, ch.cy + edge, edge + thick*0.5, thick * 0.5},
+		{ch.cx, ch.cy - edge, edge + thick*0.5, thick * 0.5},
+		{ch.cx + edge, ch.cy, thick * 0.5, edge - thick*0.5},
+		{ch.cx - edge, ch.cy, thick * 0.5, edge - thick*0.5},
+	}
+	for cnt, rim := range ch.rim {
+		rim.Cull(!showDot || !ch.hollow)
+		rim.SetAt(rims[cnt][0], rims[cnt][1], 0).SetScale(rims[cnt][2], rims[cnt][3], 1)
+		rim.SetColor(r, g, b)
+	}
+
+	// the arms start a gap away from the center.
+	gap, length := s*1.5*spread, s*1.5
+	mid := gap + length*0.5
+	arms := [][4]float64{
+		{ch.cx + mid, ch.cy, length * 0.5, thick * 0.5},
+		{ch.cx - mid, ch.cy, length * 0.5, thick * 0.5},
+		{ch.cx, ch.cy + mid, thick * 0.5, length * 0.5},
+		{ch.cx, ch.cy - mid, thick * 0.5, length * 0.5},
+	}
+	alpha := 1.0
+	if ch.hollow {
+		alpha = crossHollow
+	}
+	for cnt, arm := range ch.arms {
+		arm.Cull(!showArms)
+		arm.SetAt(arms[cnt][0], arms[cnt][1], 0).SetScale(arms[cnt][2], arms[cnt][3], 1)
+		arm.SetColor(r, g, b).SetAlpha(alpha)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Custom levels are player authored mazes. Mazes are text files, or JSON
+// files with a list of rows, placed in a "custom" directory in the save
+// directory or the game directory. Each maze character is one grid spot:
+//    # wall
+//    . floor
+//    * floor where cores can be dropped.
+//    @ maze center.
+// Cores can be dropped on any floor when there are no '*' spots.
+// Unknown characters are treated as walls.
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/gazed/vu/grid"
+)
+
+// Custom maze characters.
+const (
+	customWall   = '#'
+	customFloor  = '.'
+	customDrop   = '*'
+	customCenter = '@'
+)
+
+// customPlan is a player authored floorplan. It implements grid.Grid so
+// that it can be used in place of the generated floorplans.
+type customPlan struct {
+	name   string   // Maze file name.
+	rows   []string // Maze rows, all the same length.
+	cx, cy int      // Maze center.
+	drops  bool     // True if the maze marks core drop spots.
+}
+
+// newCustomPlan creates a floorplan from maze rows. Short rows are padded
+// with walls. Returns nil if the maze is too small to play.
+func newCustomPlan(name string, rows []string) *customPlan {
+	cp := &customPlan{name: name}
+	width := 0
+	for _, row := range rows {
+		row = strings.TrimRight(row, "\r\n")
+		if len(row) > 0 {
+			cp.rows = append(cp.rows, row)
+			if len(row) > width {
+				width = len(row)
+			}
+		}
+	}
+	if width < minLevelSize || len(cp.rows) < minLevelSize {
+		logf("custom maze %s: must be at least %dx%d", name, minLevelSize, minLevelSize)
+		return nil
+	}
+	cp.cx, cp.cy = width/2, len(cp.rows)/2
+	for y, row := range cp.rows {
+		if len(row) < width {
+			cp.rows[y] = row + strings.Repeat(string(customWall), width-len(row))
+		}
+		if x := strings.IndexRune(row, customCenter); x >= 0 {
+			cp.cx, cp.cy = x, y
+		}
+		if strings.IndexRune(row, customDrop) >= 0 {
+			cp.drops = true
+		}
+	}
+	return cp
+}
+
+// Implement grid.Grid. The maze is fixed, so Seed and Generate do nothing.
+func (cp *customPlan) Size() (width, depth int)            { return len(cp.rows[0]), len(cp.rows) }
+func (cp *customPlan) Seed(seed int64)                     {}
+func (cp *customPlan) Generate(width, depth int) grid.Grid { return cp }
+
+// IsOpen returns true for any spot that is not a wall.
+func (cp *customPlan) IsOpen(x, y int) bool {
+	switch cp.at(x, y) {
+	case customFloor, customDrop, customCenter:
+		return true
+	}
+	return false
+}
+
+// Band returns the distance to the nearest maze edge.
+func (cp *customPlan) Band(x, y int) int {
+	w, h := cp.Size()
+	band := x
+	for _, edge := range []int{y, w - 1 - x, h - 1 - y} {
+		if edge < band {
+			band = edge
+		}
+	}
+	if band < 0 {
+		return 0
+	}
+	return band
+}
+
+// center returns the maze center location.
+func (cp *customPlan) center() (x, y int) { return cp.cx, cp.cy }
+
+// isDrop returns true if cores can be dropped at the given open spot.
+func (cp *customPlan) isDrop(x, y int) bool {
+	return !cp.drops || cp.at(x, y) == customDrop
+}
+
+// at returns the maze character at the given grid location.
+func (cp *customPlan) at(x, y int) byte {
+	if y < 0 || y >= len(cp.rows) || x < 0 || x >= len(cp.rows[y]) {
+		return customWall
+	}
+	return cp.rows[y][x]
+}
+
+// customPlan
+// ===========================================================================
+// custom maze files
+
+// customDirs are the directories searched for custom maze files.
+func customDirs() []string {
+	return []string{path.Join(path.Dir(newSaver().File), "custom"), "custom"}
+}
+
+// customMazes lists the available custom maze file names in sorted order.
+func customMazes() []string {
+	names := []string{}
+	for _, dir := range customDirs() {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			ext := path.Ext(file.Name())
+			if !file.IsDir() && (ext == ".txt" || ext == ".json") {
+				names = append(names, file.Name())
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadCustomPlan reads the named custom maze file. Returns nil if the
+// maze could not be read.
+func loadCustomPlan(name string) *customPlan {
+	for _, dir := range customDirs() {
+		bites, err := ioutil.ReadFile(path.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		rows := strings.Split(string(bites), "\n")
+		if path.Ext(name) == ".json" {
+			maze := struct {
+				Rows []string `json:"rows"`
+			}{}
+			if err = json.Unmarshal(bites, &maze); err != nil {
+				logf("custom maze %s: %s", name, err)
+				return nil
+			}
+			rows = maze.Rows
+		}
+		return newCustomPlan(name, rows)
+	}
+	logf("custom maze %s: not found", name)
+	return nil
+}
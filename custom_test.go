@@ -0,0 +1,39 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestCustomPlan(t *testing.T) {
+	rows := []string{
+		"#######",
+		"#*....#",
+		"#.###.#",
+		"#.#@#.#",
+		"#.....",
+		"#....*#",
+		"#######",
+	}
+	cp := newCustomPlan("test", rows)
+	if w, h := cp.Size(); w != 7 || h != 7 {
+		t.Fatalf("Expected 7x7 got %dx%d", w, h)
+	}
+	if cp.IsOpen(6, 4) {
+		t.Errorf("Expected short row to be padded with walls")
+	}
+	spots, drops := layoutPlan(cp, 2)
+	for _, spot := range spots {
+		if spot.kind == centerSpot && (spot.x != 3 || spot.y != 3) {
+			t.Errorf("Expected center at 3,3 got %d,%d", spot.x, spot.y)
+		}
+	}
+	if len(drops) != 2 {
+		t.Errorf("Expected 2 drops got %d", len(drops))
+	}
+	if newCustomPlan("small", rows[:5]) != nil {
+		t.Errorf("Expected small maze to be rejected")
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Daily challenges derive the mazes, the number of sentinels, and a set
+// of game modifiers from the current date so that all players get the
+// same challenge each day. A daily challenge always starts at the first
+// level and is scored by the time taken to finish the final level.
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// challenge is the game tuning for one day.
+type challenge struct {
+	day       string          // Challenge date, eg: 2016-01-02.
+	seed      int64           // Seeds the mazes and game randomness.
+	muster    float64         // Sentinel count multiplier.
+	modifiers map[string]bool // Active challenge modifiers.
+}
+
+// Daily challenge modifiers.
+const (
+	halfCloak     = "half cloak"     // Half the usual cloak energy.
+	fastSentinels = "fast sentinels" // Sentinels move faster.
+	earlyWaves    = "early waves"    // Sentinels are released twice as often.
+)
+
+// dailyModifiers are the modifiers that can be chosen for a challenge.
+var dailyModifiers = []string{halfCloak, fastSentinels, earlyWaves}
+
+// dailyMusters are the sentinel count multipliers that can be chosen
+// for a challenge.
+var dailyMusters = []float64{0.5, 1, 1.5, 2}
+
+// newChallenge creates the challenge for the given day.
+func newChallenge(day time.Time) *challenge {
+	dc := &challenge{day: day.Format("2006-01-02")}
+	dc.seed = int64(day.Year()*10000 + int(day.Month())*100 + day.Day())
+	random := rand.New(rand.NewSource(dc.seed))
+	dc.muster = dailyMusters[random.Intn(len(dailyMusters))]
+	dc.modifiers = map[string]bool{}
+	for _, modifier := range dailyModifiers {
+		if random.Intn(2) == 1 {
+			dc.modifiers[modifier] = true
+		}
+	}
+	return dc
+}
+
+// levelSeed returns the maze seed for the given level.
+func (dc *challenge) levelSeed(lvl int) int64 { return dc.seed*10 + int64(lvl) }
+
+// sentinels returns the challenge number of sentinels for the given level.
+func (dc *challenge) sentinels(lvl int) int {
+	count := int(float64(gameSentinels(lvl)) * dc.muster)
+	switch {
+	case count < 1:
+		return 1
+	case count > maxLevelSentries:
+		return maxLevelSentries
+	}
+	return count
+}
+
+// waveTicks returns the challenge game ticks between sentinel spawn waves.
+func (dc *challenge) waveTicks(lvl int) int {
+	if dc.modifiers[earlyWaves] {
+		return gameWaveTicks[lvl] / 2
+	}
+	return gameWaveTicks[lvl]
+}
+
+// describe returns a short summary of the challenge tuning.
+func (dc *challenge) describe() string {
+	mods := []string{fmt.Sprintf("%.1fx sentinels", dc.muster)}
+	for _, modifier := range dailyModifiers {
+		if dc.modifiers[modifier] {
+			mods = append(mods, modifier)
+		}
+	}
+	return strings.Join(mods, ", ")
+}
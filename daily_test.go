@@ -0,0 +1,26 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChallengeSameDay(t *testing.T) {
+	morning := time.Date(2016, 1, 2, 8, 0, 0, 0, time.Local)
+	evening := time.Date(2016, 1, 2, 20, 0, 0, 0, time.Local)
+	a, b := newChallenge(morning), newChallenge(evening)
+	if a.seed != b.seed || a.describe() != b.describe() {
+		t.Errorf("Expected the same challenge got %s and %s", a.describe(), b.describe())
+	}
+	if c := newChallenge(morning.AddDate(0, 0, 1)); c.seed == a.seed {
+		t.Errorf("Expected a different seed for the next day")
+	}
+	for lvl := range gameMuster {
+		if count := a.sentinels(lvl); count < 1 || count > maxLevelSentries {
+			t.Errorf("Level %d sentinels %d out of range", lvl, count)
+		}
+	}
+}
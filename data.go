@@ -0,0 +1,425 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Game data files allow levels to be changed without code changes.
+// Data files are looked for in the save directory first, so players can
+// override the shipped data, and then in the data directory. Release
+// builds ship the data directory in the asset zip.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+
+	"github.com/gazed/vu/grid"
+	"github.com/gazed/vu/load"
+)
+
+// loadData decodes the named JSON data file into v. The built-in values
+// in v are left unchanged if the data file could not be found or read.
+func loadData(name string, v interface{}) bool {
+	bites, err := ioutil.ReadFile(path.Join(path.Dir(newSaver().File), name))
+	if err != nil {
+		if bites, err = readShipped(name); err != nil {
+			return false
+		}
+	}
+	if err = json.Unmarshal(bites, v); err != nil {
+		logf("Failed to read data file %s: %s", name, err)
+		return false
+	}
+	return true
+}
+
+// readShipped reads the named file from the data directory. Release
+// builds ship the data directory in the asset zip, while development
+// builds read it from disk.
+func readShipped(name string) ([]byte, error) {
+	assets := load.NewLocator().Dir("JSON", "data")
+	defer assets.Dispose()
+	file, err := assets.GetResource(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ioutil.ReadAll(file)
+}
+
+// data
+// ===========================================================================
+// theme
+
+// Theme describes how a level looks. The band lists are indexed by the
+// maze band, which increases towards the maze center, with the last entry
+// used for any deeper bands. Theme needs to be public and visible for
+// the encoding package.
+type Theme struct {
+	Name         string     `json:"name"`         // Theme identifier.
+	Grid         string     `json:"grid"`         // Floorplan generator, see gridTypes.
+	WallMeshes   []string   `json:"wallMeshes"`   // Wall model per band.
+	WallTextures []string   `json:"wallTextures"` // Wall image per band.
+	Tiles        []string   `json:"tiles"`        // Floor tile image per band.
+	Tint         [3]float32 `json:"tint"`         // Background colour multiplier.
+	Fog          [3]float32 `json:"fog"`          // Mist colour near the maze center.
+	Sky          [3]float32 `json:"sky"`          // Sky dome colour overhead.
+	Props        []string   `json:"props"`        // Room set pieces, see propKinds.
+}
+
+// gridTypes maps the data file floorplan names to grid generators.
+// A braided maze is a maze with half its dead-ends opened up.
+var gridTypes = map[string]int{
+	"maze":    grid.PrimMaze,
+	"braid":   braidMaze,
+	"dense":   grid.DenseSkirmish,
+	"sparse":  grid.SparseSkirmish,
+	"rooms":   grid.RoomSkirmish,
+	"cave":    grid.Cave,
+	"dungeon": grid.Dungeon,
+}
+
+// gridType returns the floorplan generator for the theme,
+// defaulting to a dense skirmish grid.
+func (t *Theme) gridType() int {
+	if gridType, ok := gridTypes[t.Grid]; ok {
+		return gridType
+	}
+	logf("theme %s: unknown grid %s", t.Name, t.Grid)
+	return grid.DenseSkirmish
+}
+
+// Theme resource names for a given maze band.
+func (t *Theme) wallMesh(band int) string    { return bandName(t.WallMeshes, band) }
+func (t *Theme) wallTexture(band int) string { return bandName(t.WallTextures, band) }
+func (t *Theme) tile(band int) string        { return bandName(t.Tiles, band) }
+
+// bandName returns the name for the given band, using the last name
+// for bands past the end of the list.
+func bandName(names []string, band int) string {
+	switch {
+	case len(names) == 0:
+		return ""
+	case band < 0:
+		return names[0]
+	case band >= len(names):
+		return names[len(names)-1]
+	}
+	return names[band]
+}
+
+// gameThemes are the per-level themes. The built-in themes are replaced
+// by those in themes.json when it is available.
+var gameThemes = defaultThemes()
+
+// loadThemes replaces the built-in themes with the themes data file.
+// Levels without a theme in the data file keep their built-in theme
+// and missing theme values are copied from the built-in theme.
+func loadThemes() {
+	themes := []*Theme{}
+	if loadData("themes.json", &themes) {
+		for cnt, theme := range themes {
+			if cnt < len(gameThemes) && theme != nil {
+				theme.fill(gameThemes[cnt])
+				gameThemes[cnt] = theme
+			}
+		}
+	}
+}
+
+// fill copies any missing values from the given theme.
+func (t *Theme) fill(from *Theme) {
+	if t.Grid == "" {
+		t.Grid = from.Grid
+	}
+	if len(t.WallMeshes) == 0 {
+		t.WallMeshes = from.WallMeshes
+	}
+	if len(t.WallTextures) == 0 {
+		t.WallTextures = from.WallTextures
+	}
+	if len(t.Tiles) == 0 {
+		t.Tiles = from.Tiles
+	}
+	if t.Tint == [3]float32{} {
+		t.Tint = from.Tint
+	}
+	if t.Fog == [3]float32{} {
+		t.Fog = from.Fog
+	}
+	if t.Sky == [3]float32{} {
+		t.Sky = from.Sky
+	}
+	if len(t.Props) == 0 {
+		t.Props = from.Props
+	}
+}
+
+// defaultThemes are the original per-level themes. Each level has
+// its own fog and sky colours.
+func defaultThemes() []*Theme {
+	grids := []string{"dense", "dense", "sparse", "rooms", "rooms"}
+	fogs := [][3]float32{{0, 0, 0.1}, {0.05, 0, 0.1}, {0, 0.08, 0.08}, {0.1, 0.05, 0}, {0.1, 0, 0}}
+	skies := [][3]float32{{0.75, 0.85, 1}, {0.8, 0.75, 0.95}, {0.7, 0.9, 0.85}, {0.95, 0.8, 0.65}, {0.9, 0.6, 0.6}}
+	themes := make([]*Theme, len(grids))
+	for cnt, gridName := range grids {
+		theme := &Theme{Name: "classic", Grid: gridName, Tint: [3]float32{1, 1, 1}}
+		theme.Fog, theme.Sky = fogs[cnt], skies[cnt]
+		if gridName == "rooms" {
+			theme.Props = []string{"pillar", "rubble", "vent"}
+		}
+		for band := 0; band < 6; band++ {
+			theme.WallMeshes = append(theme.WallMeshes, fmt.Sprintf("%dwall", band))
+			theme.WallTextures = append(theme.WallTextures, fmt.Sprintf("wall%d0", band))
+			theme.Tiles = append(theme.Tiles, fmt.Sprintf("tile%d0", band))
+		}
+		themes[cnt] = theme
+	}
+	return themes
+}
+
+// theme
+// ===========================================================================
+// levels
+
+// LevelDef describes the tuning for one game level. Missing or zero
+// values keep the built-in value. LevelDef needs to be public and
+// visible for the encoding package.
+type LevelDef struct {
+	Grid      string     `json:"grid"`      // Floorplan generator, overrides the theme.
+	Size      int        `json:"size"`      // Grid width and height.
+	Sentinels int        `json:"sentinels"` // Number of sentinels.
+	Gain      int        `json:"gain"`      // Cells gained for each core.
+	Loss      int        `json:"loss"`      // Cells lost for each sentinel collision.
+	Fade      float64    `json:"fade"`      // Distance where the level fades from view.
+	Proximity int        `json:"proximity"` // Sentinel warning grid distance, -1 for none.
+	Collision string     `json:"collision"` // Sentinel collision: "teleport" or "knockback".
+	Forgive   int        `json:"forgive"`   // Cells lost on the first collision, -1 for the regular loss.
+	Cracks    int        `json:"cracks"`    // Cracked walls, -1 for none.
+	Gates     int        `json:"gates"`     // One-way gates, -1 for none.
+	Voids     int        `json:"voids"`     // Void tiles, -1 for none.
+	Trail     int        `json:"trail"`     // Sentinel trail length in tiles, -1 for none.
+	Interdict int        `json:"interdict"` // No teleport grid distance around the center, -1 for none.
+	Turrets   int        `json:"turrets"`   // Dead-end turrets, -1 for none.
+	Pacing    *PacingDef `json:"pacing"`    // Core drop pacing.
+}
+
+// PacingDef tunes how fast cores are dropped and where they land.
+// Missing or zero values keep the built-in value. PacingDef needs
+// to be public and visible for the encoding package.
+type PacingDef struct {
+	Delay    int     `json:"delay"`    // Milliseconds between drops.
+	Deficit  float64 `json:"deficit"`  // Delay shortening for each missing core.
+	Distance float64 `json:"distance"` // Delay lengthening at the maze edge.
+	Away     int     `json:"away"`     // Spots tried to find one away from the player.
+}
+
+// Level definition limits.
+const (
+	minLevelSize      = 7   // Smallest grid the generators support.
+	maxLevelSize      = 99  // Keep level creation reasonably fast.
+	maxLevelSentries  = 500 // Keep the per-tick cost reasonable.
+	maxLevelCracks    = 50  // Keep the maze a maze.
+	maxLevelGates     = 20  // Leave some ways back out.
+	maxLevelVoids     = 10  // Keep voids rare.
+	maxLevelTrail     = 8   // Leave room to get past the sentinels.
+	maxLevelInterdict = 5   // Leave most of the maze for teleporting.
+	maxLevelTurrets   = 8   // Leave some quiet corridors.
+)
+
+// levelCells is the most cells the player can hold on the given level.
+// Each game level uses the next larger trooper, see trooper.health.
+func levelCells(lvl int) int {
+	inner, outer := lvl*2, (lvl+2)*2
+	return outer*outer*outer - inner*inner*inner
+}
+
+// loadLevels replaces the built-in level tuning with the levels data file.
+// Invalid values are logged and the built-in value is kept.
+func loadLevels() {
+	defs := []*LevelDef{}
+	if loadData("levels.json", &defs) {
+		for lvl, def := range defs {
+			switch {
+			case lvl >= len(gameMuster):
+				logf("levels.json: ignoring extra level %d", lvl)
+			case def != nil:
+				def.apply(lvl)
+			}
+		}
+	}
+}
+
+// apply validates the level definition and copies the valid values
+// into the game tuning tables for the given level.
+func (def *LevelDef) apply(lvl int) {
+	if def.Grid != "" {
+		if _, ok := gridTypes[def.Grid]; ok {
+			gameThemes[lvl].Grid = def.Grid
+		} else {
+			logf("levels.json: level %d unknown grid %s", lvl, def.Grid)
+		}
+	}
+	switch {
+	case def.Size == 0:
+	case def.Size < minLevelSize || def.Size > maxLevelSize:
+		logf("levels.json: level %d size %d not in %d-%d", lvl, def.Size, minLevelSize, maxLevelSize)
+	case def.Size%2 == 0:
+		logf("levels.json: level %d size %d must be odd", lvl, def.Size)
+	default:
+		gameSizes[lvl] = def.Size
+	}
+	switch {
+	case def.Sentinels == 0:
+	case def.Sentinels < 0 || def.Sentinels > maxLevelSentries:
+		logf("levels.json: level %d sentinels %d not in 1-%d", lvl, def.Sentinels, maxLevelSentries)
+	default:
+		gameMuster[lvl] = def.Sentinels
+	}
+	switch {
+	case def.Gain == 0:
+	case def.Gain < 0 || def.Gain > levelCells(lvl):
+		logf("levels.json: level %d gain %d not in 1-%d", lvl, def.Gain, levelCells(lvl))
+	default:
+		gameCellGain[lvl] = def.Gain
+	}
+	switch {
+	case def.Loss == 0:
+	case def.Loss < 0 || def.Loss > levelCells(lvl):
+		logf("levels.json: level %d loss %d not in 1-%d", lvl, def.Loss, levelCells(lvl))
+	default:
+		gameCellLoss[lvl] = def.Loss
+	}
+	switch {
+	case def.Fade == 0:
+	case def.Fade < 0:
+		logf("levels.json: level %d fade %f must be positive", lvl, def.Fade)
+	default:
+		gameFade[lvl] = def.Fade
+	}
+	switch {
+	case def.Proximity == 0:
+	case def.Proximity == -1:
+		gameProximity[lvl] = 0
+	case def.Proximity < 0 || def.Proximity > maxLevelSize:
+		logf("levels.json: level %d proximity %d not in 1-%d", lvl, def.Proximity, maxLevelSize)
+	default:
+		gameProximity[lvl] = def.Proximity
+	}
+	switch def.Collision {
+	case "":
+	case "teleport":
+		gameKnockback[lvl] = false
+	case "knockback":
+		gameKnockback[lvl] = true
+	default:
+		logf("levels.json: level %d unknown collision %s", lvl, def.Collision)
+	}
+	switch {
+	case def.Forgive == 0:
+	case def.Forgive == -1:
+		gameForgive[lvl] = 0
+	case def.Forgive < 0:
+		logf("levels.json: level %d forgive %d must be positive or -1", lvl, def.Forgive)
+	default:
+		gameForgive[lvl] = def.Forgive
+	}
+	switch {
+	case def.Cracks == 0:
+	case def.Cracks == -1:
+		gameCracks[lvl] = 0
+	case def.Cracks < 0 || def.Cracks > maxLevelCracks:
+		logf("levels.json: level %d cracks %d not in 1-%d", lvl, def.Cracks, maxLevelCracks)
+	default:
+		gameCracks[lvl] = def.Cracks
+	}
+	switch {
+	case def.Gates == 0:
+	case def.Gates == -1:
+		gameGates[lvl] = 0
+	case def.Gates < 0 || def.Gates > maxLevelGates:
+		logf("levels.json: level %d gates %d not in 1-%d", lvl, def.Gates, maxLevelGates)
+	default:
+		gameGates[lvl] = def.Gates
+	}
+	switch {
+	case def.Voids == 0:
+	case def.Voids == -1:
+		gameVoids[lvl] = 0
+	case def.Voids < 0 || def.Voids > maxLevelVoids:
+		logf("levels.json: level %d voids %d not in 1-%d", lvl, def.Voids, maxLevelVoids)
+	default:
+		gameVoids[lvl] = def.Voids
+	}
+	switch {
+	case def.Trail == 0:
+	case def.Trail == -1:
+		gameTrail[lvl] = 0
+	case def.Trail < 0 || def.Trail > maxLevelTrail:
+		logf("levels.json: level %d trail %d not in 1-%d", lvl, def.Trail, maxLevelTrail)
+	default:
+		gameTrail[lvl] = def.Trail
+	}
+	switch {
+	case def.Interdict == 0:
+	case def.Interdict == -1:
+		gameInterdict[lvl] = 0
+	case def.Interdict < 0 || def.Interdict > maxLevelInterdict:
+		logf("levels.json: level %d interdict %d not in 1-%d", lvl, def.Interdict, maxLevelInterdict)
+	default:
+		gameInterdict[lvl] = def.Interdict
+	}
+	switch {
+	case def.Turrets == 0:
+	case def.Turrets == -1:
+		gameTurrets[lvl] = 0
+	case def.Turrets < 0 || def.Turrets > maxLevelTurrets:
+		logf("levels.json: level %d turrets %d not in 1-%d", lvl, def.Turrets, maxLevelTurrets)
+	default:
+		gameTurrets[lvl] = def.Turrets
+	}
+	if def.Pacing != nil {
+		def.Pacing.apply(lvl)
+	}
+}
+
+// Core pacing limits.
+const (
+	minDropDelay = 50    // Milliseconds. Don't flood the maze.
+	maxDropDelay = 10000 // Milliseconds. Cores still have to appear.
+	maxDropAway  = 10    // Spots tried for each drop.
+)
+
+// apply validates the pacing and copies the valid values into
+// the game pacing for the given level.
+func (p *PacingDef) apply(lvl int) {
+	switch {
+	case p.Delay == 0:
+	case p.Delay < minDropDelay || p.Delay > maxDropDelay:
+		logf("levels.json: level %d pacing delay %d not in %d-%d", lvl, p.Delay, minDropDelay, maxDropDelay)
+	default:
+		gamePacing[lvl].Delay = p.Delay
+	}
+	switch {
+	case p.Deficit == 0:
+	case p.Deficit < 0:
+		logf("levels.json: level %d pacing deficit %f must be positive", lvl, p.Deficit)
+	default:
+		gamePacing[lvl].Deficit = p.Deficit
+	}
+	switch {
+	case p.Distance == 0:
+	case p.Distance < 0:
+		logf("levels.json: level %d pacing distance %f must be positive", lvl, p.Distance)
+	default:
+		gamePacing[lvl].Distance = p.Distance
+	}
+	switch {
+	case p.Away == 0:
+	case p.Away < 0 || p.Away > maxDropAway:
+		logf("levels.json: level %d pacing away %d not in 1-%d", lvl, p.Away, maxDropAway)
+	default:
+		gamePacing[lvl].Away = p.Away
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestLevelDefApply(t *testing.T) {
+	size, muster, gain, loss := gameSizes[1], gameMuster[1], gameCellGain[1], gameCellLoss[1]
+	defer func() { gameSizes[1], gameMuster[1], gameCellGain[1], gameCellLoss[1] = size, muster, gain, loss }()
+
+	// valid values replace the built-in values.
+	def := &LevelDef{Size: 17, Sentinels: 7}
+	def.apply(1)
+	if gameSizes[1] != 17 || gameMuster[1] != 7 || gameCellGain[1] != gain {
+		t.Errorf("Expected 17 7 %d got %d %d %d", gain, gameSizes[1], gameMuster[1], gameCellGain[1])
+	}
+
+	// invalid values are ignored.
+	def = &LevelDef{Size: 3, Sentinels: -1, Gain: -2}
+	def.apply(1)
+	if gameSizes[1] != 17 || gameMuster[1] != 7 || gameCellGain[1] != gain {
+		t.Errorf("Expected 17 7 %d got %d %d %d", gain, gameSizes[1], gameMuster[1], gameCellGain[1])
+	}
+	(&LevelDef{Size: 18, Loss: levelCells(1) + 1}).apply(1)
+	if gameSizes[1] != 17 || gameCellLoss[1] != loss {
+		t.Errorf("Expected even sizes and huge losses to be ignored, got %d %d", gameSizes[1], gameCellLoss[1])
+	}
+	if levelCells(0) != 64 {
+		t.Errorf("Expected 64 cells on the first level, got %d", levelCells(0))
+	}
+}
+
+func TestLevelDefCollision(t *testing.T) {
+	knockback := gameKnockback[1]
+	defer func() { gameKnockback[1] = knockback }()
+	(&LevelDef{Collision: "teleport"}).apply(1)
+	if gameKnockback[1] {
+		t.Errorf("Expected teleport collisions")
+	}
+	(&LevelDef{Collision: "bounce"}).apply(1) // unknown values are ignored.
+	(&LevelDef{}).apply(1)
+	if gameKnockback[1] {
+		t.Errorf("Expected teleport collisions")
+	}
+	(&LevelDef{Collision: "knockback"}).apply(1)
+	if !gameKnockback[1] {
+		t.Errorf("Expected knockback collisions")
+	}
+}
+
+func TestFlavorLine(t *testing.T) {
+	defer func(saved []*FlavorDef) { gameFlavor = saved }(gameFlavor)
+	gameFlavor = []*FlavorDef{{Start: []string{"begin"}}, nil}
+	if line := flavorLine(0, flavorStart); line != "begin" {
+		t.Errorf("expected start line, got %q", line)
+	}
+	for _, lvl := range []int{-1, 1, 2} {
+		if line := flavorLine(lvl, flavorStart); line != "" {
+			t.Errorf("expected no line for level %d, got %q", lvl, line)
+		}
+	}
+	if line := flavorLine(0, flavorCenter); line != "" {
+		t.Errorf("expected no center line, got %q", line)
+	}
+}
+
+func TestLevelDefForgive(t *testing.T) {
+	forgive := gameForgive[0]
+	defer func() { gameForgive[0] = forgive }()
+	(&LevelDef{Forgive: 3}).apply(0)
+	if gameForgive[0] != 3 {
+		t.Errorf("Expected 3 got %d", gameForgive[0])
+	}
+	(&LevelDef{Forgive: -2}).apply(0) // invalid values are ignored.
+	if gameForgive[0] != 3 {
+		t.Errorf("Expected 3 got %d", gameForgive[0])
+	}
+	(&LevelDef{Forgive: -1}).apply(0)
+	if gameForgive[0] != 0 {
+		t.Errorf("Expected no forgiveness got %d", gameForgive[0])
+	}
+}
+
+func TestLevelDefTrail(t *testing.T) {
+	trail := gameTrail[3]
+	defer func() { gameTrail[3] = trail }()
+	(&LevelDef{Trail: 5}).apply(3)
+	if gameTrail[3] != 5 {
+		t.Errorf("Expected 5 got %d", gameTrail[3])
+	}
+	(&LevelDef{Trail: maxLevelTrail + 1}).apply(3) // invalid values are ignored.
+	if gameTrail[3] != 5 {
+		t.Errorf("Expected 5 got %d", gameTrail[3])
+	}
+	(&LevelDef{Trail: -1}).apply(3)
+	if gameTrail[3] != 0 {
+		t.Errorf("Expected no trail got %d", gameTrail[3])
+	}
+}
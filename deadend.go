@@ -0,0 +1,68 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Dead-ends are open maze spots with only one way in or out. Voids and
+// turrets are placed in dead-ends, away from the player start and the
+// maze center, so that they never block the only way through the maze.
+
+import (
+	"github.com/gazed/bampf/gridmath"
+	"github.com/gazed/vu/grid"
+)
+
+// deadEnds picks up to count dead-end spots, spread evenly through the
+// maze, that are at least clear grid steps from each of the keep spots.
+// The same plan always gives the same spots.
+func deadEnds(plan grid.Grid, count, clear int, keep []gridmath.Spot) []gridmath.Spot {
+	width, height := plan.Size()
+	candidates := []gridmath.Spot{}
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			if _, ok := deadEnd(plan, x, y); ok && clearOf(x, y, clear, keep) {
+				candidates = append(candidates, gridmath.Spot{X: x, Y: y})
+			}
+		}
+	}
+	return spreadOut(candidates, count)
+}
+
+// spreadOut picks up to count of the candidate spots, spread evenly
+// through the candidates.
+func spreadOut(candidates []gridmath.Spot, count int) (spots []gridmath.Spot) {
+	if count > len(candidates) {
+		count = len(candidates)
+	}
+	for cnt := 0; cnt < count; cnt++ {
+		spots = append(spots, candidates[(cnt+1)*len(candidates)/(count+1)])
+	}
+	return spots
+}
+
+// deadEnd returns the one step direction out of a dead-end. False is
+// returned if the spot is a wall or has more than one open neighbour.
+func deadEnd(plan grid.Grid, x, y int) (exit gridmath.Spot, ok bool) {
+	if !plan.IsOpen(x, y) {
+		return exit, false
+	}
+	exits := 0
+	for _, dir := range []gridmath.Spot{{X: 1, Y: 0}, {X: -1, Y: 0}, {X: 0, Y: 1}, {X: 0, Y: -1}} {
+		if plan.IsOpen(x+dir.X, y+dir.Y) {
+			exit = dir
+			exits++
+		}
+	}
+	return exit, exits == 1
+}
+
+// clearOf returns true if the grid spot is at least clear
+// grid steps from all the keep spots.
+func clearOf(x, y, clear int, keep []gridmath.Spot) bool {
+	for _, k := range keep {
+		if abs(x-k.X)+abs(y-k.Y) < clear {
+			return false
+		}
+	}
+	return true
+}
@@ -9,8 +9,16 @@ package main
 //     go build -tags debug
 
 import (
+	"bufio"
+	"fmt"
+	"image/png"
 	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/gazed/bampf/gridmath"
 	"github.com/gazed/vu"
 )
 
@@ -35,8 +43,48 @@ func (g *game) processDebugInput(in *vu.Input) {
 			g.cl.debugCloak() // Gain longer cloak.
 		case press == vu.KO && down == 1:
 			g.mp.state(finishGame) // Jump to the end game animation.
+		case press == vu.KP && down == 1:
+			g.cl.stress(stressSentinels, stressCores) // Crowd the level.
 		}
 	}
+	watched.poll()  // Reload changed art.
+	stages.report() // Log the level update costs.
+}
+
+// Extra sentinels and cores added each time the stress test key is pressed.
+const (
+	stressSentinels = 25
+	stressCores     = 10
+)
+
+// stress adds active sentinels and dropped cores to the level to see
+// how the level update copes with bigger levels. The stage timings
+// are logged once the first stress test starts.
+func (lvl *level) stress(sentinels, cores int) {
+	for cnt := 0; cnt < sentinels; cnt++ {
+		sentry := newSentinel(lvl.scene.AddPart(), lvl.num, lvl.units, lvl.fade, lvl.moves)
+		sentry.setScale(0.25)
+		spot := lvl.spawns.points[cnt%len(lvl.spawns.points)]
+		sentry.setGridAt(spot.X, spot.Y)
+		sentry.setActive(true)
+		lvl.sentries = append(lvl.sentries, sentry)
+		lvl.enemies = append(lvl.enemies, sentry)
+	}
+	if lvl.mirror != nil {
+		lvl.mirror.dispose() // rebuilt to match the new sentinels.
+		lvl.mirror = nil
+		lvl.showMirror(lvl.mp.opts[mirrorOption])
+	}
+	px, _, pz := lvl.cam.At()
+	gx, gy := gridmath.ToGrid(px, 0, pz, float64(lvl.units))
+	for cnt := 0; cnt < cores && len(lvl.cc.tiles) > 0; cnt++ {
+		gridx, gridy := lvl.cc.dropSpot(gx, gy, 1)
+		gamex, gamez := lvl.cc.dropCore(lvl.scene.AddPart(), lvl.fade, gridx, gridy)
+		lvl.hd.addCore(gamex, gamez)
+	}
+	stages.on = true
+	log.Printf("stress: level %d has %d sentinels and %d cores",
+		lvl.num, len(lvl.sentries), len(lvl.cc.cores))
 }
 
 // toggleFly is used to flip into and out of flying mode.
@@ -59,3 +107,174 @@ func (g *game) toggleFly() {
 		g.dir = g.cl.cam.Look
 	}
 }
+
+// debug
+// ===========================================================================
+// stageTimes
+
+// stageTimes collects the time spent in each timed stage of the level
+// update and logs the average and worst cost per tick every few seconds.
+type stageTimes struct {
+	on    bool                     // True once a stress test has started.
+	total map[string]time.Duration // Time spent in each stage.
+	worst map[string]time.Duration // Slowest single call of each stage.
+	calls map[string]int           // Number of calls of each stage.
+	next  time.Time                // When to next log the timings.
+}
+
+// stages are the level update timings for debug builds.
+var stages = &stageTimes{
+	total: map[string]time.Duration{},
+	worst: map[string]time.Duration{},
+	calls: map[string]int{},
+}
+
+// init replaces the production stage timing.
+func init() { timeStage = stages.start }
+
+// start times one call of the given stage.
+func (st *stageTimes) start(stage string) (stop func()) {
+	if !st.on {
+		return func() {}
+	}
+	begin := time.Now()
+	return func() {
+		took := time.Since(begin)
+		st.total[stage] += took
+		st.calls[stage]++
+		if took > st.worst[stage] {
+			st.worst[stage] = took
+		}
+	}
+}
+
+// report logs and resets the collected timings every few seconds.
+func (st *stageTimes) report() {
+	if !st.on || time.Now().Before(st.next) {
+		return
+	}
+	st.next = time.Now().Add(5 * time.Second)
+	names := []string{}
+	for stage := range st.calls {
+		names = append(names, stage)
+	}
+	sort.Strings(names)
+	for _, stage := range names {
+		avg := st.total[stage] / time.Duration(st.calls[stage])
+		log.Printf("stress: %-16s avg %8s worst %8s over %d ticks", stage, avg, st.worst[stage], st.calls[stage])
+		delete(st.total, stage)
+		delete(st.worst, stage)
+		delete(st.calls, stage)
+	}
+}
+
+// stageTimes
+// ===========================================================================
+// assetWatch
+
+// assetWatch reloads textures and materials that are changed on disk
+// so that art can be tweaked without restarting the game. Textures are
+// reloaded from the images directory and materials from the models
+// directory. Only the material colour and transparency are reloaded.
+type assetWatch struct {
+	ents  map[string][]*vu.Ent // Models using each texture or material.
+	times map[string]time.Time // Last modification time of each asset.
+	next  time.Time            // When to next check the asset files.
+}
+
+// watched is the asset watcher for debug builds.
+var watched = &assetWatch{ents: map[string][]*vu.Ent{}, times: map[string]time.Time{}}
+
+// init replaces the production asset tracking.
+func init() { trackAsset = watched.track }
+
+// track remembers a model built from the given texture or material.
+func (aw *assetWatch) track(e *vu.Ent, asset string) {
+	aw.ents[asset] = append(aw.ents[asset], e)
+	if _, ok := aw.times[asset]; !ok {
+		aw.times[asset] = aw.modified(asset)
+	}
+}
+
+// poll checks the tracked asset files once a second and reloads
+// any that have changed.
+func (aw *assetWatch) poll() {
+	if time.Now().Before(aw.next) {
+		return
+	}
+	aw.next = time.Now().Add(time.Second)
+	for asset, last := range aw.times {
+		if mod := aw.modified(asset); mod.After(last) {
+			aw.times[asset] = mod
+			aw.reload(asset)
+		}
+	}
+}
+
+// file returns the file name for a texture or material asset.
+func (aw *assetWatch) file(asset string) string {
+	switch {
+	case strings.HasPrefix(asset, "tex:"):
+		return "images/" + strings.TrimPrefix(asset, "tex:") + ".png"
+	case strings.HasPrefix(asset, "mat:"):
+		return "models/" + strings.TrimPrefix(asset, "mat:") + ".mtl"
+	}
+	return ""
+}
+
+// modified returns the asset file modification time. The zero time
+// is returned for assets without a file.
+func (aw *assetWatch) modified(asset string) time.Time {
+	if info, err := os.Stat(aw.file(asset)); err == nil {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
+
+// reload applies a changed asset to the models that are still using it.
+func (aw *assetWatch) reload(asset string) {
+	live := aw.ents[asset][:0]
+	for _, e := range aw.ents[asset] {
+		if e.Exists() {
+			live = append(live, e)
+		}
+	}
+	aw.ents[asset] = live
+	if len(live) == 0 {
+		return
+	}
+	file, err := os.Open(aw.file(asset))
+	if err != nil {
+		log.Printf("reload %s: %s", asset, err)
+		return
+	}
+	defer file.Close()
+	if strings.HasPrefix(asset, "tex:") {
+		img, err := png.Decode(file)
+		if err != nil {
+			log.Printf("reload %s: %s", asset, err)
+			return
+		}
+		for _, e := range live {
+			if t := e.Texture(0); t != nil {
+				t.Set(img)
+			}
+		}
+	} else {
+		var r, g, b, a float64 = 0, 0, 0, 1
+		scan := bufio.NewScanner(file)
+		for scan.Scan() {
+			line := strings.TrimSpace(scan.Text())
+			switch {
+			case strings.HasPrefix(line, "Kd "):
+				fmt.Sscanf(line, "Kd %f %f %f", &r, &g, &b)
+			case strings.HasPrefix(line, "d "):
+				fmt.Sscanf(line, "d %f", &a)
+			}
+		}
+		for _, e := range live {
+			e.SetColor(r, g, b).SetAlpha(a)
+		}
+	}
+	log.Printf("reloaded %s", asset)
+}
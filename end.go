@@ -14,33 +14,41 @@ import (
 // a silicon atom. No one is expected to get here based on the current game
 // difficulty settings.
 type end struct {
-	scene    *vu.Ent     // 3D scene.
-	bg       *vu.Ent     // Background.
-	atom     *vu.Ent     // Group the animated atom.
-	e1       *vu.Ent     // Up/down electron group.
-	e2       *vu.Ent     // Left/right electron group.
-	e3       *vu.Ent     // Slash electron group.
-	e4       *vu.Ent     // Backslash electron group.
-	eles     []*electron // All electrons.
-	scale    float64     // Used for the fade in animation.
-	fov      float64     // Field of view.
-	evolving bool        // Used to disable keys during screen transitions.
+	scene    *vu.Ent         // 3D scene.
+	ui       *vu.Ent         // 2D overlay for the share code.
+	code     *vu.Ent         // Share code for the finished run.
+	ww, wh   int             // Window size.
+	bg       *vu.Ent         // Background.
+	atom     *vu.Ent         // Group the animated atom.
+	e1       *vu.Ent         // Up/down electron group.
+	e2       *vu.Ent         // Left/right electron group.
+	e3       *vu.Ent         // Slash electron group.
+	e4       *vu.Ent         // Backslash electron group.
+	eles     []*electron     // All electrons.
+	scale    float64         // Used for the fade in animation.
+	fov      float64         // Field of view.
+	evolving bool            // Used to disable keys during screen transitions.
+	copying  <-chan clipping // Clipboard being written with the share code, nil if not copying.
+	mp       *bampf          // Main program.
 }
 
 // Implement the screen interface.
 func (e *end) fadeIn() animation        { return e.createFadeIn() }
 func (e *end) fadeOut() animation       { return nil }
-func (e *end) resize(width, height int) {}
+func (e *end) resize(width, height int) { e.handleResize(width, height) }
 func (e *end) activate(state int) {
 	switch state {
 	case screenActive:
 		e.scene.Cull(false)
+		e.ui.Cull(false)
 		e.evolving = false
 	case screenDeactive:
 		e.scene.Cull(true)
+		e.ui.Cull(true)
 		e.evolving = false
 	case screenEvolving:
 		e.scene.Cull(false)
+		e.ui.Cull(false)
 		e.evolving = true
 	default:
 		logf("end state error")
@@ -49,12 +57,21 @@ func (e *end) activate(state int) {
 
 // User input to game events. Implements screen interface.
 func (e *end) processInput(in *vu.Input, eventq *list.List) {
-	for press, down := range in.Down {
-		switch {
-		case press == vu.KEsc && down == 1 && !e.evolving:
-			publish(eventq, toggleOptions, nil)
+	if !e.evolving && e.mp.input.pressed(vu.KEsc) {
+		publish(eventq, toggleOptions, nil)
+	}
+	if !e.evolving && e.mp.input.pressed(vu.KLm) && labelClicked(e.code, in.Mx, in.My) {
+		publish(eventq, copyShare, nil)
+	}
+	if e.copying != nil {
+		select {
+		case copied := <-e.copying:
+			e.copying = nil
+			publish(eventq, copiedShare, copied)
+		default:
 		}
 	}
+	publish(eventq, statusChanged, Status{Mode: modeFinished, Level: len(gameMuster) - 1})
 }
 
 // Process game events. Implements screen interface.
@@ -65,6 +82,18 @@ func (e *end) processEvents(eventq *list.List) (transition int) {
 		switch event.id {
 		case toggleOptions:
 			return configGame
+		case copyShare:
+			e.copyCode()
+		case copiedShare:
+			if copied, ok := event.data.(clipping); ok {
+				e.copiedCode(copied)
+			} else {
+				logf("end.processEvents: did not receive copiedShare clipping")
+			}
+		case statusChanged:
+			if st, ok := event.data.(Status); ok {
+				e.mp.presence.update(st)
+			}
 		}
 	}
 	return finishGame
@@ -74,6 +103,7 @@ func (e *end) processEvents(eventq *list.List) (transition int) {
 // Expected to be called once on game startup.
 func newEndScreen(mp *bampf, ww, wh int) *end {
 	e := &end{}
+	e.mp = mp
 	e.scale = 0.01
 	e.fov = 75
 	e.scene = mp.eng.AddScene()
@@ -87,9 +117,62 @@ func newEndScreen(mp *bampf, ww, wh int) *end {
 
 	// create the atom and its electrons.
 	e.newAtom()
+
+	// show the share code over the atom.
+	e.ww, e.wh = ww, wh
+	e.ui = mp.eng.AddScene().SetUI()
+	e.ui.Cam().SetClip(0, 10)
+	e.ui.Cull(true)
+	e.code = e.ui.AddPart().MakeLabel("labeled", "lucidiaSu22")
 	return e
 }
 
+// showCode shows the share code for the finished run.
+// Runs that can't be shared have an empty code.
+func (e *end) showCode(code string) {
+	e.code.Cull(code == "")
+	if code != "" {
+		e.code.SetStr("Share code " + code + " (click to copy)")
+		e.placeCode()
+	}
+}
+
+// copyCode starts copying the share code to the clipboard.
+// The result is shown by copiedCode when the copy finishes.
+func (e *end) copyCode() {
+	if e.copying == nil {
+		e.copying = copyLater(e.mp.clip, e.mp.shared)
+	}
+}
+
+// copiedCode shows whether the share code made it to the clipboard.
+// The share file is the fallback on platforms without a clipboard.
+func (e *end) copiedCode(copied clipping) {
+	if copied.err != nil && copied.err != errNoClipboard {
+		logf("Failed to copy: %s", copied.err)
+	}
+	note := " (copied)"
+	if copied.err != nil {
+		note = " (saved to " + shareFile + ")"
+	}
+	e.code.SetStr("Share code " + copied.text + note)
+	e.placeCode()
+}
+
+// handleResize keeps the share code placed for the new window size.
+func (e *end) handleResize(width, height int) {
+	e.ww, e.wh = width, height
+	e.placeCode()
+}
+
+// placeCode centers the share code near the bottom of the screen.
+func (e *end) placeCode() {
+	if e.code != nil {
+		w, _ := labelSize(scaleLabel(e.code, 1))
+		e.code.SetAt(float64(e.ww/2-w/2), 40*textScale, 0)
+	}
+}
+
 // createFadeIn returns a new fade-in animation. The initial setup is necessary for
 // cases where the user finishes the game and then plays again and finishes again
 // all in one application session.
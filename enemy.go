@@ -0,0 +1,36 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Enemies are anything in the maze that costs the player cells on contact.
+// The level moves the enemies, checks them for player collisions, and shows
+// them on the minimap, the detector, and as shadows using only the enemy
+// interface. New kinds of enemy implement enemy and are added to the level
+// enemies. Turrets and their bolts are the other enemies. Spawning,
+// freezing, stunning, and trails are sentinel abilities and work on the
+// level sentinels directly.
+
+import (
+	"github.com/gazed/vu/grid"
+)
+
+// enemy is one player enemy in the maze.
+type enemy interface {
+	move(plan grid.Grid, far bool)   // Moves the enemy for one game tick.
+	location() (x, y, z float64)     // Current game location.
+	setGridAt(gridx, gridy int)      // Puts the enemy down on a grid location.
+	isActive() bool                  // Inactive enemies are hidden and harmless.
+	onPlayerCollide(lvl *level) bool // Reacts to the player, returns false to ignore the contact.
+	minimapStyle() markerStyle       // How the enemy is shown on the minimap.
+}
+
+// markerStyle is the minimap marker used for an enemy.
+type markerStyle int
+
+// Minimap marker styles.
+const (
+	solidMarker markerStyle = iota // Enemies that can hurt the player.
+	fadedMarker                    // Enemies that are stopped for now.
+	fixedMarker                    // Enemies that never move.
+)
@@ -24,6 +24,7 @@ import shutil       # for directory and file manipulation
 import shlex        # run and control shell commands
 import subprocess   # for calling shell commands
 import glob         # for unix pattern matching
+import time         # for the build date
 
 def cleanProject():
     # Remove all generated files.
@@ -54,7 +55,8 @@ def buildBinary(flags):
         version = subprocess.check_output(shlex.split('git describe')).strip()
     except subprocess.CalledProcessError:
         version = 'v0.0'
-    command = 'go build -ldflags "-s -X main.version='+version+' '+flags+'" -o target/bampf.raw bampf'
+    built = time.strftime('%Y-%m-%d')
+    command = 'go build -ldflags "-s -X main.version='+version+' -X main.buildDate='+built+' '+flags+'" -o target/bampf.raw bampf'
     out, err = subprocess.Popen(command, universal_newlines=True, shell=True, stdout=subprocess.PIPE, stderr=subprocess.PIPE).communicate()
     print('built binary with command: ' + command)
 
@@ -64,7 +66,7 @@ def zipAssets():
     cwd = os.getcwd()
     os.chdir('..')
     subprocess.call(['zip', 'assets.zip']+glob.glob('models/*')+glob.glob('source/*')+
-                    glob.glob('images/*')+glob.glob('audio/*'))
+                    glob.glob('images/*')+glob.glob('audio/*')+glob.glob('data/*'))
     os.chdir(cwd)
     shutil.move('../assets.zip', 'target/assets.zip')
 
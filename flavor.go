@@ -0,0 +1,71 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Flavor text is shown in the HUD banner when a level starts and when the
+// player first reaches the maze center. The lines are read from the flavor
+// data file so that they can be changed without code changes.
+
+import "math/rand"
+
+// FlavorDef holds the flavor text for one level. One of the lines for an
+// event is picked at random each time. FlavorDef needs to be public and
+// visible for the encoding package.
+type FlavorDef struct {
+	Start  []string `json:"start"`  // Shown when the level starts.
+	Center []string `json:"center"` // Shown on first reaching the center.
+}
+
+// Flavor text events.
+const (
+	flavorStart  = iota // Level started.
+	flavorCenter        // Maze center reached.
+)
+
+// gameFlavor is the flavor text for each level. There is no flavor
+// text unless the flavor data file is found.
+var gameFlavor []*FlavorDef
+
+// loadFlavor reads the level flavor text from the flavor data file.
+func loadFlavor() {
+	defs := []*FlavorDef{}
+	if loadData("flavor.json", &defs) {
+		gameFlavor = defs
+	}
+}
+
+// flavorLine returns a flavor text line for the given level and event.
+// Returns the empty string if there is no flavor text.
+func flavorLine(lvl, event int) string {
+	if lvl < 0 || lvl >= len(gameFlavor) || gameFlavor[lvl] == nil {
+		return ""
+	}
+	lines := gameFlavor[lvl].Start
+	if event == flavorCenter {
+		lines = gameFlavor[lvl].Center
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return lines[rand.Intn(len(lines))]
+}
+
+// flavored tracks the flavor text already shown for the current level.
+type flavored struct {
+	level  int  // Level that the start text was shown for.
+	center bool // True once the center text was shown.
+}
+
+// tellFlavor shows the level flavor text for any new flavor events.
+func (g *game) tellFlavor() {
+	lvl, told := g.cl, &g.told
+	if told.level != lvl.num {
+		*told = flavored{level: lvl.num}
+		lvl.hd.showBanner(flavorLine(lvl.num, flavorStart))
+	}
+	if !told.center && g.atCenter() {
+		told.center = true
+		lvl.hd.showBanner(flavorLine(lvl.num, flavorCenter))
+	}
+}
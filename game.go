@@ -6,25 +6,53 @@ package main
 import (
 	"container/list"
 	"math"
+	"math/rand"
+	"time"
 
+	"github.com/gazed/bampf/gridmath"
 	"github.com/gazed/vu"
+	"github.com/gazed/vu/grid"
 	"github.com/gazed/vu/math/lin"
 )
 
 // game keeps track of the game play screen. This includes all game levels
 // and the heads up display (hud).
 type game struct {
-	mp        *bampf          // Main program.
-	levels    map[int]*level  // Game levels.
-	cl        *level          // Current level.
-	dt        float64         // Delta time updated per game tick.
-	keys      []int           // Key bindings.
-	lens      *cam            // Dictates how the camera moves.
-	ww, wh    int             // Window size.
-	mxp, myp  int             // Previous mouse locations.
-	procDebug func(*vu.Input) // Debugging commands in debug loads.
-	evolving  bool            // True when player is moving between levels.
-	dir       *lin.Q          // Movement direction.
+	mp        *bampf                  // Main program.
+	levels    map[int]*level          // Game levels.
+	cl        *level                  // Current level.
+	dt        float64                 // Delta time updated per game tick.
+	keys      []int                   // Key bindings.
+	lens      *cam                    // Dictates how the camera moves.
+	ww, wh    int                     // Window size.
+	mxp, myp  int                     // Previous mouse locations.
+	captured  bool                    // True while the mouse pointer is held in the window.
+	procDebug func(*vu.Input)         // Debugging commands in debug loads.
+	evolving  bool                    // True when player is moving between levels.
+	dir       *lin.Q                  // Movement direction.
+	autoRun   bool                    // True if the player keeps moving forward.
+	porting   bool                    // True while the teleport key is down.
+	countdown float64                 // Seconds until the player evolves, 0 if not counting.
+	confirm   float64                 // Seconds left to confirm a descend, 0 if not asked.
+	daily     *challenge              // Daily challenge tuning, nil for regular games.
+	elapsed   float64                 // Seconds spent playing the current game.
+	started   float64                 // Elapsed seconds when the current level started.
+	ups       upgrades                // Upgrades earned during the current game.
+	summary   *about                  // Level summary, nil unless choosing an upgrade.
+	splits    []float64               // Elapsed seconds when each level was completed.
+	lastSplit string                  // Description of the last split for the HUD.
+	coop      *coop                   // Experimental co-op session, nil if not playing co-op.
+	hudHidden bool                    // True if the player has hidden the HUD.
+	said      announced               // Events already spoken for the current level.
+	told      flavored                // Flavor text already shown for the current level.
+	rules     *runConfig              // Conditions of the current run.
+	logged    bool                    // True once the current level outcome is recorded.
+	dropped   map[int][]gridmath.Spot // Cores left on each level during this run.
+	practice  bool                    // True for practice runs without penalties or records.
+	resuming  float64                 // Seconds left before a paused game resumes.
+	bgticks   int                     // Game ticks since the other levels were last simulated.
+	seed      int64                   // Maze seed, kept between runs so that cached levels stay valid.
+	used      usage                   // Cloak and teleport usage during the current run.
 
 	// Debug variables
 	fly  bool     // Debug flying ability switch, see game_debug.go
@@ -43,16 +71,15 @@ func (g *game) resize(width, height int) { g.handleResize(width, height) }
 func (g *game) activate(state int) {
 	switch state {
 	case screenActive:
-		g.mp.eng.Set(vu.CursorOn(false))
 		g.cl.setVisible(true)
 		g.setKeys(g.keys)
 		g.evolving = false
 	case screenDeactive:
-		g.mp.eng.Set(vu.CursorOn(true))
+		g.releaseMouse()
 		g.cl.setVisible(false)
 		g.evolving = false
 	case screenPaused:
-		g.mp.eng.Set(vu.CursorOn(true))
+		g.releaseMouse()
 	case screenEvolving:
 		g.evolving = true
 	}
@@ -63,38 +90,93 @@ func (g *game) processInput(in *vu.Input, eventq *list.List) {
 	if g.cl == nil { // no current level just yet... still starting.
 		return
 	}
+	if g.summary != nil {
+		g.summaryInput(g.mp.input) // wait for an upgrade choice.
+		return
+	}
+	if g.resuming > 0 {
+		g.resumeInput(in.Dt, eventq) // wait for the resume countdown.
+		return
+	}
 
 	// update game state if the game is active and not transitioning between levels.
 	// Do the evolve check before processing any other input.
+	if g.mp.opts[freeMouseOption] {
+		g.releaseMouse()
+	} else {
+		g.captureMouse(in.Mx, in.My)
+	}
 	g.spinView(in.Mx, in.My, g.dt)
 	if !g.evolving {
 		g.lens.update(g.cl.cam) // smooth camera.
 		g.cl.update()           // level per-tick updates.
-		g.evolveCheck(eventq)   // kick off any necessary level transitions.
+		g.simulateLevels()
+		g.used.track(g.cl, in.Dt)
+		g.elapsed += in.Dt
+		g.voidCheck()
+		g.evolveCheck(eventq, in.Dt) // kick off any necessary level transitions.
+		g.cl.hd.showTimer(g.mp.opts[timerOption], g.elapsed, g.lastSplit)
+		g.syncCoop()
+		g.announce(eventq)
+		g.tellFlavor()
+		g.tellRules()
+		publish(eventq, statusChanged, g.status())
 	}
-	g.centerMouse(in.Mx, in.My) // keep centering the mouse.
+	g.centerMouse(in.Mx, in.My) // keep a captured mouse centered.
 
-	// process any new input.
+	// process any new input. Presses that happen while evolving
+	// are buffered by the input state and handled afterwards.
 	g.dt = in.Dt
-	for press, down := range in.Down {
-		switch {
-		case press == vu.KEsc && down == 1 && !g.evolving:
+	ip := g.mp.input
+	if ip.pressed(vu.KSpace) {
+		publish(eventq, skipAnim, nil)
+	}
+	if !g.evolving {
+		if ip.pressed(vu.KEsc) {
 			publish(eventq, toggleOptions, nil)
-		case press == vu.KSpace && down == 1:
-			publish(eventq, skipAnim, nil)
-		case press == g.keys[0] && !g.evolving: // rebindable keys from here on.
-			publish(eventq, goForward, down)
-		case press == g.keys[1] && !g.evolving:
-			publish(eventq, goBack, down)
-		case press == g.keys[2] && !g.evolving:
-			publish(eventq, goLeft, down)
-		case press == g.keys[3] && !g.evolving:
-			publish(eventq, goRight, down)
-		case press == g.keys[4] && down == 1 && !g.evolving:
+		}
+
+		// rebindable keys from here on. Movement repeats while the key is
+		// held and stops when the key is released.
+		g.autoRunInput(ip, eventq)
+		for cnt, eventID := range []int{goForward, goBack, goLeft, goRight} {
+			if down := ip.isHeld(g.keys[cnt]); down > 0 {
+				publish(eventq, eventID, down)
+			} else if ip.isReleased(g.keys[cnt]) && !(g.autoRun && eventID == goForward) {
+				publish(eventq, eventID, -1)
+			}
+		}
+		if g.autoRun && ip.isHeld(g.keys[0]) == 0 {
+			publish(eventq, goForward, 1)
+		}
+
+		// cloak toggles on each press unless it only lasts while held.
+		switch {
+		case !g.mp.opts[holdCloakOption] && ip.pressed(g.keys[4]):
 			publish(eventq, cloak, nil)
-		case press == g.keys[5] && down == 1 && !g.evolving:
-			publish(eventq, teleport, nil)
+		case g.mp.opts[holdCloakOption] && ip.pressed(g.keys[4]):
+			publish(eventq, cloak, true)
+		case g.mp.opts[holdCloakOption] && ip.isReleased(g.keys[4]):
+			publish(eventq, cloak, false)
+		}
+		g.teleportInput(ip, eventq)
+		if ip.pressed(g.keys[6]) {
+			publish(eventq, quickTurn, nil)
+		}
+		if ip.pressed(g.keys[7]) {
+			publish(eventq, descend, nil)
+		}
+		if !g.isBound(hideHudKey) && ip.pressed(hideHudKey) {
+			publish(eventq, toggleHud, nil)
+		}
+		if !g.isBound(mapKey) && ip.pressed(mapKey) {
+			publish(eventq, exportMap, nil)
+		}
+		if !g.isBound(pathKey) && ip.pressed(pathKey) {
+			publish(eventq, findPath, nil)
 		}
+		g.pingInput(in, eventq)
+		g.confirmTimeout(in.Dt)
 	}
 	g.procDebug(in) // noop method call in production loads.
 }
@@ -132,10 +214,45 @@ func (g *game) processEvents(eventq *list.List) (transition int) {
 				logf("game.processEvents: did not receive goRight down")
 			}
 		case cloak:
-			g.cl.cloak()
+			if on, ok := event.data.(bool); ok {
+				g.cl.setCloak(on)
+			} else {
+				g.cl.cloak()
+			}
 		case teleport:
 			g.lens.reset(g.cl.cam)
-			g.cl.teleport()
+			if g.cl.teleport() {
+				g.used.teleports++
+			}
+		case quickTurn:
+			g.lens.turn()
+		case descend:
+			g.descend()
+		case speak:
+			if text, ok := event.data.(string); ok {
+				g.mp.speech.say(text)
+			} else {
+				logf("game.processEvents: did not receive speak text")
+			}
+		case toggleHud:
+			g.hudHidden = !g.hudHidden
+			g.setHudLayout()
+		case exportMap:
+			g.cl.exportMap()
+		case findPath:
+			g.cl.usePathfinder()
+		case placePing:
+			if pe, ok := event.data.(pingEvent); ok {
+				g.placePing(pe)
+			} else {
+				logf("game.processEvents: did not receive placePing location")
+			}
+		case previewTeleport:
+			if on, ok := event.data.(bool); ok {
+				g.cl.previewTeleport(on)
+			} else {
+				logf("game.processEvents: did not receive previewTeleport bool")
+			}
 		case keysRebound:
 			if keys, ok := event.data.([]int); ok {
 				g.setKeys(keys)
@@ -144,7 +261,18 @@ func (g *game) processEvents(eventq *list.List) (transition int) {
 			}
 		case skipAnim:
 			g.mp.ani.skip()
+		case statusChanged:
+			if st, ok := event.data.(Status); ok {
+				g.mp.presence.update(st)
+			} else {
+				logf("game.processEvents: did not receive statusChanged status")
+			}
 		case wonGame:
+			g.recordFinish()
+			g.mp.end.showCode(g.shareRun())
+			g.endRun()
+			g.finishSplits()
+			g.finishCoop()
 			g.activate(screenDeactive)
 			return finishGame
 		}
@@ -156,13 +284,14 @@ func (g *game) processEvents(eventq *list.List) (transition int) {
 func newGameScreen(mp *bampf) (scr *game) {
 	g := &game{}
 	g.mp = mp
-	g.lens = &cam{}
+	g.lens = &cam{deflect: g.deflect}
 	g.ww, g.wh = mp.ww, mp.wh
 	g.run = 10  // shared constant
 	g.spin = 25 // shared constant
 	g.vr = 25   // shared constant
 	g.levels = make(map[int]*level)
 	g.procDebug = g.setDebugProcessor(g)
+	sounds.listener = g.caption
 	return g
 }
 
@@ -183,6 +312,9 @@ func (g *game) handleResize(width, height int) {
 	for _, stage := range g.levels {
 		stage.resize(width, height)
 	}
+	if g.summary != nil {
+		g.summary.resize(width, height)
+	}
 }
 
 // spinView updates the camera look direction based on amount of mouse movement
@@ -193,16 +325,87 @@ func (g *game) spinView(mx, my int, dt float64) {
 	g.mxp, g.myp = mx, my
 }
 
-// centerMouse pops the mouse back to the center of the window, but only
-// when the mouse starts to stray too far away.
+// centerMouse pops a captured mouse back to the center of the window
+// after each move so that spinView only sees the relative motion.
+// The pointer never reaches the window edge, or another monitor.
 func (g *game) centerMouse(mx, my int) {
 	cx, cy := g.ww/2, g.wh/2
-	if math.Abs(float64(cx-mx)) > 200 || math.Abs(float64(cy-my)) > 200 {
-		g.mp.eng.Set(vu.CursorAt(g.ww/2, g.wh/2))
+	if g.captured && (mx != cx || my != cy) {
+		g.mp.eng.Set(vu.CursorAt(cx, cy))
 		g.mxp, g.myp = cx, cy
 	}
 }
 
+// blur stops the player moving, cloaking, and teleporting when the
+// game window loses focus. Returns true if the game can be paused,
+// which isn't possible during level transitions or the level summary.
+func (g *game) blur() bool {
+	if g.cl == nil {
+		return false
+	}
+	g.autoRun, g.porting, g.confirm = false, false, 0
+	g.resuming = 0
+	g.cl.hd.showPrompt("")
+	g.cl.hd.showResume(0)
+	g.cl.previewTeleport(false)
+	g.cl.setCloak(false)
+	if body := g.cl.body.Body(); body != nil {
+		body.Stop()
+		body.Rest()
+	}
+	return !g.evolving && g.summary == nil
+}
+
+// unpauseDelay is the number of seconds counted down before a paused
+// game resumes.
+const unpauseDelay = 3.0
+
+// startResume starts the countdown shown before a paused game resumes,
+// giving the player a moment to spot any sentinels that wandered close
+// while the game was paused. The countdown can be turned off.
+func (g *game) startResume() {
+	if g.cl == nil || g.evolving || g.summary != nil || g.mp.opts[quickResumeOption] {
+		return
+	}
+	g.resuming = unpauseDelay
+	g.cl.hd.showResume(int(unpauseDelay))
+}
+
+// resumeInput counts down to resuming the game. The level and the player
+// stay still until the countdown ends, though the game can be paused again.
+func (g *game) resumeInput(dt float64, eventq *list.List) {
+	if g.resuming -= dt; g.resuming < 0 {
+		g.resuming = 0
+	}
+	g.cl.hd.showResume(int(math.Ceil(g.resuming)))
+	if g.mp.input.pressed(vu.KEsc) {
+		g.resuming = 0
+		g.cl.hd.showResume(0)
+		publish(eventq, toggleOptions, nil)
+	}
+}
+
+// captureMouse hides the mouse pointer and holds it in the game window.
+// The current pointer location is the starting point for the next
+// view change so that capturing doesn't jerk the view.
+func (g *game) captureMouse(mx, my int) {
+	if !g.captured {
+		g.captured = true
+		g.mp.eng.Set(vu.CursorOn(false))
+		g.mxp, g.myp = mx, my
+	}
+}
+
+// releaseMouse shows the mouse pointer and lets it leave the window.
+// Called when the game is paused or loses focus, and every tick while
+// the free mouse option is on.
+func (g *game) releaseMouse() {
+	if g.captured {
+		g.captured = false
+		g.mp.eng.Set(vu.CursorOn(true))
+	}
+}
+
 // limitWandering puts a limit on how far the player can get from the center
 // of the level. This allows the player to feel like they are traveling away
 // forever, but they can then return to the center in very little time.
@@ -232,44 +435,147 @@ func (g *game) limitWandering(down int) {
 
 // Player movement handlers.
 func (g *game) goForward(dt float64, down int) {
-	g.lens.forward(g.cl.body, dt, g.run, g.dir)
+	if !g.walk(dt, 0, -1) {
+		g.lens.forward(g.cl.body, dt, g.run, g.dir)
+	}
 	g.limitWandering(down)
 }
 func (g *game) goBack(dt float64, down int) {
-	g.lens.back(g.cl.body, dt, g.run, g.dir)
+	if !g.walk(dt, 0, 1) {
+		g.lens.back(g.cl.body, dt, g.run, g.dir)
+	}
 	g.limitWandering(down)
 }
 func (g *game) goLeft(dt float64, down int) {
-	g.lens.left(g.cl.body, dt, g.run, g.dir)
+	if !g.walk(dt, -1, 0) {
+		g.lens.left(g.cl.body, dt, g.run, g.dir)
+	}
 	g.limitWandering(down)
 }
 func (g *game) goRight(dt float64, down int) {
-	g.lens.right(g.cl.body, dt, g.run, g.dir)
+	if !g.walk(dt, 1, 0) {
+		g.lens.right(g.cl.body, dt, g.run, g.dir)
+	}
 	g.limitWandering(down)
 }
 
 // evolveCheck looks for a player at full health that is at the center
-// of the level. This is the trigger to complete the level.
-func (g *game) evolveCheck(eventq *list.List) {
-	if g.cl.isPlayerWorthy() {
-		x, y, z := g.cl.cam.At()
-		gridx, gridy := toGrid(x, y, z, float64(g.cl.units))
-		if gridx == g.cl.gcx && gridy == g.cl.gcy {
-			if g.cl.num < 4 {
-				g.mp.ani.addAnimation(g.newEvolveAnimation(1))
-			} else if g.cl.num == 4 {
-				publish(eventq, wonGame, nil)
-			}
+// of the level. This is the trigger to complete the level. The level is
+// completed after a short countdown which is cancelled if the player
+// steps off the center.
+func (g *game) evolveCheck(eventq *list.List, dt float64) {
+	if !g.cl.isPlayerWorthy() || !g.atCenter() {
+		if g.countdown > 0 {
+			g.countdown = 0
+			g.cl.hd.showCountdown(0)
 		}
+		return
+	}
+	if !g.mp.opts[quickEvolveOption] {
+		if g.countdown == 0 {
+			g.countdown = evolveDelay
+		}
+		g.countdown -= dt
+		if g.countdown > 0 {
+			g.cl.hd.showCountdown(int(math.Ceil(g.countdown)))
+			return
+		}
+		g.countdown = 0
+		g.cl.hd.showCountdown(0)
+	}
+	g.recordLevel()
+	g.logOutcome(evolvedOutcome)
+	g.split()
+	if !g.practice {
+		newSaver().persistUnlock(g.cl.num + 1)
+	}
+	if g.cl.num < 4 {
+		g.showSummary() // evolves once an upgrade is chosen.
+	} else if g.cl.num == 4 {
+		publish(eventq, wonGame, nil)
+	}
+}
+
+// evolveDelay is the number of seconds a worthy player waits at the
+// center before evolving.
+const evolveDelay = 3.0
+
+// atCenter returns true if the player is on the center tile.
+func (g *game) atCenter() bool {
+	x, y, z := g.cl.cam.At()
+	gridx, gridy := gridmath.ToGrid(x, y, z, float64(g.cl.units))
+	return gridx == g.cl.gcx && gridy == g.cl.gcy
+}
+
+// descend drops a worthy player at the center down a level instead of
+// evolving. The first request asks for confirmation, which must be given
+// by a second request before the confirmation times out.
+func (g *game) descend() {
+	switch {
+	case g.cl.num == 0 || !g.cl.isPlayerWorthy() || !g.atCenter():
+		return
+	case g.confirm <= 0:
+		g.confirm = confirmDelay
+		prompt := "Press descend again to descend a level."
+		if sym := vu.Symbol(g.keys[7]); sym > 0 {
+			prompt = "Press " + string(sym) + " again to descend a level."
+		}
+		g.cl.hd.showPrompt(prompt)
+	default:
+		g.confirm = 0
+		g.countdown = 0
+		g.cl.hd.showPrompt("")
+		g.cl.hd.showCountdown(0)
+		g.logOutcome(descendedOutcome)
+		g.mp.ani.addAnimation(g.newEvolveAnimation(-1))
 	}
 }
 
+// confirmTimeout cancels an unconfirmed descend once the player waits
+// too long or steps off the center.
+func (g *game) confirmTimeout(dt float64) {
+	if g.confirm > 0 {
+		g.confirm -= dt
+		if g.confirm <= 0 || !g.atCenter() {
+			g.confirm = 0
+			g.cl.hd.showPrompt("")
+		}
+	}
+}
+
+// voidCheck drops a player that steps into a void down a level.
+// The first level has nothing below it, so the player loses a few
+// cells and is put back outside the maze instead.
+func (g *game) voidCheck() {
+	switch {
+	case !g.cl.inVoid():
+	case g.cl.num == 0:
+		g.cl.climbOutOfVoid()
+	default:
+		g.confirm = 0
+		g.countdown = 0
+		g.cl.hd.showPrompt("")
+		g.cl.hd.showCountdown(0)
+		g.logOutcome(voidOutcome)
+		g.mp.ani.addAnimation(g.newEvolveAnimation(-1))
+	}
+}
+
+// Fixed controls and the descend confirmation delay.
+const (
+	confirmDelay = 3.0   // Seconds to confirm a descend.
+	hideHudKey   = vu.KH // Hide or show the HUD.
+	mapKey       = vu.KM // Save a picture of the level map.
+	pathKey      = vu.KG // Show the way to the maze center.
+)
+
 // healthUpdated is a callback whenever player health changes.
 // Players that have full health are worthy to descend to the
 // next level, they just have to reach the center first.
 func (g *game) healthUpdated(health, warn, high int) {
 	if health <= 0 {
 		if g.cl.num > 0 {
+			g.logOutcome(diedOutcome)
 			g.mp.ani.addAnimation(g.newEvolveAnimation(-1))
 		}
 	}
@@ -282,6 +588,165 @@ func (g *game) healthUpdated(health, warn, high int) {
 	}
 }
 
+// status returns the current game status for the presence exports.
+func (g *game) status() Status {
+	health, _, max := g.cl.player.health()
+	return Status{Mode: modePlaying, Level: g.cl.num, Health: health, Max: max}
+}
+
+// newGame prepares for a daily challenge for the current date or,
+// when daily is false, a regular game with the given mutators.
+// Daily challenges ignore mutators so that everyone plays the same game.
+func (g *game) newGame(daily bool, mutators map[string]bool) {
+	g.daily, g.elapsed = nil, 0
+	g.splits, g.lastSplit = nil, ""
+	g.ups, g.used = upgrades{}, usage{}
+	g.dropped = map[int][]gridmath.Spot{}
+	g.practice = g.mp.practice && !daily
+	g.startCoop()
+	if g.seed == 0 {
+		g.seed = 1 + rand.Int63n(maxShareSeed)
+	}
+	if daily {
+		g.daily = newChallenge(time.Now())
+		mutators = nil
+	}
+
+	// previously generated levels are discarded when the mutators change.
+	if mutatorKey(mutators) != mutatorKey(gameMutators) {
+		for lvl, stage := range g.levels {
+			stage.dispose()
+			delete(g.levels, lvl)
+		}
+	}
+	gameMutators = map[string]bool{}
+	for id, on := range mutators {
+		gameMutators[id] = on
+	}
+	g.rules = newRunConfig(g.daily, g.mp.launchMaze, gameMutators)
+	g.rules.useAssists(g.mp.opts)
+}
+
+// recordLevel saves the time taken to complete the current level if it
+// is the best time for the level. Only regular games are recorded so
+// that the level times can be compared.
+func (g *game) recordLevel() {
+	if !g.practice && g.daily == nil && g.cl.source == "" && mutatorKey(gameMutators) == "" {
+		newSaver().persistLevel(g.cl.num, g.elapsed-g.started)
+	}
+}
+
+// recordFinish saves the time taken to finish the game if it is the best
+// time for the daily challenge or for the active mutators. Only games
+// started from the first level are recorded for the mutators so that
+// the finish times can be compared.
+func (g *game) recordFinish() {
+	switch {
+	case g.practice:
+	case g.daily != nil:
+		newSaver().persistDaily(g.daily.day, g.elapsed)
+	case g.mp.launchLevel == 0:
+		newSaver().persistBest(mutatorKey(gameMutators), g.elapsed)
+	}
+}
+
+// hudLayout returns the HUD layout mode from the stream layout option
+// and the hide HUD key.
+func (g *game) hudLayout() int {
+	switch {
+	case g.hudHidden:
+		return hiddenHud
+	case g.mp.opts[streamOption]:
+		return streamHud
+	}
+	return normalHud
+}
+
+// setHudLayout updates the HUD layout of all levels.
+func (g *game) setHudLayout() {
+	for _, stage := range g.levels {
+		stage.hd.setLayout(g.hudLayout())
+	}
+}
+
+// setSafeMode turns the photo-sensitive effects on or off for all levels.
+func (g *game) setSafeMode(safe bool) {
+	for _, stage := range g.levels {
+		stage.hd.setSafeMode(safe)
+	}
+}
+
+// setCrosshair changes the crosshair style for all levels.
+func (g *game) setCrosshair(style []string) {
+	for _, stage := range g.levels {
+		stage.hd.setCrosshair(style)
+	}
+}
+
+// setMirror turns the rear-view mirror on or off for the current level.
+// Other levels pick up the option when they become visible.
+func (g *game) setMirror(on bool) {
+	if g.cl != nil {
+		g.cl.showMirror(on)
+	}
+}
+
+// caption shows the description of a sound that was just played
+// when sound captions are turned on.
+func (g *game) caption(text string) {
+	if g.cl != nil && g.mp.opts[captionOption] && text != "" {
+		g.cl.hd.caption(text)
+	}
+}
+
+// autoRunInput turns auto-run on or off when the optional auto-run key
+// is pressed. Auto-run is also stopped by moving backwards.
+func (g *game) autoRunInput(ip *inputState, eventq *list.List) {
+	wasRunning := g.autoRun
+	switch {
+	case !g.mp.opts[autoRunOption]:
+		g.autoRun = false
+	case ip.isHeld(g.keys[1]) > 0:
+		g.autoRun = false
+	case !g.isBound(autoRunKey) && ip.pressed(autoRunKey):
+		g.autoRun = !g.autoRun
+	}
+	if wasRunning && !g.autoRun && ip.isHeld(g.keys[0]) == 0 {
+		publish(eventq, goForward, -1) // stop moving.
+	}
+}
+
+// autoRunKey toggles auto-run when the auto-run option is on.
+const autoRunKey = vu.KR
+
+// teleportInput teleports the player when the teleport key is released.
+// Holding the key first shows a preview of the teleport destination.
+func (g *game) teleportInput(ip *inputState, eventq *list.List) {
+	switch {
+	case ip.pressed(g.keys[5]):
+		g.porting = true
+	case g.porting && ip.isReleased(g.keys[5]):
+		g.porting = false
+		publish(eventq, teleport, nil)
+	case g.porting && ip.isHeld(g.keys[5]) == previewTicks:
+		publish(eventq, previewTeleport, true)
+	}
+}
+
+// previewTicks is how long the teleport key is held before the
+// teleport destination is shown.
+const previewTicks = 15
+
+// isBound returns true if the key is one of the rebindable keys.
+func (g *game) isBound(key int) bool {
+	for _, bound := range g.keys {
+		if bound == key {
+			return true
+		}
+	}
+	return false
+}
+
 // setKeys sets the rebindable keys.
 func (g *game) setKeys(keys []int) {
 	g.keys = keys
@@ -294,18 +759,105 @@ func (g *game) setKeys(keys []int) {
 // generating a new level if necessary.
 func (g *game) setLevel(lvl int) {
 	if g.cl != nil {
+		if g.mp.opts[keepCoresOption] && g.dropped != nil {
+			g.dropped[g.cl.num] = g.cl.cc.dropped()
+		}
 		g.cl.deactivate()
 	}
+	g.autoRun = false
+	g.porting = false
+	g.countdown = 0
+	g.confirm = 0
+	g.started = g.elapsed
+	g.said = announced{level: -1}
+	g.told = flavored{level: -1}
+	g.logged = false
+
+	// daily challenges use the daily mazes for every level and
+	// the starting level uses the custom maze when one was chosen.
+	source := ""
+	switch {
+	case g.daily != nil:
+		source = "daily " + g.daily.day
+	case lvl == g.mp.launchLevel:
+		source = g.mp.launchMaze
+	}
+	seed := g.seed + int64(lvl)
+	switch {
+	case g.daily != nil:
+		seed = g.daily.levelSeed(lvl)
+	case g.coop != nil:
+		seed = g.coop.seed + int64(lvl) // same mazes for both players.
+	}
+	if stage, ok := g.levels[lvl]; ok && (stage.source != source || stage.seed != seed) {
+		stage.dispose()
+		delete(g.levels, lvl)
+	}
 	if _, ok := g.levels[lvl]; !ok {
-		g.levels[lvl] = newLevel(g, lvl)
+		var plan grid.Grid = newSeededPlan(lvl, seed)
+		if g.daily == nil && source != "" {
+			if custom := loadCustomPlan(source); custom != nil {
+				plan = custom
+			} else {
+				source = ""
+			}
+		}
+		g.levels[lvl] = newLevel(g, lvl, plan)
+		g.levels[lvl].source = source
+		g.levels[lvl].seed = seed
 	} else {
 		g.levels[lvl].player.reset()
 	}
 	g.cl = g.levels[lvl]
+	g.ups.apply(g.cl.player)
+	g.cl.practice = g.practice
+	g.cl.hd.showPractice(g.practice)
+	if g.coop != nil {
+		g.coop.resetCores(lvl)
+	}
 	g.lens.reset(g.cl.cam)
 	g.cl.activate(g)
+	if g.mp.opts[keepCoresOption] {
+		g.cl.restoreCores(g.dropped[lvl])
+	}
 	g.cl.updateKeys(g.keys)
 	g.dir = g.cl.cam.Look
+	g.evictLevels(lvl)
+	g.saveRun()
+}
+
+// backgroundTicks is the number of game ticks between sentinel moves
+// on the cached levels that are not being played.
+const backgroundTicks = 10
+
+// simulateLevels keeps the sentinels on the other cached levels moving,
+// at a low tick rate, so that returning to a level doesn't find them
+// exactly where they were left. Only done when the option is on.
+func (g *game) simulateLevels() {
+	if !g.mp.opts[liveLevelsOption] {
+		return
+	}
+	if g.bgticks++; g.bgticks < backgroundTicks {
+		return
+	}
+	g.bgticks = 0
+	for _, stage := range g.levels {
+		if stage != g.cl {
+			stage.simulate(backgroundTicks)
+		}
+	}
+}
+
+// evictLevels disposes the cached levels that are not next to the given
+// level so that long sessions don't hold on to every level ever played.
+// The current level is always kept since it is still being shown.
+func (g *game) evictLevels(lvl int) {
+	for num, stage := range g.levels {
+		if stage != g.cl && (num < lvl-1 || num > lvl+1) {
+			stage.dispose()
+			delete(g.levels, num)
+		}
+	}
 }
 
 // newStartGameAnimation descends to the initial level from
@@ -322,8 +874,12 @@ func (g *game) newEndGameAnimation() animation {
 // newEvolveAnimation descends or ascends from one game level to another.
 func (g *game) newEvolveAnimation(dir int) animation {
 	g.activate(screenEvolving)
+	kind := gameTransitions[g.cl.num+dir]
 	fadeOut := &fadeLevelAnimation{g: g, gameState: screenDeactive, dir: dir, out: true, ticks: 100}
+	fadeOut.path = newCameraPath(kind)
 	fadeIn := &fadeLevelAnimation{g: g, gameState: screenActive, dir: dir, out: false, ticks: 100}
+	fadeIn.path = newCameraPath(kind)
+	fadeIn.grows = dir > 0
 	transition := func() { g.switchLevel(fadeOut, fadeIn) }
 	return newTransitionAnimation(fadeOut, fadeIn, transition)
 }
@@ -343,20 +899,20 @@ func (g *game) switchLevel(fo, fi *fadeLevelAnimation) {
 // fadeLevelAnimation animates the transition between levels.
 
 // Animation to fade a level.  This does both up and down evovle directions
-// and does fade ins and fade outs.
+// and does fade ins and fade outs. The camera movement is delegated to
+// a camera path.
 type fadeLevelAnimation struct {
-	g         *game   // All the state needed to do the fade.
-	gameState int     // Will be set after finishing the second of two animations.
-	dir       int     // Which way the level is fading (up or down).
-	out       bool    // true if the level is fading out, false otherwise.
-	ticks     int     // Animation run rate - number of animation steps.
-	tickCnt   int     // Current step.
-	distA     float64 // Animation start height.
-	distB     float64 // The height where the animation stops.
-	tiltA     float64 // Animation start tilt.
-	tiltB     float64 // Animation end tilt.
-	state     int     // Track animation progress 0:start, 1:run, 2:done.
-	colr      float32 // Amount needed to change colour.
+	g         *game      // All the state needed to do the fade.
+	gameState int        // Will be set after finishing the second of two animations.
+	dir       int        // Which way the level is fading (up or down).
+	out       bool       // true if the level is fading out, false otherwise.
+	path      cameraPath // Camera movement. Defaults to a dropPath.
+	ticks     int        // Animation run rate - number of animation steps.
+	tickCnt   int        // Current step.
+	state     int        // Track animation progress 0:start, 1:run, 2:done.
+	colr      float32    // Amount needed to change colour.
+	grows     bool       // True to show the trooper growing into the level.
+	growth    *growth    // Growing trooper, nil if not shown.
 }
 
 // fade in/out the level.
@@ -368,46 +924,29 @@ func (f *fadeLevelAnimation) Animate(dt float64) bool {
 		g.cl.body.DisposeBody()
 		x, z := 4.0, 10.0 // standard starting spot.
 		if f.out {
-
-			// fading out:
-			// start level drop below if dir == 1
-			//   cam tilt from 0 to 75
-			//   location goes down from 0 to -g.vr.
-			// start level and rise if dir == -1
-			//   cam tilt from 0 to -75
-			//   location goes up from 0 to g.vr.
-			f.tiltA, f.tiltB = 0.0, float64(75*f.dir)
-			f.distA, f.distB = 0.0, float64(f.dir)*-g.vr
 			x, _, z = g.cl.cam.At() // start from player location.
-		} else {
-
-			// fading in:
-			// start high and drop to level if dir == 1
-			//   cam tilt from -75 to 0
-			//   location goes from g.vr down to 0.
-			// start low and rise to level if dir == -1
-			//   cam tilt from 75 to 0
-			//   location goes from -g.vr down to 0.
-			f.tiltA, f.tiltB = float64(-75*f.dir), 0.0
-			f.distA, f.distB = float64(f.dir)*g.vr, 0.0
 		}
-
-		g.lens.pitch = f.tiltA
-		g.cl.cam.SetAt(x, f.distA, z)
+		if f.path == nil {
+			f.path = &dropPath{}
+		}
+		f.path.begin(f, x, z)
 		f.colr = (float32(1) - g.cl.colour) / float32(f.ticks)
+		g.mp.ani.addAnimation(newSentinelFadeAnimation(g.cl.sentries, !f.out, f.ticks))
 		g.cl.setVisible(true)
 		g.cl.setHudVisible(false)
+		if f.grows {
+			f.growth = newGrowth(g.mp.eng, g.cl.num+1, g.ww, g.wh)
+		}
 		f.state = 1
 		return true
 	case 1:
 		g := f.g
 		g.cl.colour += f.colr
 		g.cl.setBackgroundColour(g.cl.colour)
-		move := (f.distB - f.distA) / float64(f.ticks)
-		g.cl.cam.Move(0, move, 0, lin.QI)
-		tilt := (f.tiltB - f.tiltA) / float64(f.ticks) * 2
-		g.lens.pitch = g.lens.updatePitch(g.lens.pitch, tilt, g.spin, g.dt)
-		g.cl.cam.SetPitch(g.lens.pitch)
+		f.path.step(f)
+		if f.growth != nil {
+			f.growth.step(f.ratio())
+		}
 		if f.tickCnt >= f.ticks {
 			f.Wrap()
 			return false // animation done.
@@ -419,17 +958,27 @@ func (f *fadeLevelAnimation) Animate(dt float64) bool {
 	}
 }
 
+// ratio returns how far along the animation is from 0 to 1.
+func (f *fadeLevelAnimation) ratio() float64 {
+	return math.Min(float64(f.tickCnt)/float64(f.ticks), 1)
+}
+
 // Wrap finishes the fade level animation and sets the player position to
 // a safe and stable location.
 func (f *fadeLevelAnimation) Wrap() {
 	g := f.g
-	g.lens = &cam{}
+	if f.growth != nil {
+		f.growth.dispose()
+		f.growth = nil
+	}
+	g.lens = &cam{deflect: g.deflect}
 	g.cl.setHudVisible(true)
 	g.cl.body.DisposeBody()
 	g.cl.body.MakeBody(vu.Sphere(0.25))
 	g.cl.body.SetSolid(1, 0)
 	x, _, z := g.cl.cam.At()
 	g.cl.cam.SetAt(x, 0.5, z)
+	g.cl.cam.SetFov(g.cl.fov)
 	g.lens.pitch = 0
 	g.cl.cam.SetPitch(g.lens.pitch)
 	g.cl.body.SetAt(x, 0.5, z)
@@ -442,12 +991,228 @@ func (f *fadeLevelAnimation) Wrap() {
 	f.state = 2
 }
 
+// growth shows a copy of the HUD trooper large in the screen center while
+// it gains the starting cells of the new level. The trooper then shrinks
+// back to its HUD location where the HUD trooper takes over.
+type growth struct {
+	ui     *vu.Ent  // Overlay scene shown above the fading level.
+	tr     *trooper // Growing trooper.
+	cells  int      // Starting cell count of the new level.
+	cx, cy float64  // Screen center.
+}
+
+// Growth animation tuning.
+const (
+	growScale  = 250 // Trooper size while growing.
+	growShrink = 0.7 // Fade progress when the trooper starts to shrink.
+	growHudX   = 100 // HUD trooper location, see newPlayer.
+	growHudY   = 100 //   "
+	growHudFit = 100 // HUD trooper size, see makePlayer.
+)
+
+// newGrowth creates an empty trooper for the given trooper level.
+func newGrowth(eng vu.Eng, level, ww, wh int) *growth {
+	gr := &growth{cx: float64(ww / 2), cy: float64(wh / 2)}
+	gr.ui = eng.AddScene().SetUI()
+	gr.ui.Cam().SetClip(0, 10)
+	gr.tr = newTrooper(gr.ui.AddPart(), level)
+	gr.tr.part.SetView(&lin.Q{X: 0.24, Y: 0.16, Z: 0.16, W: 0.95}) // match the HUD tilt.
+	gr.cells, _, _ = gr.tr.health()
+	gr.tr.detachCores(gr.cells)
+	gr.step(0)
+	return gr
+}
+
+// step attaches cells and then shrinks the trooper back to the HUD
+// for the given fade progress from 0 to 1.
+func (gr *growth) step(ratio float64) {
+	want := int(float64(gr.cells) * math.Min(ratio/growShrink, 1))
+	for health, _, _ := gr.tr.health(); health < want; health++ {
+		gr.tr.attach()
+	}
+	shrink := math.Max(ratio-growShrink, 0) / (1 - growShrink)
+	gr.tr.setScale(growScale + (growHudFit-growScale)*shrink)
+	gr.tr.setLoc(gr.cx+(growHudX-gr.cx)*shrink, gr.cy+(growHudY-gr.cy)*shrink, 0)
+}
+
+// dispose removes the growing trooper.
+func (gr *growth) dispose() { gr.ui.Dispose() }
+
 // fadeLevelAnimation
 // ===========================================================================
+// cameraPath
+
+// cameraPath moves the camera while a level fades in or out. Paths are run
+// by fadeLevelAnimation which also fades the level colour and restores the
+// player when the fade is done.
+type cameraPath interface {
+	begin(f *fadeLevelAnimation, x, z float64) // Place the camera near x, z.
+	step(f *fadeLevelAnimation)                // Move the camera one tick.
+}
+
+// Level transition camera paths.
+const (
+	dropTransition   = iota // Tilt and drop through the floor.
+	spiralTransition        // Spin while dropping through the floor.
+	irisTransition          // Narrow the view to a point and back.
+	flyTransition           // Fly over the maze.
+)
+
+// gameTransitions is the camera path used when moving to a given level.
+var gameTransitions = []int{dropTransition, dropTransition, spiralTransition, flyTransition, irisTransition}
+
+// newCameraPath creates the indicated camera path.
+func newCameraPath(kind int) cameraPath {
+	switch kind {
+	case spiralTransition:
+		return &spiralPath{}
+	case irisTransition:
+		return &irisPath{}
+	case flyTransition:
+		return &flyPath{}
+	}
+	return &dropPath{}
+}
+
+// dropPath tilts the camera and drops it below the level when going
+// down, or raises it above the level when going up.
+type dropPath struct {
+	distA float64 // Animation start height.
+	distB float64 // The height where the animation stops.
+	tiltA float64 // Animation start tilt.
+	tiltB float64 // Animation end tilt.
+}
+
+// begin implements cameraPath.
+func (p *dropPath) begin(f *fadeLevelAnimation, x, z float64) {
+	g := f.g
+	if f.out {
+
+		// fading out:
+		// start level drop below if dir == 1
+		//   cam tilt from 0 to 75
+		//   location goes down from 0 to -g.vr.
+		// start level and rise if dir == -1
+		//   cam tilt from 0 to -75
+		//   location goes up from 0 to g.vr.
+		p.tiltA, p.tiltB = 0.0, float64(75*f.dir)
+		p.distA, p.distB = 0.0, float64(f.dir)*-g.vr
+	} else {
+
+		// fading in:
+		// start high and drop to level if dir == 1
+		//   cam tilt from -75 to 0
+		//   location goes from g.vr down to 0.
+		// start low and rise to level if dir == -1
+		//   cam tilt from 75 to 0
+		//   location goes from -g.vr down to 0.
+		p.tiltA, p.tiltB = float64(-75*f.dir), 0.0
+		p.distA, p.distB = float64(f.dir)*g.vr, 0.0
+	}
+	g.lens.pitch = p.tiltA
+	g.cl.cam.SetAt(x, p.distA, z)
+}
+
+// step implements cameraPath.
+func (p *dropPath) step(f *fadeLevelAnimation) {
+	g := f.g
+	move := (p.distB - p.distA) / float64(f.ticks)
+	g.cl.cam.Move(0, move, 0, lin.QI)
+	tilt := (p.tiltB - p.tiltA) / float64(f.ticks) * 2
+	g.lens.pitch = g.lens.updatePitch(g.lens.pitch, tilt, g.spin, g.dt)
+	g.cl.cam.SetPitch(g.lens.pitch)
+}
+
+// spiralPath is a dropPath that also spins the camera one full turn.
+type spiralPath struct {
+	dropPath
+	yaw float64 // Starting camera direction.
+}
+
+// begin implements cameraPath.
+func (p *spiralPath) begin(f *fadeLevelAnimation, x, z float64) {
+	p.dropPath.begin(f, x, z)
+	p.yaw = f.g.cl.cam.Yaw
+}
+
+// step implements cameraPath.
+func (p *spiralPath) step(f *fadeLevelAnimation) {
+	p.dropPath.step(f)
+	f.g.cl.cam.SetYaw(p.yaw + 360*f.ratio()*float64(f.dir))
+}
+
+// irisPath keeps the camera in place and narrows the field of view to
+// a point when fading out, and opens it back up when fading in.
+type irisPath struct{}
+
+// Field of view, in degrees, when the iris is closed.
+const irisClosed = 1.0
+
+// begin implements cameraPath.
+func (p *irisPath) begin(f *fadeLevelAnimation, x, z float64) {
+	g := f.g
+	g.lens.pitch = 0
+	g.cl.cam.SetAt(x, 0.5, z).SetPitch(0)
+	p.step(f)
+}
+
+// step implements cameraPath.
+func (p *irisPath) step(f *fadeLevelAnimation) {
+	open := f.ratio()
+	if f.out {
+		open = 1 - open
+	}
+	f.g.cl.cam.SetFov(irisClosed + (f.g.cl.fov-irisClosed)*open)
+}
+
+// flyPath lifts the camera and flies it over the maze towards the
+// center when fading out, and flies from over the center down to the
+// player when fading in.
+type flyPath struct {
+	ax, ay, az float64 // Flight start.
+	bx, by, bz float64 // Flight end.
+	tiltA      float64 // Flight start tilt.
+	tiltB      float64 // Flight end tilt.
+}
+
+// begin implements cameraPath.
+func (p *flyPath) begin(f *fadeLevelAnimation, x, z float64) {
+	g := f.g
+	cx, _, cz := g.cl.center.At()
+	if f.out {
+		p.ax, p.ay, p.az, p.tiltA = x, 0.5, z, 0
+		p.bx, p.by, p.bz, p.tiltB = cx, g.vr, cz, -60
+	} else {
+		p.ax, p.ay, p.az, p.tiltA = cx, g.vr, cz, -60
+		p.bx, p.by, p.bz, p.tiltB = x, 0.5, z, 0
+	}
+	p.step(f)
+}
+
+// step implements cameraPath.
+func (p *flyPath) step(f *fadeLevelAnimation) {
+	g, r := f.g, f.ratio()
+	g.cl.cam.SetAt(lin.Lerp(p.ax, p.bx, r), lin.Lerp(p.ay, p.by, r), lin.Lerp(p.az, p.bz, r))
+	g.lens.pitch = lin.Lerp(p.tiltA, p.tiltB, r)
+	g.cl.cam.SetPitch(g.lens.pitch)
+}
+
+// cameraPath
+// ===========================================================================
 // Various game algorithms
 
+// gameTickRate is the number of game logic updates each second. The
+// engine calls update at this fixed rate.
+const gameTickRate = 50
+
 // gameMapSize gives the grid size for a given level.
-func gameMapSize(lvl int) int { return lvl*6 + 9 }
+func gameMapSize(lvl int) int { return gameSizes[lvl] }
+
+// gameSizes is the grid width and height for a given level.
+var gameSizes = []int{9, 15, 21, 27, 33}
+
+// gameFade is the distance where a given level fades from view.
+var gameFade = []float64{17.5, 17.5, 17.5, 17.5, 17.5}
 
 // gameCcol is the inverse background colour for the center of the given level.
 func gameCcol(lvl int) float64 { return float64(lvl+1) * 0.15 }
@@ -455,6 +1220,14 @@ func gameCcol(lvl int) float64 { return float64(lvl+1) * 0.15 }
 // gameMuster is the number of sentinels generated for a given level.
 var gameMuster = []int{1, 5, 25, 50, 100}
 
+// gameWaveSize is the number of sentinels released in each spawn wave
+// for a given level.
+var gameWaveSize = []int{1, 5, 5, 10, 10}
+
+// gameWaveTicks is the number of game ticks between sentinel spawn
+// waves for a given level.
+var gameWaveTicks = []int{0, 100, 100, 75, 50}
+
 // gameCellGain gives the per-level number of cells gained for each core
 // collected.
 var gameCellGain = []int{1, 2, 4, 8, 8}
@@ -463,6 +1236,70 @@ var gameCellGain = []int{1, 2, 4, 8, 8}
 // with a sentinel. These are multiples of the corresponding cell gains.
 var gameCellLoss = []int{1, 12, 24, 48, 64}
 
+// gameProximity is the per-level grid distance where a nearby sentinel
+// sets off a proximity warning. Zero turns off the warnings.
+var gameProximity = []int{3, 3, 3, 3, 3}
+
+// gameKnockback is true for the levels where a sentinel collision knocks
+// the player back instead of teleporting the sentinel out of the maze.
+// The knockback mutator turns it on for every level.
+var gameKnockback = []bool{false, false, false, false, false}
+
+// gameForgive is the per-level number of cells lost on the first
+// sentinel collision. It eases new players into the game. Zero means
+// the first collision costs the regular loss.
+var gameForgive = []int{1, 0, 0, 0, 0}
+
+// gameCracks is the per-level number of cracked walls that can be
+// broken open by teleporting from beside them when the hazards
+// mutator is on.
+var gameCracks = []int{0, 2, 3, 4, 5}
+
+// gameGates is the per-level number of one-way gates when the
+// hazards mutator is on.
+var gameGates = []int{0, 0, 1, 2, 3}
+
+// gameVoids is the per-level number of void tiles that drop the
+// player down a level when the hazards mutator is on.
+var gameVoids = []int{1, 1, 1, 2, 2}
+
+// gameCoreLife is the per-level number of seconds a dropped core lasts
+// when the optional core lifetime is turned on.
+var gameCoreLife = []int{40, 30, 30, 25, 20}
+
+// gamePacing is the per-level core drop pacing. Later levels drop
+// cores more slowly, especially when the player wanders far from the
+// maze center.
+var gamePacing = []PacingDef{
+	{Delay: 200, Deficit: 0.05, Distance: 0.25, Away: 2},
+	{Delay: 200, Deficit: 0.05, Distance: 0.25, Away: 2},
+	{Delay: 250, Deficit: 0.05, Distance: 0.5, Away: 2},
+	{Delay: 300, Deficit: 0.05, Distance: 0.5, Away: 3},
+	{Delay: 350, Deficit: 0.05, Distance: 0.5, Away: 3},
+}
+
+// gameFreezeChance is the per-level chance that a core drop also drops
+// a sentinel freeze pickup.
+var gameFreezeChance = []float64{0, 0, 0.02, 0.03, 0.04}
+
+// gameTrail is the per-level number of damaging floor tiles left
+// behind each sentinel when the hazards mutator is on. Zero means
+// the sentinels leave no trail.
+var gameTrail = []int{0, 0, 0, 3, 3}
+
+// gameInterdict is the per-level grid distance around the maze center
+// where teleports can't be started when the hazards mutator is on.
+// Zero means no interdiction zone.
+var gameInterdict = []int{0, 0, 0, 2, 3}
+
+// gameTurrets is the per-level number of dead-end turrets when the
+// hazards mutator is on. Zero means the level has no turrets.
+var gameTurrets = []int{0, 0, 0, 2, 4}
+
+// gameCloakPickups is the per-level number of cloak pickups. Collecting
+// all of them on a visit earns the pathfinder charge.
+var gameCloakPickups = []int{3, 3, 4, 4, 5}
+
 // lastSpot is used during debug to return the player to their previous
 // position when debug fly mode is turned off.
 type lastSpot struct {
@@ -471,9 +1308,3 @@ type lastSpot struct {
 	pitch float64 // up/down.
 	yaw   float64 // spin.
 }
-
-// calculate a unique id for an x, y coordinate.
-func id(x, y, size int) int { return x*size + y }
-
-// get the x, y coordinate for a unique identifier.
-func at(id, size int) (x, y int) { return id % size, id / size }
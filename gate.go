@@ -0,0 +1,158 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// One-way gates are corridor spots that the player can only pass in one
+// direction. A few gates are added to the larger levels after the maze is
+// generated. Gates always let the player head towards the maze center,
+// so the center can still be reached, but the way back out has to be
+// found some other way. Sentinels treat gates as walls. Gates are only
+// added with the hazards mutator.
+
+import (
+	"github.com/gazed/bampf/gridmath"
+	"github.com/gazed/vu"
+	"github.com/gazed/vu/grid"
+)
+
+// gate is a one-way gate.
+type gate struct {
+	spot   gridmath.Spot // Gate grid location.
+	dx, dy int           // Grid direction that the gate can be passed.
+	tile   *vu.Ent       // Portal tile model.
+}
+
+// gateSpots picks up to count straight corridor spots, spread evenly
+// through the maze, and points each gate towards the maze center.
+// The same plan always gives the same gates.
+func gateSpots(plan grid.Grid, count, cx, cy int) (gates []*gate) {
+	dist := centerDistances(plan, cx, cy)
+	width, height := plan.Size()
+	candidates := []*gate{}
+	for x := 1; x < width-1; x++ {
+		for y := 1; y < height-1; y++ {
+			if !plan.IsOpen(x, y) || dist[x][y] <= 0 {
+				continue // walls, the center, and unreachable spots.
+			}
+			g := &gate{spot: gridmath.Spot{X: x, Y: y}}
+			switch {
+			case plan.IsOpen(x-1, y) && plan.IsOpen(x+1, y) && !plan.IsOpen(x, y-1) && !plan.IsOpen(x, y+1):
+				g.dx = 1
+				if dist[x+1][y] > dist[x-1][y] {
+					g.dx = -1
+				}
+			case plan.IsOpen(x, y-1) && plan.IsOpen(x, y+1) && !plan.IsOpen(x-1, y) && !plan.IsOpen(x+1, y):
+				g.dy = 1
+				if dist[x][y+1] > dist[x][y-1] {
+					g.dy = -1
+				}
+			default:
+				continue // not a straight corridor.
+			}
+			candidates = append(candidates, g)
+		}
+	}
+	if count > len(candidates) {
+		count = len(candidates)
+	}
+	for cnt := 0; cnt < count; cnt++ {
+		gates = append(gates, candidates[(cnt+1)*len(candidates)/(count+1)])
+	}
+	return gates
+}
+
+// centerDistances returns the number of grid steps from each open spot
+// to the maze center, -1 for spots that can't reach the center.
+func centerDistances(plan grid.Grid, cx, cy int) [][]int {
+	width, height := plan.Size()
+	dist := make([][]int, width)
+	for x := range dist {
+		dist[x] = make([]int, height)
+		for y := range dist[x] {
+			dist[x][y] = -1
+		}
+	}
+	dist[cx][cy] = 0
+	queue := []gridmath.Spot{{X: cx, Y: cy}}
+	for len(queue) > 0 {
+		at := queue[0]
+		queue = queue[1:]
+		for _, to := range []gridmath.Spot{{X: at.X + 1, Y: at.Y}, {X: at.X - 1, Y: at.Y}, {X: at.X, Y: at.Y + 1}, {X: at.X, Y: at.Y - 1}} {
+			if to.X >= 0 && to.Y >= 0 && to.X < width && to.Y < height &&
+				dist[to.X][to.Y] < 0 && plan.IsOpen(to.X, to.Y) {
+				dist[to.X][to.Y] = dist[at.X][at.Y] + 1
+				queue = append(queue, to)
+			}
+		}
+	}
+	return dist
+}
+
+// gatedPlan is the floorplan seen by sentinels, where gates are walls.
+type gatedPlan struct {
+	grid.Grid                         // Player floorplan.
+	gates     map[gridmath.Spot]*gate // Gates by location.
+}
+
+// IsOpen returns false for gates.
+func (gp *gatedPlan) IsOpen(x, y int) bool {
+	if _, ok := gp.gates[gridmath.Spot{X: x, Y: y}]; ok {
+		return false
+	}
+	return gp.Grid.IsOpen(x, y)
+}
+
+// gatedPlan
+// ===========================================================================
+// level gate handling.
+
+// buildGates adds the gate tiles to the level and the minimap.
+func (lvl *level) buildGates(scene *vu.Ent, hd *hud, plan grid.Grid) {
+	lvl.gates = map[gridmath.Spot]*gate{}
+	for _, g := range gateSpots(plan, gameHazard(gameGates, lvl.num), lvl.gcx, lvl.gcy) {
+		gamex, gamez := gridmath.ToGame(g.spot.X, g.spot.Y, float64(lvl.units))
+		g.tile = scene.AddPart().SetAt(gamex, gateLift, gamez)
+		g.tile.Spin(0, gateYaw(g.dx, g.dy), 0)
+		m := g.tile.MakeModel("uva", "msh:tile", "tex:gate")
+		trackAsset(m, "tex:gate")
+		m.SetAlpha(0.8).SetUniform("fd", lvl.fade)
+		lvl.gates[g.spot] = g
+		hd.addGate(gamex, gamez, g.dx, g.dy)
+	}
+}
+
+// gateLift raises gate tiles just above the floor tiles.
+const gateLift = 0.01
+
+// gateYaw returns the tile spin, in degrees, that points the gate
+// arrows along the given grid direction. Unspun arrows point along
+// increasing grid y.
+func gateYaw(dx, dy int) float64 {
+	switch {
+	case dx > 0:
+		return -90
+	case dx < 0:
+		return 90
+	case dy < 0:
+		return 180
+	}
+	return 0
+}
+
+// passGates stops the player from going through a gate the wrong way by
+// putting the player back where they were on the previous tick.
+func (lvl *level) passGates() {
+	x, y, z := lvl.body.At()
+	gx, gy := gridmath.ToGrid(x, y, z, float64(lvl.units))
+	at := gridmath.Spot{X: gx, Y: gy}
+	if g, ok := lvl.gates[at]; ok && lvl.last == (gridmath.Spot{X: gx + g.dx, Y: gy + g.dy}) {
+		if body := lvl.body.Body(); body != nil {
+			body.Stop()
+			body.Rest()
+		}
+		lvl.body.SetAt(lvl.lastx, y, lvl.lastz)
+		return
+	}
+	lvl.last, lvl.lastx, lvl.lastz = at, x, z
+}
@@ -1,3 +1,5 @@
 module github.com/gazed/bampf
 
+go 1.16
+
 require github.com/gazed/vu v0.10.0
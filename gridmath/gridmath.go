@@ -0,0 +1,49 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+// Package gridmath converts between maze grid locations and game
+// locations. Grid locations are whole numbered maze cells where walls,
+// cores, and sentinels are placed. Game locations are the continuous
+// x, z floor coordinates used by the models and the camera.
+//
+// Grid x runs along game x. Grid y runs along game -z, so moving up the
+// grid moves away from the starting camera view. A game location maps to
+// the grid cell whose center is nearest, with locations exactly half way
+// between cells rounding away from the grid origin. This keeps the cells
+// either side of the origin the same size for negative locations, which
+// are used to park sentinels outside the maze.
+package gridmath
+
+// Spot is a grid location. Spots are comparable and
+// are used as map keys to track grid locations.
+type Spot struct{ X, Y int }
+
+// ToGame returns the game location of the center of the given grid cell.
+// Game locations are where models of cores, walls, and tiles are placed.
+func ToGame(gridx, gridy int, units float64) (gamex, gamez float64) {
+	return float64(gridx) * units, float64(-gridy) * units
+}
+
+// ToGrid returns the grid cell that holds the given game location. The
+// game height is ignored. Grid locations are where cores are dropped
+// or fetched.
+func ToGrid(gamex, gamey, gamez, units float64) (gridx, gridy int) {
+	inv := 1.0 / units
+	adj := units * 0.5
+	xadj := adj
+	if gamex < 0 {
+		xadj = -xadj
+	}
+	yadj := adj
+	if gamez > 0 {
+		yadj = -yadj
+	}
+	return int((gamex + xadj) * inv), int((-gamez + yadj) * inv)
+}
+
+// ID returns a unique identifier for a grid location in a grid
+// that is size cells high. Both coordinates must be in 0 to size-1.
+func ID(x, y, size int) int { return x*size + y }
+
+// At returns the grid location for an identifier from ID.
+func At(id, size int) (x, y int) { return id / size, id % size }
@@ -0,0 +1,115 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package gridmath
+
+import (
+	"math"
+	"testing"
+	"testing/quick"
+)
+
+func TestToGrid(t *testing.T) {
+	units := 2.0
+	gridx, gridy := ToGrid(-0.9, 0, -0.9, units)
+	if gridx != 0 || gridy != 0 {
+		t.Errorf("Expected 0,0 got %d,%d", gridx, gridy)
+	}
+	gridx, gridy = ToGrid(0.9, 0, 0.9, units)
+	if gridx != 0 || gridy != 0 {
+		t.Errorf("Expected 0,0 got %d,%d", gridx, gridy)
+	}
+	gridx, gridy = ToGrid(-0.9, 0, 0.9, units)
+	if gridx != 0 || gridy != 0 {
+		t.Errorf("Expected 0,0 got %d,%d", gridx, gridy)
+	}
+	gridx, gridy = ToGrid(0.9, 0, -0.9, units)
+	if gridx != 0 || gridy != 0 {
+		t.Errorf("Expected 0,0 got %d,%d", gridx, gridy)
+	}
+
+	gridx, gridy = ToGrid(1.01, 0, -1.01, units)
+	if gridx != 1 || gridy != 1 {
+		t.Errorf("Expected 1,1 got %d,%d", gridx, gridy)
+	}
+	gridx, gridy = ToGrid(2.99, 0, -2.99, units)
+	if gridx != 1 || gridy != 1 {
+		t.Errorf("Expected 1,1 got %d,%d", gridx, gridy)
+	}
+	gridx, gridy = ToGrid(1.01, 0, -2.99, units)
+	if gridx != 1 || gridy != 1 {
+		t.Errorf("Expected 1,1 got %d,%d", gridx, gridy)
+	}
+	gridx, gridy = ToGrid(2.99, 0, -1.01, units)
+	if gridx != 1 || gridy != 1 {
+		t.Errorf("Expected 1,1 got %d,%d", gridx, gridy)
+	}
+
+	gridx, gridy = ToGrid(-1.01, 0, 1.01, units)
+	if gridx != -1 || gridy != -1 {
+		t.Errorf("Expected -1,-1 got %d,%d", gridx, gridy)
+	}
+	gridx, gridy = ToGrid(-2.99, 0, 2.99, units)
+	if gridx != -1 || gridy != -1 {
+		t.Errorf("Expected -1,-1 got %d,%d", gridx, gridy)
+	}
+	gridx, gridy = ToGrid(-1.01, 0, 2.99, units)
+	if gridx != -1 || gridy != -1 {
+		t.Errorf("Expected -1,-1 got %d,%d", gridx, gridy)
+	}
+	gridx, gridy = ToGrid(-2.99, 0, 1.01, units)
+	if gridx != -1 || gridy != -1 {
+		t.Errorf("Expected -1,-1 got %d,%d", gridx, gridy)
+	}
+}
+
+// Game locations exactly half way between cells round away from the origin.
+func TestToGridHalfway(t *testing.T) {
+	if x, y := ToGrid(1, 0, -1, 2); x != 1 || y != 1 {
+		t.Errorf("Expected 1,1 got %d,%d", x, y)
+	}
+	if x, y := ToGrid(-1, 0, 1, 2); x != -1 || y != -1 {
+		t.Errorf("Expected -1,-1 got %d,%d", x, y)
+	}
+}
+
+// Every grid cell maps to a game location that maps back to the same cell.
+func TestGridRoundTrip(t *testing.T) {
+	roundTrip := func(x, y int16, scale uint8) bool {
+		units := 0.5 + float64(scale)/16
+		gamex, gamez := ToGame(int(x), int(y), units)
+		gridx, gridy := ToGrid(gamex, 0, gamez, units)
+		return gridx == int(x) && gridy == int(y)
+	}
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Every game location is within half a cell of the center of its grid cell.
+func TestGameRoundTrip(t *testing.T) {
+	nearest := func(x, z int16) bool {
+		units, gamex, gamez := 2.0, float64(x)/100, float64(z)/100 // maze sized locations.
+		gridx, gridy := ToGrid(gamex, 0, gamez, units)
+		cx, cz := ToGame(gridx, gridy, units)
+		return math.Abs(cx-gamex) <= units*0.5 && math.Abs(cz-gamez) <= units*0.5
+	}
+	config := &quick.Config{MaxCount: 1000}
+	if err := quick.Check(nearest, config); err != nil {
+		t.Error(err)
+	}
+}
+
+// Grid identifiers are unique and convert back to the same grid location.
+func TestIDRoundTrip(t *testing.T) {
+	size, seen := 7, map[int]bool{}
+	for x := 0; x < size; x++ {
+		for y := 0; y < size; y++ {
+			id := ID(x, y, size)
+			if ax, ay := At(id, size); ax != x || ay != y || seen[id] {
+				t.Fatalf("Expected %d,%d to have a unique id, got %d at %d,%d", x, y, id, ax, ay)
+			}
+			seen[id] = true
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Haptics rumbles a game controller on sentinel collisions, teleports, and
+// core pickups. The vu engine does not yet support game controllers, so
+// rumbles go to a quiet device until a controller device is added.
+// Rumbling always stops when the game is paused or loses focus.
+
+import "time"
+
+// rumbler is implemented by game controllers that can vibrate.
+type rumbler interface {
+	rumble(strength float64, d time.Duration) // Vibrate, strength from 0 to 1.
+	stop()                                    // Stop any vibration.
+}
+
+// quietRumbler is the rumbler used when there is no game controller.
+type quietRumbler struct{}
+
+// rumble and stop implement rumbler by doing nothing.
+func (qr quietRumbler) rumble(strength float64, d time.Duration) {}
+func (qr quietRumbler) stop()                                    {}
+
+// Rumble intensity settings. These are also the saved setting names.
+const (
+	rumbleOff  = "off"
+	rumbleLow  = "low"
+	rumbleFull = "full"
+)
+
+// rumbleSettings lists the rumble intensities in the order they are chosen.
+var rumbleSettings = []string{rumbleFull, rumbleLow, rumbleOff}
+
+// Game feedback rumbles.
+var (
+	collideRumble  = rumble{1.0, 400 * time.Millisecond}
+	teleportRumble = rumble{0.6, 250 * time.Millisecond}
+	fetchRumble    = rumble{0.3, 100 * time.Millisecond}
+)
+
+// rumble is a vibration at full intensity.
+type rumble struct {
+	strength float64       // Vibration strength from 0 to 1.
+	duration time.Duration // Vibration length.
+}
+
+// haptics scales game feedback to the chosen intensity and holds
+// the feedback while the game is paused.
+type haptics struct {
+	device  rumbler // Game controller.
+	scale   float64 // Rumble intensity from 0 to 1.
+	stopped bool    // True while the game is paused or unfocused.
+}
+
+// newHaptics returns haptics at full intensity with no game controller.
+func newHaptics() *haptics {
+	return &haptics{device: quietRumbler{}, scale: 1}
+}
+
+// setIntensity changes the rumble intensity to one of the rumble settings.
+func (h *haptics) setIntensity(setting string) {
+	switch setting {
+	case rumbleOff:
+		h.scale = 0
+		h.device.stop()
+	case rumbleLow:
+		h.scale = 0.4
+	default:
+		h.scale = 1
+	}
+}
+
+// play rumbles the controller unless rumble is off or the game is paused.
+func (h *haptics) play(r rumble) {
+	if h.scale > 0 && !h.stopped {
+		h.device.rumble(r.strength*h.scale, r.duration)
+	}
+}
+
+// hold stops any rumble, and ignores new rumbles, while the game is
+// paused or unfocused. Rumbles are allowed again once hold is false.
+func (h *haptics) hold(stopped bool) {
+	if stopped && !h.stopped {
+		h.device.stop()
+	}
+	h.stopped = stopped
+}
@@ -4,6 +4,7 @@
 package main
 
 import (
+	"math"
 	"strconv"
 
 	"github.com/gazed/vu"
@@ -12,14 +13,42 @@ import (
 
 // hud is the 2D controller for all parts of the games heads-up-display (HUD).
 type hud struct {
-	ui   *vu.Ent  // 2D scene.
-	area          // Hud fills up the full screen.
-	pl   *player  // Player model.
-	xp   *xpbar   // Show cores collected and current energy.
-	mm   *minimap // Show overhead map centered on player.
-	ce   *vu.Ent  // Cloaking effect.
-	te   *vu.Ent  // Teleport effect.
-	ee   *vu.Ent  // Energy loss effect.
+	ui   *vu.Ent    // 2D scene.
+	area            // Hud fills up the full screen.
+	pl   *player    // Player model.
+	xp   *xpbar     // Show cores collected and current energy.
+	rd   *radial    // Show teleport energy around the teleport icon.
+	sc   *captions  // Show captions for game sounds.
+	mm   *minimap   // Show overhead map centered on player.
+	dv   *detector  // Show nearby sentinels through walls while cloaked.
+	ce   *vu.Ent    // Cloaking effect.
+	te   *vu.Ent    // Teleport effect.
+	ee   *vu.Ent    // Energy loss effect.
+	tv   *vu.Ent    // Teleport effect for photo-sensitive players.
+	ev   *vu.Ent    // Energy loss effect for photo-sensitive players.
+	lv   *vu.Ent    // Lingering red vignette while hits leave health low.
+	lp   int        // Game ticks the low health vignette has shown, 0 if hidden.
+	cp   *vu.Ent    // Evolve countdown prompt.
+	pp   *vu.Ent    // General player prompt.
+	fz   *vu.Ent    // Sentinel freeze timer.
+	rt   *vu.Ent    // Optional run timer.
+	rs   *vu.Ent    // Last level split below the run timer.
+	rv   *vu.Ent    // Network race rival progress.
+	bh   *vu.Ent    // Large health counter for the stream layout.
+	bc   *vu.Ent    // Large core counter for the stream layout.
+	bn   *vu.Ent    // Level flavor text banner.
+	bt   int        // Game ticks until the banner is hidden.
+	rl   *vu.Ent    // Run conditions shown below the banner at run start.
+	ls   *vu.Ent    // Cells lost by the last sentinel hit.
+	lt   int        // Game ticks until the cells lost are hidden.
+	gn   *vu.Ent    // Cells gained by the last core.
+	gt   int        // Game ticks until the cells gained are hidden.
+	ch   *crosshair // Crosshair at the center of the screen.
+	pw   *vu.Ent    // Practice mode watermark.
+	rc   *vu.Ent    // Countdown before a paused game resumes.
+	mode int        // Layout mode, one of normalHud, streamHud, or hiddenHud.
+	show bool       // False while the HUD is hidden for level transitions.
+	safe bool       // True to use the photo-sensitive effects.
 }
 
 // newHud creates all the various parts of the heads up display.
@@ -32,10 +61,46 @@ func newHud(eng vu.Eng, sentryCount, wx, wy, ww, wh int) *hud {
 	// create the HUD parts.
 	hd.pl = newPlayer(hd.ui.AddPart(), hd.w, hd.h)
 	hd.xp = newXpbar(hd.ui, hd.w, hd.h)
+	hd.rd = newRadial(hd.ui)
 	hd.mm = newMinimap(eng, sentryCount)
+	hd.sc = newCaptions(hd.ui.AddPart())
+	hd.dv = newDetector(hd.ui, sentryCount)
 	hd.ce = hd.cloakingEffect(hd.ui.AddPart())
 	hd.te = hd.teleportEffect(hd.ui.AddPart())
 	hd.ee = hd.energyLossEffect(hd.ui.AddPart())
+	hd.tv = hd.vignetteEffect(hd.ui.AddPart(), "smokeedge")
+	hd.ev = hd.vignetteEffect(hd.ui.AddPart(), "lossedge")
+	hd.lv = hd.vignetteEffect(hd.ui.AddPart(), "lossedge")
+	hd.cp = hd.ui.AddPart().MakeLabel("labeled", "lucidiaSu22")
+	hd.cp.Cull(true)
+	hd.pp = hd.ui.AddPart().MakeLabel("labeled", "lucidiaSu22")
+	hd.pp.Cull(true)
+	hd.fz = hd.ui.AddPart().MakeLabel("labeled", "lucidiaSu22")
+	hd.fz.Cull(true)
+	hd.rt = hd.ui.AddPart().MakeLabel("labeled", "lucidiaSu22")
+	hd.rs = hd.ui.AddPart().MakeLabel("labeled", "lucidiaSu18")
+	hd.rv = hd.ui.AddPart().MakeLabel("labeled", "lucidiaSu18")
+	hd.bh = hd.ui.AddPart().MakeLabel("labeled", "lucidiaSu22")
+	hd.bc = hd.ui.AddPart().MakeLabel("labeled", "lucidiaSu22")
+	hd.bn = hd.ui.AddPart().MakeLabel("labeled", "lucidiaSu22")
+	hd.bn.Cull(true)
+	hd.rl = hd.ui.AddPart().MakeLabel("labeled", "lucidiaSu18")
+	hd.rl.Cull(true)
+	hd.ls = hd.ui.AddPart().MakeLabel("labeled", "lucidiaSu22")
+	hd.ls.SetColor(1, 0.3, 0.3)
+	hd.ls.Cull(true)
+	hd.gn = hd.ui.AddPart().MakeLabel("labeled", "lucidiaSu22")
+	hd.gn.SetColor(0.3, 1, 0.6)
+	hd.gn.Cull(true)
+	hd.ch = newCrosshair(hd.ui.AddPart())
+	hd.pw = hd.ui.AddPart().MakeLabel("labeled", "lucidiaSu22")
+	hd.pw.SetStr("PRACTICE - nothing is recorded")
+	hd.pw.SetColor(1, 0.9, 0.2)
+	hd.pw.Cull(true)
+	hd.rc = hd.ui.AddPart().MakeLabel("labeled", "lucidiaSu22")
+	hd.rc.Cull(true)
+	hd.show = true
+	hd.setLayout(normalHud)
 	hd.resize(hd.w, hd.h)
 	return hd
 }
@@ -50,7 +115,20 @@ func (hd *hud) setSize(screenX, screenY, screenWidth, screenHeight int) {
 func (hd *hud) resize(screenWidth, screenHeight int) {
 	hd.setSize(0, 0, screenWidth, screenHeight)
 	hd.xp.resize(screenWidth, screenHeight)
+	hd.rd.resize(screenWidth, screenHeight)
 	hd.mm.resize(screenWidth, screenHeight)
+	hd.sc.resize(screenWidth, screenHeight)
+
+	// keep the text legible on large windows.
+	for _, label := range []*vu.Ent{hd.cp, hd.pp, hd.fz, hd.rt, hd.rs, hd.rv, hd.bn, hd.rl, hd.ls, hd.gn, hd.pw} {
+		scaleLabel(label, 1)
+	}
+	w, _ := labelSize(hd.pw)
+	hd.pw.SetAt(hd.cx-float64(w/2), float64(hd.h)-40*textScale, 0)
+	scaleLabel(hd.bh, 2)
+	scaleLabel(hd.bc, 2)
+	scaleLabel(hd.rc, 3)
+	hd.ch.place(hd.cx, hd.cy)
 
 	// resize the animation effects.
 	hd.ce.SetScale(float64(hd.w), float64(hd.h), 1)
@@ -59,13 +137,51 @@ func (hd *hud) resize(screenWidth, screenHeight int) {
 	hd.te.SetAt(hd.cx, hd.cy, -1)
 	hd.ee.SetScale(float64(hd.w), float64(hd.h), 1)
 	hd.ee.SetAt(hd.cx, hd.cy, -1)
+	hd.tv.SetScale(float64(hd.w), float64(hd.h), 1)
+	hd.tv.SetAt(hd.cx, hd.cy, -1)
+	hd.ev.SetScale(float64(hd.w), float64(hd.h), 1)
+	hd.ev.SetAt(hd.cx, hd.cy, -1)
 }
 
 // setVisible turns the HUD on/off. This is used when transitioning
 // between levels.
 func (hd *hud) setVisible(isVisible bool) {
-	hd.ui.Cull(!isVisible)
-	hd.mm.setVisible(isVisible)
+	hd.show = isVisible
+	hd.ui.Cull(!isVisible || hd.mode == hiddenHud)
+	hd.mm.setVisible(isVisible && hd.mode == normalHud)
+}
+
+// HUD layout modes.
+const (
+	normalHud = iota // All the HUD parts.
+	streamHud        // Large counters and no minimap for streaming.
+	hiddenHud        // No HUD at all.
+)
+
+// setLayout switches between the HUD layout modes.
+func (hd *hud) setLayout(mode int) {
+	hd.mode = mode
+	hd.bh.Cull(mode != streamHud)
+	hd.bc.Cull(mode != streamHud)
+	hd.setVisible(hd.show)
+}
+
+// showCounters updates the large health and core counters
+// shown in the stream layout.
+func (hd *hud) showCounters(health, max, cores int) {
+	if hd.mode != streamHud {
+		return
+	}
+	hd.bh.SetStr("Cells " + strconv.Itoa(health) + "/" + strconv.Itoa(max))
+	hd.bh.SetAt(20, float64(hd.h)-70*textScale, 0)
+	hd.bc.SetStr("Cores " + strconv.Itoa(cores))
+	hd.bc.SetAt(20, float64(hd.h)-120*textScale, 0)
+}
+
+// dispose removes the HUD scenes.
+func (hd *hud) dispose() {
+	hd.ui.Dispose()
+	hd.mm.ui.Dispose()
 }
 
 // setLevel is called when a level transition happens.
@@ -73,48 +189,353 @@ func (hd *hud) setLevel(lvl *level) {
 	hd.pl.setLevel(lvl)
 	hd.xp.setLevel(lvl)
 	hd.mm.setLevel(lvl.cam, lvl)
+	lvl.player.monitorEnergy("radial", hd.rd)
+	hd.rd.energyUpdated(lvl.player.energy())
+	hd.lingerLoss(false)
 }
 
 // have the hud wrap the minimap specifics so as to provide a single
 // outside interface.
-func (hd *hud) addWall(gamex, gamez float64)              { hd.mm.addWall(gamex, gamez) }
-func (hd *hud) remCore(gamex, gamez float64)              { hd.mm.remCore(gamex, gamez) }
-func (hd *hud) addCore(gamex, gamez float64)              { hd.mm.addCore(gamex, gamez) }
-func (hd *hud) resetCores()                               { hd.mm.resetCores() }
-func (hd *hud) update(c *vu.Camera, sentries []*sentinel) { hd.mm.update(c, sentries) }
+func (hd *hud) addWall(gamex, gamez float64) { hd.mm.addWall(gamex, gamez) }
+func (hd *hud) remWall(gamex, gamez float64) { hd.mm.remWall(gamex, gamez) }
+func (hd *hud) addGate(gamex, gamez float64, dx, dy int) {
+	hd.mm.addGate(gamex, gamez, dx, dy)
+}
+func (hd *hud) addBoundary(minx, minz, maxx, maxz float64) {
+	hd.mm.addBoundary(minx, minz, maxx, maxz)
+}
+func (hd *hud) remCore(gamex, gamez float64) { hd.mm.remCore(gamex, gamez) }
+func (hd *hud) addCore(gamex, gamez float64) { hd.mm.addCore(gamex, gamez) }
+func (hd *hud) resetCores()                  { hd.mm.resetCores() }
+func (hd *hud) showTrails(burns []square)    { hd.mm.showTrails(burns) }
+func (hd *hud) blockTeleport(blocked bool)   { hd.rd.setBlocked(blocked) }
+func (hd *hud) caption(text string)          { hd.sc.add(text) }
+func (hd *hud) previewTeleport(on bool)      { hd.mm.tpm.Cull(!on) }
+
+// pointToCore updates the nearest core hint for the current camera.
+func (hd *hud) pointToCore(c *vu.Camera, cc *coreControl) {
+	x, _, z := c.At()
+	hd.xp.pointToCore(x, z, c.Yaw, cc)
+}
+
+// update is called each game tick to update the minimap and captions.
+// Returns the number of new sentinel proximity warnings.
+func (hd *hud) update(c *vu.Camera, enemies []enemy, cloaked bool) (warnings int) {
+	stop := timeStage("minimap")
+	warnings = hd.mm.update(c, enemies, cloaked)
+	stop()
+	hd.sc.update()
+	hd.rd.update()
+	hd.xp.update()
+	hd.ch.update()
+	if hd.bt > 0 {
+		hd.bt--
+		hd.bn.Cull(hd.bt <= 0)
+		hd.rl.Cull(hd.bt <= 0)
+	}
+	if hd.lt > 0 {
+		hd.lt--
+		hd.ls.SetAlpha(float64(hd.lt) / lossTicks)
+		hd.ls.Cull(hd.lt <= 0)
+	}
+	if hd.gt > 0 {
+		hd.gt--
+		hd.gn.SetAlpha(float64(hd.gt) / gainTicks)
+		hd.gn.Cull(hd.gt <= 0)
+		hd.placeGain()
+	}
+	return warnings
+}
+
+// showCountdown prompts the player with the number of seconds until
+// they evolve. The prompt is hidden when there are no seconds left.
+func (hd *hud) showCountdown(secs int) {
+	hd.cp.Cull(secs <= 0)
+	if secs > 0 {
+		hd.cp.SetStr("Evolving in " + strconv.Itoa(secs) + ". Step off the center to wait.")
+		w, _ := labelSize(hd.cp)
+		hd.cp.SetAt(hd.cx-float64(w/2), hd.cy+80*textScale, 0)
+	}
+}
+
+// showResume shows the seconds left until a paused game resumes in
+// large numbers just above the crosshair. No seconds hides the countdown.
+func (hd *hud) showResume(secs int) {
+	hd.rc.Cull(secs <= 0)
+	if secs > 0 {
+		hd.rc.SetStr(strconv.Itoa(secs))
+		w, _ := labelSize(hd.rc)
+		hd.rc.SetAt(hd.cx-float64(w/2), hd.cy+40*textScale, 0)
+	}
+}
+
+// showPrompt asks the player something below the countdown prompt.
+// An empty prompt is hidden.
+func (hd *hud) showPrompt(text string) {
+	hd.pp.Cull(text == "")
+	if text != "" {
+		hd.pp.SetStr(text)
+		w, _ := labelSize(hd.pp)
+		hd.pp.SetAt(hd.cx-float64(w/2), hd.cy+50*textScale, 0)
+	}
+}
+
+// showBanner shows the flavor text near the top of the screen for
+// a few seconds. An empty banner is ignored.
+func (hd *hud) showBanner(text string) {
+	if text != "" {
+		hd.bt = bannerTicks
+		hd.bn.SetStr(text)
+		w, _ := labelSize(hd.bn)
+		hd.bn.SetAt(hd.cx-float64(w/2), float64(hd.h)-100*textScale, 0)
+		hd.bn.Cull(false)
+	}
+}
+
+// showRules shows the run conditions below the banner for as long
+// as the banner is shown.
+func (hd *hud) showRules(text string) {
+	hd.bt = bannerTicks
+	hd.rl.SetStr(text)
+	w, _ := labelSize(hd.rl)
+	hd.rl.SetAt(hd.cx-float64(w/2), float64(hd.h)-130*textScale, 0)
+	hd.rl.Cull(false)
+}
+
+// showPractice shows or hides the practice mode watermark.
+func (hd *hud) showPractice(on bool) { hd.pw.Cull(!on) }
+
+// bannerTicks is the number of game ticks that flavor text is shown.
+const bannerTicks = 200
+
+// showLoss briefly flashes the number of cells lost just below the
+// crosshair, and the crosshair itself. The number fades out over a
+// second or so.
+func (hd *hud) showLoss(cells int) {
+	if cells > 0 {
+		hd.ch.flashRed()
+		hd.lt = lossTicks
+		hd.ls.SetStr("-" + strconv.Itoa(cells))
+		hd.ls.SetAlpha(1)
+		w, _ := labelSize(hd.ls)
+		hd.ls.SetAt(hd.cx-float64(w/2), hd.cy-60*textScale, 0)
+		hd.ls.Cull(false)
+	}
+}
+
+// lossTicks is the number of game ticks that the cells lost are shown.
+const lossTicks = 60
+
+// showCombo shows the combo strength, from 0 for no combo to 1
+// for the largest combo, on the health bar and minimap.
+func (hd *hud) showCombo(strength float64) {
+	hd.xp.tint(strength)
+	hd.mm.combo = strength
+}
+
+// showFreeze shows the seconds until frozen sentinels move again.
+// The timer is hidden when there are no seconds left.
+func (hd *hud) showFreeze(secs int) {
+	hd.fz.Cull(secs <= 0)
+	if secs > 0 {
+		hd.fz.SetStr("Sentinels frozen " + strconv.Itoa(secs))
+		w, _ := labelSize(hd.fz)
+		hd.fz.SetAt(hd.cx-float64(w/2), hd.cy+110*textScale, 0)
+	}
+}
+
+// showTimer shows the run time and the last level split in the top right
+// corner when the run timer is on.
+func (hd *hud) showTimer(on bool, secs float64, split string) {
+	hd.rt.Cull(!on)
+	hd.rs.Cull(!on || split == "")
+	if on {
+		hd.rt.SetStr(formatSplit(secs))
+		w, _ := labelSize(hd.rt)
+		hd.rt.SetAt(float64(hd.w-w-20), float64(hd.h)-35*textScale, 0)
+		hd.rs.SetStr(split)
+		w, _ = labelSize(hd.rs)
+		hd.rs.SetAt(float64(hd.w-w-20), float64(hd.h)-60*textScale, 0)
+	}
+}
+
+// showRival shows the rival progress in the top left corner.
+func (hd *hud) showRival(text string) {
+	hd.rv.SetStr(text)
+	hd.rv.SetAt(20, float64(hd.h)-35*textScale, 0)
+}
+
+// showPartner shows or hides the co-op partner on the minimap.
+func (hd *hud) showPartner(x, z float64, visible bool) {
+	hd.mm.pm.Cull(!visible)
+	hd.mm.pm.SetAt(x, -z, 0)
+}
+
+// newPingAnimation highlights a new core on the minimap.
+func (hd *hud) newPingAnimation(gamex, gamez float64) animation {
+	return hd.mm.newPingAnimation(gamex, gamez)
+}
 
 // cloakingEffect creates the model shown when the user cloaks.
 func (hd *hud) cloakingEffect(ce *vu.Ent) *vu.Ent {
 	ce.Cull(true)
 	ce.MakeModel("textured", "msh:icon", "tex:cloakon")
+	trackAsset(ce, "tex:cloakon")
 	ce.SetAlpha(0.5)
 	return ce
 }
-func (hd *hud) cloakingActive(isActive bool) { hd.ce.Cull(!isActive) }
+func (hd *hud) cloakingActive(isActive bool) {
+	hd.ce.Cull(!isActive)
+	hd.ch.setHollow(isActive)
+}
+
+// fetchedCore blooms the crosshair when a core is collected. Cores worth
+// more than one cell bloom wider and float the cells gained above the
+// health bar.
+func (hd *hud) fetchedCore(cells int) {
+	hd.ch.bloomOut(cells)
+	if cells > 1 {
+		hd.gt = gainTicks
+		hd.gn.SetStr("+" + strconv.Itoa(cells))
+		hd.gn.SetAlpha(1)
+		hd.gn.Cull(false)
+		hd.placeGain()
+	}
+}
+
+// gainTicks is the number of game ticks that the cells gained are shown.
+const gainTicks = 50
+
+// placeGain floats the cells gained upwards as they fade.
+func (hd *hud) placeGain() {
+	w, _ := labelSize(hd.gn)
+	rise := float64(gainTicks-hd.gt) * 0.6
+	hd.gn.SetAt(hd.cx-float64(w/2), (110+rise)*textScale, 0)
+}
+
+// setCrosshair changes the crosshair shape, size, and colour.
+func (hd *hud) setCrosshair(style []string) { hd.ch.setStyle(style) }
+
+// detect shows nearby sentinels through walls while the player is cloaked.
+func (hd *hud) detect(c *vu.Camera, enemies []enemy, cloaked bool) {
+	hd.dv.update(c, enemies, cloaked, hd.w, hd.h)
+}
 
 // teleportEffect creates the model shown when the user teleports.
 func (hd *hud) teleportEffect(te *vu.Ent) *vu.Ent {
 	te.Cull(true)
 	m := te.MakeModel("uvra", "msh:icon", "tex:smoke")
+	trackAsset(m, "tex:smoke")
 	m.SetAlpha(0.5).SetUniform("spin", 10.0).SetUniform("fd", 1000)
 	return te
 }
-func (hd *hud) teleportActive(isActive bool) { hd.te.Cull(!isActive) }
-func (hd *hud) teleportFade(alpha float64) {
-	hd.te.SetAlpha(lin.Clamp(alpha, 0, 1))
-}
+func (hd *hud) teleportActive(isActive bool) { hd.effect(hd.te, hd.tv).Cull(!isActive) }
+func (hd *hud) teleportFade(alpha float64)   { hd.fade(hd.effect(hd.te, hd.tv), alpha) }
 
 // energyLossEffect creates the model shown when the player gets hit
 // by a sentinel.
 func (hd *hud) energyLossEffect(ee *vu.Ent) *vu.Ent {
 	ee.Cull(true)
 	m := ee.MakeModel("uvra", "msh:icon", "tex:loss")
+	trackAsset(m, "tex:loss")
 	m.SetAlpha(0.5).SetUniform("fd", 1000).SetUniform("spin", 2.0)
 	return ee
 }
-func (hd *hud) energyLossActive(isActive bool) { hd.ee.Cull(!isActive) }
-func (hd *hud) energyLossFade(alpha float64) {
-	hd.ee.SetAlpha(lin.Clamp(alpha, 0, 1))
+func (hd *hud) energyLossActive(isActive bool) { hd.effect(hd.ee, hd.ev).Cull(!isActive) }
+func (hd *hud) energyLossFade(alpha float64)   { hd.fade(hd.effect(hd.ee, hd.ev), alpha) }
+
+// Energy loss effect tuning. The effect grows with the share
+// of the maximum health lost in one hit.
+const (
+	lossFull  = 0.25 // Share of health lost that shows the strongest effect.
+	lossFaint = 0.3  // Flash alpha for the smallest losses.
+	lossBrief = 15   // Flash length in animation steps for the smallest losses.
+	lossLong  = 45   // Flash length in animation steps for the largest losses.
+	lossShake = 8.0  // Largest screen shake in pixels.
+	lossPulse = 0.05 // Low health vignette pulse speed.
+)
+
+// lossEffect returns the energy loss flash alpha, length in animation
+// steps, and screen shake in pixels for the share of health lost.
+func lossEffect(lost float64) (alpha float64, steps int, shake float64) {
+	strength := clamp(lost/lossFull, 0, 1)
+	alpha = lossFaint + (1-lossFaint)*strength
+	steps = lossBrief + int(float64(lossLong-lossBrief)*strength+0.5)
+	return alpha, steps, lossShake * strength
+}
+
+// shake offsets the HUD, and with it the energy loss flash, by the given
+// pixels. There is no shake for photo-sensitive players.
+func (hd *hud) shake(dx, dy float64) {
+	if hd.safe {
+		dx, dy = 0, 0
+	}
+	hd.ui.Cam().SetAt(dx, dy, 0)
+}
+
+// lingerLoss pulses a faint red vignette while low is true. The vignette
+// is started by a hit that leaves the player below the warning health, see
+// level.loseCells, and hidden once the health recovers or low is false.
+// Expected to be called each game tick.
+func (hd *hud) lingerLoss(low bool) {
+	if !low || hd.lp == 0 {
+		hd.lp = 0
+		hd.lv.Cull(true)
+		return
+	}
+	hd.lp++
+	hd.lv.SetAlpha(safeAlpha * (0.6 + 0.4*math.Sin(float64(hd.lp)*lossPulse)))
+	hd.lv.Cull(false)
+}
+
+// startLinger shows the low health vignette from the next game tick.
+func (hd *hud) startLinger() {
+	if hd.lp == 0 {
+		hd.lp = 1
+	}
+}
+
+// vignetteEffect creates a screen border used in place of a full screen
+// flash for photo-sensitive players. There is no spin and the effect
+// stays faint, see fade.
+func (hd *hud) vignetteEffect(ve *vu.Ent, texture string) *vu.Ent {
+	ve.Cull(true)
+	ve.MakeModel("textured", "msh:icon", "tex:"+texture)
+	trackAsset(ve, "tex:"+texture)
+	ve.SetAlpha(safeAlpha)
+	return ve
+}
+
+// safeAlpha is the maximum vignette alpha in photo-sensitive mode.
+const safeAlpha = 0.4
+
+// setSafeMode switches between the full screen effects
+// and the photo-sensitive effects.
+func (hd *hud) setSafeMode(safe bool) {
+	hd.te.Cull(true)
+	hd.ee.Cull(true)
+	hd.tv.Cull(true)
+	hd.ev.Cull(true)
+	hd.safe = safe
+	hd.rd.safe = safe
+	hd.xp.safe = safe
+}
+
+// effect returns the full screen effect or, in photo-sensitive mode,
+// its vignette replacement.
+func (hd *hud) effect(full, vignette *vu.Ent) *vu.Ent {
+	if hd.safe {
+		return vignette
+	}
+	return full
+}
+
+// fade sets the effect transparency, keeping vignettes faint.
+func (hd *hud) fade(effect *vu.Ent, alpha float64) {
+	if hd.safe {
+		alpha *= safeAlpha
+	}
+	effect.SetAlpha(lin.Clamp(alpha, 0, 1))
 }
 
 // hud
@@ -137,6 +558,7 @@ func newPlayer(pov *vu.Ent, screenWidth, screenHeight int) *player {
 	pl.cx, pl.cy = 100, 100
 	pl.bg = pov.SetScale(110, 110, 1).SetAt(pl.cx, pl.cy, 0)
 	pl.bg.MakeModel("textured", "msh:icon", "tex:hudbg")
+	trackAsset(pl.bg, "tex:hudbg")
 	return pl
 }
 
@@ -159,54 +581,49 @@ func (pl *player) setLevel(lvl *level) {
 type xpbar struct {
 	area
 	border int      // Offset from the edge of the screen.
-	linew  int      // Line width for the box.
 	bh, bw int      // Bar height and width.
-	bg     *vu.Ent  // Health background bar.
-	fg     *vu.Ent  // Health foreground bar.
-	cbg    *vu.Ent  // Cloak energy background bar.
-	cfg    *vu.Ent  // Cloak energy foreground bar.
-	tbg    *vu.Ent  // Teleport energy background bar.
-	tfg    *vu.Ent  // Teleport energy foreground bar.
+	hbar   *segbar  // Health bar.
+	cbar   *segbar  // Cloak energy bar.
+	tbar   *segbar  // Teleport energy bar.
 	hb     *vu.Ent  // Display health amount.
 	hbw    int      // Display health width in pixels.
+	ca     *vu.Ent  // Direction to the nearest core.
+	cd     *vu.Ent  // Distance to the nearest core.
 	tk     *vu.Ent  // Display teleport key.
 	tkw    int      // Display key width in pixels.
 	ck     *vu.Ent  // Display cloak key.
 	ckw    int      // Display key width in pixels.
 	tr     *trooper // Current player injected with SetStage.
+	safe   bool     // True to show a steady low cloak warning.
+	combo  float64  // Current health bar combo tint.
 }
 
 // newXpbar creates all three status bars.
 func newXpbar(scene *vu.Ent, screenWidth, screenHeight int) *xpbar {
 	xp := &xpbar{}
 	xp.border = 5
-	xp.linew = 2
 	xp.setSize(screenWidth, screenHeight)
 
-	// add the xp background and foreground bars.
-	xp.bg = scene.AddPart()
-	xp.bg.MakeModel("colored", "msh:square", "mat:tgray")
-	xp.fg = scene.AddPart()
-	xp.fg.MakeModel("textured", "msh:icon", "tex:xpcyan", "tex:xpred")
+	// add the health bar.
+	xp.hbar = newSegbar(scene, healthSegments, "xpcyan", "xpred")
 
 	// add the xp bar text.
 	xp.hb = scene.AddPart()
 	xp.hb.MakeLabel("labeled", "lucidiaSu22")
 
-	// teleport energy background and foreground bars.
-	xp.tbg = scene.AddPart()
-	xp.tbg.MakeModel("colored", "msh:square", "mat:tgray")
-	xp.tfg = scene.AddPart()
-	xp.tfg.MakeModel("textured", "msh:icon", "tex:xpblue", "tex:xpred")
+	// the nearest core direction and distance.
+	xp.ca = scene.AddPart().SetScale(10, 10, 1)
+	xp.ca.MakeModel("colored", "msh:tri", "mat:tblack")
+	xp.cd = scene.AddPart().MakeLabel("labeled", "lucidiaSu18")
+
+	// teleport energy bar.
+	xp.tbar = newSegbar(scene, energySegments, "xpblue", "xpred")
 
 	// the teleport bar text.
 	xp.tk = scene.AddPart().MakeLabel("labeled", "lucidiaSu18")
 
-	// cloak energy background and foreground bars.
-	xp.cbg = scene.AddPart()
-	xp.cbg.MakeModel("colored", "msh:square", "mat:tgray")
-	xp.cfg = scene.AddPart()
-	xp.cfg.MakeModel("textured", "msh:icon", "tex:xpblue")
+	// cloak energy bar.
+	xp.cbar = newSegbar(scene, energySegments, "xpblue", "xpred")
 
 	// the cloak bar text.
 	xp.ck = scene.AddPart().MakeLabel("labeled", "lucidiaSu18")
@@ -214,23 +631,27 @@ func newXpbar(scene *vu.Ent, screenWidth, screenHeight int) *xpbar {
 	return xp
 }
 
+// Number of segments in each status bar.
+const (
+	healthSegments = 10 // Health bar segments.
+	energySegments = 5  // Teleport and cloak bar segments.
+)
+
 // resize adjusts the graphics to fit the new window dimensions.
 func (xp *xpbar) resize(screenWidth, screenHeight int) {
 	xp.setSize(screenWidth, screenHeight)
-	xp.bg.SetAt(xp.cx+5, xp.cy+5, 1)
-	xp.bg.SetScale(float64(xp.bw/2), float64(xp.bh-xp.y), 1)
-
-	// adjust the teleport energy bar.
-	xp.tbg.SetAt(xp.cx-float64(xp.w)/10, xp.cy+35, 1)
-	xp.tbg.SetScale(float64(xp.bw/10), float64(xp.bh-xp.y)-5, 1)
-	bw := xp.tkw
-	xp.tk.SetAt(xp.cx-float64(xp.bw)/10-float64(bw/2), xp.cy+26, 0)
+	for _, label := range []*vu.Ent{xp.hb, xp.cd, xp.tk, xp.ck} {
+		scaleLabel(label, 1)
+	}
+	mid := float64(screenWidth) * 0.5
+	xp.hbar.place(mid, xp.cy+5, float64(xp.bw), float64(2*(xp.bh-xp.y)))
 
-	// adjust the cloaking energy bar.
-	xp.cbg.SetAt(xp.cx+float64(xp.bw)/10, xp.cy+35, 1)
-	xp.cbg.SetScale(float64(xp.bw/10), float64(xp.bh-xp.y)-5, 1)
-	bw = xp.ckw
-	xp.ck.SetAt(xp.cx+float64(xp.bw)/10-float64(bw/2), xp.cy+26, 0)
+	// the teleport and cloak energy bars sit side by side above the health bar.
+	ew, eh := float64(xp.bw)/5, float64(2*(xp.bh-xp.y-5))
+	xp.tbar.place(mid-ew*0.5-segGap, xp.cy+35, ew, eh)
+	xp.tk.SetAt(mid-ew*0.5-segGap-float64(xp.tkw/2), xp.cy+26, 0)
+	xp.cbar.place(mid+ew*0.5+segGap, xp.cy+35, ew, eh)
+	xp.ck.SetAt(mid+ew*0.5+segGap-float64(xp.ckw/2), xp.cy+26, 0)
 
 	// adjust the energy amounts for the bars.
 	if xp.tr != nil {
@@ -247,41 +668,73 @@ func (xp *xpbar) setSize(screenWidth, screenHeight int) {
 	xp.cx, xp.cy = float64(screenWidth)*0.5-float64(xp.border), float64(xp.bh)*0.5+float64(xp.border)
 }
 
+// tint colours the health bar background from the usual dark gray
+// to gold as the combo strength goes from 0 to 1.
+func (xp *xpbar) tint(strength float64) {
+	if strength != xp.combo {
+		xp.combo = strength
+		xp.hbar.setColor(strength, strength*0.8, strength*0.2)
+	}
+}
+
 // healthMonitor:healthUpdated. Updates the health banner when it changes.
 func (xp *xpbar) healthUpdated(health, warn, high int) {
-	maxCores := high / gameCellGain[xp.tr.lvl-1]
-	coresNeeded := (high - health) / gameCellGain[xp.tr.lvl-1]
+	maxCores := high / gameGain(xp.tr.lvl-1)
+	coresNeeded := (high - health) / gameGain(xp.tr.lvl-1)
 	coreCount := strconv.Itoa(maxCores-coresNeeded) + "/" + strconv.Itoa(maxCores)
 	xp.hb.SetStr(coreCount)
-	xp.hbw, _ = xp.hb.Size()
+	xp.hbw, _ = labelSize(xp.hb)
 	xp.hb.SetAt(xp.cx-float64(xp.hbw/2), xp.cy*0.5, 0)
+	xp.ca.SetAt(xp.cx+float64(xp.hbw/2)+15, xp.cy*0.5+8, 0)
+	xp.cd.SetAt(xp.cx+float64(xp.hbw/2)+25, xp.cy*0.5, 0)
 
 	// turn on the warning colour if player has less than the starting amount of cores.
-	barMax := float64(xp.bw/2 - xp.linew)
 	if health >= warn {
-		xp.fg.SetFirst("xpcyan")
+		xp.hbar.setFirst("xpcyan")
 	} else {
-		xp.fg.SetFirst("xpred")
+		xp.hbar.setFirst("xpred")
+	}
+	xp.hbar.set(float64(health) / float64(high))
+}
+
+// pointToCore points the core arrow from the player, at the given game
+// location and looking in the yaw direction, to the nearest core.
+// The arrow and distance are hidden when there are no cores.
+func (xp *xpbar) pointToCore(gamex, gamez, yaw float64, cc *coreControl) {
+	dx, dz, ok := cc.nearest(gamex, gamez)
+	xp.ca.Cull(!ok)
+	xp.cd.Cull(!ok)
+	if ok {
+		// the arrow points up when the core is straight ahead.
+		angle := math.Atan2(-dx, -dz) - lin.Rad(yaw)
+		xp.ca.SetAa(0, 0, 1, angle)
+		dist := math.Sqrt(dx*dx+dz*dz) / cc.units
+		xp.cd.SetStr(strconv.Itoa(int(dist + 0.5)))
 	}
-	healthBar := float64(health) / float64(high) * barMax
-	zeroSpot := float64(xp.border) + healthBar + float64(xp.linew-xp.border)
-	xp.fg.SetAt(zeroSpot+5, xp.cy+5, 0)
-	xp.fg.SetScale(healthBar, float64(xp.bh-xp.y-xp.linew)-1, 1)
 }
 
 // energyMonitor:energyUpdated. Update the energy banner when it changes.
 func (xp *xpbar) energyUpdated(teleportEnergy, tmax, cloakEnergy, cmax int) {
 	tratio := float64(teleportEnergy) / float64(tmax)
 	if tratio == 1.0 {
-		xp.tfg.SetFirst("xpblue")
+		xp.tbar.setFirst("xpblue")
+	} else {
+		xp.tbar.setFirst("xpred")
+	}
+	xp.tbar.set(tratio)
+	if xp.tr != nil && xp.tr.cloakLow() && (xp.safe || (cloakEnergy/40)%2 == 0) {
+		xp.cbar.setFirst("xpred") // pulse when the cloak is running out.
 	} else {
-		xp.tfg.SetFirst("xpred")
+		xp.cbar.setFirst("xpblue")
 	}
-	xp.tfg.SetAt(xp.cx-float64(xp.w)/10, xp.cy+35, 0)
-	xp.tfg.SetScale((float64(xp.bw/10))*tratio, float64(xp.bh-xp.y)-7, 1)
-	cratio := float64(cloakEnergy) / float64(cmax)
-	xp.cfg.SetAt(xp.cx+float64(xp.w)/10-1, xp.cy+35, 0)
-	xp.cfg.SetScale((float64(xp.bw/10))*cratio, float64(xp.bh-xp.y)-7, 1)
+	xp.cbar.set(float64(cloakEnergy) / float64(cmax))
+}
+
+// update is called each game tick to animate the bars.
+func (xp *xpbar) update() {
+	xp.hbar.update()
+	xp.tbar.update()
+	xp.cbar.update()
 }
 
 // setLevel sets the xpbars values and must be called at least once before rendering.
@@ -291,6 +744,9 @@ func (xp *xpbar) setLevel(lvl *level) {
 	xp.tr.monitorEnergy("xpbar", xp)
 	xp.healthUpdated(xp.tr.health())
 	xp.energyUpdated(xp.tr.energy())
+	xp.hbar.reset() // a new level starts without animating the bars.
+	xp.tbar.reset()
+	xp.cbar.reset()
 }
 
 // updateKeys needs to be called on startup and whenever the displayed key
@@ -308,6 +764,110 @@ func (xp *xpbar) updateKeys(teleportKey, cloakKey int) {
 
 // xpbar
 // ===========================================================================
+// radial
+
+// radial shows the teleport energy as a ring of segments around the
+// teleport icon. The ring fills as the teleport energy regenerates
+// and the icon flashes when the player can teleport.
+type radial struct {
+	icon  *vu.Ent   // Teleport icon in the middle of the ring.
+	segs  []*vu.Ent // Ring segments, clockwise from the top.
+	ready bool      // True when there is enough energy to teleport.
+	block bool      // True while teleporting is blocked by an interdiction zone.
+	flash int       // Game ticks left in the ready flash.
+	safe  bool      // True to skip flashing for photo-sensitive players.
+}
+
+// Radial layout and timing.
+const (
+	radialSegments = 24 // Number of ring segments.
+	radialRadius   = 22 // Ring size in pixels.
+	radialFlash    = 40 // Game ticks that the ready flash lasts.
+)
+
+// newRadial creates the teleport icon and its ring.
+func newRadial(scene *vu.Ent) *radial {
+	rd := &radial{}
+	rd.icon = scene.AddPart().SetScale(12, 12, 1)
+	rd.icon.MakeModel("textured", "msh:icon", "tex:teleport")
+	trackAsset(rd.icon, "tex:teleport")
+	for cnt := 0; cnt < radialSegments; cnt++ {
+		seg := scene.AddPart().SetScale(1.5, 3, 1)
+		seg.MakeModel("colored", "msh:square", "mat:blue")
+		rd.segs = append(rd.segs, seg)
+	}
+	return rd
+}
+
+// resize keeps the ring centered above the energy bars.
+func (rd *radial) resize(screenWidth, screenHeight int) {
+	cx, cy := float64(screenWidth/2), 85.0
+	rd.icon.SetAt(cx, cy, 0)
+	for cnt, seg := range rd.segs {
+		angle := 2 * math.Pi * float64(cnt) / radialSegments
+		seg.SetAt(cx+radialRadius*math.Sin(angle), cy+radialRadius*math.Cos(angle), 0)
+		seg.SetAa(0, 0, 1, -angle)
+	}
+}
+
+// energyMonitor:energyUpdated. Fill the ring with the teleport energy
+// and start the flash when the player can teleport again.
+func (rd *radial) energyUpdated(teleportEnergy, tmax, cloakEnergy, cmax int) {
+	filled := teleportEnergy * radialSegments / tmax
+	for cnt, seg := range rd.segs {
+		if cnt < filled {
+			seg.SetAlpha(0.9)
+		} else {
+			seg.SetAlpha(0.15)
+		}
+	}
+	ready := teleportEnergy >= tmax
+	if ready && !rd.ready && !rd.safe {
+		rd.flash = radialFlash
+	}
+	rd.ready = ready
+	rd.showIcon()
+}
+
+// setBlocked turns the ring red while teleporting is blocked.
+func (rd *radial) setBlocked(blocked bool) {
+	if blocked != rd.block {
+		rd.block = blocked
+		for _, seg := range rd.segs {
+			if blocked {
+				seg.SetColor(0.86, 0.2, 0.18)
+			} else {
+				seg.SetColor(0.4, 0.5, 0.8)
+			}
+		}
+		rd.showIcon()
+	}
+}
+
+// update is called each game tick to run the ready flash.
+func (rd *radial) update() {
+	if rd.flash > 0 {
+		rd.flash--
+		rd.showIcon()
+	}
+}
+
+// showIcon dims the icon until teleporting is ready.
+func (rd *radial) showIcon() {
+	switch {
+	case rd.block:
+		rd.icon.SetAlpha(0.2)
+	case rd.flash > 0 && rd.flash/5%2 == 0:
+		rd.icon.SetAlpha(0.2)
+	case rd.ready:
+		rd.icon.SetAlpha(1)
+	default:
+		rd.icon.SetAlpha(0.4)
+	}
+}
+
+// radial
+// ===========================================================================
 // minimap
 
 // minimap displays a limited portion of the current level from the overhead
@@ -315,14 +875,36 @@ func (xp *xpbar) updateKeys(teleportKey, cloakKey int) {
 type minimap struct {
 	ui     *vu.Ent   // 2D overlay scene.
 	area             // Rectangular area.
-	cores  []*vu.Ent // Keep track of the cores for removal.
+	walls  []square  // Wall marker locations.
+	cores  []square  // Core marker locations.
+	edges  []square  // Maze boundary dots.
+	burns  []square  // Sentinel trail tile locations.
+	wm     *markers  // Wall markers.
+	cm     *markers  // Core markers.
+	sm     *markers  // Sentry markers.
+	bm     *markers  // Boundary markers.
+	gm     *markers  // One-way gate markers.
+	fm     *markers  // Sentinel trail markers.
+	um     *markers  // Stunned sentry markers.
+	tm     *markers  // Turret markers.
+	gates  []square  // One-way gate glyphs.
+	drawn  bool      // False when the wall, core, and boundary markers need redrawing.
+	dx, dy float64   // Player location when the markers were last drawn.
 	top    *vu.Ent   // Map scale and position on screen.
 	root   *vu.Ent   // Reposition map as player move.s
 	bg     *vu.Ent   // The white background.
 	scale  float64   // Minimap sizing.
 	ppm    *vu.Ent   // Player position marker.
 	cpm    *vu.Ent   // Center of map position marker.
-	spms   []*vu.Ent // Sentry position markers.
+	tpm    *vu.Ent   // Teleport destination preview marker.
+	pm     *vu.Ent   // Co-op partner marker.
+	trail  []*vu.Ent // Combo trail behind the player marker.
+	next   int       // Trail marker to move next.
+	tticks int       // Game ticks until the next trail marker is dropped.
+	combo  float64   // Combo strength from 0 to 1. No trail for 0.
+	sentry []square  // Sentry marker locations and pulse sizes.
+	warns  []int     // Per sentry proximity warning cooldown ticks.
+	near   float64   // Proximity warning distance in game units.
 	radius int       // Limits map visibility. Distance squared in pixels.
 }
 
@@ -331,7 +913,6 @@ func newMinimap(eng vu.Eng, numTroops int) *minimap {
 	mm := &minimap{}
 	mm.radius = 120
 	mm.scale = 5.0
-	mm.cores = []*vu.Ent{}
 	mm.ui = eng.AddScene().SetUI()
 	mm.ui.Cam().SetClip(0, 10)
 	mm.ui.SetCuller(mm) // mm implements Culler
@@ -344,20 +925,44 @@ func newMinimap(eng vu.Eng, numTroops int) *minimap {
 	mm.bg = mm.root.AddPart().SetScale(110, 110, 1)
 	mm.bg = mm.root.AddPart().SetScale(110, 110, 1)
 	mm.bg.MakeModel("textured", "msh:icon", "tex:hudbg")
+	trackAsset(mm.bg, "tex:hudbg")
 
-	// create the sentinel position markers
-	mm.spms = []*vu.Ent{}
-	for cnt := 0; cnt < numTroops; cnt++ {
-		tpm := mm.root.AddPart()
-		tpm.MakeModel("colored", "msh:square", "mat:tred")
-		mm.spms = append(mm.spms, tpm)
-	}
+	// create the wall, core, and sentinel markers.
+	mm.wm = newMarkers(mm.root, "gray")
+	mm.cm = newMarkers(mm.root, "green")
+	mm.sm = newMarkers(mm.root, "tred")
+	mm.bm = newMarkers(mm.root, "tgray")
+	mm.gm = newMarkers(mm.root, "blue")
+	mm.fm = newMarkers(mm.root, "orange")
+	mm.um = newMarkers(mm.root, "tblue")
+	mm.tm = newMarkers(mm.root, "red")
+	mm.sentry = make([]square, numTroops)
+	mm.warns = make([]int, numTroops)
 
 	// create the player marker and center map marker.
 	mm.cpm = mm.root.AddPart()
 	mm.cpm.MakeModel("colored", "msh:square", "mat:blue")
 	mm.ppm = mm.root.AddPart()
 	mm.ppm.MakeModel("colored", "msh:tri", "mat:tblack")
+
+	// create the hidden teleport destination marker.
+	x, _, z := teleportSpot()
+	mm.tpm = mm.root.AddPart().SetAt(x, -z, 0)
+	mm.tpm.MakeModel("colored", "msh:square", "mat:tgreen")
+	mm.tpm.Cull(true)
+
+	// create the hidden co-op partner marker.
+	mm.pm = mm.root.AddPart()
+	mm.pm.MakeModel("colored", "msh:tri", "mat:red")
+	mm.pm.Cull(true)
+
+	// create the hidden combo trail markers.
+	for cnt := 0; cnt < trailMarkers; cnt++ {
+		tm := mm.root.AddPart().SetScale(0.4, 0.4, 1)
+		tm.MakeModel("colored", "msh:square", "mat:green")
+		tm.Cull(true)
+		mm.trail = append(mm.trail, tm)
+	}
 	return mm
 }
 
@@ -388,35 +993,73 @@ func (mm *minimap) setLevel(cam *vu.Camera, lvl *level) {
 
 	// adjust the center location based on the game maze center.
 	mm.cx, mm.cy = float64(lvl.gcx*lvl.units), float64(lvl.gcy*lvl.units)
+	mm.near = float64(gameProximity[lvl.num] * lvl.units)
 	mm.ppm.SetAt(x, -z, 0)
 	mm.bg.SetAt(x, -z, 0)
 	mm.ppm.SetAa(0, 0, 1, lin.Rad(cam.Yaw))
-	mm.setSentryAt(lvl.sentries)
+	mm.drawn = false
+	mm.drawMarkers(x, -z)
+	mm.setSentryAt(lvl.enemies)
 	lvl.player.monitorHealth("mmap", mm)
 }
 
 // addWall adds a block representing a wall to the minimap.
 func (mm *minimap) addWall(x, y float64) {
-	wall := mm.root.AddPart().SetAt(x, -y, 0)
-	wall.MakeModel("colored", "msh:square", "mat:gray")
+	mm.walls = append(mm.walls, square{x, -y, 1})
+	mm.drawn = false
 }
 
+// remWall removes a broken wall from the minimap.
+func (mm *minimap) remWall(x, y float64) {
+	for index, wall := range mm.walls {
+		if wall.x == x && wall.y == -y {
+			mm.walls = append(mm.walls[:index], mm.walls[index+1:]...)
+			mm.drawn = false
+			return
+		}
+	}
+}
+
+// addGate adds a one-way gate glyph: a square with a smaller square
+// on the side the gate leads to. Grid directions match the minimap.
+func (mm *minimap) addGate(x, y float64, dx, dy int) {
+	mm.gates = append(mm.gates, square{x, -y, 0.5},
+		square{x + float64(dx)*0.8, -y + float64(dy)*0.8, 0.25})
+	mm.drawn = false
+}
+
+// addBoundary outlines the maze boundary, given in game coordinates,
+// with a dotted rectangle.
+func (mm *minimap) addBoundary(minx, minz, maxx, maxz float64) {
+	mm.edges = mm.edges[:0]
+	for x := minx; x <= maxx; x += boundaryGap {
+		mm.edges = append(mm.edges, square{x, -minz, boundaryDot}, square{x, -maxz, boundaryDot})
+	}
+	for z := minz + boundaryGap; z < maxz; z += boundaryGap {
+		mm.edges = append(mm.edges, square{minx, -z, boundaryDot}, square{maxx, -z, boundaryDot})
+	}
+	mm.drawn = false
+}
+
+// Minimap boundary dot spacing and size in game units.
+const (
+	boundaryGap = 0.5
+	boundaryDot = 0.15
+)
+
 // addCore adds a small block representing an energy core to the minimap.
 func (mm *minimap) addCore(gamex, gamez float64) {
-	cm := mm.root.AddPart().SetAt(gamex, -gamez, 0).SetScale(0.5, 0.5, 1)
-	cm.MakeModel("colored", "msh:square", "mat:green")
-	mm.cores = append(mm.cores, cm)
+	mm.cores = append(mm.cores, square{gamex, -gamez, 0.5})
+	mm.drawn = false
 }
 
 // remCore removes a collected energy core from the minimap.
 func (mm *minimap) remCore(gamex, gamez float64) {
 	gx, gy := lin.Round(gamex, 0), lin.Round(-gamez, 0)
 	for index, core := range mm.cores {
-		cx, cy, _ := core.At()
-		cx, cy = lin.Round(cx, 0), lin.Round(cy, 0)
-		if cx == gx && cy == gy {
-			core.Dispose()
+		if lin.Round(core.x, 0) == gx && lin.Round(core.y, 0) == gy {
 			mm.cores = append(mm.cores[:index], mm.cores[index+1:]...)
+			mm.drawn = false
 			return
 		}
 	}
@@ -426,10 +1069,31 @@ func (mm *minimap) remCore(gamex, gamez float64) {
 // resetCores is expected to be called when switching levels so that
 // this level is clear of cores the next time it is activated.
 func (mm *minimap) resetCores() {
-	for _, core := range mm.cores {
-		core.Dispose()
+	mm.cores = mm.cores[:0]
+	mm.drawn = false
+}
+
+// showTrails replaces the sentinel trail markers. Only the trail markers
+// are redrawn since the trails change much more often than the maze.
+func (mm *minimap) showTrails(burns []square) {
+	mm.burns = burns
+	mm.fm.draw(mm.dx, mm.dy, float64(mm.radius)/mm.scale, mm.burns)
+}
+
+// drawMarkers redraws the wall, core, and boundary markers around the given player
+// location when they have changed or the player has moved far enough
+// that different markers are in view.
+func (mm *minimap) drawMarkers(x, y float64) {
+	if mm.drawn && math.Abs(x-mm.dx) < 1 && math.Abs(y-mm.dy) < 1 {
+		return
 	}
-	mm.cores = []*vu.Ent{}
+	mm.drawn, mm.dx, mm.dy = true, x, y
+	reach := float64(mm.radius) / mm.scale
+	mm.wm.draw(x, y, reach, mm.walls)
+	mm.cm.draw(x, y, reach, mm.cores)
+	mm.bm.draw(x, y, reach, mm.edges)
+	mm.gm.draw(x, y, reach, mm.gates)
+	mm.fm.draw(x, y, reach, mm.burns)
 }
 
 // healthMonitor:healthUpdated. Update the center colour of the maze
@@ -443,14 +1107,87 @@ func (mm *minimap) healthUpdated(health, warn, high int) {
 }
 
 // update adjusts the minimap according to the players new position.
-func (mm *minimap) update(cam *vu.Camera, sentries []*sentinel) {
+// Returns the number of sentinels that have just come close to an
+// uncloaked player.
+func (mm *minimap) update(cam *vu.Camera, enemies []enemy, cloaked bool) (warnings int) {
 	x, _, z := cam.At()
 	mm.root.SetAt(-x, z, 0)
 	mm.setCenterAt(x, -z)
 	mm.bg.SetAt(x, -z, 0)
 	mm.ppm.SetAt(x, -z, 0)
 	mm.ppm.SetAa(0, 0, 1, lin.Rad(cam.Yaw))
-	mm.setSentryAt(sentries)
+	mm.drawMarkers(x, -z)
+	warnings = mm.warnSentries(x, z, enemies, cloaked)
+	mm.setSentryAt(enemies)
+	mm.updateTrail(x, -z)
+	return warnings
+}
+
+// Combo trail tuning.
+const (
+	trailMarkers = 12   // Trail length in markers.
+	trailGap     = 5    // Game ticks between trail markers.
+	trailFade    = 0.95 // Alpha kept by each marker every tick.
+)
+
+// updateTrail fades the combo trail and drops a new marker at the given
+// minimap location every few ticks. Stronger combos have a brighter and
+// more golden trail. The trail is cleared as soon as the combo ends.
+func (mm *minimap) updateTrail(x, y float64) {
+	if mm.combo <= 0 {
+		for _, tm := range mm.trail {
+			tm.Cull(true)
+		}
+		mm.tticks = 0
+		return
+	}
+	for _, tm := range mm.trail {
+		tm.SetAlpha(tm.Alpha() * trailFade)
+	}
+	if mm.tticks > 0 {
+		mm.tticks--
+		return
+	}
+	mm.tticks = trailGap
+	tm := mm.trail[mm.next]
+	mm.next = (mm.next + 1) % len(mm.trail)
+	tm.SetAt(x, y, 0).Cull(false)
+	tm.SetColor(0.2+0.8*mm.combo, 0.8, 0.2)
+	tm.SetAlpha(0.4 + 0.6*mm.combo)
+}
+
+// Proximity warning timing in game ticks.
+const (
+	warnCooldown = 150 // Minimum ticks between warnings for one sentinel.
+	warnPulse    = 50  // Ticks that a warned sentinel marker pulses.
+)
+
+// warnSentries pulses the markers of any enemies near the player.
+// Each enemy only warns again after its cooldown has expired.
+func (mm *minimap) warnSentries(x, z float64, enemies []enemy, cloaked bool) (warnings int) {
+	if len(mm.warns) != len(enemies) {
+		return 0
+	}
+	for cnt, foe := range enemies {
+		if mm.warns[cnt] > 0 {
+			mm.warns[cnt]--
+		}
+		sx, _, sz := foe.location()
+		dx, dz := sx-x, sz-z
+		near := foe.isActive() && !cloaked && dx*dx+dz*dz < mm.near*mm.near
+		if near && mm.warns[cnt] == 0 {
+			mm.warns[cnt] = warnCooldown
+			warnings++
+		}
+
+		// pulse the marker for the start of the cooldown.
+		scale := 1.0
+		if pulse := mm.warns[cnt] - (warnCooldown - warnPulse); pulse > 0 {
+			scale += math.Abs(math.Sin(float64(pulse) * 0.25))
+		}
+		mm.sentry[cnt].size = scale
+	}
+	return warnings
 }
 
 // set the position of the maze center marker. Ensure the center marker
@@ -467,15 +1204,287 @@ func (mm *minimap) setCenterAt(x, y float64) {
 	}
 }
 
-// set the position for all the sentry markers.
-func (mm *minimap) setSentryAt(sentinels []*sentinel) {
-	if len(mm.spms) != len(sentinels) {
+// set the position for all the enemy markers. The markers are redrawn
+// every update since the enemies are always moving.
+func (mm *minimap) setSentryAt(enemies []enemy) {
+	if len(mm.sentry) != len(enemies) {
 		logf("hud.minimap.setSentryAt: sentry length mismatch")
 		return
 	}
-	for cnt, sentry := range sentinels {
-		tpm := mm.spms[cnt]
-		x, _, z := sentry.location()
-		tpm.SetAt(x, -z, 0)
+	active, stunned, fixed := mm.sentry[:0:0], mm.sentry[:0:0], mm.sentry[:0:0]
+	for cnt, foe := range enemies {
+		if foe.isActive() { // markers appear as enemies are spawned.
+			x, _, z := foe.location()
+			mm.sentry[cnt].x, mm.sentry[cnt].y = x, -z
+			if mm.sentry[cnt].size == 0 {
+				mm.sentry[cnt].size = 1
+			}
+			switch foe.minimapStyle() {
+			case fadedMarker:
+				stunned = append(stunned, mm.sentry[cnt]) // shown faded.
+			case fixedMarker:
+				fixed = append(fixed, mm.sentry[cnt])
+			default:
+				active = append(active, mm.sentry[cnt])
+			}
+		}
+	}
+	px, py, _ := mm.ppm.At()
+	mm.sm.draw(px, py, float64(mm.radius)/mm.scale, active)
+	mm.um.draw(px, py, float64(mm.radius)/mm.scale, stunned)
+	mm.tm.draw(px, py, float64(mm.radius)/mm.scale, fixed)
+}
+
+// minimap
+// ===========================================================================
+// markers
+
+// square is one minimap marker. Square markers are centered on
+// the x, y minimap location and extend size in each direction.
+type square struct{ x, y, size float64 }
+
+// markers draws many minimap squares of the same colour as a single
+// model instead of one model for each square.
+type markers struct {
+	ent   *vu.Ent   // Model holding all the squares.
+	verts []float32 // Reused vertex buffer.
+	faces []uint16  // Reused face buffer.
+}
+
+// markerMeshes counts generated marker meshes so each has a unique name.
+var markerMeshes int
+
+// newMarkers creates an empty marker model using the given material.
+func newMarkers(root *vu.Ent, mat string) *markers {
+	m := &markers{}
+	markerMeshes++
+	m.ent = root.AddPart()
+	m.ent.MakeModel("colored", "mat:"+mat).GenMesh("markers" + strconv.Itoa(markerMeshes))
+	msh := m.ent.Mesh()
+	msh.InitData(0, 3, vu.DynamicDraw, false)
+	msh.InitFaces(vu.DynamicDraw)
+	m.ent.Cull(true)
+	return m
+}
+
+// draw replaces the marker squares with the given squares that are within
+// reach of the x, y minimap location. The model is placed at x, y so that
+// the minimap culler, which measures distance from the player marker,
+// keeps it visible.
+func (m *markers) draw(x, y, reach float64, squares []square) {
+	m.verts, m.faces = m.verts[:0], m.faces[:0]
+	for _, sq := range squares {
+		dx, dy := sq.x-x, sq.y-y
+		if dx*dx+dy*dy > reach*reach || len(m.verts)/3 > maxMarkerVerts-4 {
+			continue
+		}
+		base := uint16(len(m.verts) / 3)
+		s := sq.size
+		m.verts = append(m.verts,
+			float32(dx+s), float32(dy-s), 1,
+			float32(dx-s), float32(dy+s), 1,
+			float32(dx-s), float32(dy-s), 1,
+			float32(dx+s), float32(dy+s), 1)
+		m.faces = append(m.faces, base, base+1, base+2, base, base+3, base+1)
+	}
+	m.ent.Cull(len(m.faces) == 0)
+	if len(m.faces) > 0 {
+		m.ent.SetAt(x, y, 0)
+		msh := m.ent.Mesh()
+		msh.SetData(0, m.verts)
+		msh.SetFaces(m.faces)
+	}
+}
+
+// maxMarkerVerts keeps marker meshes under the engine vertex limit.
+const maxMarkerVerts = 65000
+
+// markers
+// ===========================================================================
+// pingAnimation
+
+// newPingAnimation creates a minimap ping at the given game location.
+func (mm *minimap) newPingAnimation(gamex, gamez float64) animation {
+	return &pingAnimation{mm: mm, x: gamex, y: -gamez, ticks: 40}
+}
+
+// newMarkAnimation creates a longer lasting minimap ping for a co-op ping.
+func (mm *minimap) newMarkAnimation(gamex, gamez float64) animation {
+	return &pingAnimation{mm: mm, x: gamex, y: -gamez, ticks: pingLife}
+}
+
+// gameAt returns the game location shown at the given screen location.
+// Return false if the screen location is outside the visible minimap.
+// The player at game location px, pz is shown at the minimap center.
+func (mm *minimap) gameAt(mx, my int, px, pz float64) (gamex, gamez float64, ok bool) {
+	dx, dy := mx-mm.x, my-mm.y
+	if mm.ui.Culled() || dx*dx+dy*dy > mm.radius*mm.radius {
+		return 0, 0, false
+	}
+	return px + float64(dx)/mm.scale, pz - float64(dy)/mm.scale, true
+}
+
+// pingAnimation shows a briefly expanding ring on the minimap to draw
+// attention to a newly dropped core.
+type pingAnimation struct {
+	mm    *minimap // Minimap holding the ping.
+	ring  *vu.Ent  // Expanding ring model.
+	x, y  float64  // Minimap location.
+	ticks int      // Animation run rate - number of animation steps.
+	tkcnt int      // Current step.
+	state int      // Track progress 0:start, 1:run, 2:done.
+}
+
+// Animate is called each game loop while the animation is active.
+func (pa *pingAnimation) Animate(dt float64) bool {
+	switch pa.state {
+	case 0:
+		pa.ring = pa.mm.root.AddPart().SetAt(pa.x, pa.y, 0).SetScale(0.5, 0.5, 1)
+		pa.ring.MakeModel("textured", "msh:icon", "tex:halo")
+		pa.ring.SetAlpha(1)
+		pa.state = 1
+		return true
+	case 1:
+		if pa.tkcnt >= pa.ticks {
+			pa.Wrap()
+			return false // animation done.
+		}
+		pa.tkcnt++
+		ratio := float64(pa.tkcnt) / float64(pa.ticks)
+		scale := 0.5 + ratio*3.5
+		pa.ring.SetScale(scale, scale, 1)
+		pa.ring.SetAlpha(1 - ratio)
+		return true
+	default:
+		return false // animation done.
+	}
+}
+
+// Wrap removes the ping from the minimap.
+func (pa *pingAnimation) Wrap() {
+	if pa.ring != nil {
+		pa.ring.Dispose()
+		pa.ring = nil
+	}
+	pa.state = 2
+}
+
+// pingAnimation
+// ===========================================================================
+// detector
+
+// detector is the cloaked "detector vision". Each nearby sentinel is
+// marked by a faint glow drawn over the level, so sentinels show through
+// walls. The glows are pooled, one for each sentinel, and are hidden as
+// soon as the player decloaks.
+type detector struct {
+	glows []*vu.Ent // Glow markers in the same order as the sentinels.
+}
+
+// Detector vision tuning.
+const (
+	detectRange = 10.0 // Game distance that sentinels are detected.
+	detectSize  = 24.0 // Glow radius in pixels for a sentinel next to the player.
+	detectAlpha = 0.4  // Glow transparency for a sentinel next to the player.
+)
+
+// newDetector creates the glow pool for the given number of sentinels.
+func newDetector(ui *vu.Ent, count int) *detector {
+	dv := &detector{}
+	for cnt := 0; cnt < count; cnt++ {
+		glow := ui.AddPart()
+		glow.MakeModel("textured", "msh:icon", "tex:halo")
+		glow.Cull(true)
+		dv.glows = append(dv.glows, glow)
+	}
+	return dv
+}
+
+// update places a glow over each sentinel within range of the camera that
+// is in front of the player. Closer sentinels have larger, brighter glows.
+func (dv *detector) update(c *vu.Camera, enemies []enemy, cloaked bool, ww, wh int) {
+	x, _, z := c.At()
+	for cnt, glow := range dv.glows {
+		if !cloaked || cnt >= len(enemies) || !enemies[cnt].isActive() {
+			glow.Cull(true)
+			continue
+		}
+		sx, sy, sz := enemies[cnt].location()
+		dist := math.Hypot(sx-x, sz-z)
+		gx, gy := c.Screen(sx, sy, sz, ww, wh)
+		if dist > detectRange || gx < 0 {
+			glow.Cull(true)
+			continue
+		}
+		near := 1 - dist/detectRange
+		size := detectSize * (0.5 + 0.5*near)
+		glow.SetAt(float64(gx), float64(gy), 0).SetScale(size, size, 1)
+		glow.SetAlpha(detectAlpha * near)
+		glow.Cull(false)
+	}
+}
+
+// detector
+// ===========================================================================
+// captions
+
+// captions shows a short description of each recent game sound in the
+// top right corner of the HUD. This helps players that can't hear the
+// game, or are playing with the sound muted.
+type captions struct {
+	lines []*vu.Ent // Caption labels, newest first.
+	texts []string  // Caption text for each label.
+	ttls  []int     // Game ticks until each caption is removed.
+	w, h  int       // Screen size.
+}
+
+// Caption display limits.
+const (
+	captionLines = 3   // Maximum captions shown at once.
+	captionTicks = 100 // Game ticks that a caption is shown.
+)
+
+// newCaptions creates the caption labels.
+func newCaptions(root *vu.Ent) *captions {
+	cc := &captions{}
+	for cnt := 0; cnt < captionLines; cnt++ {
+		line := root.AddPart()
+		line.MakeLabel("labeled", "lucidiaSu18")
+		line.Cull(true)
+		cc.lines = append(cc.lines, line)
+		cc.texts = append(cc.texts, "")
+		cc.ttls = append(cc.ttls, 0)
+	}
+	return cc
+}
+
+// resize keeps the captions in the top right corner.
+func (cc *captions) resize(width, height int) {
+	cc.w, cc.h = width, height
+	for cnt, line := range cc.lines {
+		scaleLabel(line, 1)
+		line.SetAt(float64(cc.w)-210*textScale, float64(cc.h)-float64(30+cnt*22)*textScale, 0)
+	}
+}
+
+// add shows a new caption, pushing the older captions down.
+func (cc *captions) add(text string) {
+	for cnt := len(cc.lines) - 1; cnt > 0; cnt-- {
+		cc.ttls[cnt], cc.texts[cnt] = cc.ttls[cnt-1], cc.texts[cnt-1]
+		cc.lines[cnt].SetStr(cc.texts[cnt])
+		cc.lines[cnt].Cull(cc.ttls[cnt] <= 0)
+	}
+	cc.ttls[0], cc.texts[0] = captionTicks, "["+text+"]"
+	cc.lines[0].SetStr(cc.texts[0])
+	cc.lines[0].Cull(false)
+}
+
+// update removes expired captions.
+func (cc *captions) update() {
+	for cnt, line := range cc.lines {
+		if cc.ttls[cnt] > 0 {
+			cc.ttls[cnt]--
+			line.Cull(cc.ttls[cnt] <= 0)
+		}
 	}
 }
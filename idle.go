@@ -0,0 +1,53 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// The menu screens slow down once the player stops using them so that an
+// idle game doesn't drain laptop batteries. The engine renders a frame for
+// each update, so pausing each update also slows the rendering. Any input
+// brings back the full rate on the next update. Game play, and screen
+// transitions, always run at the full rate.
+
+import (
+	"time"
+
+	"github.com/gazed/vu"
+)
+
+// Idle throttle tuning.
+const (
+	idleDelay = 5.0                   // Seconds without input before slowing down.
+	idleSleep = 80 * time.Millisecond // Pause added to each idle update.
+)
+
+// idler tracks how long the player has been away from the controls.
+type idler struct {
+	quiet  float64 // Seconds since the last input.
+	mx, my int     // Mouse location on the previous update.
+}
+
+// idle returns true once there has been no input for idleDelay seconds.
+// Mouse moves, key and button presses, scrolling, and window resizes all
+// count as input.
+func (id *idler) idle(in *vu.Input) bool {
+	if len(in.Down) > 0 || in.Scroll != 0 || in.Resized || in.Mx != id.mx || in.My != id.my {
+		id.quiet = 0
+	} else {
+		id.quiet += in.Dt
+	}
+	id.mx, id.my = in.Mx, in.My
+	return id.quiet >= idleDelay
+}
+
+// idler
+// ===========================================================================
+// bampf screen throttling.
+
+// throttle pauses the update when the active screen is an idle menu
+// screen. Expected to be called once each update.
+func (mp *bampf) throttle(in *vu.Input) {
+	if mp.idler.idle(in) && mp.active != mp.game && !mp.ani.running() {
+		time.Sleep(idleSleep)
+	}
+}
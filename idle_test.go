@@ -0,0 +1,26 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/gazed/vu"
+)
+
+func TestIdler(t *testing.T) {
+	id := &idler{}
+	in := &vu.Input{Down: map[int]int{}, Dt: 0.02}
+	ticks := 0
+	for !id.idle(in) && ticks < 1000 {
+		ticks++
+	}
+	if secs := float64(ticks) * in.Dt; secs < idleDelay-0.1 || secs > idleDelay+0.1 {
+		t.Errorf("Expected to be idle after %.1fs, got %.2fs", idleDelay, secs)
+	}
+	in.Mx = 10
+	if id.idle(in) {
+		t.Errorf("Expected a mouse move to end idling")
+	}
+}
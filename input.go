@@ -0,0 +1,111 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"sort"
+
+	"github.com/gazed/vu"
+)
+
+// inputState converts the engine key and mouse button down durations into
+// pressed, held, and released states. Presses are buffered for a few
+// updates so that a press that happens while a screen is busy, ie: during
+// a transition, is handled once the screen is ready instead of being lost.
+// Screens consume the presses they handle.
+type inputState struct {
+	presses  map[int]int  // Buffered presses and the updates left for each.
+	held     map[int]int  // Keys that are down and their down durations.
+	released map[int]bool // Keys released since the previous update.
+	stale    map[int]bool // Keys that were down when focus was lost.
+}
+
+// pressBuffer is the number of updates that an unhandled press is kept.
+const pressBuffer = 5
+
+// newInputState creates an empty input state.
+func newInputState() *inputState {
+	return &inputState{
+		presses:  map[int]int{},
+		held:     map[int]int{},
+		released: map[int]bool{},
+		stale:    map[int]bool{},
+	}
+}
+
+// update is called once per engine update, before the screens process
+// input, with the latest engine input.
+func (is *inputState) update(in *vu.Input) {
+	for key, left := range is.presses {
+		if left <= 1 {
+			delete(is.presses, key)
+		} else {
+			is.presses[key] = left - 1
+		}
+	}
+	for key := range is.released {
+		delete(is.released, key)
+	}
+	for key := range is.stale {
+		if down, ok := in.Down[key]; !ok || down < 0 || down == 1 {
+			delete(is.stale, key) // released, or pressed again.
+		}
+	}
+	for key, down := range in.Down {
+		switch {
+		case is.stale[key]:
+		case down < 0:
+			delete(is.held, key)
+			is.released[key] = true
+		case down == 1:
+			is.presses[key] = pressBuffer
+			is.held[key] = down
+		default:
+			is.held[key] = down
+		}
+	}
+}
+
+// blur forgets all keys when the window loses focus. Keys that are still
+// down are ignored after focus returns until they are released or pressed
+// again, since their release may have happened in another window.
+func (is *inputState) blur() {
+	for key := range is.held {
+		is.stale[key] = true
+		delete(is.held, key)
+	}
+	for key := range is.presses {
+		delete(is.presses, key)
+	}
+	for key := range is.released {
+		delete(is.released, key)
+	}
+}
+
+// pressed returns true and consumes the press if the key
+// was recently pressed.
+func (is *inputState) pressed(key int) bool {
+	if _, ok := is.presses[key]; ok {
+		delete(is.presses, key)
+		return true
+	}
+	return false
+}
+
+// pressedKeys consumes and returns all recently pressed keys in key order.
+func (is *inputState) pressedKeys() []int {
+	keys := []int{}
+	for key := range is.presses {
+		keys = append(keys, key)
+		delete(is.presses, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// isHeld returns the down duration for a key that is down, or 0.
+func (is *inputState) isHeld(key int) int { return is.held[key] }
+
+// isReleased returns true if the key was released since the previous update.
+func (is *inputState) isReleased(key int) bool { return is.released[key] }
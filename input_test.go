@@ -0,0 +1,64 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/gazed/vu"
+)
+
+func TestInputState(t *testing.T) {
+	is := newInputState()
+	in := &vu.Input{Down: map[int]int{vu.KA: 1}}
+	is.update(in)
+	in.Down = map[int]int{vu.KA: 2}
+	is.update(in)
+	if is.isHeld(vu.KA) != 2 {
+		t.Errorf("Expected held duration 2 got %d", is.isHeld(vu.KA))
+	}
+	if !is.pressed(vu.KA) || is.pressed(vu.KA) {
+		t.Errorf("Expected one buffered press")
+	}
+	in.Down = map[int]int{vu.KA: -3}
+	is.update(in)
+	if !is.isReleased(vu.KA) || is.isHeld(vu.KA) != 0 {
+		t.Errorf("Expected key release")
+	}
+
+	// unhandled presses expire.
+	in.Down = map[int]int{vu.KB: 1}
+	is.update(in)
+	in.Down = map[int]int{}
+	for cnt := 0; cnt < pressBuffer; cnt++ {
+		is.update(in)
+	}
+	if is.pressed(vu.KB) {
+		t.Errorf("Expected press to expire")
+	}
+}
+
+func TestInputBlur(t *testing.T) {
+	is := newInputState()
+	in := &vu.Input{Down: map[int]int{vu.KW: 1}}
+	is.update(in)
+	is.blur()
+	if is.isHeld(vu.KW) != 0 || is.pressed(vu.KW) {
+		t.Errorf("Expected no held or pressed keys after losing focus")
+	}
+
+	// a key still reported down after focus returns is ignored...
+	in.Down = map[int]int{vu.KW: 40}
+	is.update(in)
+	if is.isHeld(vu.KW) != 0 {
+		t.Errorf("Expected a stale key to be ignored")
+	}
+
+	// ...until it is pressed again.
+	in.Down = map[int]int{vu.KW: 1}
+	is.update(in)
+	if is.isHeld(vu.KW) != 1 || !is.pressed(vu.KW) {
+		t.Errorf("Expected a new press to be seen")
+	}
+}
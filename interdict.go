@@ -0,0 +1,58 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Interdiction zones are the floor tiles around the maze center where
+// teleports can't be started. With the hazards mutator, they stop players
+// on the later levels from teleporting out of trouble on the final approach
+// to the center. The teleport icon turns red while the player stands in
+// a zone.
+
+import (
+	"github.com/gazed/bampf/gridmath"
+	"github.com/gazed/vu"
+	"github.com/gazed/vu/grid"
+)
+
+// interdictSpots returns the open grid spots, other than the center,
+// that are within radius grid steps of the center in both directions.
+func interdictSpots(plan grid.Grid, radius, cx, cy int) (spots []gridmath.Spot) {
+	width, height := plan.Size()
+	for x := cx - radius; x <= cx+radius; x++ {
+		for y := cy - radius; y <= cy+radius; y++ {
+			if x < 0 || y < 0 || x >= width || y >= height || (x == cx && y == cy) {
+				continue
+			}
+			if plan.IsOpen(x, y) {
+				spots = append(spots, gridmath.Spot{X: x, Y: y})
+			}
+		}
+	}
+	return spots
+}
+
+// interdictSpots
+// ===========================================================================
+// level interdiction handling.
+
+// buildInterdiction marks the interdiction zone around the maze center.
+// Expected to be called after the maze center is known.
+func (lvl *level) buildInterdiction(scene *vu.Ent, plan grid.Grid) {
+	lvl.interdict = map[gridmath.Spot]bool{}
+	for _, spot := range interdictSpots(plan, gameHazard(gameInterdict, lvl.num), lvl.gcx, lvl.gcy) {
+		gamex, gamez := gridmath.ToGame(spot.X, spot.Y, float64(lvl.units))
+		tile := scene.AddPart().SetAt(gamex, gateLift*0.5, gamez)
+		m := tile.MakeModel("flata", "msh:tile", "mat:tblack")
+		m.SetAlpha(0.25).SetUniform("fd", lvl.fade)
+		lvl.interdict[spot] = true
+	}
+}
+
+// interdicted returns true if the player is standing in the
+// interdiction zone.
+func (lvl *level) interdicted() bool {
+	x, y, z := lvl.body.At()
+	gx, gy := gridmath.ToGrid(x, y, z, float64(lvl.units))
+	return lvl.interdict[gridmath.Spot{X: gx, Y: gy}]
+}
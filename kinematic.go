@@ -0,0 +1,146 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Kinematic movement is an optional replacement for the physics pushes that
+// normally move the player. Physics pushes accumulate velocity differently
+// at different tick rates, which makes replays and races hard to compare.
+// Kinematic moves are a fixed distance for the elapsed time and slide along
+// any walls in the way, so the same input always gives the same path.
+//
+// Both kinds of movement are assisted near walls. Moves into a wall are
+// turned along the wall and moves that clip a wall corner are nudged
+// around the corner so that the player doesn't stop dead.
+
+import (
+	"math"
+
+	"github.com/gazed/bampf/gridmath"
+	"github.com/gazed/vu/grid"
+	"github.com/gazed/vu/math/lin"
+)
+
+// Kinematic movement tuning.
+const (
+	kinematicSpeed  = 6.0  // Game units moved per second.
+	kinematicRadius = 0.25 // Player size, matches the physics sphere.
+	knockbackSlide  = 1.5  // Game units a sentinel knocks the player back.
+)
+
+// Wall assist tuning.
+const (
+	wallProbe    = 0.3 // Distance ahead checked for walls.
+	cornerAssist = 0.4 // Sideways distance checked for a way around a corner.
+	assistMin    = 0.1 // Smallest direction component that can slide.
+)
+
+// deflect adjusts a move dx, dz from the game location x, z that runs into
+// a wall. A move at an angle to the wall keeps the part along the wall. A
+// move straight into the edge of a wall is turned sideways around the edge.
+// Other moves are returned unchanged.
+func deflect(plan grid.Grid, units, x, z, dx, dz float64) (float64, float64) {
+	dist := math.Hypot(dx, dz)
+	if dist == 0 {
+		return dx, dz
+	}
+	ux, uz := dx/dist, dz/dist
+	ahead := func(ox, oz float64) bool { return !blocked(plan, units, x+ox, z+oz) }
+	if ahead(ux*wallProbe, uz*wallProbe) {
+		return dx, dz
+	}
+
+	// slide along the wall.
+	xOpen := math.Abs(ux) > assistMin && ahead(ux*wallProbe, 0)
+	zOpen := math.Abs(uz) > assistMin && ahead(0, uz*wallProbe)
+	switch {
+	case xOpen && zOpen: // clipping a corner diagonally.
+		if math.Abs(dx) > math.Abs(dz) {
+			return dx, 0
+		}
+		return 0, dz
+	case xOpen:
+		return dx, 0
+	case zOpen:
+		return 0, dz
+	}
+
+	// round the corner when only one side is clear.
+	sx, sz := -uz*cornerAssist, ux*cornerAssist
+	left := ahead(ux*wallProbe+sx, uz*wallProbe+sz)
+	right := ahead(ux*wallProbe-sx, uz*wallProbe-sz)
+	switch {
+	case left && !right:
+		return -uz * dist, ux * dist
+	case right && !left:
+		return uz * dist, -ux * dist
+	}
+	return dx, dz // facing a wall or in a corner.
+}
+
+// deflect adjusts player movement for nearby walls.
+func (g *game) deflect(dx, dz float64) (float64, float64) {
+	if g.cl == nil {
+		return dx, dz
+	}
+	x, _, z := g.cl.body.At()
+	return deflect(g.cl.plan, float64(g.cl.units), x, z, dx, dz)
+}
+
+// slide moves from the game location x, z by dx, dz and returns the new
+// location. Each direction is tried separately so that a move into a wall
+// slides along the wall instead of stopping.
+func slide(plan grid.Grid, units, x, z, dx, dz float64) (float64, float64) {
+	if !blocked(plan, units, x+dx, z) {
+		x += dx
+	}
+	if !blocked(plan, units, x, z+dz) {
+		z += dz
+	}
+	return x, z
+}
+
+// blocked returns true if the player at the given game location would
+// overlap a maze wall. The area around the maze is open.
+func blocked(plan grid.Grid, units, x, z float64) bool {
+	width, height := plan.Size()
+	for _, corner := range [][2]float64{{-1, -1}, {-1, 1}, {1, -1}, {1, 1}} {
+		cx, cz := x+corner[0]*kinematicRadius, z+corner[1]*kinematicRadius
+		gx, gy := gridmath.ToGrid(cx, 0, cz, units)
+		if gx >= 0 && gy >= 0 && gx < width && gy < height && !plan.IsOpen(gx, gy) {
+			return true
+		}
+	}
+	return false
+}
+
+// slideBy moves the player without physics. Moves that would take the
+// player further than the given distance from the maze center are ignored.
+func (lvl *level) slideBy(dx, dz, maxd float64) {
+	x, _, z := lvl.body.At()
+	nx, nz := slide(lvl.plan, float64(lvl.units), x, z, dx, dz)
+	cx, _, cz := lvl.center.At()
+	if math.Hypot(nx-cx, nz-cz) > maxd && math.Hypot(nx-cx, nz-cz) > math.Hypot(x-cx, z-cz) {
+		return
+	}
+	if body := lvl.body.Body(); body != nil {
+		body.Stop()
+		body.Rest()
+	}
+	y := 0.5 // players stay at floor level.
+	lvl.body.SetAt(nx, y, nz)
+}
+
+// walk moves the player in the given view relative direction when
+// kinematic movement is on. Returns false if the player is moved
+// by physics instead.
+func (g *game) walk(dt, x, z float64) bool {
+	if !g.mp.opts[kinematicOption] {
+		return false
+	}
+	step := dt * kinematicSpeed
+	dx, _, dz := lin.MultSQ(x*step, 0, z*step, g.dir)
+	dx, dz = g.deflect(dx, dz)
+	g.cl.slideBy(dx, dz, g.vr*3)
+	return true
+}
@@ -5,8 +5,13 @@ package main
 
 import (
 	"container/list"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"time"
 
 	"github.com/gazed/vu"
+	"github.com/gazed/vu/math/lin"
 )
 
 // launch is the application menu/start screen.  It is the first screen after the
@@ -17,8 +22,30 @@ type launch struct {
 	area                       // The launch screen fills up the game window.
 	anim       *startAnimation // The start button animation.
 	buttons    []*button       // The game select and option screen buttons.
+	mazes      *chooser        // Custom maze browser.
+	daily      *toggle         // Daily challenge switch.
+	dailyInfo  *vu.Ent         // Daily challenge description.
+	practice   *toggle         // Practice mode switch.
+	mutators   []*toggle       // Game mutator switches.
 	bg1        *vu.Ent         // Background rotating one way.
 	bg2        *vu.Ent         // Background rotating the other way.
+	backdrops  *chooser        // Background theme browser.
+	colours    *chooser        // Trooper colour scheme browser.
+	profiles   *profileMenu    // Local player profile browser.
+	px, py     float64         // Background parallax offset.
+	notice     *vu.Ent         // Offers to show the last crash report.
+	report     *about          // Last crash report viewer.
+	changes    *about          // Changes in a new version, nil once seen.
+	run        *RunSave        // Saved run that can be continued, or nil.
+	cont       *vu.Ent         // Continues the saved run.
+	fresh      *vu.Ent         // Starts a new run instead of the saved run.
+	replacing  bool            // True once asked to confirm replacing the saved run.
+	share      *vu.Ent         // Last share code, click to type a share code.
+	copy       *vu.Ent         // Copies the last share code.
+	typing     bool            // True while a share code is being typed.
+	code       string          // Share code typed so far.
+	pasting    <-chan clipping // Clipboard being read for a share code, nil if not pasting.
+	copying    <-chan clipping // Clipboard being written with a share code, nil if not copying.
 	buttonSize int             // Width and height of each button.
 	mp         *bampf          // Needed for toggling the option screen.
 	evolving   bool            // True when player is moving between levels.
@@ -34,6 +61,13 @@ func (l *launch) activate(state int) {
 		l.anim.scale = 200
 		l.ui.Cull(false)
 		l.evolving = false
+		l.mazes.setChoices(append([]string{generatedMaze}, customMazes()...))
+		l.setBackdrop(l.mp.backdrop)
+		l.setColours(l.mp.colours)
+		l.showDaily()
+		l.showLevels()
+		l.showRun()
+		l.showShare("")
 	case screenDeactive:
 		l.ui.Cull(true)
 		l.evolving = false
@@ -44,28 +78,113 @@ func (l *launch) activate(state int) {
 
 // User input to game events. Implements screen interface.
 func (l *launch) processInput(in *vu.Input, eventq *list.List) {
-	for press, down := range in.Down {
-		switch {
-		case press == vu.KEsc && down == 1 && !l.evolving:
-			publish(eventq, toggleOptions, nil)
-		case press == vu.KSpace && down == 1:
-			publish(eventq, skipAnim, nil)
-		case press == vu.KLm && down == 1:
-			for _, btn := range l.buttons {
-				if btn.clicked(in.Mx, in.My) {
-					publish(eventq, btn.eventID, btn.eventData)
-				}
+	ip := l.mp.input
+	if l.changes != nil && l.changes.visible() {
+		if l.changes.handleInput(in, ip) {
+			publish(eventq, viewChanges, nil)
+		}
+		return
+	}
+	if l.report != nil && l.report.visible() {
+		if l.report.handleInput(in, ip) {
+			publish(eventq, viewCrash, nil)
+		}
+		return
+	}
+	if l.profiles.naming {
+		if name, done := l.profiles.typeName(ip); done {
+			publish(eventq, renameProfile, name)
+		}
+		return
+	}
+	if l.pasting != nil {
+		select {
+		case pasted := <-l.pasting:
+			l.pasting = nil
+			publish(eventq, pasteShare, pasted)
+		default:
+		}
+	}
+	if l.copying != nil {
+		select {
+		case copied := <-l.copying:
+			l.copying = nil
+			publish(eventq, copiedShare, copied)
+		default:
+		}
+	}
+	if l.typing {
+		if code, done := l.typeCode(ip); done {
+			publish(eventq, replayShare, code)
+		}
+		return
+	}
+	if !l.evolving && ip.pressed(vu.KEsc) {
+		publish(eventq, toggleOptions, nil)
+	}
+	if ip.pressed(vu.KSpace) {
+		publish(eventq, skipAnim, nil)
+	}
+	if !l.evolving && ip.pressed(vu.KLm) {
+		for _, btn := range l.buttons {
+			if btn.clicked(in.Mx, in.My) {
+				publish(eventq, btn.eventID, btn.eventData)
 			}
-			if l.anim.clicked(in.Mx, in.My) {
-				publish(eventq, startGame, nil)
+		}
+		for _, mut := range l.mutators {
+			if mut.clicked(in.Mx, in.My) {
+				publish(eventq, toggleMutator, mut.id)
 			}
 		}
+		switch {
+		case l.mazes.clicked(in.Mx, in.My):
+			publish(eventq, pickMaze, nil)
+		case l.backdrops.clicked(in.Mx, in.My):
+			publish(eventq, pickBackdrop, nil)
+		case l.colours.clicked(in.Mx, in.My):
+			publish(eventq, pickColours, nil)
+		case l.profiles.names.clicked(in.Mx, in.My):
+			publish(eventq, pickProfile, nil)
+		case l.profiles.link(in.Mx, in.My) != "":
+			publish(eventq, editProfile, l.profiles.link(in.Mx, in.My))
+		case l.noticeClicked(in.Mx, in.My):
+			publish(eventq, viewCrash, nil)
+		case labelClicked(l.cont, in.Mx, in.My):
+			publish(eventq, continueGame, nil)
+		case labelClicked(l.fresh, in.Mx, in.My):
+			publish(eventq, startGame, nil)
+		case labelClicked(l.share, in.Mx, in.My):
+			publish(eventq, pickShare, nil)
+		case labelClicked(l.copy, in.Mx, in.My):
+			publish(eventq, copyShare, nil)
+		case l.daily.clicked(in.Mx, in.My):
+			publish(eventq, pickDaily, nil)
+		case l.practice.clicked(in.Mx, in.My):
+			publish(eventq, pickPractice, nil)
+		case l.anim.clicked(in.Mx, in.My):
+			l.anim.grab(in.Mx, in.My)
+		}
+	}
+
+	// drag to rotate the trooper and scroll to zoom. Clicking
+	// without dragging starts the game.
+	if l.anim.grabbed {
+		if !ip.isReleased(vu.KLm) {
+			l.anim.drag(in.Mx, in.My)
+		} else if l.anim.release() && !l.evolving {
+			publish(eventq, startGame, nil)
+		}
+	}
+	if in.Scroll != 0 && l.anim.clicked(in.Mx, in.My) {
+		l.anim.zoomBy(in.Scroll)
 	}
 
 	// handle once per game tick processing.
 	l.hover(in)
 	l.rotateBackdrop()
+	l.parallax(in.Mx, in.My)
 	l.anim.rotate(in.Ut, in.Dt)
+	publish(eventq, statusChanged, Status{Mode: modeMenu, Level: l.mp.launchLevel})
 }
 
 // Process game events. Implements screen interface.
@@ -85,8 +204,117 @@ func (l *launch) processEvents(eventq *list.List) (transition int) {
 			} else {
 				logf("launch.processEvents: did not receive startGame level")
 			}
+		case pickMaze:
+			l.mp.launchMaze = ""
+			if maze := l.mazes.next(); maze != generatedMaze {
+				l.mp.launchMaze = maze
+			}
+		case pickBackdrop:
+			l.mp.backdrop = l.backdrops.next()
+			l.setBackdrop(l.mp.backdrop)
+			newSaver().persistBackdrop(l.mp.backdrop)
+		case pickColours:
+			l.mp.colours = l.colours.next()
+			l.setColours(l.mp.colours)
+			newSaver().persistColours(l.mp.colours)
+		case viewCrash:
+			l.viewCrash()
+		case viewChanges:
+			l.closeChanges()
+		case pickProfile:
+			l.mp.useProfile(l.profiles.names.next())
+		case editProfile:
+			if link, ok := event.data.(string); ok && link == "rename" {
+				l.profiles.startNaming()
+			} else if ok {
+				l.mp.manageProfile(link, "")
+			} else {
+				logf("launch.processEvents: did not receive editProfile link")
+			}
+		case renameProfile:
+			if name, ok := event.data.(string); ok {
+				l.mp.manageProfile("rename", name)
+				l.profiles.show() // shows the old name if renaming failed.
+			} else {
+				logf("launch.processEvents: did not receive renameProfile name")
+			}
+		case pickDaily:
+			l.mp.launchDaily = !l.mp.launchDaily
+			l.daily.set(l.mp.launchDaily)
+			if l.mp.launchDaily && l.mp.practice {
+				l.mp.practice = false // daily challenges are for real.
+				l.practice.set(false)
+				l.showLevels()
+			}
+			l.showDaily()
+		case pickPractice:
+			l.mp.practice = !l.mp.practice
+			l.practice.set(l.mp.practice)
+			if l.mp.practice && l.mp.launchDaily {
+				l.mp.launchDaily = false
+				l.daily.set(false)
+				l.showDaily()
+			}
+			l.showLevels()
+		case toggleMutator:
+			if id, ok := event.data.(string); ok {
+				l.toggleMutator(id)
+			} else {
+				logf("launch.processEvents: did not receive toggleMutator id")
+			}
 		case startGame:
+			if l.run != nil && !l.replacing {
+				l.replacing = true // ask before replacing the saved run.
+				l.fresh.SetStr("Click new run again to replace the saved run.")
+				l.placeRun()
+				break
+			}
+			l.mp.resume = nil
+			return playGame
+		case continueGame:
+			l.mp.resume = l.run
 			return playGame
+		case pickShare:
+			l.startCode()
+		case copyShare:
+			l.copyCode()
+		case pasteShare:
+			if pasted, ok := event.data.(clipping); ok {
+				l.pasteCode(pasted)
+			} else {
+				logf("launch.processEvents: did not receive pasteShare clipping")
+			}
+		case copiedShare:
+			if copied, ok := event.data.(clipping); ok {
+				l.copiedCode(copied)
+			} else {
+				logf("launch.processEvents: did not receive copiedShare clipping")
+			}
+		case replayShare:
+			code, ok := event.data.(string)
+			if !ok {
+				logf("launch.processEvents: did not receive replayShare code")
+				break
+			}
+			if code == "" {
+				l.showShare("") // typing was cancelled.
+				break
+			}
+			sc, err := checkShare(code, time.Now())
+			if err != nil {
+				l.showShare(err.Error())
+				break
+			}
+			if sc.day == 0 {
+				l.mp.launchLevel, l.mp.launchMaze = sc.start, ""
+				l.anim.showLevel(sc.start)
+			}
+			l.mp.resume, l.mp.replay = nil, sc
+			return playGame
+		case statusChanged:
+			if st, ok := event.data.(Status); ok {
+				l.mp.presence.update(st)
+			}
 		}
 	}
 	return chooseGame
@@ -128,8 +356,30 @@ func newLaunchScreen(mp *bampf) *launch {
 	for _, btn := range l.buttons {
 		btn.icon.SetScale(1, 1, 0)
 	}
+	l.mazes = newChooser(buttonPart, "maze", []string{generatedMaze})
+	l.backdrops = newChooser(buttonPart, "backdrop", backdropNames())
+	l.colours = newChooser(buttonPart, "trooper", colourNames())
+	l.profiles = newProfileMenu(buttonPart)
+	l.daily = newToggle(buttonPart, "daily", "daily challenge", mp.launchDaily)
+	l.dailyInfo = buttonPart.AddPart()
+	l.dailyInfo.MakeLabel("labeled", "lucidiaSu18")
+	l.practice = newToggle(buttonPart, "practice", "practice mode", mp.practice)
+	l.mutators = []*toggle{}
+	for _, id := range gameMutatorIDs {
+		l.mutators = append(l.mutators, newToggle(buttonPart, id, id, mp.mutators[id]))
+	}
+	l.notice = l.ui.AddPart().MakeLabel("labeled", "lucidiaSu18")
+	l.notice.Cull(true)
+	l.cont = l.ui.AddPart().MakeLabel("labeled", "lucidiaSu22")
+	l.cont.Cull(true)
+	l.fresh = l.ui.AddPart().MakeLabel("labeled", "lucidiaSu18")
+	l.fresh.Cull(true)
+	l.share = l.ui.AddPart().MakeLabel("labeled", "lucidiaSu18")
+	l.copy = l.ui.AddPart().MakeLabel("labeled", "lucidiaSu18")
+	l.showCrash()
 	l.layout(0)
 	l.handleResize(l.w, l.h)
+	l.showChanges()
 
 	// start the button animation.
 	l.mp.ani.addAnimation(l.newButtonAnimation())
@@ -149,9 +399,8 @@ func (l *launch) handleResize(width, height int) {
 			size = l.h
 		}
 		l.bg1.SetScale(float64(size), float64(size), 1)
-		l.bg1.SetAt(float64(l.w/2)-5, float64(l.h/2)-5, 1)
 		l.bg2.SetScale(float64(size), float64(size), 1)
-		l.bg2.SetAt(float64(l.w/2)-5, float64(l.h/2)-5, 1)
+		l.parallax(l.w/2, l.h/2)
 	}
 	l.layout(1)
 }
@@ -162,11 +411,14 @@ func (l *launch) setSize(x, y, width, height int) {
 	l.cx, l.cy = l.center()
 }
 
-// hover hilites any button the mouse is over.
+// hover hilites any button the mouse is over. Level buttons show a
+// sample maze for the level, which is drawn the first time it is needed.
 func (l *launch) hover(i *vu.Input) {
 	l.anim.hover(i.Mx, i.My)
-	for _, btn := range l.buttons {
-		btn.hover(i.Mx, i.My)
+	for index, btn := range l.buttons {
+		if btn.hover(i.Mx, i.My) && index < len(gameMuster) && btn.thumb == nil {
+			btn.setThumb(levelThumb(index), "thumb"+strconv.Itoa(index))
+		}
 	}
 }
 
@@ -185,17 +437,296 @@ func (l *launch) layout(buttonIndex float64) {
 	l.buttons[3].position(cx+dx, cy)
 	l.buttons[4].position(cx+dx*2, cy)
 	l.buttons[5].position(cx, cy-float64(l.buttonSize)-10)
+	if l.mazes != nil {
+		ts := textScale
+		l.mazes.position(cx-float64(l.mazes.w)*ts*0.5, cy+float64(l.buttonSize/2)+10*ts)
+		l.daily.position(cx-float64(l.daily.w)*ts*0.5, cy+float64(l.buttonSize/2)+35*ts)
+		scaleLabel(l.dailyInfo, 1).SetAt(cx-float64(l.daily.w)*ts*0.5, cy+float64(l.buttonSize/2)+60*ts, 0)
+		l.practice.position(cx-float64(l.practice.w)*ts*0.5, cy+float64(l.buttonSize/2)+85*ts)
+	}
+	for cnt, mut := range l.mutators {
+		mut.position(20, float64(l.h)-float64(40+cnt*25)*textScale)
+	}
+	if l.backdrops != nil {
+		x := float64(l.w-20) - float64(l.backdrops.w)*textScale
+		l.backdrops.position(x, float64(l.h)-40*textScale)
+		l.profiles.position(x, float64(l.h)-70*textScale)
+		l.colours.position(x, float64(l.h)-125*textScale)
+	}
+	if l.notice != nil {
+		w, _ := labelSize(scaleLabel(l.notice, 1))
+		l.notice.SetAt(l.cx-float64(w/2), float64(l.h)-70*textScale, 0)
+	}
+	if l.report != nil {
+		l.report.resize(l.w, l.h)
+	}
+	if l.cont != nil {
+		l.placeRun()
+	}
+	if l.share != nil {
+		l.placeShare()
+	}
+	if l.changes != nil {
+		l.changes.resize(l.w, l.h)
+	}
 }
 
 // rotateBackdrop rotates the start screen backgrounds in opposite
 // directions and different speeds.
 func (l *launch) rotateBackdrop() {
-	l.bg1.Spin(0, 0, 0.2)
-	l.bg2.Spin(0, 0, -0.166)
+	spin := getBackdrop(l.mp.backdrop).spin
+	l.bg1.Spin(0, 0, spin)
+	l.bg2.Spin(0, 0, -spin*0.83)
+}
+
+// parallax shifts the backgrounds a little away from the mouse,
+// the top background more than the bottom one.
+func (l *launch) parallax(mx, my int) {
+	dx, dy := float64(mx-l.w/2), float64(my-l.h/2)
+	l.px = l.px + (dx*parallaxShift-l.px)*0.1 // ease towards the mouse.
+	l.py = l.py + (dy*parallaxShift-l.py)*0.1
+	x, y := float64(l.w/2)-5, float64(l.h/2)-5
+	l.bg1.SetAt(x-l.px, y-l.py, 1)
+	l.bg2.SetAt(x-l.px*2, y-l.py*2, 1)
+}
+
+// parallaxShift is how far the backgrounds move for each pixel
+// the mouse is away from the screen center.
+const parallaxShift = 0.02
+
+// setBackdrop changes the background to the named theme.
+func (l *launch) setBackdrop(name string) {
+	bd := getBackdrop(name)
+	for l.backdrops.choice() != bd.name {
+		l.backdrops.next()
+	}
+	l.bg1.SetFirst(bd.texture)
+	l.bg2.SetFirst(bd.texture)
+	l.mp.eng.Set(vu.Color(bd.tint[0], bd.tint[1], bd.tint[2], 1))
+}
+
+// setColours changes the trooper colours to the named scheme. The launch
+// trooper is rebuilt to show the new colours. Game troopers pick up the
+// colours when their level is created.
+func (l *launch) setColours(name string) {
+	scheme := getColours(name)
+	for l.colours.choice() != scheme.name {
+		l.colours.next()
+	}
+	if trooperColours.name != scheme.name {
+		trooperColours = scheme
+		l.anim.showLevel(l.anim.player.lvl)
+	}
+}
+
+// setProfile refreshes the screen after the active profile changes.
+func (l *launch) setProfile() {
+	l.profiles.show()
+	l.setBackdrop(l.mp.backdrop)
+	l.setColours(l.mp.colours)
+	l.showDaily()
+	l.showLevels()
+	l.showRun()
+}
+
+// toggleMutator turns the given game mutator on or off.
+func (l *launch) toggleMutator(id string) {
+	l.mp.mutators[id] = !l.mp.mutators[id]
+	for _, mut := range l.mutators {
+		if mut.id == id {
+			mut.set(l.mp.mutators[id])
+		}
+	}
+}
+
+// showLevels annotates the level buttons with the best completion time
+// and a checkmark for each completed level. Levels that have not been
+// unlocked are disabled when the optional level progression is on.
+// Any level can be practiced.
+func (l *launch) showLevels() {
+	saver := newSaver()
+	saver.restore()
+	progress := l.mp.opts[progressOption] && !l.mp.practice
+	for lvl, btn := range l.buttons[:len(gameMuster)] {
+		note, badge := "", ""
+		if lvl < len(saver.Levels) && saver.Levels[lvl] > 0 {
+			note, badge = formatTime(saver.Levels[lvl]), "check"
+		}
+		locked := progress && lvl > saver.Unlocked
+		if locked {
+			badge = "lock"
+		}
+		btn.setNote(note)
+		btn.setBadge(badge)
+		btn.setEnabled(!locked)
+	}
+
+	// fall back to the first level if the chosen level is locked.
+	if progress && l.mp.launchLevel > saver.Unlocked {
+		l.mp.launchLevel = 0
+		l.anim.showLevel(0)
+	}
+}
+
+// formatTime formats seconds as minutes and seconds, eg: 2:05.
+func formatTime(secs float64) string {
+	whole := int(secs + 0.5)
+	return fmt.Sprintf("%d:%02d", whole/60, whole%60)
+}
+
+// showDaily describes the daily challenge, and the best time for the
+// day, when the daily challenge is choosen.
+func (l *launch) showDaily() {
+	l.dailyInfo.Cull(!l.mp.launchDaily)
+	if l.mp.launchDaily {
+		dc := newChallenge(time.Now())
+		info := dc.day + ": " + dc.describe()
+		saver := newSaver()
+		saver.restore()
+		if saver.Daily == dc.day {
+			info += fmt.Sprintf(" best %.1fs", saver.DailyBest)
+		}
+		l.dailyInfo.SetStr(info)
+	}
 }
 
+// showCrash offers to show the crash report if the game crashed
+// the last time it was played.
+func (l *launch) showCrash() {
+	saver := newSaver()
+	saver.restore()
+	if saver.Crash == "" {
+		return
+	}
+	bites, err := ioutil.ReadFile(saver.Crash)
+	if err != nil {
+		logf("launch.showCrash: %s", err)
+		saver.persistCrash("")
+		return
+	}
+	l.report = newTextView(l.ui, textLines(string(bites)))
+	l.notice.SetStr("Bampf crashed last time. Click here to view the report.")
+	l.notice.Cull(false)
+}
+
+// noticeClicked returns true if the crash notice was clicked.
+func (l *launch) noticeClicked(mx, my int) bool { return labelClicked(l.notice, mx, my) }
+
+// labelClicked returns true if the given visible label was clicked.
+func labelClicked(label *vu.Ent, mx, my int) bool {
+	if label.Culled() {
+		return false
+	}
+	x, y, _ := label.At()
+	w, h := labelSize(label)
+	return mx >= int(x) && mx <= int(x)+w && my >= int(y) && my <= int(y)+h
+}
+
+// showRun offers to continue the saved run, if there is one, or to
+// start a new run. Saved runs that can no longer be played are deleted.
+func (l *launch) showRun() {
+	saver := newSaver()
+	saver.restore()
+	l.run, l.replacing = saver.Run, false
+	if l.run != nil && !l.run.canContinue(time.Now()) {
+		saver.persistRun(nil)
+		l.run = nil
+	}
+	l.cont.Cull(l.run == nil)
+	l.fresh.Cull(l.run == nil)
+	if l.run != nil {
+		l.cont.SetStr(l.run.describe())
+		l.fresh.SetStr("New run")
+		l.placeRun()
+	}
+}
+
+// placeRun centers the continue and new run choices below the crash notice.
+func (l *launch) placeRun() {
+	w, _ := labelSize(scaleLabel(l.cont, 1))
+	l.cont.SetAt(l.cx-float64(w/2), float64(l.h)-110*textScale, 0)
+	w, _ = labelSize(scaleLabel(l.fresh, 1))
+	l.fresh.SetAt(l.cx-float64(w/2), float64(l.h)-140*textScale, 0)
+}
+
+// viewCrash shows or hides the crash report. The crash notice is only
+// shown until the report has been seen.
+func (l *launch) viewCrash() {
+	if l.report != nil {
+		l.report.toggle()
+		l.notice.Cull(true)
+		newSaver().persistCrash("")
+	}
+}
+
+// showChanges shows what is new the first time a new version is played.
+func (l *launch) showChanges() {
+	saver := newSaver()
+	saver.restore()
+	if lines := changesToShow(saver); len(lines) > 0 {
+		l.changes = newTextView(l.ui, lines)
+		l.changes.resize(l.w, l.h)
+		l.changes.toggle()
+	}
+}
+
+// closeChanges hides the changes and remembers that they were seen.
+func (l *launch) closeChanges() {
+	if l.changes != nil {
+		l.changes.toggle()
+		l.changes = nil
+		newSaver().persistSeen(version)
+	}
+}
+
+// generatedMaze is the maze browser choice for randomly generated mazes.
+const generatedMaze = "generated"
+
 // launch
 // ===========================================================================
+// backdrop
+
+// backdrop is a launch screen background theme. The tint is the screen
+// colour showing through the backgrounds. It also tints the options
+// screen.
+type backdrop struct {
+	name    string     // Theme name shown on the launch screen.
+	texture string     // Background image.
+	spin    float64    // Background rotation speed.
+	tint    [3]float32 // Background colour.
+}
+
+// backdrops are the available launch screen themes. The first
+// theme is the default.
+var backdrops = []backdrop{
+	{name: "classic", texture: "backdrop", spin: 0.2, tint: [3]float32{1, 1, 1}},
+	{name: "dusk", texture: "backdrop", spin: 0.1, tint: [3]float32{0.55, 0.45, 0.7}},
+	{name: "ember", texture: "backdrop", spin: 0.3, tint: [3]float32{0.9, 0.55, 0.35}},
+	{name: "storm", texture: "smoke", spin: 0.4, tint: [3]float32{0.4, 0.5, 0.6}},
+}
+
+// getBackdrop returns the named backdrop theme or the default
+// theme if the name is unknown.
+func getBackdrop(name string) backdrop {
+	for _, bd := range backdrops {
+		if bd.name == name {
+			return bd
+		}
+	}
+	return backdrops[0]
+}
+
+// backdropNames lists the backdrop themes in launch screen order.
+func backdropNames() []string {
+	names := []string{}
+	for _, bd := range backdrops {
+		names = append(names, bd.name)
+	}
+	return names
+}
+
+// backdrop
+// ===========================================================================
 // fadeStartAnimation fades out the start screen.
 
 // newFadeAnimation creates the launch screen fade out animation.
@@ -330,13 +861,32 @@ type startAnimation struct {
 	player *trooper // Player can be new or saved.
 	hilite *vu.Ent  // Hover overlay.
 	scale  float64  // Controls the animation size.
+	zoom   float64  // User zoom, 1 is the normal size.
+
+	// user rotation control.
+	grabbed bool    // True while the mouse button is down on the trooper.
+	dragged bool    // True once the trooper has been dragged.
+	gx, gy  int     // Mouse location when the trooper was grabbed.
+	mx, my  int     // Previous mouse location while grabbed.
+	idle    float64 // Seconds since the last user rotation or zoom.
 }
 
+// Trooper preview user control limits.
+const (
+	dragStart   = 4   // Pixels the mouse moves before a click becomes a drag.
+	dragSpin    = 0.5 // Degrees of rotation per pixel dragged.
+	minZoom     = 0.5 // Smallest user zoom.
+	maxZoom     = 1.5 // Largest user zoom.
+	resumeDelay = 2.0 // Idle seconds before the automatic spin resumes.
+)
+
 // newStartAnimation creates the start screen animation.
 func newStartAnimation(mp *bampf, parent *vu.Ent, screenWidth, screenHeight int) *startAnimation {
 	sa := &startAnimation{}
 	sa.parent = parent
 	sa.scale = 200
+	sa.zoom = 1
+	sa.idle = resumeDelay
 	sa.hilite = parent.AddPart()
 	sa.hilite.MakeModel("colored", "msh:square", "mat:white")
 	sa.hilite.Cull(true)
@@ -353,7 +903,7 @@ func (sa *startAnimation) showLevel(level int) {
 	sa.player = newTrooper(sa.parent.AddPart(), level)
 	sa.player.part.Spin(15, 0, 0)
 	sa.player.part.Spin(0, 0, 15)
-	sa.player.setScale(sa.scale)
+	sa.player.setScale(sa.scale * sa.zoom)
 	sa.player.setLoc(sa.cx, sa.cy, 0)
 }
 
@@ -393,11 +943,50 @@ func (sa *startAnimation) hover(mx, my int) {
 	}
 }
 
+// grab starts user control of the trooper rotation.
+func (sa *startAnimation) grab(mx, my int) {
+	sa.grabbed, sa.dragged = true, false
+	sa.gx, sa.gy, sa.mx, sa.my = mx, my, mx, my
+	sa.idle = 0
+}
+
+// drag rotates the trooper by the mouse movement once the mouse has
+// moved far enough from where the trooper was grabbed.
+func (sa *startAnimation) drag(mx, my int) {
+	dx, dy := mx-sa.mx, my-sa.my
+	if !sa.dragged {
+		gdx, gdy := mx-sa.gx, my-sa.gy
+		sa.dragged = gdx*gdx+gdy*gdy > dragStart*dragStart
+	}
+	if sa.dragged {
+		sa.player.part.Spin(-float64(dy)*dragSpin, float64(dx)*dragSpin, 0)
+	}
+	sa.mx, sa.my = mx, my
+	sa.idle = 0
+}
+
+// release ends user control of the trooper rotation. Returns true
+// if the trooper was clicked rather than dragged.
+func (sa *startAnimation) release() (clicked bool) {
+	sa.grabbed = false
+	return !sa.dragged
+}
+
+// zoomBy changes the trooper size by the given mouse scroll amount.
+func (sa *startAnimation) zoomBy(scroll int) {
+	sa.zoom = lin.Clamp(sa.zoom+float64(scroll)*0.1, minZoom, maxZoom)
+	sa.idle = 0
+}
+
 // rotate is called each game loop to update the player rotation.
+// The automatic spin pauses while the user is controlling the trooper.
 func (sa *startAnimation) rotate(updateTicks uint64, deltaTime float64) {
 	spinSpeed := float64(25) // degrees per second.
-	sa.player.part.Spin(0, deltaTime*spinSpeed, 0)
-	sa.player.setScale(sa.scale)
+	sa.idle += deltaTime
+	if !sa.grabbed && sa.idle >= resumeDelay {
+		sa.player.part.Spin(0, deltaTime*spinSpeed, 0)
+	}
+	sa.player.setScale(sa.scale * sa.zoom)
 	sa.player.setLoc(sa.player.loc())
 
 	// regenerate cubes faster as the player gets bigger.
@@ -6,7 +6,11 @@ package main
 import (
 	"fmt"
 	"math"
+	"math/rand"
+	"strconv"
+	"time"
 
+	"github.com/gazed/bampf/gridmath"
 	"github.com/gazed/vu"
 	"github.com/gazed/vu/grid"
 	"github.com/gazed/vu/math/lin"
@@ -15,43 +19,77 @@ import (
 // level groups everything needed for a single level.
 // This includes the player, the sentinels, and the level map.
 type level struct {
-	scene     *vu.Ent      // 2D scene
-	cam       *vu.Camera   // Quick access to the 3D scene camera.
-	hd        *hud         // 2D information display for the stage.
-	mp        *bampf       // Main program.
-	num       int          // Level number.
-	gcx, gcy  int          // Grid level center.
-	center    *vu.Ent      // Center tile model.
-	walls     []*vu.Ent    // Walls.
-	floor     *vu.Ent      // Large invisible floor.
-	body      *vu.Ent      // Physics body for the player.
-	player    *trooper     // Player size/shape for this stage.
-	sentries  []*sentinel  // Sentinels: player enemy AI's.
-	cc        *coreControl // Controls dropping cores on a stage.
-	plan      grid.Grid    // Stage floorplan.
-	coreLimit int          // Max cores for this level.
-	units     int          // Reference base size for all game elements.
-	fade      float64      // distance to fade out.
-	colour    float32      // Current background shade-of-gray colour.
-	fov       float64      // Field of view.
-}
-
-// newLevel creates the indicated game level.
-func newLevel(g *game, levelNum int) *level {
-	var levelType = map[int]grid.Grid{
-		0: grid.New(grid.DenseSkirmish),
-		1: grid.New(grid.DenseSkirmish),
-		2: grid.New(grid.SparseSkirmish),
-		3: grid.New(grid.RoomSkirmish),
-		4: grid.New(grid.RoomSkirmish),
-	}
+	scene     *vu.Ent                  // 2D scene
+	cam       *vu.Camera               // Quick access to the 3D scene camera.
+	hd        *hud                     // 2D information display for the stage.
+	mp        *bampf                   // Main program.
+	num       int                      // Level number.
+	gcx, gcy  int                      // Grid level center.
+	center    *vu.Ent                  // Center tile model.
+	walls     []*vu.Ent                // Walls.
+	cracks    map[gridmath.Spot]*crack // Cracked walls that have not been broken.
+	gates     map[gridmath.Spot]*gate  // One-way gates.
+	guards    grid.Grid                // Floorplan for sentinels where gates are walls.
+	voids     map[gridmath.Spot]bool   // Void tiles that drop the player a level.
+	interdict map[gridmath.Spot]bool   // Floor near the center where teleports can't start.
+	last      gridmath.Spot            // Player grid location on the previous tick.
+	lastx     float64                  // Player game location on the previous tick.
+	lastz     float64                  //   "
+	floor     *vu.Ent                  // Large invisible floor.
+	body      *vu.Ent                  // Physics body for the player.
+	ghost     *vu.Ent                  // Teleport destination preview.
+	reach     *vu.Ent                  // Core pickup reach ring around the player.
+	shadows   *shadows                 // Blob shadows under the player and nearby sentinels.
+	frozen    int                      // Ticks left until frozen sentinels move again.
+	fetched   int                      // Cores collected since the level was activated.
+	alarms    int                      // Sentinel proximity warnings since the level was activated.
+	hits      int                      // Sentinel collisions since the level was activated.
+	forgiven  bool                     // True once the first sentinel collision was forgiven.
+	combo     combo                    // Cores collected in quick succession.
+	partner   *vu.Ent                  // Co-op partner marker.
+	player    *trooper                 // Player size/shape for this stage.
+	sentries  []*sentinel              // Sentinels: player enemy AI's.
+	moves     *rand.Rand               // Sentinel movement randomness, seeded per level so replays match.
+	enemies   []enemy                  // All player enemies, including the sentinels.
+	far       []bool                   // Enemies well beyond the visible distance.
+	farCheck  int                      // Ticks until the far enemies are checked again.
+	turrets   []*turret                // Dead-end turrets, if any.
+	bolts     []*bolt                  // Turret bolt pool.
+	spawns    *spawner                 // Releases the sentinels into the level.
+	trails    *trails                  // Sentinel trails, nil for levels without trails.
+	practice  bool                     // True while the level is played in practice mode.
+	guides    []*guide                 // Practice mode sentinel markers.
+	path      *vu.Ent                  // Pathfinder arrows, nil when not shown.
+	arrows    []*vu.Ent                // Pathfinder arrow models.
+	pathTicks int                      // Game ticks until the pathfinder arrows fade away.
+	pickups   []*cloakPickup           // Cloak pickups that earn the pathfinder charge.
+	charged   bool                     // True once the pathfinder is earned, until it is used.
+	pathUsed  bool                     // True once the pathfinder is used on this visit.
+	pings     []*ping                  // Co-op ping beacons.
+	cc        *coreControl             // Controls dropping cores on a stage.
+	plan      grid.Grid                // Stage floorplan.
+	theme     *Theme                   // Stage look.
+	source    string                   // Custom maze name or empty for generated mazes.
+	seed      int64                    // Maze seed used to generate the floorplan.
+	coreLimit int                      // Max cores for this level.
+	units     int                      // Reference base size for all game elements.
+	fade      float64                  // distance to fade out.
+	colour    float32                  // Current background shade-of-gray colour.
+	sky       *skyDome                 // Gradient sky that follows the mist colour.
+	mirror    *mirror                  // Optional rear-view mirror, created when first shown.
+	fov       float64                  // Field of view.
+}
+
+// newLevel creates the indicated game level using the given floorplan.
+func newLevel(g *game, levelNum int, plan grid.Grid) *level {
 
 	// initialize the scenes.
 	lvl := &level{}
-	lvl.fade = g.vr * 0.7
+	lvl.fade = gameFade[levelNum]
 	lvl.units = 2
 	lvl.colour = 1.0
 	lvl.fov = 75
+	lvl.moves = rand.New(rand.NewSource(time.Now().UnixNano()))
 	lvl.scene = g.mp.eng.AddScene()
 	lvl.scene.SetCuller(vu.NewFrontCull(g.vr))
 	lvl.cam = lvl.scene.Cam()
@@ -60,34 +98,65 @@ func newLevel(g *game, levelNum int) *level {
 	// save everything as one game stage.
 	lvl.mp = g.mp
 	lvl.num = levelNum
+	lvl.theme = gameThemes[levelNum]
+
+	// daily challenges change the level tuning.
+	muster, waveTicks := gameSentinels(levelNum), gameWaveTicks[levelNum]
+	if g.daily != nil {
+		muster, waveTicks = g.daily.sentinels(levelNum), g.daily.waveTicks(levelNum)
+	}
 
 	// create hud before player since player is drawn within hd.scene.
 	s := g.mp.eng.State()
-	lvl.hd = newHud(g.mp.eng, gameMuster[lvl.num], s.X, s.Y, s.W, s.H)
+	lvl.hd = newHud(g.mp.eng, muster+turretEnemies(levelNum), s.X, s.Y, s.W, s.H)
+	lvl.hd.setSafeMode(g.mp.opts[safeFlashOption])
+	lvl.hd.setLayout(g.hudLayout())
+	lvl.hd.setCrosshair(g.mp.crosshair)
 	lvl.player = lvl.makePlayer(lvl.hd.ui.AddPart(), lvl.num+1)
-	lvl.makeSentries(lvl.scene, lvl.num)
+	lvl.makeSentries(lvl.scene, lvl.num, muster)
+	lvl.makeTurrets(lvl.scene, lvl.num)
+	if g.daily != nil && g.daily.modifiers[halfCloak] {
+		lvl.player.cemax /= 2
+	}
+	if g.daily != nil && g.daily.modifiers[fastSentinels] {
+		for _, sentry := range lvl.sentries {
+			sentry.speed = sentinelSpeed * 2 / 3
+		}
+	}
+
+	// create the sky before the maze so it is drawn behind the maze.
+	lvl.sky = newSkyDome(lvl.scene, lvl.theme.Sky)
 
 	// create one large floor.
 	lvl.floor = lvl.scene.AddPart().SetAt(0, 0.2, 0)
 
-	// create a new layout for the stage.
-	plan := levelType[lvl.num]
-	levelSize := gameMapSize(lvl.num)
-	plan.Generate(levelSize, levelSize)
-
 	// build and populate the floorplan
 	lvl.walls = []*vu.Ent{}
 	lvl.cc = newCoreControl(lvl.units, g.mp.ani)
 	lvl.buildFloorPlan(lvl.scene, lvl.hd, plan)
-	lvl.plan = plan
+	lvl.plan = &crackedPlan{Grid: plan, broken: map[gridmath.Spot]bool{}}
+	lvl.buildGates(lvl.scene, lvl.hd, plan)
+	lvl.guards = &gatedPlan{Grid: lvl.plan, gates: lvl.gates}
+	lvl.buildVoids(lvl.scene, plan)
+	lvl.buildInterdiction(lvl.scene, plan)
+	lvl.buildProps(lvl.scene, plan)
+	lvl.buildTurrets(plan)
+	lvl.buildPickups(lvl.scene, plan)
+	if trail := gameHazard(gameTrail, levelNum); trail > 0 {
+		lvl.trails = newTrails(lvl.scene, trail, lvl.units, lvl.fade)
+	}
 
 	// set the intial player location.
-	lvl.body = lvl.scene.AddPart().SetAt(4, 0.5, 10)
+	lvl.body = lvl.scene.AddPart().SetAt(startSpot())
+	lvl.ghost = lvl.newGhost(lvl.scene.AddPart())
+	lvl.reach = lvl.newReach(lvl.scene.AddPart())
+	lvl.shadows = lvl.newShadows()
+	lvl.partner = lvl.scene.AddPart().SetScale(0.3, 0.3, 0.3)
+	lvl.partner.MakeModel("flata", "msh:cube", "mat:tred").SetUniform("fd", lvl.fade)
+	lvl.partner.Cull(true)
 
-	// start sentinels at the center of the stage.
-	for _, sentry := range lvl.sentries {
-		sentry.setGridAt(lvl.gcx, lvl.gcy)
-	}
+	// sentinels are released in waves from around the stage.
+	lvl.spawns = newSpawner(plan, gameWaveSize[lvl.num], waveTicks)
 	lvl.player.resetEnergy()
 	lvl.setVisible(false)
 	return lvl
@@ -102,11 +171,25 @@ func (lvl *level) setHudVisible(isVisible bool) {
 func (lvl *level) setVisible(isVisible bool) {
 	lvl.scene.Cull(!isVisible)
 	lvl.hd.setVisible(isVisible)
+	lvl.showMirror(isVisible && lvl.mp.opts[mirrorOption])
+}
+
+// showMirror turns the rear-view mirror on or off.
+func (lvl *level) showMirror(on bool) {
+	if on && lvl.mirror == nil {
+		lvl.mirror = newMirror(lvl)
+	}
+	if lvl.mirror != nil {
+		lvl.mirror.setVisible(on)
+	}
 }
 
 // resize adjusts the level to the new window dimensions.
 func (lvl *level) resize(width, height int) {
 	lvl.hd.resize(width, height)
+	if lvl.mirror != nil {
+		lvl.mirror.resize(width, height)
+	}
 }
 
 // update is called from game update.
@@ -115,17 +198,50 @@ func (lvl *level) update() {
 
 	// use the camera's orientation and the physics bodies location.
 	lvl.body.SetView(lvl.cam.Look)
+	lvl.passGates()
 	lvl.cam.SetAt(lvl.body.At())
 
 	// run animations and other regular checks.
 	lvl.setMist()
+	if lvl.mirror != nil && lvl.mp.opts[mirrorOption] {
+		lvl.mirror.update(lvl)
+	}
+	lvl.showReach()
+	lvl.hd.blockTeleport(lvl.interdicted())
 	lvl.fetchCores()
-	lvl.moveSentinels()
-	lvl.collideSentinels()
+	lvl.fetchFreeze()
+	lvl.cc.tick()
+	lvl.expireCores()
+	x, y, z := lvl.cam.At()
+	px, py := gridmath.ToGrid(x, y, z, float64(lvl.units))
+	lvl.spawns.spawn(lvl.sentries, px, py)
+	stop := timeStage("moveSentinels")
+	lvl.moveEnemies()
+	lvl.updateTurrets()
+	stop()
+	stop = timeStage("collideSentinels")
+	lvl.collideEnemies()
+	stop()
+	lvl.updateTrails()
+	lvl.updatePathfinder()
+	lvl.updatePings()
+	lvl.showGuides()
+	lvl.showShadows()
 	lvl.createCore()
-	lvl.hd.update(lvl.cam, lvl.sentries)
+	lvl.combo.tick()
+	lvl.hd.showCombo(lvl.combo.strength())
+	if lvl.hd.update(lvl.cam, lvl.enemies, lvl.player.cloaked) > 0 {
+		lvl.player.play(pingSound)
+		lvl.alarms++
+	}
+	lvl.hd.pointToCore(lvl.cam, lvl.cc)
 	lvl.player.updateEnergy()
+	lvl.player.updateDetail()
 	lvl.hd.cloakingActive(lvl.player.cloaked)
+	lvl.hd.detect(lvl.cam, lvl.enemies, lvl.player.cloaked)
+	health, warn, max := lvl.player.health()
+	lvl.hd.lingerLoss(health < warn)
+	lvl.hd.showCounters(health, max, lvl.fetched)
 }
 
 // updateKeys ensures the displayed action keys and labels are correct.
@@ -136,10 +252,11 @@ func (lvl *level) updateKeys(keys []int) {
 	}
 }
 
-// The background colour becomes darker the deeper into the maze
-// and the greater the level.
+// The background colour becomes darker, shading to the theme fog colour,
+// the deeper into the maze and the greater the level.
 func (lvl *level) setMist() {
 	px, _, pz := lvl.cam.At()
+	lvl.sky.follow(px, pz)
 	cx, _, cz := lvl.center.At()
 	dx, dz := float64(px-cx), float64(pz-cz)
 	dist := math.Sqrt(dx*dx + dz*dz)
@@ -156,9 +273,12 @@ func (lvl *level) setMist() {
 	lvl.setBackgroundColour(colour)
 }
 
-// setBackgroundColour uses colour to form a gray based background.
+// setBackgroundColour uses colour to blend between the theme tint and
+// fog colours. The sky dome horizon matches the background.
 func (lvl *level) setBackgroundColour(colour float32) {
-	lvl.mp.eng.Set(vu.Color(colour, colour, colour, 1))
+	r, g, b := skyBlend(lvl.theme.Fog, lvl.theme.Tint, colour)
+	lvl.mp.eng.Set(vu.Color(r, g, b, 1))
+	lvl.sky.tint([3]float32{r, g, b})
 }
 
 // isPlayerWorthy returns true if the player is able to ascend
@@ -167,6 +287,16 @@ func (lvl *level) isPlayerWorthy() bool {
 	return lvl.player.fullHealth() && !lvl.player.cloaked
 }
 
+// dispose removes the level and its heads-up-display. Expected to be
+// called on deactivated levels that are no longer needed.
+func (lvl *level) dispose() {
+	lvl.scene.Dispose()
+	lvl.hd.dispose()
+	if lvl.mirror != nil {
+		lvl.mirror.dispose()
+	}
+}
+
 // deactivate means this level is being taken out of action.
 // Tidy it up by ensuring all of its parts are out of the
 // physics simulation.
@@ -182,17 +312,37 @@ func (lvl *level) deactivate() {
 	// remove the cores.
 	lvl.cc.reset()
 	lvl.hd.resetCores()
+	if lvl.trails != nil {
+		lvl.trails.reset()
+		lvl.hd.showTrails(nil)
+	}
+	lvl.hd.showCountdown(0)
+	lvl.hd.showPrompt("")
+	lvl.previewTeleport(false)
+	sounds.stopLoop() // the cloak hum stays with the level.
+	if lvl.frozen > 0 {
+		lvl.frozen = 0
+		lvl.freezeSentinels(false)
+		lvl.hd.showFreeze(0)
+	}
 }
 
 // activate the current level. Add physics parts to the physics simulation.
 func (lvl *level) activate(hm healthMonitor) {
 	lvl.player.monitorHealth("game", hm)
+	lvl.fetched, lvl.alarms, lvl.hits = 0, 0, 0
+	lvl.cc.reseed(lvl.seed)
+	lvl.moves.Seed(lvl.seed)
+	lvl.combo.hit()
 	lvl.player.resetEnergy()
 	lvl.hd.setLevel(lvl)
 
 	// reset the camera each time, so it is in a known position.
-	lvl.cam.SetAt(4, 0.5, 10)
+	lvl.cam.SetAt(startSpot())
 	lvl.player.resetEnergy()
+	lvl.clearEntry()
+	lvl.resetTurrets()
+	lvl.resetPathfinder()
 
 	// ensure the walls and floor are added to the physics simulation.
 	for _, wall := range lvl.walls {
@@ -207,63 +357,184 @@ func (lvl *level) activate(hm healthMonitor) {
 	// add a physics body for the camera.
 	lvl.body.MakeBody(vu.Sphere(0.25))
 	lvl.body.SetSolid(1, 0)
+	lvl.passGates() // start tracking the player location for the gates.
 }
 
-// Generate the specific resource filenames for a particular level.
-func (lvl *level) wallMeshLabel(band int) string    { return fmt.Sprintf("%dwall", band) }
-func (lvl *level) wallTextureLabel(band int) string { return fmt.Sprintf("wall%d0", band) }
-func (lvl *level) tileLabel(band int) string        { return fmt.Sprintf("tile%d0", band) }
+// clearEntry returns the sentinels near the player start location, left
+// there from an earlier visit, to the spawner so that the player doesn't
+// arrive on top of them. The next wave is held back for a moment so the
+// returned sentinels are released gradually away from the player.
+func (lvl *level) clearEntry() {
+	x, y, z := startSpot()
+	px, py := gridmath.ToGrid(x, y, z, float64(lvl.units))
+	for _, sentry := range lvl.sentries {
+		if sentry.active {
+			sx, sy, sz := sentry.location()
+			if gx, gy := gridmath.ToGrid(sx, sy, sz, float64(lvl.units)); abs(gx-px) < spawnSafety && abs(gy-py) < spawnSafety {
+				sentry.setActive(false)
+			}
+		}
+	}
+	lvl.spawns.holdBack()
+}
 
-// buildFloorPlan creates the level layout.
-func (lvl *level) buildFloorPlan(scene *vu.Ent, hd *hud, plan grid.Grid) {
+// newPlan generates a new floorplan for the given level.
+func newPlan(levelNum int) grid.Grid { return newSeededPlan(levelNum, 0) }
+
+// newSeededPlan generates the same floorplan for the given level each
+// time it is called with the same seed. A zero seed is a random floorplan.
+func newSeededPlan(levelNum int, seed int64) grid.Grid {
+	plan := newGrid(gameThemes[levelNum].gridType())
+	if seed != 0 {
+		plan.Seed(seed)
+	}
+	levelSize := gameMapSize(levelNum)
+	plan.Generate(levelSize, levelSize)
+	return plan
+}
+
+// Floorplan spot types.
+const (
+	centerSpot = iota // Maze center tile.
+	floorSpot         // Open floor tile.
+	wallSpot          // Maze wall.
+)
+
+// planSpot is one laid out floorplan location.
+type planSpot struct {
+	kind   int     // One of centerSpot, floorSpot, or wallSpot.
+	x, y   int     // Grid location.
+	xc, yc float64 // Game location.
+	band   int     // Concentric maze band used to pick textures.
+}
+
+// layoutPlan converts a generated floorplan into the spots needed to build
+// the level and the core drop locations. No models are created so that the
+// layout can be benchmarked and tested without the engine.
+func layoutPlan(plan grid.Grid, units int) (spots []planSpot, drops []gridmath.Spot) {
 	width, height := plan.Size()
+	cx, cy := width/2, height/2
+	if c, ok := plan.(interface {
+		center() (x, y int)
+	}); ok {
+		cx, cy = c.center() // custom mazes can put the center anywhere.
+	}
+	isDrop, limited := func(x, y int) bool { return true }, false
+	if d, ok := plan.(interface {
+		isDrop(x, y int) bool
+	}); ok {
+		isDrop, limited = d.isDrop, true // custom mazes can limit drop spots.
+	}
+	spots = make([]planSpot, 0, width*height)
 	for x := 0; x < width; x++ {
 		for y := 0; y < height; y++ {
-			xc := float64(x * lvl.units)
-			yc := float64(-y * lvl.units)
-			band := plan.Band(x, y) / 3
-			if x == width/2 && y == height/2 {
-				lvl.gcx, lvl.gcy = x, y // remember the maze center location
-				lvl.center = scene.AddPart().SetAt(xc, 0, yc)
-				m := lvl.center.MakeModel("uvra", "msh:tile", "tex:drop1")
-				m.SetAlpha(0.7).SetUniform("spin", 1.0).SetUniform("fd", lvl.fade)
-			} else if plan.IsOpen(x, y) {
-
-				// the floor tiles.
-				tileLabel := lvl.tileLabel(band)
-				tile := scene.AddPart().SetAt(xc, 0, yc)
-				m := tile.MakeModel("uva", "msh:tile", "tex:"+tileLabel)
-				m.SetAlpha(0.7).SetUniform("fd", lvl.fade)
+			spot := planSpot{x: x, y: y, band: plan.Band(x, y) / 3}
+			spot.xc, spot.yc = float64(x*units), float64(-y*units)
+			switch {
+			case x == cx && y == cy:
+				spot.kind = centerSpot
+			case plan.IsOpen(x, y):
+				spot.kind = floorSpot
 
 				// remember the tile locations for drop spots inside the maze.
-				lvl.cc.addDropAt(x, y)
-			} else {
-
-				// draw flat on the y plane with the maze extending into the screen.
-				wm := lvl.wallMeshLabel(band)
-				wt := lvl.wallTextureLabel(band)
-				wall := scene.AddPart().SetAt(xc, 0, yc)
-				m := wall.MakeModel("uva", "msh:"+wm, "tex:"+wt)
-				m.SetUniform("fd", lvl.fade)
-				lvl.walls = append(lvl.walls, wall)
-
-				// add the wall to the minimap
-				hd.addWall(xc, yc)
+				if isDrop(x, y) {
+					drops = append(drops, gridmath.Spot{X: x, Y: y})
+				}
+			default:
+				spot.kind = wallSpot
 			}
+			spots = append(spots, spot)
 		}
 	}
 
 	// add core drop locations around the outside of the maze.
+	if !limited {
+		drops = append(drops, perimeter(width, height)...)
+	}
+	return spots, drops
+}
+
+// perimeter returns the ring of grid locations one cell beyond
+// the edge of a maze with the given size.
+func perimeter(width, height int) (ring []gridmath.Spot) {
 	for x := -1; x < width+1; x++ {
-		lvl.cc.addDropAt(x, -1)
-		lvl.cc.addDropAt(x, height)
+		ring = append(ring, gridmath.Spot{X: x, Y: -1}, gridmath.Spot{X: x, Y: height})
 	}
 	for y := 0; y < height; y++ {
-		lvl.cc.addDropAt(-1, y)
-		lvl.cc.addDropAt(width, y)
+		ring = append(ring, gridmath.Spot{X: -1, Y: y}, gridmath.Spot{X: width, Y: y})
+	}
+	return ring
+}
+
+// buildFloorPlan creates the level layout.
+func (lvl *level) buildFloorPlan(scene *vu.Ent, hd *hud, plan grid.Grid) {
+	spots, drops := layoutPlan(plan, lvl.units)
+	lvl.cracks = map[gridmath.Spot]*crack{}
+	for _, spot := range crackSpots(plan, gameHazard(gameCracks, lvl.num)) {
+		lvl.cracks[spot] = &crack{}
+	}
+	for _, spot := range spots {
+		switch spot.kind {
+		case centerSpot:
+			lvl.gcx, lvl.gcy = spot.x, spot.y // remember the maze center location
+			lvl.center = scene.AddPart().SetAt(spot.xc, 0, spot.yc)
+			m := lvl.center.MakeModel("uvra", "msh:tile", "tex:drop1")
+			trackAsset(m, "tex:drop1")
+			m.SetAlpha(0.7).SetUniform("spin", 1.0).SetUniform("fd", lvl.fade)
+		case floorSpot:
+
+			// the floor tiles.
+			tileLabel := lvl.theme.tile(spot.band)
+			tile := scene.AddPart().SetAt(spot.xc, 0, spot.yc)
+			m := tile.MakeModel("uva", "msh:tile", "tex:"+tileLabel)
+			trackAsset(m, "tex:"+tileLabel)
+			m.SetAlpha(0.7).SetUniform("fd", lvl.fade)
+		case wallSpot:
+
+			// draw flat on the y plane with the maze extending into the screen.
+			wm := lvl.theme.wallMesh(spot.band)
+			wt := lvl.theme.wallTexture(spot.band)
+			wall := scene.AddPart().SetAt(spot.xc, 0, spot.yc)
+			if c, ok := lvl.cracks[gridmath.Spot{X: spot.x, Y: spot.y}]; ok {
+				wt, c.wall = "crack", wall
+			}
+			m := wall.MakeModel("uva", "msh:"+wm, "tex:"+wt)
+			trackAsset(m, "tex:"+wt)
+			m.SetUniform("fd", lvl.fade)
+			lvl.walls = append(lvl.walls, wall)
+
+			// add the wall to the minimap
+			hd.addWall(spot.xc, spot.yc)
+		}
+	}
+	for _, drop := range drops {
+		lvl.cc.addDropAt(drop.X, drop.Y)
 	}
+	lvl.buildBoundary(scene, hd, plan)
 }
 
+// buildBoundary marks the maze boundary with a faint ring of floor tiles
+// one cell beyond the maze edge and outlines it on the minimap. The ring
+// has no physics bodies so the player can still walk across it.
+func (lvl *level) buildBoundary(scene *vu.Ent, hd *hud, plan grid.Grid) {
+	width, height := plan.Size()
+	tileLabel := lvl.theme.tile(0)
+	for _, spot := range perimeter(width, height) {
+		gamex, gamez := gridmath.ToGame(spot.X, spot.Y, float64(lvl.units))
+		tile := scene.AddPart().SetAt(gamex, 0, gamez)
+		m := tile.MakeModel("uva", "msh:tile", "tex:"+tileLabel)
+		trackAsset(m, "tex:"+tileLabel)
+		m.SetAlpha(boundaryAlpha).SetUniform("fd", lvl.fade)
+	}
+	half := float64(lvl.units) * 0.5
+	minx, maxz := gridmath.ToGame(-1, -1, float64(lvl.units))
+	maxx, minz := gridmath.ToGame(width, height, float64(lvl.units))
+	hd.addBoundary(minx-half, minz-half, maxx+half, maxz+half)
+}
+
+// boundaryAlpha keeps the maze boundary fainter than the maze floor.
+const boundaryAlpha = 0.2
+
 // makePlayer: the player is the camera... the player-trooper is used by the hud
 // to show player status and as such this trooper is part of the hud scene.
 func (lvl *level) makePlayer(pov *vu.Ent, levelNum int) *trooper {
@@ -271,116 +542,445 @@ func (lvl *level) makePlayer(pov *vu.Ent, levelNum int) *trooper {
 	player.part.Spin(15, 0, 0)
 	player.part.Spin(0, 15, 0)
 	player.setScale(100)
+	player.simplify = true
 	return player
 }
 
 // makeSentries creates some AI sentinels.
-func (lvl *level) makeSentries(scene *vu.Ent, levelNum int) {
+func (lvl *level) makeSentries(scene *vu.Ent, levelNum, numSentinels int) {
 	sentinels := []*sentinel{}
-	numSentinels := gameMuster[levelNum]
 	for cnt := 0; cnt < numSentinels; cnt++ {
-		sentry := newSentinel(scene.AddPart(), levelNum, lvl.units, lvl.fade)
+		sentry := newSentinel(scene.AddPart(), levelNum, lvl.units, lvl.fade, lvl.moves)
 		sentry.setScale(0.25)
+		sentry.setActive(false)
 		sentinels = append(sentinels, sentry)
+		lvl.enemies = append(lvl.enemies, sentry)
 	}
 	lvl.sentries = sentinels
 }
 
-// moveSentinels updates the sentinels locations by moving them a bit
-// forward along their paths.
-func (lvl *level) moveSentinels() {
+// moveEnemies updates the enemy locations by moving them a bit
+// forward along their paths. Enemies well beyond the visible distance
+// are moved less often.
+func (lvl *level) moveEnemies() {
+	if lvl.farCheck--; lvl.farCheck <= 0 || len(lvl.far) != len(lvl.enemies) {
+		lvl.markFar()
+	}
+	for index, foe := range lvl.enemies {
+		if foe.isActive() {
+			foe.move(lvl.guards, lvl.far[index])
+		}
+	}
+}
+
+// markFar finds the enemies well beyond the visible distance. The check
+// is only needed as often as far enemies are moved.
+func (lvl *level) markFar() {
+	lvl.farCheck = farTicks
+	if len(lvl.far) != len(lvl.enemies) {
+		lvl.far = make([]bool, len(lvl.enemies))
+	}
+	x, _, z := lvl.cam.At()
+	farSq := farFade * lvl.fade * farFade * lvl.fade
+	for index, foe := range lvl.enemies {
+		sx, _, sz := foe.location()
+		lvl.far[index] = (sx-x)*(sx-x)+(sz-z)*(sz-z) > farSq
+	}
+}
+
+// simulate moves the active sentinels of a level that is not being
+// played as if the given number of ticks had passed.
+func (lvl *level) simulate(ticks int) {
 	for _, sentry := range lvl.sentries {
-		sentry.move(lvl.plan)
+		if sentry.active {
+			sentry.skip(lvl.guards, ticks)
+		}
 	}
 }
 
-// collideSentinels checks if the player collided with a sentinel.
+// farFade is the multiple of the fade distance beyond which
+// enemies are moved less often.
+const farFade = 2.0
+
+// collideEnemies checks if the player collided with an enemy.
 // The check is grid based, not physics based.
-func (lvl *level) collideSentinels() {
-	if lvl.player.cloaked {
+func (lvl *level) collideEnemies() {
+	if lvl.player.cloaked || lvl.player.grace > 0 {
 		return // player is immume from sentries.
 	}
 	x, y, z := lvl.cam.At()
-	pgx, pgy := toGrid(x, y, z, float64(lvl.units))
-	for _, sentry := range lvl.sentries {
-		sx, sy, sz := sentry.location()
-		sgx, sgy := toGrid(sx, sy, sz, float64(lvl.units))
-		if pgx == sgx && pgy == sgy {
+	pgx, pgy := gridmath.ToGrid(x, y, z, float64(lvl.units))
+	for _, foe := range lvl.enemies {
+		if !foe.isActive() {
+			continue
+		}
+		sx, sy, sz := foe.location()
+		sgx, sgy := gridmath.ToGrid(sx, sy, sz, float64(lvl.units))
+		if pgx == sgx && pgy == sgy && foe.onPlayerCollide(lvl) {
 			lvl.player.play(collideSound)
+			lvl.mp.haptics.play(collideRumble)
+			lvl.combo.hit()
+			lvl.hits++
 
-			// teleport the sentinel to the outside of the maze so that the
-			// collision doesn't happen again.
-			safex, safey := lvl.plan.Size() // top right corner.
-			sentry.setGridAt(safex, safey)
-			if pgx == safex && pgy == safey {
-				sentry.setGridAt(-1, -1) // bottom left corner.
+			// remove health from the player and show the energy loss animation.
+			// Practice hits are free.
+			if !lvl.practice {
+				lvl.loseCells(lvl.collisionLoss())
 			}
+		}
+	}
+}
 
-			// remove health from the player and show the energy loss animation.
-			lvl.player.detachCores(gameCellLoss[lvl.num])
-			lvl.mp.ani.addAnimation(lvl.newEnergyLossAnimation())
+// loseCells takes cells from the player and shows the energy loss
+// effect, scaled by the share of health lost. Hits that leave the
+// player below the warning health start the low health vignette.
+func (lvl *level) loseCells(cells int) {
+	lost := lvl.player.detachCores(cells)
+	lvl.hd.showLoss(cells)
+	lvl.mp.ani.addAnimation(lvl.newEnergyLossAnimation(lost))
+	if health, warn, _ := lvl.player.health(); health < warn {
+		lvl.hd.startLinger()
+	}
+}
+
+// collisionLoss returns the cells lost for a sentinel collision. The first
+// collision on a forgiving level costs less and reminds the player that
+// cloaking hides them from the sentinels.
+func (lvl *level) collisionLoss() int {
+	if lvl.forgiven || gameForgive[lvl.num] <= 0 {
+		return gameLoss(lvl.num)
+	}
+	lvl.forgiven = true
+	hint := "Cloak to slip past sentinels"
+	if len(lvl.mp.keys) > 4 {
+		if sym := vu.Symbol(lvl.mp.keys[4]); sym > 0 {
+			hint = "Press " + string(sym) + " to cloak and slip past sentinels"
+		}
+	}
+	lvl.hd.showBanner(hint)
+	return gameForgive[lvl.num]
+}
+
+// describeLoss returns the cells lost for each sentinel collision
+// on this level, noting when the next collision costs less.
+func (lvl *level) describeLoss() string {
+	loss := gameLoss(lvl.num)
+	text := "Sentinel hits cost " + strconv.Itoa(loss) + " cells"
+	if loss == 1 {
+		text = "Sentinel hits cost 1 cell"
+	}
+	if !lvl.forgiven && gameForgive[lvl.num] > 0 {
+		text += ", " + strconv.Itoa(gameForgive[lvl.num]) + " for the first hit"
+	}
+	return text
+}
+
+// describeOverview summarizes the level so that the player can plan
+// while paused: the cores still needed to evolve, the cores on the
+// ground, the sentinels released so far, and the maze size.
+func (lvl *level) describeOverview() string {
+	health, _, max := lvl.player.health()
+	needed := max - health
+	if needed < 0 {
+		needed = 0
+	}
+	active := 0
+	for _, sentry := range lvl.sentries {
+		if sentry.active {
+			active++
 		}
 	}
+	w, h := lvl.plan.Size()
+	return fmt.Sprintf("%d cores needed, %d on the ground, %d of %d sentinels, %dx%d maze",
+		needed, len(lvl.cc.cores), active, len(lvl.sentries), w, h)
 }
 
+// knockback pushes the player away from the given enemy. Enemies ignore
+// the player for a short time afterwards so that they can continue along
+// their paths, see sentinel.onPlayerCollide.
+func (lvl *level) knockback(foe enemy) {
+	body := lvl.body.Body()
+	if body == nil {
+		return
+	}
+	x, _, z := lvl.body.At()
+	sx, _, sz := foe.location()
+	away := &lin.V3{X: x - sx, Y: 0, Z: z - sz}
+	if lin.AeqZ(away.Len()) {
+		away.X, away.Y, away.Z = lin.MultSQ(0, 0, 1, lvl.cam.Look) // straight back.
+	}
+	if lvl.mp.opts[kinematicOption] {
+		away.Unit().Scale(away, knockbackSlide)
+		lvl.slideBy(away.X, away.Z, math.MaxFloat64)
+		return
+	}
+	away.Unit().Scale(away, knockbackPush)
+	body.Stop()
+	body.Rest()
+	body.Push(away.X, 0, away.Z)
+}
+
+// Sentinel knockback tuning.
+const (
+	knockbackTicks = 75  // Ticks the player and sentinel ignore each other.
+	knockbackPush  = 8.0 // Speed the player is pushed away.
+)
+
 // fetchCores picks up any nearby free cores if the core is in the
 // same grid element as the player. No need to check for actual collision.
 func (lvl *level) fetchCores() {
 	px, _, pz := lvl.cam.At()
-	coreIndex := lvl.cc.hitCore(px, pz)
+	coreIndex := lvl.cc.hitCore(px, pz, lvl.pickupReach())
 
 	// attach the core to the player.
 	health, _, max := lvl.player.health()
 	if coreIndex >= 0 && health != max && !lvl.player.cloaked {
-		lvl.player.play(fetchSound)
+		gain := lvl.coreGain()
+		lvl.player.play(fetchSounds[fetchVariant(gain, len(fetchSounds))])
+		lvl.mp.haptics.play(fetchRumble)
 		gamex, gamez := lvl.cc.remCore(coreIndex)
 		lvl.hd.remCore(gamex, gamez)
-		for cnt := 0; cnt < gameCellGain[lvl.num]; cnt++ {
-			lvl.player.attach()
+		lvl.gainCore()
+		lvl.hd.fetchedCore(gain)
+		lvl.fetched++
+		lvl.combo.fetch()
+	}
+}
+
+// fetchVariant picks one of the given number of core pickup sound
+// variants for the cells gained. Larger gains sound deeper.
+func fetchVariant(gain, variants int) int {
+	index := 0
+	for step := 1; step < gain && index < variants-1; step *= 4 {
+		index++
+	}
+	return index
+}
+
+// coreGain is the number of cells gained for each core.
+func (lvl *level) coreGain() int { return gameGain(lvl.num) + lvl.player.gain }
+
+// gainCore adds the cells from one core to the player.
+func (lvl *level) gainCore() {
+	for cnt := 0; cnt < lvl.coreGain(); cnt++ {
+		lvl.player.attach()
+	}
+
+	// add more cloaking energy each time a core is picked up.
+	lvl.player.addCloakEnergy()
+}
+
+// showPartner shows the co-op partner at the given game location.
+// Cloaked partners are faint.
+func (lvl *level) showPartner(x, z float64, cloaked, visible bool) {
+	lvl.partner.Cull(!visible)
+	lvl.hd.showPartner(x, z, visible)
+	if visible {
+		alpha := 0.5
+		if cloaked {
+			alpha = 0.15
 		}
+		lvl.partner.SetAt(x, 0.5, z).SetAlpha(alpha)
+	}
+}
 
-		// add more cloaking energy each time a core is picked up.
-		lvl.player.addCloakEnergy()
+// expireCores removes cores that have been lying around too long when
+// the optional core lifetime is turned on.
+func (lvl *level) expireCores() {
+	if !lvl.mp.opts[coreExpiryOption] {
+		return
+	}
+	if coreIndex := lvl.cc.expired(gameCoreLife[lvl.num] * gameTickRate); coreIndex >= 0 {
+		gamex, gamez := lvl.cc.expireCore(coreIndex)
+		lvl.hd.remCore(gamex, gamez)
 	}
 }
 
 // createCore creates a core if necessary. The core is dropped onto
-// an empty floor tile.
+// an empty floor tile. How often cores are dropped, and where, depends
+// on the level pacing.
 func (lvl *level) createCore() {
-	if !lvl.cc.timeToDrop() {
-		return
-	}
 	health, _, max := lvl.player.health()
 	energyNeeded := max - health
-	coresNeeded := energyNeeded / gameCellGain[lvl.num]
+	coresNeeded := energyNeeded / gameGain(lvl.num)
+	px, _, pz := lvl.cam.At()
+	gx, gy := gridmath.ToGrid(px, 0, pz, float64(lvl.units))
+	half := float64(gameMapSize(lvl.num) / 2)
+	reach := math.Hypot(float64(gx-lvl.gcx), float64(gy-lvl.gcy)) / half
+	missing := coresNeeded - len(lvl.cc.cores)
+	if missing < 0 {
+		missing = 0
+	}
+	pacing := gamePacing[lvl.num]
+	if !lvl.cc.timeToDrop(dropDelay(pacing, missing, reach)) {
+		return
+	}
 	if lvl.cc.canDrop(coresNeeded) {
-		gridx, gridy := lvl.cc.dropSpot()
+		gridx, gridy := lvl.cc.dropSpot(gx, gy, pacing.Away)
 		gamex, gamez := lvl.cc.dropCore(lvl.scene.AddPart(), lvl.fade, gridx, gridy)
 		lvl.hd.addCore(gamex, gamez)
+		lvl.mp.ani.addAnimation(lvl.hd.newPingAnimation(gamex, gamez))
+		lvl.cc.dropFreeze(lvl.scene, lvl.fade, gameFreezeChance[lvl.num])
+	}
+}
+
+// restoreCores drops cores back onto the grid locations where they were
+// left the last time the level was played. Locations that are no longer
+// open floor are skipped.
+func (lvl *level) restoreCores(spots []gridmath.Spot) {
+	for _, spot := range spots {
+		if lvl.cc.isTile(spot.X, spot.Y) {
+			gamex, gamez := lvl.cc.dropCore(lvl.scene.AddPart(), lvl.fade, spot.X, spot.Y)
+			lvl.hd.addCore(gamex, gamez)
+		}
+	}
+}
+
+// fetchFreeze picks up the freeze pickup if the player is in the same grid
+// element. All the sentinels stop moving until the freeze wears off.
+func (lvl *level) fetchFreeze() {
+	px, _, pz := lvl.cam.At()
+	if lvl.cc.hitFreeze(px, pz) {
+		lvl.cc.remFreeze()
+		lvl.player.play(freezeSound)
+		lvl.frozen = freezeTicks
+		lvl.freezeSentinels(true)
+	}
+	if lvl.frozen > 0 {
+		lvl.frozen--
+		lvl.hd.showFreeze((lvl.frozen + 49) / 50)
+		if lvl.frozen == 0 {
+			lvl.freezeSentinels(false)
+		}
+	}
+}
+
+// freezeTicks is how long, in game ticks, the sentinels stay frozen.
+const freezeTicks = 250
+
+// freezeSentinels stops or restarts all the sentinels.
+func (lvl *level) freezeSentinels(frozen bool) {
+	for _, sentry := range lvl.sentries {
+		sentry.setFrozen(frozen)
 	}
 }
 
 // teleport puts the player back to the starting location, safe from
 // any sentinels. The up/down and view direction are also reset to
 // their original values in case the player has lost sight of the maze.
-func (lvl *level) teleport() {
-	if lvl.player.teleport() {
+// Return true if the player teleported.
+func (lvl *level) teleport() bool {
+	lvl.previewTeleport(false)
+	if lvl.interdicted() {
+		return false // the teleport icon already shows that teleporting is blocked.
+	}
+	if lvl.practice {
+		lvl.player.chargeTeleport() // practice teleports are free.
+	}
+	if gameTeleport() && lvl.player.teleport() {
+		lvl.stunSentinels()
+		x, y, z := teleportSpot()
+		lvl.hitCracks()
 		lvl.body.DisposeBody()
-		lvl.body.SetAt(0, 0.5, 10)
+		lvl.body.SetAt(x, y, z)
 		lvl.body.SetView(lin.QI)
-		lvl.cam.SetAt(0, 0.5, 10)
+		lvl.cam.SetAt(x, y, z)
 		lvl.body.MakeBody(vu.Sphere(0.25))
 		lvl.body.SetSolid(1, 0)
 		lvl.mp.ani.addAnimation(lvl.newTeleportAnimation())
+		lvl.mp.haptics.play(teleportRumble)
+		return true
+	}
+	return false
+}
+
+// Teleport stun tuning.
+const (
+	stunReach = 2   // Grid distance from the player that teleporting stuns sentinels.
+	stunTicks = 100 // Game ticks that stunned sentinels stay in place.
+)
+
+// stunSentinels stuns the sentinels close to the player as the player
+// teleports away, rewarding a last moment escape.
+func (lvl *level) stunSentinels() {
+	x, y, z := lvl.body.At()
+	pgx, pgy := gridmath.ToGrid(x, y, z, float64(lvl.units))
+	for _, sentry := range lvl.sentries {
+		if sentry.active {
+			sx, sy, sz := sentry.location()
+			sgx, sgy := gridmath.ToGrid(sx, sy, sz, float64(lvl.units))
+			if abs(sgx-pgx) <= stunReach && abs(sgy-pgy) <= stunReach {
+				sentry.stunFor(stunTicks)
+			}
+		}
 	}
 }
 
+// startSpot is the game location where players start each level.
+func startSpot() (x, y, z float64) { return 4, 0.5, 10 }
+
+// teleportSpot is the game location where teleporting players arrive.
+func teleportSpot() (x, y, z float64) { return 0, 0.5, 10 }
+
+// newGhost creates the marker that previews the teleport destination.
+func (lvl *level) newGhost(ghost *vu.Ent) *vu.Ent {
+	ghost.SetAt(teleportSpot()).SetScale(0.25, 0.25, 0.25)
+	m := ghost.MakeModel("flata", "msh:cube", "mat:tgreen")
+	m.SetUniform("fd", lvl.fade)
+	ghost.Cull(true)
+	return ghost
+}
+
+// newReach creates the faint floor ring showing how close the player
+// needs to be to collect a core.
+func (lvl *level) newReach(reach *vu.Ent) *vu.Ent {
+	m := reach.MakeModel("uvra", "msh:tile", "tex:reach")
+	trackAsset(m, "tex:reach")
+	m.SetAlpha(0.4).SetUniform("spin", 0).SetUniform("fd", lvl.fade)
+	reach.Cull(true)
+	return reach
+}
+
+// pickupReach is the distance from the player, in game units, at which
+// cores are collected. Cores in the same grid spot are always collected.
+func (lvl *level) pickupReach() float64 {
+	return float64(lvl.units)*0.5 + lvl.player.reach
+}
+
+// showReach keeps the optional reach ring under the player. The ring
+// is hidden while cloaked since cloaked players can't collect cores.
+func (lvl *level) showReach() {
+	on := lvl.mp.opts[reachOption] && !lvl.player.cloaked
+	lvl.reach.Cull(!on)
+	if on {
+		x, _, z := lvl.body.At()
+		r := lvl.pickupReach()
+		lvl.reach.SetAt(x, voidLift, z).SetScale(r, 1, r)
+	}
+}
+
+// previewTeleport shows or hides the teleport destination in the
+// level and on the minimap. Nothing is shown when teleporting
+// is not possible.
+func (lvl *level) previewTeleport(on bool) {
+	on = on && gameTeleport() && !lvl.interdicted()
+	lvl.ghost.Cull(!on)
+	lvl.hd.previewTeleport(on)
+}
+
 // cloak toggles player cloaking. Cloaking only enables if there is
 // sufficient cloaking energy.
 func (lvl *level) cloak() {
 	lvl.player.cloak(!lvl.player.cloaked)
 }
 
+// setCloak turns the players cloak on or off.
+func (lvl *level) setCloak(on bool) {
+	if on != lvl.player.cloaked {
+		lvl.player.cloak(on)
+	}
+}
+
 // debugCloak is a debug only method that greatly expands the cloaking time.
 func (lvl *level) debugCloak() {
 	lvl.player.cloakEnergy += lvl.player.cemax * 10
@@ -439,15 +1039,21 @@ func (ta *teleportAnimation) Wrap() {
 // ===========================================================================
 // energyLossAnimation
 
-func (lvl *level) newEnergyLossAnimation() animation {
-	return &energyLossAnimation{hd: lvl.hd, ticks: 25}
+// newEnergyLossAnimation creates the energy loss flash for the given
+// share of health lost, see lossEffect.
+func (lvl *level) newEnergyLossAnimation(lost float64) animation {
+	peak, ticks, shake := lossEffect(lost)
+	return &energyLossAnimation{hd: lvl.hd, peak: peak, ticks: ticks, shake: shake}
 }
 
 // energyLossAnimation shows a brief flash to indicate a player has been hit
-// by a sentry and has lost some energy.
+// by a sentry and has lost some energy. Larger losses flash brighter and
+// longer, and shake the screen.
 type energyLossAnimation struct {
 	hd    *hud    // needed to access energy loss effect.
 	fade  float64 // quick fade the teleport effect.
+	peak  float64 // starting flash alpha.
+	shake float64 // starting screen shake in pixels.
 	ticks int     // animation run rate - number of animation steps.
 	tkcnt int     // current step
 	state int     // track progress 0:start, 1:run, 2:done.
@@ -458,13 +1064,15 @@ func (ea *energyLossAnimation) Animate(dt float64) bool {
 	switch ea.state {
 	case 0:
 		ea.hd.energyLossActive(true)
-		ea.fade = 1
+		ea.fade = ea.peak
 		ea.hd.energyLossFade(ea.fade)
 		ea.state = 1
 		return true
 	case 1:
-		ea.fade -= 1 / float64(ea.ticks)
+		ea.fade -= ea.peak / float64(ea.ticks)
 		ea.hd.energyLossFade(ea.fade)
+		amount := ea.shake * float64(ea.ticks-ea.tkcnt) / float64(ea.ticks)
+		ea.hd.shake(amount*math.Sin(float64(ea.tkcnt)*2.3), amount*math.Cos(float64(ea.tkcnt)*3.1))
 		if ea.tkcnt >= ea.ticks {
 			ea.Wrap()
 			return false // animation done.
@@ -481,5 +1089,6 @@ func (ea *energyLossAnimation) Wrap() {
 	ea.fade = 0.5
 	ea.hd.energyLossFade(ea.fade)
 	ea.hd.energyLossActive(false)
+	ea.hd.shake(0, 0)
 	ea.state = 2
 }
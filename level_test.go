@@ -0,0 +1,418 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/gazed/bampf/gridmath"
+	"github.com/gazed/vu/grid"
+)
+
+// testPlan returns a floorplan made from the given maze rows,
+// using the custom maze characters. Fixtures that are not valid
+// custom mazes fail the test.
+func testPlan(t testing.TB, rows ...string) *customPlan {
+	t.Helper()
+	plan := newCustomPlan("test", rows)
+	if plan == nil {
+		t.Fatalf("Expected a valid test maze of at least %dx%d, got %v", minLevelSize, minLevelSize, rows)
+	}
+	return plan
+}
+
+// loopPlan is a corridor loop around the maze center
+// with a single entrance to the center.
+func loopPlan(t testing.TB) *customPlan {
+	return testPlan(t,
+		"#######",
+		"#.....#",
+		"#.###.#",
+		"#.#@..#",
+		"#.###.#",
+		"#.....#",
+		"#######",
+	)
+}
+
+// Level benchmarks exercise the engine independent parts of level
+// creation and the per-tick level update. Run with:
+//     go test -run none -bench .
+
+func BenchmarkNewPlan(b *testing.B) {
+	for cnt := 0; cnt < b.N; cnt++ {
+		newPlan(len(gameMuster) - 1)
+	}
+}
+
+func BenchmarkLayoutPlan(b *testing.B) {
+	plan := newPlan(len(gameMuster) - 1)
+	b.ResetTimer()
+	for cnt := 0; cnt < b.N; cnt++ {
+		layoutPlan(plan, 2)
+	}
+}
+
+// BenchmarkSentinelTick is the per-tick sentinel movement cost, including
+// finding each sentinels grid spot, for the largest level using sentinel
+// stubs without models. Collisions need the engine and are not included.
+func BenchmarkSentinelTick(b *testing.B) {
+	lvl := len(gameMuster) - 1
+	units := 2.0
+	plan := newPlan(lvl)
+	w, h := plan.Size()
+	sentries := make([]*sentinel, gameMuster[lvl])
+	locs := make([][2]float64, len(sentries))
+	for cnt := range sentries {
+		s := &sentinel{units: units, speed: sentinelSpeed, moves: rand.New(rand.NewSource(1))}
+		s.prev, s.next = &gridmath.Spot{X: w / 2, Y: h / 2}, &gridmath.Spot{X: w / 2, Y: h / 2}
+		sentries[cnt] = s
+		locs[cnt] = [2]float64{float64(w / 2), float64(h / 2)}
+	}
+	b.ResetTimer()
+	for cnt := 0; cnt < b.N; cnt++ {
+		for index, s := range sentries {
+			fx, fy := s.advance(locs[index][0], locs[index][1], plan, 1)
+			locs[index][0], locs[index][1] = fx, fy
+			gridmath.ToGrid(fx*units, 0.5, -fy*units, units)
+		}
+	}
+}
+
+func TestLayoutPlan(t *testing.T) {
+	plan := newPlan(0)
+	w, h := plan.Size()
+	spots, drops := layoutPlan(plan, 2)
+	if len(spots) != w*h {
+		t.Errorf("Expected %d spots got %d", w*h, len(spots))
+	}
+	centers, floors := 0, 0
+	for _, spot := range spots {
+		switch spot.kind {
+		case centerSpot:
+			centers++
+		case floorSpot:
+			floors++
+		}
+	}
+	border := 2*(w+2) + 2*h
+	if centers != 1 || len(drops) != floors+border {
+		t.Errorf("Expected 1 center and %d drops got %d, %d", floors+border, centers, len(drops))
+	}
+}
+
+func TestLevelMap(t *testing.T) {
+	plan := newSeededPlan(0, 1)
+	w, h := plan.Size()
+	cores := []gridmath.Spot{{X: -1, Y: -1}}
+	pic := levelMap(plan, cores, gridmath.Spot{X: 2, Y: -5})
+	if b := pic.Bounds(); b.Dx() != (w+2)*mapScale || b.Dy() != (h+6)*mapScale {
+		t.Errorf("expected map to include the start, got %v", b)
+	}
+	if got := pic.RGBAAt(mapScale/2, 4*mapScale+mapScale/2); got != mapCore {
+		t.Errorf("expected core colour at the corner, got %v", got)
+	}
+	if got := pic.RGBAAt(3*mapScale+mapScale/2, mapScale/2); got != mapStart {
+		t.Errorf("expected start colour, got %v", got)
+	}
+}
+
+func TestLevelThumb(t *testing.T) {
+	a, b := levelThumb(2), levelThumb(2)
+	if a.Bounds() != b.Bounds() || string(a.Pix) != string(b.Pix) {
+		t.Errorf("Expected the same thumbnail each time")
+	}
+}
+
+func TestSlide(t *testing.T) {
+	plan := newSeededPlan(0, 1)
+	w, _ := plan.Size()
+	units := 2.0
+	wx := 0
+	for wx < w && plan.IsOpen(wx, 0) {
+		wx++
+	}
+	if wx == w {
+		t.Skip("no wall on the maze edge")
+	}
+
+	// move from outside the maze into a wall on the edge and along it.
+	x, z := gridmath.ToGame(wx, -2, units)
+	if nx, nz := slide(plan, units, x, z, 0.5, -3.5); nx != x+0.5 || nz != z {
+		t.Errorf("expected to slide along the wall, got %f %f from %f %f", nx, nz, x, z)
+	}
+	if nx, nz := slide(plan, units, x, z, 0.5, 1); nx != x+0.5 || nz != z+1 {
+		t.Errorf("expected an open move, got %f %f", nx, nz)
+	}
+}
+
+func TestDeflect(t *testing.T) {
+	plan := newSeededPlan(0, 1)
+	w, _ := plan.Size()
+	units := 2.0
+	wx := 0
+	for wx < w && plan.IsOpen(wx, 0) {
+		wx++
+	}
+	if wx == w {
+		t.Skip("no wall on the maze edge")
+	}
+
+	// just outside a wall on the maze edge.
+	x, _ := gridmath.ToGame(wx, 0, units)
+	z := units*0.5 + kinematicRadius + 0.1
+	if dx, dz := deflect(plan, units, x, z, 0.5, -0.5); dx != 0.5 || dz != 0 {
+		t.Errorf("expected to turn along the wall, got %f %f", dx, dz)
+	}
+	if dx, dz := deflect(plan, units, x, z, 0.5, 0.5); dx != 0.5 || dz != 0.5 {
+		t.Errorf("expected an open move to be unchanged, got %f %f", dx, dz)
+	}
+}
+
+func TestBraidPlan(t *testing.T) {
+	maze := grid.New(grid.PrimMaze)
+	maze.Seed(7)
+	maze.Generate(21, 21)
+	braid := newGrid(braidMaze)
+	braid.Seed(7)
+	braid.Generate(21, 21)
+	mazeEnds, braidEnds := deadEnds(maze, 1000, 0, nil), deadEnds(braid, 1000, 0, nil)
+	if len(braidEnds) == 0 || len(braidEnds) >= len(mazeEnds) {
+		t.Errorf("Expected some of the %d dead-ends to be opened, got %d", len(mazeEnds), len(braidEnds))
+	}
+	w, h := braid.Size()
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			edge := x == 0 || y == 0 || x == w-1 || y == h-1
+			if (maze.IsOpen(x, y) && !braid.IsOpen(x, y)) || (edge && braid.IsOpen(x, y)) {
+				t.Fatalf("Expected braiding to only open inner walls, see %d,%d", x, y)
+			}
+		}
+	}
+}
+
+func TestCrackSpots(t *testing.T) {
+	plan := testPlan(t,
+		"#######",
+		"#..@..#",
+		"###.###",
+		"#.#.#.#",
+		"#.....#",
+		"#.###.#",
+		"#######",
+	)
+	spots := crackSpots(plan, 10)
+	if len(spots) != 2 {
+		t.Fatalf("Expected 2 cracked walls, got %v", spots)
+	}
+	for _, spot := range spots {
+		if plan.IsOpen(spot.X, spot.Y) {
+			t.Errorf("Expected cracked wall at %v to be a wall", spot)
+		}
+	}
+	cracked := &crackedPlan{Grid: plan, broken: map[gridmath.Spot]bool{spots[0]: true}}
+	if !cracked.IsOpen(spots[0].X, spots[0].Y) || cracked.IsOpen(0, 0) {
+		t.Errorf("Expected only the broken wall to open")
+	}
+}
+
+func TestGateSpots(t *testing.T) {
+	plan := loopPlan(t)
+	gates := gateSpots(plan, 1, 3, 3)
+	if len(gates) != 1 {
+		t.Fatalf("Expected 1 gate, got %d", len(gates))
+	}
+	g := gates[0]
+	dist := centerDistances(plan, 3, 3)
+	ax, ay := g.spot.X-g.dx, g.spot.Y-g.dy // entry side.
+	bx, by := g.spot.X+g.dx, g.spot.Y+g.dy // exit side.
+	if dist[bx][by] >= dist[ax][ay] {
+		t.Errorf("Expected gate at %v to lead towards the center", g.spot)
+	}
+	guards := &gatedPlan{Grid: plan, gates: map[gridmath.Spot]*gate{g.spot: g}}
+	if guards.IsOpen(g.spot.X, g.spot.Y) || !guards.IsOpen(3, 3) {
+		t.Errorf("Expected sentinels to see only the gate as a wall")
+	}
+}
+
+func TestVoidSpots(t *testing.T) {
+	plan := testPlan(t,
+		"#########",
+		"#.......#",
+		"#.#####.#",
+		"#.#...#.#",
+		"#.#.@.#.#",
+		"#.#...#.#",
+		"#.###.#.#",
+		"#.....#.#",
+		"#########",
+	)
+	spots := deadEnds(plan, 5, voidClear, []gridmath.Spot{{X: 4, Y: 4}})
+	if len(spots) != 1 {
+		t.Fatalf("Expected 1 void, got %v", spots)
+	}
+	if x, y := spots[0].X, spots[0].Y; !plan.IsOpen(x, y) || abs(x-4)+abs(y-4) < voidClear {
+		t.Errorf("Expected void at %v to be floor away from the center", spots[0])
+	}
+}
+
+func TestInterdictSpots(t *testing.T) {
+	plan := newSeededPlan(3, 1)
+	w, h := plan.Size()
+	cx, cy := w/2, h/2
+	if spots := interdictSpots(plan, 0, cx, cy); len(spots) != 0 {
+		t.Errorf("Expected no spots for no radius, got %v", spots)
+	}
+	spots := interdictSpots(plan, 2, cx, cy)
+	if len(spots) == 0 {
+		t.Fatalf("Expected spots around the center")
+	}
+	for _, spot := range spots {
+		if !plan.IsOpen(spot.X, spot.Y) || abs(spot.X-cx) > 2 || abs(spot.Y-cy) > 2 || spot == (gridmath.Spot{X: cx, Y: cy}) {
+			t.Errorf("Unexpected interdiction spot %v", spot)
+		}
+	}
+}
+
+func TestFetchVariant(t *testing.T) {
+	for gain, want := range map[int]int{1: 0, 2: 1, 4: 1, 8: 2, 64: 2} {
+		if got := fetchVariant(gain, 3); got != want {
+			t.Errorf("Expected variant %d for gain %d, got %d", want, gain, got)
+		}
+	}
+	if got := fetchVariant(8, 1); got != 0 {
+		t.Errorf("Expected the only variant, got %d", got)
+	}
+}
+
+func TestSpawnSafety(t *testing.T) {
+	sp := newSpawner(newSeededPlan(0, 1), 1, 10)
+	first := sp.points[0]
+	if !sp.skipUnsafe(first.X+spawnSafety, first.Y) || sp.next != 0 {
+		t.Errorf("Expected a distant player to keep the first spawn point")
+	}
+	if !sp.skipUnsafe(first.X, first.Y) || sp.next != 1 {
+		t.Errorf("Expected the spawn point next to the player to be skipped")
+	}
+	sp.points, sp.next = []gridmath.Spot{first}, 0
+	if sp.skipUnsafe(first.X+1, first.Y-1) {
+		t.Errorf("Expected no safe spawn point")
+	}
+}
+
+func TestPropSpots(t *testing.T) {
+	plan := testPlan(t,
+		"###########",
+		"#.........#",
+		"#.........#",
+		"#.........#",
+		"#.........#",
+		"#....@....#",
+		"#.........#",
+		"#.........#",
+		"#.........#",
+		"#.........#",
+		"###########",
+	)
+	keep := []gridmath.Spot{{X: 5, Y: 5}}
+	spots := propSpots(plan, keep)
+	if len(spots) != 4 {
+		t.Fatalf("Expected 4 props, got %v", spots)
+	}
+	for _, spot := range spots {
+		if !roomInterior(plan, spot.X, spot.Y) || !propClearOf(spot.X, spot.Y, keep) {
+			t.Errorf("Expected prop at %v to be inside the room away from the center", spot)
+		}
+	}
+	corridors := testPlan(t,
+		"#######",
+		"#.....#",
+		"#.###.#",
+		"#.#@..#",
+		"#.###.#",
+		"#.....#",
+		"#######",
+	)
+	if spots := propSpots(corridors, nil); len(spots) != 0 {
+		t.Errorf("Expected no props in corridors, got %v", spots)
+	}
+}
+
+func TestCenterPath(t *testing.T) {
+	plan := loopPlan(t)
+	path := centerPath(plan, 3, 3, 1, 1)
+	if len(path) != centerDistances(plan, 3, 3)[1][1] || path[len(path)-1] != (gridmath.Spot{X: 3, Y: 3}) {
+		t.Fatalf("Expected a shortest path to the center, got %v", path)
+	}
+	prev := gridmath.Spot{X: 1, Y: 1}
+	for _, at := range path {
+		if !plan.IsOpen(at.X, at.Y) || abs(at.X-prev.X)+abs(at.Y-prev.Y) != 1 {
+			t.Errorf("Unexpected step from %v to %v", prev, at)
+		}
+		prev = at
+	}
+	if path := centerPath(plan, 3, 3, 2, -5); path != nil {
+		t.Errorf("Expected no path from outside the maze, got %v", path)
+	}
+}
+
+func TestPickupSpots(t *testing.T) {
+	plan := loopPlan(t)
+	spots := pickupSpots(plan, 4, 2, []gridmath.Spot{{X: 3, Y: 3}})
+	if len(spots) != 4 {
+		t.Fatalf("Expected 4 pickup spots, got %v", spots)
+	}
+	for _, spot := range spots {
+		if !plan.IsOpen(spot.X, spot.Y) || !clearOf(spot.X, spot.Y, 2, []gridmath.Spot{{X: 3, Y: 3}}) {
+			t.Errorf("Expected open spots away from the center, got %v", spot)
+		}
+	}
+	if again := pickupSpots(plan, 4, 2, []gridmath.Spot{{X: 3, Y: 3}}); again[0] != spots[0] || again[3] != spots[3] {
+		t.Errorf("Expected the same spots each time")
+	}
+	if spots := pickupSpots(plan, 100, 2, nil); len(spots) != 18 {
+		t.Errorf("Expected the pickups to be limited to the open spots, got %d", len(spots))
+	}
+}
+
+func TestLossEffect(t *testing.T) {
+	small, smallSteps, smallShake := lossEffect(1.0 / 64)
+	big, bigSteps, bigShake := lossEffect(0.5)
+	if small >= big || smallSteps >= bigSteps || smallShake >= bigShake {
+		t.Errorf("Expected a larger effect for a larger loss")
+	}
+	if big != 1 || bigSteps != lossLong || bigShake != lossShake {
+		t.Errorf("Expected the strongest effect to be capped, got %f %d %f", big, bigSteps, bigShake)
+	}
+}
+
+func TestSentinelEnemy(t *testing.T) {
+	var foe enemy = &sentinel{active: true, immune: 1}
+	if !foe.isActive() || foe.onPlayerCollide(nil) {
+		t.Errorf("Expected an immune sentinel to ignore the player")
+	}
+	if foe.minimapStyle() != solidMarker || (&sentinel{stun: 5}).minimapStyle() != fadedMarker {
+		t.Errorf("Expected only stunned sentinels to have faded markers")
+	}
+}
+
+func TestSentinelCatchUp(t *testing.T) {
+	plan := loopPlan(t)
+	start := func() *sentinel {
+		s := &sentinel{units: 2, speed: sentinelSpeed, moves: rand.New(rand.NewSource(1))}
+		s.prev, s.next = &gridmath.Spot{X: 1, Y: 1}, &gridmath.Spot{X: 1, Y: 1}
+		return s
+	}
+	slow, sx, sy := start(), 1.0, 1.0
+	for cnt := 0; cnt < 200; cnt++ {
+		sx, sy = slow.advance(sx, sy, plan, 1)
+	}
+	fast := start()
+	fx, fy := fast.advance(1, 1, plan, 200)
+	if *fast.next != *slow.next || math.Abs(fx-sx) > 0.001 || math.Abs(fy-sy) > 0.001 {
+		t.Errorf("Expected %f %f towards %v, got %f %f towards %v", sx, sy, *slow.next, fx, fy, *fast.next)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// The map key saves a picture of the current level floorplan next to the
+// save file. The picture shows the walls, floors, maze center, current
+// core locations, and the player start, and is meant for sharing custom
+// and daily mazes and for attaching to bug reports.
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path"
+	"time"
+
+	"github.com/gazed/bampf/gridmath"
+	"github.com/gazed/vu/grid"
+)
+
+// mapScale is the size in pixels of one grid location in a map picture.
+const mapScale = 8
+
+// Map picture colours.
+var (
+	mapBackground = color.RGBA{0, 0, 0, 255}
+	mapWall       = color.RGBA{90, 90, 110, 255}
+	mapFloor      = color.RGBA{200, 200, 190, 255}
+	mapCenter     = color.RGBA{40, 200, 40, 255}
+	mapCore       = color.RGBA{40, 110, 240, 255}
+	mapStart      = color.RGBA{230, 40, 40, 255}
+)
+
+// levelMap draws the floorplan with the given cores and player start.
+// Cores are drawn as smaller squares so the floor around them shows.
+// The picture includes the core drop locations around the outside of the
+// maze and grows to include any spots that are further out.
+func levelMap(plan grid.Grid, cores []gridmath.Spot, start gridmath.Spot) *image.RGBA {
+	width, height := plan.Size()
+	bounds := image.Rect(-1, -1, width+1, height+1)
+	for _, spot := range append(cores, start) {
+		bounds = bounds.Union(image.Rect(spot.X, spot.Y, spot.X+1, spot.Y+1))
+	}
+	pic := image.NewRGBA(image.Rect(0, 0, bounds.Dx()*mapScale, bounds.Dy()*mapScale))
+	fill := func(spot gridmath.Spot, inset int, c color.RGBA) {
+		x, y := (spot.X-bounds.Min.X)*mapScale, (spot.Y-bounds.Min.Y)*mapScale
+		for px := x + inset; px < x+mapScale-inset; px++ {
+			for py := y + inset; py < y+mapScale-inset; py++ {
+				pic.SetRGBA(px, py, c)
+			}
+		}
+	}
+	for px := 0; px < pic.Bounds().Dx(); px++ {
+		for py := 0; py < pic.Bounds().Dy(); py++ {
+			pic.SetRGBA(px, py, mapBackground)
+		}
+	}
+	spots, _ := layoutPlan(plan, 1)
+	for _, spot := range spots {
+		switch spot.kind {
+		case centerSpot:
+			fill(gridmath.Spot{X: spot.x, Y: spot.y}, 0, mapCenter)
+		case floorSpot:
+			fill(gridmath.Spot{X: spot.x, Y: spot.y}, 0, mapFloor)
+		case wallSpot:
+			fill(gridmath.Spot{X: spot.x, Y: spot.y}, 0, mapWall)
+		}
+	}
+	for _, core := range cores {
+		fill(core, mapScale/4, mapCore)
+	}
+	fill(start, mapScale/4, mapStart)
+	return pic
+}
+
+// coreSpots returns the grid locations of the cores waiting to be collected.
+func (cc *coreControl) coreSpots() (spots []gridmath.Spot) {
+	for _, core := range cc.cores {
+		x, y, z := core.At()
+		gx, gy := gridmath.ToGrid(x, y, z, cc.units)
+		spots = append(spots, gridmath.Spot{X: gx, Y: gy})
+	}
+	return spots
+}
+
+// levelThumb draws a sample floorplan for the given level. The same
+// sample is drawn every time for a given level size and generator.
+func levelThumb(lvl int) *image.RGBA {
+	sx, sy, sz := startSpot()
+	start := gridmath.Spot{}
+	start.X, start.Y = gridmath.ToGrid(sx, sy, sz, 2)
+	return levelMap(newSeededPlan(lvl, thumbSeed), nil, start)
+}
+
+// thumbSeed generates the sample floorplans for the level thumbnails.
+const thumbSeed = 1
+
+// exportMap saves a picture of the current level to a new timestamped
+// file in the save directory.
+func (lvl *level) exportMap() {
+	sx, sy, sz := startSpot()
+	start := gridmath.Spot{}
+	start.X, start.Y = gridmath.ToGrid(sx, sy, sz, float64(lvl.units))
+	pic := levelMap(lvl.plan, lvl.cc.coreSpots(), start)
+	dir := path.Dir(newSaver().File)
+	name := fmt.Sprintf("map-L%d-%s.png", lvl.num, time.Now().Format("20060102-150405"))
+	file, err := os.Create(path.Join(dir, name))
+	if err != nil {
+		logf("level.exportMap: %s", err)
+		return
+	}
+	defer file.Close()
+	if err := png.Encode(file, pic); err != nil {
+		logf("level.exportMap: %s", err)
+	}
+}
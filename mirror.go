@@ -0,0 +1,121 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// The optional rear-view mirror shows what is behind the player in a small
+// panel at the top of the HUD. The mirror is a separate scene with its own
+// copy of the maze walls and the sentinels. It is rendered to a texture
+// that is shown on the HUD panel. Drawing the maze a second time is not
+// cheap, so the mirror is off by default.
+
+import (
+	"github.com/gazed/bampf/gridmath"
+	"github.com/gazed/vu"
+)
+
+// Rear-view mirror tuning.
+const (
+	mirrorFade = 0.5  // Fraction of the level fade distance shown behind.
+	mirrorSize = 0.2  // Panel size as a fraction of the window size.
+	mirrorEdge = 10.0 // Gap in pixels between the panel and the top of the window.
+)
+
+// mirror is the rear-view scene along with the HUD panel that shows it.
+type mirror struct {
+	scene    *vu.Ent                   // Rendered to a texture instead of the display.
+	cam      *vu.Camera                // Looks backwards from the player.
+	sky      *skyDome                  // Background behind the maze.
+	floor    *vu.Ent                   // Fills in the view below the sky.
+	walls    map[gridmath.Spot]*vu.Ent // Copies of the maze walls.
+	sentries []*sentinel               // Copies of the level sentinels.
+	panel    *vu.Ent                   // HUD panel showing the mirror scene.
+}
+
+// newMirror creates a rear-view mirror for the given level.
+// The mirror starts hidden.
+func newMirror(lvl *level) *mirror {
+	m := &mirror{}
+	fade := lvl.fade * mirrorFade
+	m.scene = lvl.mp.eng.AddScene().AsTex(true)
+	m.cam = m.scene.Cam()
+	m.cam.SetClip(0.1, 50).SetFov(lvl.fov)
+	m.sky = newSkyDome(m.scene, lvl.theme.Sky)
+	m.floor = m.scene.AddPart().SetScale(skyRadius, 1, skyRadius)
+	m.floor.MakeModel("colored", "msh:tile", "mat:gray")
+
+	// copy the maze walls, but not the floor tiles.
+	m.walls = map[gridmath.Spot]*vu.Ent{}
+	spots, _ := layoutPlan(lvl.plan, lvl.units)
+	for _, spot := range spots {
+		if spot.kind == wallSpot {
+			wm := lvl.theme.wallMesh(spot.band)
+			wt := lvl.theme.wallTexture(spot.band)
+			if _, ok := lvl.cracks[gridmath.Spot{X: spot.x, Y: spot.y}]; ok {
+				wt = "crack"
+			}
+			wall := m.scene.AddPart().SetAt(spot.xc, 0, spot.yc)
+			wall.MakeModel("uva", "msh:"+wm, "tex:"+wt).SetUniform("fd", fade)
+			m.walls[gridmath.Spot{X: spot.x, Y: spot.y}] = wall
+		}
+	}
+	for range lvl.sentries {
+		sentry := newSentinel(m.scene.AddPart(), lvl.num, lvl.units, fade, lvl.moves)
+		sentry.setScale(0.25)
+		sentry.setActive(false)
+		m.sentries = append(m.sentries, sentry)
+	}
+
+	// the rendered texture is upside down. Spinning the panel half way
+	// around puts it right way up and flips it left to right like a mirror.
+	m.panel = lvl.hd.ui.AddPart()
+	m.panel.Spin(0, 0, 180)
+	m.panel.MakeModel("textured", "msh:icon").SetTex(m.scene)
+	m.resize(lvl.hd.w, lvl.hd.h)
+	m.setVisible(false)
+	return m
+}
+
+// update moves the mirror camera to the player, facing backwards,
+// and copies the sentinel locations.
+func (m *mirror) update(lvl *level) {
+	x, y, z := lvl.cam.At()
+	m.cam.SetAt(x, y, z)
+	m.cam.SetYaw(lvl.cam.Yaw + 180)
+	m.sky.follow(x, z)
+	m.floor.SetAt(x, 0, z)
+	r, g, b := skyBlend(lvl.theme.Fog, lvl.theme.Tint, lvl.colour)
+	m.sky.tint([3]float32{r, g, b})
+	m.floor.SetColor(float64(r), float64(g), float64(b))
+	for cnt, sentry := range lvl.sentries {
+		m.sentries[cnt].setActive(sentry.active)
+		m.sentries[cnt].part.SetAt(sentry.location())
+	}
+}
+
+// breakWall removes a broken wall from the mirror.
+func (m *mirror) breakWall(spot gridmath.Spot) {
+	if wall, ok := m.walls[spot]; ok {
+		wall.Dispose()
+		delete(m.walls, spot)
+	}
+}
+
+// resize keeps the panel at the top center of the window.
+func (m *mirror) resize(width, height int) {
+	w, h := float64(width)*mirrorSize, float64(height)*mirrorSize
+	m.panel.SetScale(w*0.5, h*0.5, 1)
+	m.panel.SetAt(float64(width)*0.5, float64(height)-mirrorEdge-h*0.5, 0)
+}
+
+// setVisible shows or hides the mirror. Hidden mirrors are not rendered.
+func (m *mirror) setVisible(visible bool) {
+	m.scene.Cull(!visible)
+	m.panel.Cull(!visible)
+}
+
+// dispose removes the mirror scene and panel.
+func (m *mirror) dispose() {
+	m.scene.Dispose()
+	m.panel.Dispose()
+}
@@ -0,0 +1,84 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Mutators are optional rule changes that are chosen on the launch screen
+// before a game. Mutators are applied by the game tuning functions that
+// wrap the per-level game tuning tables.
+
+import (
+	"sort"
+	"strings"
+)
+
+// Game mutators.
+const (
+	doubleSentinels = "double sentinels" // Twice as many sentinels.
+	noTeleport      = "no teleport"      // Teleporting is disabled.
+	fragile         = "fragile"          // Double the cells lost to sentinels.
+	greedy          = "greedy"           // Half again the cells gained from cores.
+	knockback       = "knockback"        // Sentinel collisions push the player away.
+	hazards         = "hazards"          // Maze hazards, like voids, are placed.
+)
+
+// gameMutatorIDs lists the mutators in launch screen order.
+var gameMutatorIDs = []string{doubleSentinels, noTeleport, fragile, greedy, knockback, hazards}
+
+// gameMutators are the mutators active for the current game.
+var gameMutators = map[string]bool{}
+
+// mutatorKey returns a name for the given set of active mutators.
+// The key is empty when no mutators are active.
+func mutatorKey(mutators map[string]bool) string {
+	ids := []string{}
+	for id, on := range mutators {
+		if on {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ", ")
+}
+
+// gameSentinels is the number of sentinels for the given level.
+func gameSentinels(lvl int) int {
+	if gameMutators[doubleSentinels] && gameMuster[lvl]*2 <= maxLevelSentries {
+		return gameMuster[lvl] * 2
+	}
+	return gameMuster[lvl]
+}
+
+// gameGain is the number of cells gained for each core on the given level.
+func gameGain(lvl int) int {
+	if gameMutators[greedy] {
+		return (gameCellGain[lvl]*3 + 1) / 2
+	}
+	return gameCellGain[lvl]
+}
+
+// gameLoss is the number of cells lost for each sentinel collision
+// on the given level.
+func gameLoss(lvl int) int {
+	if gameMutators[fragile] {
+		return gameCellLoss[lvl] * 2
+	}
+	return gameCellLoss[lvl]
+}
+
+// gameKnocks is true if a sentinel collision on the given level knocks
+// the player back instead of teleporting the sentinel out of the maze.
+func gameKnocks(lvl int) bool { return gameMutators[knockback] || gameKnockback[lvl] }
+
+// gameHazard is the number of one kind of maze hazard, given by its
+// per-level table, on the given level. Hazards are only placed with
+// the hazards mutator so that the regular game is unchanged.
+func gameHazard(counts []int, lvl int) int {
+	if !gameMutators[hazards] {
+		return 0
+	}
+	return counts[lvl]
+}
+
+// gameTeleport is true if the player is allowed to teleport.
+func gameTeleport() bool { return !gameMutators[noTeleport] }
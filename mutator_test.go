@@ -0,0 +1,36 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestMutators(t *testing.T) {
+	defer func() { gameMutators = map[string]bool{} }()
+	gameMutators = map[string]bool{fragile: true, greedy: true}
+	if loss := gameLoss(1); loss != gameCellLoss[1]*2 {
+		t.Errorf("Expected double loss got %d", loss)
+	}
+	if gain := gameGain(0); gain <= gameCellGain[0] {
+		t.Errorf("Expected more gain got %d", gain)
+	}
+	if key := mutatorKey(gameMutators); key != "fragile, greedy" {
+		t.Errorf("Expected sorted mutator key got %q", key)
+	}
+	if gameKnocks(1) {
+		t.Errorf("Expected teleport collisions by default")
+	}
+	gameMutators[knockback] = true
+	if !gameKnocks(1) {
+		t.Errorf("Expected knockback collisions")
+	}
+	if gameHazard(gameVoids, 4) != 0 {
+		t.Errorf("Expected no hazards without the hazards mutator")
+	}
+	gameMutators[hazards] = true
+	if gameHazard(gameVoids, 4) != gameVoids[4] {
+		t.Errorf("Expected the level hazards")
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// The pathfinder briefly shows the shortest way from the player to the
+// maze center as glowing arrows on the floor. There is one charge for
+// each visit to a level, earned by collecting all the cloak pickups on
+// the level, and the arrows fade away after a few seconds. Each cloak
+// pickup also tops up the cloaking energy.
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/gazed/bampf/gridmath"
+	"github.com/gazed/vu"
+	"github.com/gazed/vu/grid"
+)
+
+// Pathfinder tuning.
+const (
+	pickupClear = 4    // Grid steps kept clear of cloak pickups around the start and center.
+	pathShown   = 150  // Game ticks that the path arrows are shown.
+	pathAlpha   = 0.8  // Transparency of fresh path arrows.
+	pathSize    = 0.35 // Path arrow size in game units.
+)
+
+// cloakPickup is one of the pickups that earn the pathfinder charge.
+type cloakPickup struct {
+	part  *vu.Ent       // Pickup model.
+	at    gridmath.Spot // Grid location.
+	taken bool          // True once collected on this visit.
+}
+
+// pickupSpots returns up to count open spots, spread evenly through the
+// maze, that are at least clear grid steps from each of the keep spots.
+// The same plan always gives the same spots.
+func pickupSpots(plan grid.Grid, count, clear int, keep []gridmath.Spot) []gridmath.Spot {
+	width, height := plan.Size()
+	candidates := []gridmath.Spot{}
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			if plan.IsOpen(x, y) && clearOf(x, y, clear, keep) {
+				candidates = append(candidates, gridmath.Spot{X: x, Y: y})
+			}
+		}
+	}
+	return spreadOut(candidates, count)
+}
+
+// centerPath returns the grid spots, not including the starting spot,
+// along a shortest path from the given spot to the maze center. Return
+// nil if the center can't be reached from the given spot.
+func centerPath(plan grid.Grid, cx, cy, fromx, fromy int) (path []gridmath.Spot) {
+	width, height := plan.Size()
+	if fromx < 0 || fromy < 0 || fromx >= width || fromy >= height {
+		return nil
+	}
+	dist := centerDistances(plan, cx, cy)
+	at := gridmath.Spot{X: fromx, Y: fromy}
+	if dist[at.X][at.Y] < 0 {
+		return nil
+	}
+	for dist[at.X][at.Y] > 0 {
+		for _, to := range []gridmath.Spot{{X: at.X + 1, Y: at.Y}, {X: at.X - 1, Y: at.Y}, {X: at.X, Y: at.Y + 1}, {X: at.X, Y: at.Y - 1}} {
+			if to.X >= 0 && to.Y >= 0 && to.X < width && to.Y < height && dist[to.X][to.Y] == dist[at.X][at.Y]-1 {
+				at = to
+				break
+			}
+		}
+		path = append(path, at)
+	}
+	return path
+}
+
+// centerPath
+// ===========================================================================
+// level pathfinder handling.
+
+// buildPickups places the cloak pickups away from the start and center.
+// Expected to be called after the gates, voids, interdiction zone, and
+// turrets are placed so that pickups stay off them.
+func (lvl *level) buildPickups(scene *vu.Ent, plan grid.Grid) {
+	sx, sy, sz := startSpot()
+	startx, starty := gridmath.ToGrid(sx, sy, sz, float64(lvl.units))
+	keep := []gridmath.Spot{{X: lvl.gcx, Y: lvl.gcy}, {X: startx, Y: starty}}
+	count := gameCloakPickups[lvl.num]
+	for _, spot := range pickupSpots(plan, count*2, pickupClear, keep) {
+		if len(lvl.pickups) >= count {
+			return
+		}
+		if lvl.voids[spot] || lvl.interdict[spot] || lvl.gates[spot] != nil || lvl.turretAt(spot) {
+			continue
+		}
+		gamex, gamez := gridmath.ToGame(spot.X, spot.Y, float64(lvl.units))
+		part := scene.AddPart().SetAt(gamex, 0.5, gamez).SetScale(0.15, 0.15, 0.15)
+		m := part.MakeModel("flata", "msh:cube", "mat:tgreen")
+		trackAsset(m, "mat:tgreen")
+		m.SetUniform("fd", lvl.fade)
+		lvl.pickups = append(lvl.pickups, &cloakPickup{part: part, at: spot})
+		lvl.cc.remDropAt(spot.X, spot.Y)
+	}
+}
+
+// pickupsLeft returns the number of cloak pickups not yet collected.
+func (lvl *level) pickupsLeft() (left int) {
+	for _, p := range lvl.pickups {
+		if !p.taken {
+			left++
+		}
+	}
+	return left
+}
+
+// fetchPickups collects the cloak pickup where the player is standing.
+// Collecting the last one earns the pathfinder charge.
+func (lvl *level) fetchPickups() {
+	x, y, z := lvl.body.At()
+	gx, gy := gridmath.ToGrid(x, y, z, float64(lvl.units))
+	at := gridmath.Spot{X: gx, Y: gy}
+	for _, p := range lvl.pickups {
+		if p.taken {
+			continue
+		}
+		p.part.Spin(0, 3, 0)
+		if p.at == at {
+			p.taken = true
+			p.part.Cull(true)
+			lvl.player.addCloakEnergy()
+			lvl.player.play(fetchSound)
+			if lvl.pickupsLeft() == 0 && !lvl.pathUsed {
+				lvl.charged = true
+				lvl.hd.showBanner("Pathfinder ready, press G to show the way to the center")
+			}
+		}
+	}
+}
+
+// updatePathfinder collects cloak pickups and fades out the
+// path arrows. Expected to be called each game tick.
+func (lvl *level) updatePathfinder() {
+	lvl.fetchPickups()
+	if lvl.pathTicks > 0 {
+		lvl.pathTicks--
+		for _, arrow := range lvl.arrows {
+			arrow.SetAlpha(pathAlpha * float64(lvl.pathTicks) / pathShown)
+		}
+		if lvl.pathTicks == 0 {
+			lvl.clearPath()
+		}
+	}
+}
+
+// usePathfinder spends the pathfinder charge to show the path from
+// the player to the maze center. The charge is kept if there is no
+// path from where the player is standing.
+func (lvl *level) usePathfinder() {
+	if !lvl.charged {
+		msg := "Collect " + strconv.Itoa(lvl.pickupsLeft()) + " more cloak pickups to earn the pathfinder"
+		if lvl.pathUsed {
+			msg = "The pathfinder has been used on this visit"
+		}
+		lvl.hd.showBanner(msg)
+		return
+	}
+	x, y, z := lvl.body.At()
+	gx, gy := gridmath.ToGrid(x, y, z, float64(lvl.units))
+	path := centerPath(lvl.plan, lvl.gcx, lvl.gcy, gx, gy)
+	if len(path) == 0 {
+		lvl.hd.showBanner("No path from here")
+		return
+	}
+	lvl.charged, lvl.pathUsed = false, true
+	lvl.clearPath()
+	lvl.path = lvl.scene.AddPart()
+	prev := gridmath.Spot{X: gx, Y: gy}
+	for _, next := range path {
+		lvl.arrows = append(lvl.arrows, lvl.newArrow(prev, next))
+		prev = next
+	}
+	lvl.pathTicks = pathShown
+}
+
+// newArrow creates a floor arrow on the from grid spot that points
+// towards the to grid spot. Return the arrow model.
+func (lvl *level) newArrow(from, to gridmath.Spot) *vu.Ent {
+	units := float64(lvl.units)
+	fx, fz := gridmath.ToGame(from.X, from.Y, units)
+	tx, tz := gridmath.ToGame(to.X, to.Y, units)
+	arrow := lvl.path.AddPart().SetAt(fx, 0, fz)
+	arrow.SetAa(0, 1, 0, math.Atan2(fx-tx, fz-tz))
+
+	// the triangle mesh stands up and points along y. Lay it flat,
+	// pointing forward along -z, just above the floor.
+	tri := arrow.AddPart().SetAt(0, voidLift-pathSize, 0).SetScale(pathSize, pathSize, pathSize)
+	tri.SetAa(1, 0, 0, -math.Pi*0.5)
+	m := tri.MakeModel("flata", "msh:tri", "mat:tgreen")
+	trackAsset(m, "mat:tgreen")
+	m.SetAlpha(pathAlpha).SetUniform("fd", lvl.fade)
+	return m
+}
+
+// clearPath removes any path arrows.
+func (lvl *level) clearPath() {
+	if lvl.path != nil {
+		lvl.path.Dispose()
+	}
+	lvl.path, lvl.arrows, lvl.pathTicks = nil, nil, 0
+}
+
+// resetPathfinder gives each visit to a level a new chance to earn
+// the pathfinder charge by putting back the cloak pickups. Expected to
+// be called when a level is activated.
+func (lvl *level) resetPathfinder() {
+	lvl.clearPath()
+	lvl.charged, lvl.pathUsed = len(lvl.pickups) == 0, false
+	for _, p := range lvl.pickups {
+		p.taken = false
+		p.part.Cull(false)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Practice runs let players learn a level without pressure. Each sentinel
+// shows where it is heading and how close it can get before it warns the
+// player. Sentinel hits cost nothing and teleports are always charged.
+// Practice runs are not recorded anywhere: there are no best times,
+// splits, unlocks, telemetry, or saved runs.
+
+import (
+	"github.com/gazed/bampf/gridmath"
+	"github.com/gazed/vu"
+)
+
+// guide shows one sentinel's next grid spot and proximity warning radius.
+type guide struct {
+	path *vu.Ent // Marks the grid spot the sentinel is heading to.
+	ring *vu.Ent // Shows the proximity warning distance.
+}
+
+// newGuide creates the hidden markers for one sentinel.
+func (lvl *level) newGuide() *guide {
+	g := &guide{}
+	g.path = lvl.scene.AddPart().SetScale(0.4, 1, 0.4)
+	m := g.path.MakeModel("flata", "msh:tile", "mat:tblue")
+	m.SetUniform("fd", lvl.fade)
+	g.ring = lvl.scene.AddPart()
+	m = g.ring.MakeModel("uvra", "msh:tile", "tex:reach")
+	trackAsset(m, "tex:reach")
+	m.SetAlpha(0.3).SetUniform("spin", 0).SetUniform("fd", lvl.fade)
+	g.path.Cull(true)
+	g.ring.Cull(true)
+	return g
+}
+
+// showGuides keeps the guides with their sentinels in practice mode.
+// Guides are only created the first time a level is practiced, and
+// are hidden when the level is played normally.
+func (lvl *level) showGuides() {
+	if !lvl.practice {
+		for _, g := range lvl.guides {
+			g.path.Cull(true)
+			g.ring.Cull(true)
+		}
+		return
+	}
+	radius := float64(gameProximity[lvl.num] * lvl.units)
+	for cnt, sentry := range lvl.sentries {
+		if cnt == len(lvl.guides) {
+			lvl.guides = append(lvl.guides, lvl.newGuide())
+		}
+		g := lvl.guides[cnt]
+		g.path.Cull(!sentry.active || sentry.next == nil)
+		g.ring.Cull(!sentry.active || radius <= 0)
+		if sentry.active {
+			x, _, z := sentry.location()
+			g.ring.SetAt(x, voidLift, z).SetScale(radius, 1, radius)
+			if sentry.next != nil {
+				nx, nz := gridmath.ToGame(sentry.next.X, sentry.next.Y, float64(lvl.units))
+				g.path.SetAt(nx, voidLift, nz)
+			}
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Presence exports the current game status to optional integrations
+// outside of the game, eg: a local status file that can be read by
+// streaming overlays or a chat client status adapter.
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"time"
+)
+
+// Status is a snapshot of the current game state. Status needs to be
+// public and visible for the encoding package.
+type Status struct {
+	Mode   string `json:"mode"`   // One of the status modes below.
+	Level  int    `json:"level"`  // Current game level.
+	Health int    `json:"health"` // Current number of cells.
+	Max    int    `json:"max"`    // Number of cells needed to finish the level.
+}
+
+// Status modes.
+const (
+	modeMenu     = "menu"     // Choosing a level on the launch screen.
+	modePlaying  = "playing"  // Playing a game level.
+	modeFinished = "finished" // Watching the end game animation.
+)
+
+// presenter is implemented by integrations that display the game status.
+type presenter interface {
+	present(st Status) // Called with each new game status.
+}
+
+// presence collects game status updates and passes them on to each
+// registered presenter. Exports are throttled so that presenters are
+// not called every game tick.
+type presence struct {
+	on         bool          // True if status exports are enabled.
+	presenters []presenter   // Registered integrations.
+	current    Status        // Most recent game status.
+	exported   Status        // Last exported game status.
+	last       time.Time     // Last time the status was exported.
+	holdoff    time.Duration // Minimum time between exports.
+}
+
+// newPresence returns an initialized, and disabled, presence.
+func newPresence() *presence {
+	p := &presence{}
+	p.presenters = []presenter{}
+	p.holdoff = time.Second
+	return p
+}
+
+// addPresenter registers an integration for status updates.
+func (p *presence) addPresenter(pr presenter) {
+	p.presenters = append(p.presenters, pr)
+}
+
+// setEnabled turns status exports on or off. Enabling forces an
+// export of the current status.
+func (p *presence) setEnabled(on bool) {
+	p.on = on
+	p.exported = Status{}
+}
+
+// update records the latest game status. The status is exported when it
+// has changed and enough time has passed since the previous export.
+func (p *presence) update(st Status) {
+	p.current = st
+	if !p.on || p.current == p.exported {
+		return
+	}
+	if time.Now().After(p.last.Add(p.holdoff)) {
+		p.last = time.Now()
+		p.exported = p.current
+		for _, pr := range p.presenters {
+			pr.present(p.exported)
+		}
+	}
+}
+
+// presence
+// ===========================================================================
+// statusFile
+
+// statusFile is a presenter that writes the game status as JSON to
+// a file beside the save file.
+type statusFile struct {
+	file string // Status file name.
+}
+
+// newStatusFile creates a presenter for the default status file location.
+func newStatusFile() *statusFile {
+	return &statusFile{file: path.Join(path.Dir(newSaver().File), "status.json")}
+}
+
+// present implements presenter by overwriting the status file.
+func (sf *statusFile) present(st Status) {
+	data, err := json.Marshal(st)
+	if err != nil {
+		logf("Failed to encode game status: %s", err)
+		return
+	}
+	if err = ioutil.WriteFile(sf.file, data, 0644); err != nil {
+		logf("Failed to save game status: %s", err)
+	}
+}
@@ -0,0 +1,324 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Profiles let several local players share the game. Each profile has
+// its own save file holding its key bindings, options, unlocks, and best
+// times. The default profile uses the original save file so that games
+// saved before profiles existed carry on as the default player.
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gazed/vu"
+)
+
+// activeProfile is the name of the profile used by newSaver.
+// The empty string is the default profile.
+var activeProfile string
+
+// defaultProfile is the name shown for the default profile.
+const defaultProfile = "default"
+
+// maxProfileName is the longest profile name in characters.
+const maxProfileName = 12
+
+// profileFile returns the save file name for the given profile.
+func profileFile(name string) string {
+	if name == "" {
+		return "bampf.save"
+	}
+	return "bampf-" + name + ".save"
+}
+
+// profiles is the persisted list of named local players.
+type profiles struct {
+	File   string   // Profile list file name.
+	Names  []string // Named profiles. The default profile is not listed.
+	Active string   // Active profile name, empty for the default profile.
+}
+
+// newProfiles creates an empty profile list that is saved beside the
+// game save files.
+func newProfiles() *profiles {
+	dir := path.Dir(newSaver().File)
+	return &profiles{File: path.Join(dir, "profiles.save")}
+}
+
+// active returns the active profile, falling back to the default
+// profile if the active profile no longer exists.
+func (p *profiles) active() string {
+	if p.has(p.Active) {
+		return p.Active
+	}
+	return ""
+}
+
+// has returns true if the named profile exists.
+func (p *profiles) has(name string) bool {
+	for _, existing := range p.Names {
+		if existing == name {
+			return true
+		}
+	}
+	return false
+}
+
+// create adds a new profile with a generated name and returns the name.
+func (p *profiles) create() string {
+	for cnt := len(p.Names) + 2; ; cnt++ {
+		if name := "player" + strconv.Itoa(cnt); !p.has(name) {
+			p.Names = append(p.Names, name)
+			return name
+		}
+	}
+}
+
+// rename changes a profile name and moves its save file. Returns false
+// if the new name is not valid or is already used.
+func (p *profiles) rename(from, to string) bool {
+	if !p.has(from) || p.has(to) || !validProfileName(to) {
+		return false
+	}
+	dir := path.Dir(p.File)
+	if err := os.Rename(path.Join(dir, profileFile(from)), path.Join(dir, profileFile(to))); err != nil && !os.IsNotExist(err) {
+		logf("profiles.rename: %s", err)
+		return false
+	}
+	for cnt, name := range p.Names {
+		if name == from {
+			p.Names[cnt] = to
+		}
+	}
+	if p.Active == from {
+		p.Active = to
+	}
+	return true
+}
+
+// remove deletes a profile and its save file. The default profile
+// becomes active if the active profile is removed.
+func (p *profiles) remove(name string) {
+	for cnt, existing := range p.Names {
+		if existing == name {
+			p.Names = append(p.Names[:cnt], p.Names[cnt+1:]...)
+			os.Remove(path.Join(path.Dir(p.File), profileFile(name)))
+			break
+		}
+	}
+	if p.Active == name {
+		p.Active = ""
+	}
+}
+
+// validProfileName returns true for short names of lower case letters
+// and digits that are not the default profile name.
+func validProfileName(name string) bool {
+	if name == "" || name == defaultProfile || len(name) > maxProfileName {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z') && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// persist saves the profile list.
+func (p *profiles) persist() {
+	data := &bytes.Buffer{}
+	if err := gob.NewEncoder(data).Encode(p); err == nil {
+		if err = ioutil.WriteFile(p.File, data.Bytes(), 0644); err != nil {
+			logf("Failed to save profiles: %s", err)
+		}
+	} else {
+		logf("Failed to encode profiles: %s", err)
+	}
+}
+
+// restore reads the profile list. It handles the case where a profile
+// list has not been saved yet.
+func (p *profiles) restore() {
+	if bites, err := ioutil.ReadFile(p.File); err == nil {
+		if err := gob.NewDecoder(bytes.NewBuffer(bites)).Decode(p); err != nil {
+			logf("Failed to restore profiles. %s", err)
+		}
+	}
+}
+
+// profiles
+// ===========================================================================
+// profileMenu
+
+// profileMenu is the launch screen profile browser along with links to
+// create, rename, and delete profiles.
+type profileMenu struct {
+	names  *chooser  // Profile browser.
+	links  []*vu.Ent // Profile management links.
+	naming bool      // True while a new profile name is being typed.
+	name   string    // Profile name typed so far.
+}
+
+// Profile management links in the order they are shown.
+var profileLinks = []string{"new", "rename", "delete"}
+
+// newProfileMenu creates the profile browser showing the active profile.
+func newProfileMenu(root *vu.Ent) *profileMenu {
+	pm := &profileMenu{}
+	pm.names = newChooser(root, "player", []string{defaultProfile})
+	for _, text := range profileLinks {
+		link := root.AddPart().MakeLabel("labeled", "lucidiaSu18")
+		link.SetStr(text)
+		pm.links = append(pm.links, link)
+	}
+	pm.show()
+	return pm
+}
+
+// show refreshes the browser from the saved profile list.
+func (pm *profileMenu) show() {
+	list := newProfiles()
+	list.restore()
+	pm.names.setChoices(append([]string{defaultProfile}, list.Names...))
+	active := list.active()
+	if active == "" {
+		active = defaultProfile
+	}
+	for pm.names.choice() != active {
+		pm.names.next()
+	}
+}
+
+// position places the browser with the links below it.
+func (pm *profileMenu) position(x, y float64) {
+	pm.names.position(x, y)
+	lx := x
+	for _, link := range pm.links {
+		scaleLabel(link, 1).SetAt(lx, y-25*textScale, 0)
+		w, _ := labelSize(link)
+		lx += float64(w) + 15*textScale
+	}
+}
+
+// link returns the clicked profile link or the empty string
+// if no link was clicked.
+func (pm *profileMenu) link(mx, my int) string {
+	for cnt, link := range pm.links {
+		x, y, _ := link.At()
+		w, h := labelSize(link)
+		if mx >= int(x) && mx <= int(x)+w && my >= int(y) && my <= int(y)+h {
+			return profileLinks[cnt]
+		}
+	}
+	return ""
+}
+
+// startNaming begins typing a new name for the current profile.
+// The default profile can't be renamed.
+func (pm *profileMenu) startNaming() {
+	if pm.names.choice() != defaultProfile {
+		pm.naming, pm.name = true, ""
+		pm.names.banner.SetStr(pm.names.text + ": _")
+	}
+}
+
+// typeName adds pressed letters and digits to the new profile name.
+// Returns the typed name and true when return is pressed. Escape cancels
+// naming and returns an empty name.
+func (pm *profileMenu) typeName(ip *inputState) (name string, done bool) {
+	for _, press := range ip.pressedKeys() {
+		switch press {
+		case vu.KRet:
+			pm.naming = false
+			return pm.name, true
+		case vu.KEsc:
+			pm.naming = false
+			return "", true
+		case vu.KDel:
+			if len(pm.name) > 0 {
+				pm.name = pm.name[:len(pm.name)-1]
+			}
+		default:
+			r := unicode.ToLower(vu.Symbol(press))
+			if len(pm.name) < maxProfileName && ((r >= 'a' && r <= 'z') || unicode.IsDigit(r)) {
+				pm.name += string(r)
+			}
+		}
+	}
+	pm.names.banner.SetStr(pm.names.text + ": " + pm.name + "_")
+	return "", false
+}
+
+// profileMenu
+// ===========================================================================
+// bampf profile handling.
+
+// manageProfile creates, renames, or deletes a profile and then switches
+// to the resulting profile.
+func (mp *bampf) manageProfile(action, name string) {
+	list := newProfiles()
+	list.restore()
+	current := list.active()
+	switch action {
+	case "new":
+		current = list.create()
+	case "rename":
+		if !list.rename(current, strings.TrimSpace(name)) {
+			return
+		}
+		current = list.active()
+	case "delete":
+		list.remove(current)
+		current = ""
+	}
+	list.Active = current
+	list.persist()
+	mp.switchProfile(current)
+}
+
+// useProfile makes the named profile the active profile.
+func (mp *bampf) useProfile(name string) {
+	if name == defaultProfile {
+		name = ""
+	}
+	list := newProfiles()
+	list.restore()
+	list.Active = name
+	list.persist()
+	mp.switchProfile(name)
+}
+
+// switchProfile loads the saved state of the given profile into
+// the game screens.
+func (mp *bampf) switchProfile(name string) {
+	activeProfile = name
+	saver := newSaver()
+	saver.restore()
+	mp.keys = append([]int{}, saver.Kbinds...)
+	for _, t := range mp.config.toggles {
+		mp.setOption(t.id, saver.Opts[t.id])
+	}
+	mp.config.setProfile(mp.keys)
+	mp.game.setKeys(mp.config.keys)
+	if saver.Mute != mp.mute {
+		mp.config.toggleMute()
+	}
+	mp.backdrop = saver.Backdrop
+	mp.colours = saver.Colours
+	mp.rumble = saver.Rumble
+	mp.haptics.setIntensity(mp.rumble)
+	mp.config.setRumble(mp.rumble)
+	mp.crosshair = crosshairStyle(saver.Crosshair)
+	mp.config.setCrosshair(mp.crosshair)
+	mp.game.setCrosshair(mp.crosshair)
+	mp.launch.setProfile()
+}
@@ -0,0 +1,37 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestProfiles(t *testing.T) {
+	p1 := &profiles{File: "profiles"}
+	name := p1.create()
+	if name != "player2" || !p1.has(name) {
+		t.Errorf("Expected new profile player2, got %s", name)
+	}
+	p1.Active = name
+	if p1.rename(name, "bad name") || p1.rename(name, defaultProfile) {
+		t.Error("Expected invalid profile names to be rejected")
+	}
+	if !p1.rename(name, "sam") || p1.Active != "sam" {
+		t.Errorf("Expected active profile sam, got %s", p1.Active)
+	}
+	p1.persist()
+
+	// restore and remove the active profile.
+	p2 := &profiles{File: "profiles"}
+	p2.restore()
+	if p2.active() != "sam" {
+		t.Errorf("Expected restored profile sam, got %s", p2.active())
+	}
+	p2.remove("sam")
+	if len(p2.Names) != 0 || p2.active() != "" {
+		t.Errorf("Expected only the default profile, got %v %s", p2.Names, p2.Active)
+	}
+	os.Remove("profiles")
+}
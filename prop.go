@@ -0,0 +1,140 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Props are set pieces that decorate the larger rooms of the room levels
+// so that rooms look different from corridors. Props don't collide with
+// the player or the sentinels and cores never drop on them. The props for
+// each theme are listed in the theme data, and are only placed on levels
+// that use the rooms floorplan.
+
+import (
+	"math"
+
+	"github.com/gazed/bampf/gridmath"
+	"github.com/gazed/vu"
+	"github.com/gazed/vu/grid"
+)
+
+// Prop tuning.
+const (
+	propClear   = 3  // Grid steps kept clear of props around the start and center.
+	propDensity = 8  // Room tiles for each prop.
+	propLimit   = 24 // Most props on one level.
+)
+
+// propKinds are the known prop names used in the theme data.
+var propKinds = map[string]func(lvl *level, scene *vu.Ent, gamex, gamez float64){
+	"pillar": (*level).addPillar,
+	"rubble": (*level).addRubble,
+	"vent":   (*level).addVent,
+}
+
+// propSpots returns the room interior spots, spread evenly through the
+// maze, that can hold props. Room interior spots are open spots where all
+// eight neighbours are open. Spots within propClear grid steps of the keep
+// spots are skipped. There is at most one prop for every propDensity
+// room interior spots.
+func propSpots(plan grid.Grid, keep []gridmath.Spot) (spots []gridmath.Spot) {
+	width, height := plan.Size()
+	candidates := []gridmath.Spot{}
+	for x := 1; x < width-1; x++ {
+		for y := 1; y < height-1; y++ {
+			if roomInterior(plan, x, y) && propClearOf(x, y, keep) {
+				candidates = append(candidates, gridmath.Spot{X: x, Y: y})
+			}
+		}
+	}
+	count := len(candidates) / propDensity
+	if count > propLimit {
+		count = propLimit
+	}
+	for cnt := 0; cnt < count; cnt++ {
+		spots = append(spots, candidates[(cnt*2+1)*len(candidates)/(count*2)])
+	}
+	return spots
+}
+
+// roomInterior returns true if the spot and its eight neighbours are open.
+func roomInterior(plan grid.Grid, x, y int) bool {
+	for dx := -1; dx <= 1; dx++ {
+		for dy := -1; dy <= 1; dy++ {
+			if !plan.IsOpen(x+dx, y+dy) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// propClearOf returns true if the grid spot is at least propClear
+// grid steps from all the keep spots.
+func propClearOf(x, y int, keep []gridmath.Spot) bool {
+	for _, k := range keep {
+		if abs(x-k.X)+abs(y-k.Y) < propClear {
+			return false
+		}
+	}
+	return true
+}
+
+// propSpots
+// ===========================================================================
+// level prop handling.
+
+// buildProps decorates the rooms on levels with the rooms floorplan.
+// Expected to be called after the gates, voids, and interdiction zone
+// are placed so that props stay off them.
+func (lvl *level) buildProps(scene *vu.Ent, plan grid.Grid) {
+	if lvl.theme.gridType() != grid.RoomSkirmish || len(lvl.theme.Props) == 0 {
+		return
+	}
+	sx, sy, sz := startSpot()
+	startx, starty := gridmath.ToGrid(sx, sy, sz, float64(lvl.units))
+	keep := []gridmath.Spot{{X: lvl.gcx, Y: lvl.gcy}, {X: startx, Y: starty}}
+	placed := 0
+	for _, spot := range propSpots(plan, keep) {
+		if lvl.voids[spot] || lvl.interdict[spot] || lvl.gates[spot] != nil {
+			continue
+		}
+		name := lvl.theme.Props[placed%len(lvl.theme.Props)]
+		add, ok := propKinds[name]
+		if !ok {
+			logf("theme %s: unknown prop %s", lvl.theme.Name, name)
+			return
+		}
+		gamex, gamez := gridmath.ToGame(spot.X, spot.Y, float64(lvl.units))
+		add(lvl, scene, gamex, gamez)
+		lvl.cc.remDropAt(spot.X, spot.Y)
+		placed++
+	}
+}
+
+// addPillar adds a short pillar in one corner of the tile.
+func (lvl *level) addPillar(scene *vu.Ent, gamex, gamez float64) {
+	pillar := scene.AddPart().SetAt(gamex+0.6, 0.5, gamez+0.6).SetScale(0.15, 0.5, 0.15)
+	m := pillar.MakeModel("flata", "msh:cube", "mat:gray")
+	trackAsset(m, "mat:gray")
+	m.SetUniform("fd", lvl.fade)
+}
+
+// addRubble adds a few broken cubes scattered across the tile.
+func (lvl *level) addRubble(scene *vu.Ent, gamex, gamez float64) {
+	for cnt, at := range [][3]float64{{-0.4, 0.1, 0.3}, {0.3, 0.08, -0.2}, {0.1, 0.06, 0.5}} {
+		size := at[1]
+		piece := scene.AddPart().SetAt(gamex+at[0], size, gamez+at[2]).SetScale(size, size, size)
+		piece.SetAa(0, 1, 0, float64(cnt+1)*math.Pi/5)
+		m := piece.MakeModel("flata", "msh:cube", "mat:gray")
+		trackAsset(m, "mat:gray")
+		m.SetUniform("fd", lvl.fade)
+	}
+}
+
+// addVent adds a small glowing vent in the middle of the tile.
+func (lvl *level) addVent(scene *vu.Ent, gamex, gamez float64) {
+	vent := scene.AddPart().SetAt(gamex, voidLift, gamez).SetScale(0.3, 1, 0.3)
+	m := vent.MakeModel("flata", "msh:tile", "mat:orange")
+	trackAsset(m, "mat:orange")
+	m.SetAlpha(0.7).SetUniform("fd", lvl.fade)
+}
@@ -0,0 +1,213 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// The run config describes the conditions that a run is played under:
+// the daily challenge, the custom maze, the mutators, and any assist
+// options. It is summarized when a run starts and shown as a strip of
+// badges on the pause menu so that screenshots of a score always show
+// how the score was earned.
+
+import (
+	"strings"
+
+	"github.com/gazed/vu"
+)
+
+// Run condition kinds. Each kind has its own badge colour.
+const (
+	dailyTag   = iota // Daily challenge tuning.
+	mutatorTag        // Rule changes that make the run harder or easier.
+	assistTag         // Options that help the player.
+)
+
+// runTag is one run condition shown as a badge.
+type runTag struct {
+	code string // Short badge text.
+	kind int    // One of dailyTag, mutatorTag, assistTag.
+}
+
+// runCodes are the badge texts for the mutators and daily modifiers.
+var runCodes = map[string]string{
+	doubleSentinels: "2S",
+	noTeleport:      "NT",
+	fragile:         "FR",
+	greedy:          "GR",
+	knockback:       "KB",
+	hazards:         "HZ",
+	halfCloak:       "C/2",
+	fastSentinels:   "FS",
+	earlyWaves:      "EW",
+}
+
+// runAssists are the options that are noted as assists, in the order
+// they are listed.
+var runAssists = []struct {
+	id, code, name string
+}{
+	{quickEvolveOption, "QE", "skip evolve countdown"},
+	{autoRunOption, "AR", "auto-run"},
+	{holdCloakOption, "HD", "hold to cloak"},
+	{kinematicOption, "KM", "kinematic movement"},
+	{mirrorOption, "RV", "rear-view mirror"},
+}
+
+// runConfig is the description of the current run shared by the game
+// and the pause menu.
+type runConfig struct {
+	daily    *challenge      // Daily challenge, nil for regular games.
+	maze     string          // Custom maze name, empty for generated mazes.
+	mutators map[string]bool // Active mutators.
+	assists  map[string]bool // Assists used at any time during the run.
+	told     bool            // True once the run summary has been shown.
+}
+
+// newRunConfig describes a new run. Daily challenges have their own
+// mazes and ignore the custom maze.
+func newRunConfig(daily *challenge, maze string, mutators map[string]bool) *runConfig {
+	rc := &runConfig{daily: daily, maze: maze, mutators: map[string]bool{}, assists: map[string]bool{}}
+	if daily != nil {
+		rc.maze = ""
+	}
+	for id, on := range mutators {
+		rc.mutators[id] = on
+	}
+	return rc
+}
+
+// useAssists notes the active assist options. Assists stay noted for
+// the rest of the run even if they are turned off again.
+func (rc *runConfig) useAssists(opts map[string]bool) {
+	for _, assist := range runAssists {
+		if opts[assist.id] {
+			rc.assists[assist.id] = true
+		}
+	}
+}
+
+// tags returns the run conditions as badges in a consistent order.
+func (rc *runConfig) tags() (tags []runTag) {
+	if rc.daily != nil {
+		tags = append(tags, runTag{"D", dailyTag})
+		for _, modifier := range dailyModifiers {
+			if rc.daily.modifiers[modifier] {
+				tags = append(tags, runTag{runCodes[modifier], dailyTag})
+			}
+		}
+	}
+	if rc.maze != "" {
+		tags = append(tags, runTag{"CM", mutatorTag})
+	}
+	for _, id := range gameMutatorIDs {
+		if rc.mutators[id] {
+			tags = append(tags, runTag{runCodes[id], mutatorTag})
+		}
+	}
+	for _, assist := range runAssists {
+		if rc.assists[assist.id] {
+			tags = append(tags, runTag{assist.code, assistTag})
+		}
+	}
+	return tags
+}
+
+// describe returns a one line summary of the run conditions.
+func (rc *runConfig) describe() string {
+	parts := []string{}
+	if rc.daily != nil {
+		parts = append(parts, "daily "+rc.daily.day+": "+rc.daily.describe())
+	}
+	if rc.maze != "" {
+		parts = append(parts, "custom maze "+rc.maze)
+	}
+	if key := mutatorKey(rc.mutators); key != "" {
+		parts = append(parts, key)
+	}
+	assists := []string{}
+	for _, assist := range runAssists {
+		if rc.assists[assist.id] {
+			assists = append(assists, assist.name)
+		}
+	}
+	if len(assists) > 0 {
+		parts = append(parts, "assists: "+strings.Join(assists, ", "))
+	}
+	if len(parts) == 0 {
+		return "standard rules"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// runConfig
+// ===========================================================================
+// runStrip
+
+// runStrip shows the run conditions as a row of small coloured badges.
+type runStrip struct {
+	root   *vu.Ent   // Groups the badges.
+	badges []*vu.Ent // Badge backgrounds and labels.
+}
+
+// Badge colours by run condition kind.
+var runColours = [][3]float64{
+	dailyTag:   {0.8, 0.6, 0.1},
+	mutatorTag: {0.7, 0.2, 0.2},
+	assistTag:  {0.2, 0.4, 0.8},
+}
+
+// Badge sizes in pixels.
+const (
+	badgeHeight = 22 // Badge height.
+	badgePad    = 4  // Space between the label and the badge edge.
+	badgeGap    = 6  // Space between badges.
+)
+
+// newRunStrip creates an empty badge strip.
+func newRunStrip(root *vu.Ent) *runStrip {
+	return &runStrip{root: root.AddPart()}
+}
+
+// show replaces the badges with the conditions of the given run,
+// starting at the given bottom left corner. Nothing is shown for
+// a nil run or for standard rules.
+func (rs *runStrip) show(rc *runConfig, x, y float64) {
+	for _, badge := range rs.badges {
+		badge.Dispose()
+	}
+	rs.badges = nil
+	if rc == nil {
+		return
+	}
+	for _, tag := range rc.tags() {
+		bg := rs.root.AddPart()
+		bg.MakeModel("colored", "msh:square", "mat:tblack")
+		label := rs.root.AddPart().MakeLabel("labeled", "lucidiaSu18")
+		scaleLabel(label, 1).SetStr(tag.code)
+		w, _ := labelSize(label)
+		pad, bh := badgePad*textScale, badgeHeight*textScale
+		bw := float64(w) + 2*pad
+		colour := runColours[tag.kind]
+		bg.SetColor(colour[0], colour[1], colour[2])
+		bg.SetScale(bw*0.5, bh*0.5, 1)
+		bg.SetAt(x+bw*0.5, y+bh*0.5, 0)
+		label.SetAt(x+pad, y+pad, 0)
+		rs.badges = append(rs.badges, bg, label)
+		x += bw + badgeGap*textScale
+	}
+}
+
+// setVisible shows or hides the strip.
+func (rs *runStrip) setVisible(visible bool) { rs.root.Cull(!visible) }
+
+// runStrip
+// ===========================================================================
+// game run config handling.
+
+// tellRules shows the run conditions once at the start of each run.
+func (g *game) tellRules() {
+	if g.rules != nil && !g.rules.told {
+		g.rules.told = true
+		g.cl.hd.showRules(g.rules.describe())
+	}
+}
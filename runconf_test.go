@@ -0,0 +1,24 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestRunConfig(t *testing.T) {
+	rc := newRunConfig(nil, "", nil)
+	if desc := rc.describe(); desc != "standard rules" || len(rc.tags()) != 0 {
+		t.Errorf("Expected standard rules got %q", desc)
+	}
+	rc = newRunConfig(nil, "cross.txt", map[string]bool{greedy: true})
+	rc.useAssists(map[string]bool{quickEvolveOption: true, speechOption: true})
+	rc.useAssists(map[string]bool{})
+	if desc := rc.describe(); desc != "custom maze cross.txt; greedy; assists: skip evolve countdown" {
+		t.Errorf("Unexpected run summary %q", desc)
+	}
+	if tags := rc.tags(); len(tags) != 3 || tags[2].kind != assistTag {
+		t.Errorf("Expected maze, mutator, and assist badges got %v", tags)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// A run in progress is saved at the start of each level and whenever the
+// game is paused, so that it can be continued from the launch screen the
+// next time the game is played. The saved run is deleted once the run is
+// finished or quit. Co-op runs depend on the partner and are not saved,
+// and practice runs are not worth saving.
+
+import (
+	"fmt"
+	"time"
+)
+
+// RunSave is the saved state of a run in progress. RunSave needs to be
+// public and visible for the encoding package.
+type RunSave struct {
+	Level    int             // Level to continue on.
+	Health   int             // Cells when the run was saved.
+	Max      int             // Cells needed to evolve on the level.
+	Elapsed  float64         // Run time, in seconds, when the run was saved.
+	Started  float64         // Run time, in seconds, when the level started.
+	Splits   []float64       // Run times when each level was completed.
+	Points   int             // Unspent upgrade points.
+	Ranks    []int           // Upgrade points spent on each upgrade.
+	Mutators map[string]bool // Active mutators.
+	Daily    string          // Daily challenge day, empty for regular runs.
+	Start    int             // Starting level chosen on the launch screen.
+	Maze     string          // Custom maze used for the starting level.
+	Seed     int64           // Maze seed for the run.
+	Trooper  *TrooperSave    // Exact player cells and energy, nil for older saves.
+}
+
+// canContinue returns true if the saved run can still be played.
+// Daily challenge runs expire at the end of the day.
+func (run *RunSave) canContinue(now time.Time) bool {
+	return run != nil && (run.Daily == "" || run.Daily == newChallenge(now).day)
+}
+
+// describe summarizes the saved run for the launch screen.
+func (run *RunSave) describe() string {
+	health := 0
+	if run.Max > 0 {
+		health = run.Health * 100 / run.Max
+	}
+	text := fmt.Sprintf("Continue level %d, %d%% health, %s", run.Level, health, formatTime(run.Elapsed))
+	if run.Daily != "" {
+		text += ", daily challenge"
+	}
+	return text
+}
+
+// persistRun saves the run in progress, or deletes the saved run when
+// given nil, while preserving the other information.
+func (s *Saver) persistRun(run *RunSave) {
+	s.restore()
+	s.Run = run
+	s.persist()
+}
+
+// RunSave
+// ===========================================================================
+// game run saving.
+
+// saveRun saves the current run so that it can be continued later.
+// Nothing is saved between completing a level and evolving.
+func (g *game) saveRun() {
+	if g.cl == nil || g.coop != nil || g.practice || g.summary != nil {
+		return
+	}
+	health, _, max := g.cl.player.health()
+	run := &RunSave{Level: g.cl.num, Health: health, Max: max,
+		Elapsed: g.elapsed, Started: g.started,
+		Splits: append([]float64{}, g.splits...),
+		Points: g.ups.points, Ranks: append([]int{}, g.ups.ranks[:]...),
+		Mutators: map[string]bool{}, Start: g.mp.launchLevel, Maze: g.mp.launchMaze, Seed: g.seed,
+		Trooper: g.cl.player.snapshot()}
+	for id, on := range gameMutators {
+		run.Mutators[id] = on
+	}
+	if g.daily != nil {
+		run.Daily = g.daily.day
+	}
+	newSaver().persistRun(run)
+}
+
+// endRun deletes the saved run once the run is finished or quit.
+func (g *game) endRun() { newSaver().persistRun(nil) }
+
+// continueRun restarts a saved run on the level where it was saved.
+func (g *game) continueRun(run *RunSave) {
+	g.mp.launchLevel, g.mp.launchMaze = run.Start, run.Maze
+	if run.Seed != 0 {
+		g.seed = run.Seed
+	}
+	g.newGame(run.Daily != "", run.Mutators)
+	g.practice = false
+	g.elapsed = run.Elapsed
+	g.splits = append([]float64{}, run.Splits...)
+	g.ups.points = run.Points
+	copy(g.ups.ranks[:], run.Ranks)
+	g.setLevel(run.Level)
+	g.started = run.Started
+	if !g.cl.player.restore(run.Trooper) {
+		g.cl.player.setHealth(run.Health) // older saves only have the cell count.
+	}
+	g.saveRun()
+}
@@ -0,0 +1,29 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunSave(t *testing.T) {
+	now := time.Now()
+	var none *RunSave
+	if none.canContinue(now) {
+		t.Error("Expected no run to continue")
+	}
+	run := &RunSave{Level: 2, Health: 30, Max: 120, Elapsed: 125}
+	if !run.canContinue(now) {
+		t.Error("Expected a regular run to continue")
+	}
+	if got := run.describe(); got != "Continue level 2, 25% health, 2:05" {
+		t.Errorf("Unexpected run description %q", got)
+	}
+	run.Daily = newChallenge(now.AddDate(0, 0, -1)).day
+	if run.canContinue(now) || !strings.HasSuffix(run.describe(), "daily challenge") {
+		t.Errorf("Expected yesterday's daily run to expire")
+	}
+}
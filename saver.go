@@ -20,6 +20,54 @@ type Saver struct {
 	X, Y, W, H int    // Window location.
 	Mute       bool   // True if the game is muted.
 	Full       bool   // True if the game is fullscreen.
+
+	// Opts are the optional features that can be turned on or off
+	// from the options screen.
+	Opts map[string]bool
+
+	// Daily is the date of the most recently finished daily challenge
+	// and DailyBest is the fastest time, in seconds, for that date.
+	Daily     string
+	DailyBest float64
+
+	// Bests are the fastest finish times, in seconds, for games started
+	// from the first level, keyed by the mutators that were active.
+	// No mutators is the empty key.
+	Bests map[string]float64
+
+	// Levels are the fastest completion times, in seconds, for each
+	// level of a regular game. Zero for levels that were not completed.
+	Levels []float64
+
+	// Unlocked is the highest starting level available when the optional
+	// level progression is turned on.
+	Unlocked int
+
+	// Splits are the run times, in seconds, when each level was completed
+	// in the fastest regular run from the first level.
+	Splits []float64
+
+	// Rumble is the game controller rumble intensity.
+	Rumble string
+
+	// Backdrop is the name of the launch screen backdrop theme.
+	Backdrop string
+
+	// Colours is the name of the trooper colour scheme.
+	Colours string
+
+	// Crash is the crash report file from the last time the game
+	// crashed. It is cleared once the player has been told.
+	Crash string
+
+	// Seen is the game version whose changes were last shown.
+	Seen string
+
+	// Crosshair is the crosshair shape, size, and colour.
+	Crosshair []string
+
+	// Run is the run in progress. Nil if there is no run to continue.
+	Run *RunSave
 }
 
 // newSaver creates default persistent application state. The directory
@@ -30,6 +78,8 @@ type Saver struct {
 func newSaver() *Saver {
 	s := &Saver{}
 	s.Kbinds = []int{}
+	s.Opts = map[string]bool{}
+	s.Bests = map[string]float64{}
 	dir := s.directoryLocation()
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		dir = ""
@@ -66,6 +116,125 @@ func (s *Saver) persistMute(isMuted bool) {
 	s.persist()
 }
 
+// persistCrash saves the latest crash report file name while
+// preserving the other information.
+func (s *Saver) persistCrash(file string) {
+	s.restore()
+	s.Crash = file
+	s.persist()
+}
+
+// persistBackdrop saves the launch screen backdrop theme while
+// preserving the other information.
+func (s *Saver) persistBackdrop(name string) {
+	s.restore()
+	s.Backdrop = name
+	s.persist()
+}
+
+// persistColours saves the trooper colour scheme while preserving
+// the other information.
+func (s *Saver) persistColours(name string) {
+	s.restore()
+	s.Colours = name
+	s.persist()
+}
+
+// persistRumble saves the rumble intensity while preserving
+// the other information.
+func (s *Saver) persistRumble(setting string) {
+	s.restore()
+	s.Rumble = setting
+	s.persist()
+}
+
+// persistOption saves an optional feature setting while preserving
+// the other information.
+func (s *Saver) persistOption(id string, on bool) {
+	s.restore()
+	if s.Opts == nil {
+		s.Opts = map[string]bool{}
+	}
+	s.Opts[id] = on
+	s.persist()
+}
+
+// persistCrosshair saves the crosshair style while preserving
+// the other information.
+func (s *Saver) persistCrosshair(style []string) {
+	s.restore()
+	s.Crosshair = style
+	s.persist()
+}
+
+// persistSeen saves the game version whose changes were shown,
+// while preserving the other information.
+func (s *Saver) persistSeen(version string) {
+	s.restore()
+	s.Seen = version
+	s.persist()
+}
+
+// persistDaily saves a finished daily challenge time if it is the
+// best time for the day, while preserving the other information.
+func (s *Saver) persistDaily(day string, secs float64) {
+	s.restore()
+	if s.Daily != day || secs < s.DailyBest {
+		s.Daily, s.DailyBest = day, secs
+		s.persist()
+	}
+}
+
+// persistBest saves a game finish time if it is the best time for the
+// given mutators, while preserving the other information.
+func (s *Saver) persistBest(mutators string, secs float64) {
+	s.restore()
+	if s.Bests == nil {
+		s.Bests = map[string]float64{}
+	}
+	if best, ok := s.Bests[mutators]; !ok || secs < best {
+		s.Bests[mutators] = secs
+		s.persist()
+	}
+}
+
+// persistLevel saves a level completion time if it is the best time for
+// the level, while preserving the other information.
+func (s *Saver) persistLevel(lvl int, secs float64) {
+	s.restore()
+	for len(s.Levels) <= lvl {
+		s.Levels = append(s.Levels, 0)
+	}
+	if s.Levels[lvl] == 0 || secs < s.Levels[lvl] {
+		s.Levels[lvl] = secs
+		s.persist()
+	}
+}
+
+// persistUnlock saves a newly unlocked starting level, while preserving
+// the other information.
+func (s *Saver) persistUnlock(lvl int) {
+	s.restore()
+	if lvl > s.Unlocked {
+		s.Unlocked = lvl
+		s.persist()
+	}
+}
+
+// persistSplits saves the level splits of a finished run if the run was
+// faster than the best run, while preserving the other information.
+func (s *Saver) persistSplits(splits []float64) {
+	s.restore()
+	if len(splits) == 0 {
+		return
+	}
+	total := splits[len(splits)-1]
+	if len(s.Splits) == 0 || total < s.Splits[len(s.Splits)-1] {
+		s.Splits = append([]float64{}, splits...)
+		s.persist()
+	}
+}
+
 // persist is called to record any user preferences. This is expected
 // to be called when a user preference changes.
 func (s *Saver) persist() {
@@ -7,7 +7,7 @@ import (
 	"os"
 	"testing"
 
-    "github.com/gazed/vu"
+	"github.com/gazed/vu"
 )
 
 func TestSaveRestore(t *testing.T) {
@@ -33,3 +33,59 @@ func TestSaveRestore(t *testing.T) {
 	// cleanup
 	os.Remove(file)
 }
+
+func TestPersistLevel(t *testing.T) {
+	file := "gob"
+	s1 := newSaver()
+	s1.File = file
+	s1.persistLevel(2, 90)
+	s1.persistLevel(2, 120) // slower times are not saved.
+	s2 := newSaver()
+	s2.File = file
+	s2.restore()
+	if len(s2.Levels) != 3 || s2.Levels[2] != 90 || s2.Levels[0] != 0 {
+		t.Errorf("Expected best time 90 for level 2, got %v", s2.Levels)
+	}
+	os.Remove(file)
+}
+
+func TestPersistUnlock(t *testing.T) {
+	file := "gob"
+	s1 := newSaver()
+	s1.File = file
+	s1.persistUnlock(3)
+	s1.persistUnlock(1) // unlocked levels stay unlocked.
+	s2 := newSaver()
+	s2.File = file
+	s2.restore()
+	if s2.Unlocked != 3 {
+		t.Errorf("Expected 3, got %d", s2.Unlocked)
+	}
+	os.Remove(file)
+}
+
+func TestPersistSplits(t *testing.T) {
+	file := "gob"
+	s1 := newSaver()
+	s1.File = file
+	s1.persistSplits([]float64{30, 70, 150})
+	s1.persistSplits([]float64{20, 60, 160}) // slower runs are not saved.
+	s2 := newSaver()
+	s2.File = file
+	s2.restore()
+	if len(s2.Splits) != 3 || s2.Splits[2] != 150 {
+		t.Errorf("Expected best run splits, got %v", s2.Splits)
+	}
+	os.Remove(file)
+}
+
+func TestSavedKeys(t *testing.T) {
+	old := []int{vu.KI, vu.KK, vu.KJ, vu.KL, vu.KC, vu.KT} // saved before quick turn.
+	keys := savedKeys(old)
+	if len(keys) != len(defaultKeys()) || keys[0] != vu.KI || keys[6] != vu.KQ || keys[7] != vu.KX {
+		t.Errorf("Expected old bindings with the default quick turn and descend, got %v", keys)
+	}
+	if keys = savedKeys(nil); keys[0] != vu.KW {
+		t.Errorf("Expected default bindings, got %v", keys)
+	}
+}
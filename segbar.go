@@ -0,0 +1,169 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// The segmented bar is the HUD building block for values that run from
+// empty to full, such as health and energy. Gains ease into the bar over
+// a few ticks. Large losses leave a pale ghost of the lost amount that
+// lingers for a moment before draining away, so the size of a hit is
+// easy to see.
+
+import (
+	"math"
+
+	"github.com/gazed/vu"
+)
+
+// segbar is a horizontal bar made up of equal segments.
+type segbar struct {
+	x, y   float64   // Bar center in pixels.
+	w, h   float64   // Bar size in pixels.
+	bgs    []*vu.Ent // Segment backgrounds.
+	ghosts []*vu.Ent // Segment damage ghosts.
+	fills  []*vu.Ent // Segment fills.
+	target float64   // Latest value from 0 to 1.
+	fill   float64   // Shown value, easing towards the target.
+	ghost  float64   // Shown value before the last large loss.
+	hold   int       // Game ticks until the ghost starts to drain.
+}
+
+// Segmented bar tuning.
+const (
+	segGap    = 2.0  // Pixels between segments.
+	segEase   = 0.2  // Fraction of a gain shown each game tick.
+	segDamage = 0.02 // Smallest loss, as a fraction of the bar, that leaves a ghost.
+	segHold   = 30   // Game ticks the ghost waits before draining.
+	segDrain  = 0.01 // Ghost drained each game tick as a fraction of the bar.
+)
+
+// newSegbar creates a bar with the given number of segments. The fill
+// segments use the first texture and can be switched to the second
+// texture, for example to show a warning.
+func newSegbar(scene *vu.Ent, segments int, tex1, tex2 string) *segbar {
+	sb := &segbar{}
+	for cnt := 0; cnt < segments; cnt++ {
+		bg := scene.AddPart()
+		bg.MakeModel("colored", "msh:square", "mat:tgray")
+		ghost := scene.AddPart()
+		ghost.MakeModel("colored", "msh:square", "mat:white").SetAlpha(0.5)
+		fill := scene.AddPart()
+		fill.MakeModel("textured", "msh:icon", "tex:"+tex1, "tex:"+tex2)
+		sb.bgs = append(sb.bgs, bg)
+		sb.ghosts = append(sb.ghosts, ghost)
+		sb.fills = append(sb.fills, fill)
+	}
+	return sb
+}
+
+// place centers the bar at the given pixel location with the given size.
+func (sb *segbar) place(x, y, w, h float64) {
+	sb.x, sb.y, sb.w, sb.h = x, y, w, h
+	segw := sb.segWidth()
+	left := x - w*0.5
+	for cnt, bg := range sb.bgs {
+		bg.SetAt(left+float64(cnt)*(segw+segGap)+segw*0.5, y, 0)
+		bg.SetScale(segw*0.5, h*0.5, 1)
+	}
+	sb.draw()
+}
+
+// set changes the bar value, from 0 for empty to 1 for full.
+// Losses are shown right away while gains ease in.
+func (sb *segbar) set(value float64) {
+	value = clamp(value, 0, 1)
+	if value < sb.fill {
+		if sb.fill-value >= segDamage {
+			if sb.ghost < sb.fill {
+				sb.ghost = sb.fill
+			}
+			sb.hold = segHold
+		}
+		sb.fill = value
+	}
+	sb.target = value
+	sb.draw()
+}
+
+// reset shows the current value right away without any animation.
+func (sb *segbar) reset() {
+	sb.fill, sb.ghost, sb.hold = sb.target, sb.target, 0
+	sb.draw()
+}
+
+// update is called each game tick to ease in gains and drain the ghost.
+func (sb *segbar) update() {
+	if sb.fill == sb.target && sb.ghost <= sb.fill {
+		return
+	}
+	if sb.fill < sb.target {
+		if sb.fill += (sb.target - sb.fill) * segEase; sb.target-sb.fill < 0.001 {
+			sb.fill = sb.target
+		}
+	}
+	switch {
+	case sb.hold > 0:
+		sb.hold--
+	case sb.ghost > sb.fill:
+		sb.ghost -= segDrain
+	}
+	sb.draw()
+}
+
+// setFirst switches the fill segments between their two textures.
+func (sb *segbar) setFirst(tex string) {
+	for _, fill := range sb.fills {
+		fill.SetFirst(tex)
+	}
+}
+
+// setColor colours the segment backgrounds.
+func (sb *segbar) setColor(r, g, b float64) {
+	for _, bg := range sb.bgs {
+		bg.SetColor(r, g, b)
+	}
+}
+
+// over returns true if the given pixel location is on the bar.
+func (sb *segbar) over(mx, my int) bool {
+	return math.Abs(float64(mx)-sb.x) <= sb.w*0.5 && math.Abs(float64(my)-sb.y) <= sb.h*0.5
+}
+
+// segWidth returns the width in pixels of one segment.
+func (sb *segbar) segWidth() float64 {
+	segments := float64(len(sb.bgs))
+	return (sb.w - segGap*(segments-1)) / segments
+}
+
+// draw sizes the fill and ghost of each segment to match the shown values.
+func (sb *segbar) draw() {
+	segments := float64(len(sb.fills))
+	segw := sb.segWidth()
+	left := sb.x - sb.w*0.5
+	for cnt := range sb.fills {
+		start := left + float64(cnt)*(segw+segGap)
+		sb.drawPart(sb.fills[cnt], start, segw*clamp(sb.fill*segments-float64(cnt), 0, 1))
+		sb.drawPart(sb.ghosts[cnt], start, segw*clamp(sb.ghost*segments-float64(cnt), 0, 1))
+	}
+}
+
+// drawPart sizes one segment fill or ghost starting at the left edge
+// of the segment. Empty parts are hidden.
+func (sb *segbar) drawPart(part *vu.Ent, start, width float64) {
+	part.Cull(width <= 0)
+	if width > 0 {
+		part.SetAt(start+width*0.5, sb.y, 0)
+		part.SetScale(width*0.5, sb.h*0.5, 1)
+	}
+}
+
+// clamp limits the given value to the given range.
+func clamp(value, low, high float64) float64 {
+	switch {
+	case value < low:
+		return low
+	case value > high:
+		return high
+	}
+	return value
+}
@@ -0,0 +1,45 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestSegbarGhost(t *testing.T) {
+	sb := &segbar{} // no segments, only the values are checked.
+	sb.set(1)
+	sb.reset()
+	sb.set(0.5)
+	if sb.fill != 0.5 || sb.ghost != 1 {
+		t.Fatalf("Expected an immediate loss with a ghost, got %f %f", sb.fill, sb.ghost)
+	}
+	for cnt := 0; cnt < segHold; cnt++ {
+		sb.update()
+	}
+	if sb.ghost != 1 {
+		t.Errorf("Expected the ghost to wait before draining, got %f", sb.ghost)
+	}
+	sb.update()
+	if sb.ghost >= 1 {
+		t.Errorf("Expected the ghost to drain, got %f", sb.ghost)
+	}
+	sb.set(0.495) // small losses, like cloak drain, leave no ghost.
+	if sb.hold != 0 {
+		t.Errorf("Expected no new ghost for a small loss")
+	}
+	sb.set(1)
+	sb.update()
+	if sb.fill <= 0.495 || sb.fill >= 1 {
+		t.Errorf("Expected a gain to ease in, got %f", sb.fill)
+	}
+}
+
+func TestSegbarOver(t *testing.T) {
+	sb := &segbar{}
+	sb.place(100, 40, 60, 10)
+	if !sb.over(71, 44) || sb.over(69, 40) || sb.over(100, 46) {
+		t.Errorf("Expected the bar to cover 70-130 by 35-45")
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"math"
 	"math/rand"
 
+	"github.com/gazed/bampf/gridmath"
 	"github.com/gazed/vu"
 	"github.com/gazed/vu/grid"
 )
@@ -21,101 +22,234 @@ import (
 // sentinel tracks and moves one player enemy. The maze position information
 // is kept as x,y grid spots.
 type sentinel struct {
-	part   *vu.Ent   // Top level for model transforms.
-	model  *vu.Ent   // Simple model for initial levels.
-	center *vu.Ent   // Add some difference for later levels.
-	prev   *gridSpot // Sentinels previous location.
-	next   *gridSpot // Sentinels next location.
-	units  float64   // Maze scale factor
+	part   *vu.Ent        // Top level for model transforms.
+	model  *vu.Ent        // Simple model for initial levels.
+	center *vu.Ent        // Add some difference for later levels.
+	prev   *gridmath.Spot // Sentinels previous location.
+	next   *gridmath.Spot // Sentinels next location.
+	units  float64        // Maze scale factor
+	speed  float64        // Ticks to move one grid spot, higher is slower.
+	active bool           // Inactive sentinels are hidden until spawned.
+	immune int            // Ticks left where the sentinel ignores the player.
+	frozen bool           // Frozen sentinels stay in place.
+	stun   int            // Ticks left where a stunned sentinel stays in place.
+	lag    int            // Ticks of movement not yet applied to a distant sentinel.
+	moves  *rand.Rand     // Movement randomness shared by the level sentinels.
 }
 
-// newSentinel creates a player enemy.
-func newSentinel(part *vu.Ent, level, units int, fade float64) *sentinel {
-	s := &sentinel{}
+// sentinelSpeed is the normal number of ticks for a sentinel to move
+// from one grid spot to the next.
+const sentinelSpeed = 25
+
+// newSentinel creates a player enemy that picks its
+// way through the maze using the given randomness.
+func newSentinel(part *vu.Ent, level, units int, fade float64, moves *rand.Rand) *sentinel {
+	s := &sentinel{moves: moves}
 	s.part = part
 	s.units = float64(units)
+	s.speed = sentinelSpeed
 	s.part.SetAt(0, 0.5, 0)
 	if level > 0 {
 		s.center = s.part.AddPart().SetScale(0.125, 0.125, 0.125)
 		m := s.center.MakeModel("flata", "msh:cube", "mat:tred")
+		trackAsset(m, "mat:tred")
 		m.SetUniform("fd", fade)
 	}
 	s.model = part.AddPart()
 	m := s.model.MakeModel("flata", "msh:cube", "mat:tblue")
+	trackAsset(m, "mat:tblue")
 	m.SetUniform("fd", fade)
 	return s
 }
 
 // move adjusts the sentinels current position according to the movement algorithm.
-// The sentry gets moved a little closer to its next spot. If its at the next spot,
-// then it gets a new spot to move to.
-func (s *sentinel) move(plan grid.Grid) {
-	speed := float64(25) // higher is slower
+// Sentinels far from the player are only moved every few ticks, catching up
+// on the missed ticks in one step so that they follow the same path as
+// nearby sentinels.
+func (s *sentinel) move(plan grid.Grid, far bool) {
+	if s.immune > 0 {
+		s.immune--
+	}
+	if s.stun > 0 {
+		if s.stun--; s.stun == 0 {
+			s.setFrozen(s.frozen) // restore the colour.
+		}
+	}
+	if s.frozen || s.stun > 0 {
+		s.lag = 0
+		return
+	}
+	if s.lag++; far && s.lag < farTicks {
+		return
+	}
 	gamex, gamey, gamez := s.part.At()
 	inv := float64(1) / float64(s.units)
-	gridfx, gridfy := gamex*inv, -gamez*inv
-	atx := math.Abs(float64(gridfx-float64(s.next.x))) < 0.001
-	atz := math.Abs(float64(gridfy-float64(s.next.y))) < 0.001
-	if atx && atz {
+	gridfx, gridfy := s.advance(gamex*inv, -gamez*inv, plan, s.lag)
+	s.lag = 0
+	s.part.SetAt(gridfx*float64(s.units), gamey, -gridfy*float64(s.units))
+}
 
-		// arrived at next spot... get a new one.
-		s.prev, s.next = s.next, s.nextSpot(plan)
-	} else {
+// farTicks is how often sentinels far from the player are moved.
+const farTicks = 5
+
+// skip moves the sentinel the given number of ticks at once. Used for
+// sentinels on levels that are not being played.
+func (s *sentinel) skip(plan grid.Grid, ticks int) {
+	s.lag += ticks - 1
+	s.move(plan, false)
+}
 
-		// move a bit closer to the next spot.
-		if !atx {
-			gridfx += float64(s.next.x-s.prev.x) / speed
+// advance moves the given fractional grid location the given number of
+// ticks closer to the sentinels next spot. Each time it reaches the next
+// spot, a tick is used to get a new spot to move to. The ticks between
+// spots are applied in one step. The updated fractional grid location
+// is returned.
+func (s *sentinel) advance(gridfx, gridfy float64, plan grid.Grid, ticks int) (float64, float64) {
+	speed := s.speed
+	for ticks > 0 {
+		tox := ticksTo(float64(s.next.X)-gridfx, speed)
+		toz := ticksTo(float64(s.next.Y)-gridfy, speed)
+		if tox == 0 && toz == 0 {
+
+			// arrived at next spot... get a new one.
+			s.prev, s.next = s.next, s.nextSpot(plan)
+			ticks--
+			continue
+		}
+
+		// move closer to the next spot.
+		if tox > ticks {
+			tox = ticks
 		}
-		if !atz {
-			gridfy += float64(s.next.y-s.prev.y) / speed
+		if toz > ticks {
+			toz = ticks
+		}
+		gridfx += float64(tox*(s.next.X-s.prev.X)) / speed
+		gridfy += float64(toz*(s.next.Y-s.prev.Y)) / speed
+		if tox > toz {
+			ticks -= tox
+		} else {
+			ticks -= toz
 		}
 	}
-	s.part.SetAt(gridfx*float64(s.units), gamey, -gridfy*float64(s.units))
+	return gridfx, gridfy
+}
+
+// ticksTo returns the number of ticks needed to cover the given grid
+// distance at the given speed. Zero is returned for distances close
+// enough to count as arrived.
+func ticksTo(dist, speed float64) int {
+	if dist = math.Abs(dist); dist < 0.001 {
+		return 0
+	}
+	return int((dist-0.001)*speed) + 1
 }
 
 // setGridAt puts the sentinel down at the given grid location.
 func (s *sentinel) setGridAt(gridx, gridy int) {
-	s.prev = &gridSpot{gridx, gridy}
-	s.next = &gridSpot{gridx, gridy}
+	s.lag = 0
+	s.prev = &gridmath.Spot{X: gridx, Y: gridy}
+	s.next = &gridmath.Spot{X: gridx, Y: gridy}
 	_, gamey, _ := s.part.At()
-	gamex, gamez := toGame(gridx, gridy, s.units)
+	gamex, gamez := gridmath.ToGame(gridx, gridy, s.units)
 	s.part.SetAt(gamex, gamey, gamez)
 }
 
+// setActive shows and enables the sentinel or hides and disables it.
+func (s *sentinel) setActive(active bool) {
+	s.active = active
+	s.part.Cull(!active)
+}
+
+// setFrozen stops or restarts the sentinel. Frozen sentinels are dimmed
+// to half the tblue material colour.
+func (s *sentinel) setFrozen(frozen bool) {
+	s.frozen = frozen
+	if frozen {
+		s.model.SetColor(0.075, 0.275, 0.41)
+	} else {
+		s.model.SetColor(0.15, 0.55, 0.82)
+	}
+}
+
+// stunFor stops the sentinel for the given number of ticks. Stunned
+// sentinels are dimmed like frozen sentinels.
+func (s *sentinel) stunFor(ticks int) {
+	s.stun = ticks
+	s.model.SetColor(0.075, 0.275, 0.41)
+}
+
 // location gets the sentinels current location.
 func (s *sentinel) location() (x, y, z float64) { return s.part.At() }
 
+// isActive returns true for spawned sentinels.
+func (s *sentinel) isActive() bool { return s.active }
+
+// onPlayerCollide knocks the player back on knockback levels. Otherwise
+// the sentinel is moved outside the maze so that the collision doesn't
+// happen again. After a knockback the player and the sentinel ignore
+// all sentinels and the player respectively for a while.
+func (s *sentinel) onPlayerCollide(lvl *level) bool {
+	if s.immune > 0 {
+		return false
+	}
+	if gameKnocks(lvl.num) {
+		s.immune = knockbackTicks
+		if lvl.player.grace < knockbackTicks {
+			lvl.player.grace = knockbackTicks
+		}
+		lvl.knockback(s)
+		return true
+	}
+	x, y, z := lvl.cam.At()
+	pgx, pgy := gridmath.ToGrid(x, y, z, float64(lvl.units))
+	safex, safey := lvl.plan.Size() // top right corner.
+	s.setGridAt(safex, safey)
+	if pgx == safex && pgy == safey {
+		s.setGridAt(-1, -1) // bottom left corner.
+	}
+	return true
+}
+
+// minimapStyle fades the markers of stunned sentinels.
+func (s *sentinel) minimapStyle() markerStyle {
+	if s.stun > 0 {
+		return fadedMarker
+	}
+	return solidMarker
+}
+
 // setScale changes the sentinels size.
 func (s *sentinel) setScale(scale float64) { s.model.SetScale(scale, scale, scale) }
 
 // nextSpot picks where the sentinel will be going to by considering
 // all the surrounding spaces and picking from the valid ones.
-func (s *sentinel) nextSpot(plan grid.Grid) *gridSpot {
+func (s *sentinel) nextSpot(plan grid.Grid) *gridmath.Spot {
 	at := s.next
 	was := s.prev
 	w, h := plan.Size()
 
 	// using knowledge that the grid starts at 0, 0 and goes to size, -size.
 	// and that the outside border is also valid.
-	choices := []*gridSpot{}
-	if at.x >= -1 && at.y >= -1 && at.x <= w && at.y <= h {
-		if s.isValidSpot(plan, w, h, was, at.x+1, at.y) {
-			choices = append(choices, &gridSpot{at.x + 1, at.y})
+	choices := []*gridmath.Spot{}
+	if at.X >= -1 && at.Y >= -1 && at.X <= w && at.Y <= h {
+		if s.isValidSpot(plan, w, h, was, at.X+1, at.Y) {
+			choices = append(choices, &gridmath.Spot{X: at.X + 1, Y: at.Y})
 		}
-		if s.isValidSpot(plan, w, h, was, at.x-1, at.y) {
-			choices = append(choices, &gridSpot{at.x - 1, at.y})
+		if s.isValidSpot(plan, w, h, was, at.X-1, at.Y) {
+			choices = append(choices, &gridmath.Spot{X: at.X - 1, Y: at.Y})
 		}
-		if s.isValidSpot(plan, w, h, was, at.x, at.y+1) {
-			choices = append(choices, &gridSpot{at.x, at.y + 1})
+		if s.isValidSpot(plan, w, h, was, at.X, at.Y+1) {
+			choices = append(choices, &gridmath.Spot{X: at.X, Y: at.Y + 1})
 		}
-		if s.isValidSpot(plan, w, h, was, at.x, at.y-1) {
-			choices = append(choices, &gridSpot{at.x, at.y - 1})
+		if s.isValidSpot(plan, w, h, was, at.X, at.Y-1) {
+			choices = append(choices, &gridmath.Spot{X: at.X, Y: at.Y - 1})
 		}
 	}
 	if len(choices) > 0 {
 		way := 0
 		if len(choices) > 1 {
-			way = rand.Intn(len(choices))
+			way = s.moves.Intn(len(choices))
 		}
 		return choices[way]
 	}
@@ -124,8 +258,8 @@ func (s *sentinel) nextSpot(plan grid.Grid) *gridSpot {
 
 // isValidSpot checks that a spot is valid for a sentinel, i.e. not a wall or the
 // previous location.
-func (s *sentinel) isValidSpot(plan grid.Grid, w, h int, old *gridSpot, x, y int) bool {
-	if x == old.x && y == old.y { // can't use previous position.
+func (s *sentinel) isValidSpot(plan grid.Grid, w, h int, old *gridmath.Spot, x, y int) bool {
+	if x == old.X && y == old.Y { // can't use previous position.
 		return false
 	}
 	if x >= 0 && y >= 0 && x < w && y < h { // exclude walls.
@@ -136,3 +270,159 @@ func (s *sentinel) isValidSpot(plan grid.Grid, w, h int, old *gridSpot, x, y int
 	}
 	return false // anywhere else is a no-go zone.
 }
+
+// fade shrinks and dissolves the sentinel where 1 is the normal size
+// and transparency and 0 is gone.
+func (s *sentinel) fade(amount float64) {
+	size := math.Max(amount, 0.01) // avoid a zero scale.
+	s.part.SetScale(size, size, size)
+	s.model.SetAlpha(sentinelAlpha * amount)
+	if s.center != nil {
+		s.center.SetAlpha(sentinelAlpha * amount)
+	}
+}
+
+// sentinelAlpha is the transparency of the sentinel materials.
+const sentinelAlpha = 0.3
+
+// sentinel
+// ===========================================================================
+// sentinelFadeAnimation
+
+// sentinelFadeAnimation dissolves the active sentinels as a level fades
+// out and brings them back as a level fades in.
+type sentinelFadeAnimation struct {
+	sentries []*sentinel // Sentinels to fade. Inactive sentinels are ignored.
+	in       bool        // True to bring the sentinels in.
+	ticks    int         // Animation length in game ticks.
+	tickCnt  int         // Current step.
+	state    int         // Track animation progress 0:start, 1:run, 2:done.
+}
+
+// newSentinelFadeAnimation fades the given sentinels in or out over
+// the given number of game ticks.
+func newSentinelFadeAnimation(sentries []*sentinel, in bool, ticks int) animation {
+	return &sentinelFadeAnimation{sentries: sentries, in: in, ticks: ticks}
+}
+
+// Animate implements animation. Fade the sentinels.
+func (sa *sentinelFadeAnimation) Animate(dt float64) bool {
+	switch sa.state {
+	case 0:
+		sa.fade(0)
+		sa.state = 1
+		return true
+	case 1:
+		if sa.tickCnt >= sa.ticks {
+			sa.Wrap()
+			return false // animation done.
+		}
+		sa.tickCnt++
+		sa.fade(float64(sa.tickCnt) / float64(sa.ticks))
+		return true
+	default:
+		return false // animation done.
+	}
+}
+
+// Wrap implements animation. Leave the sentinels fully in or out.
+func (sa *sentinelFadeAnimation) Wrap() {
+	sa.fade(1)
+	sa.state = 2
+}
+
+// fade applies the animation progress, from 0 to 1, to the sentinels.
+func (sa *sentinelFadeAnimation) fade(ratio float64) {
+	if !sa.in {
+		ratio = 1 - ratio
+	}
+	for _, sentry := range sa.sentries {
+		if sentry.active {
+			sentry.fade(ratio)
+		}
+	}
+}
+
+// sentinelFadeAnimation
+// ===========================================================================
+// spawner
+
+// spawner releases a levels sentinels in timed waves. Each wave starts
+// from the next spawn point around the maze perimeter so that sentinels
+// are spread out instead of starting in one big group. Spawn points too
+// close to the player are skipped.
+type spawner struct {
+	size   int             // Number of sentinels released each wave.
+	delay  int             // Game ticks between waves.
+	ticks  int             // Game ticks until the next wave.
+	points []gridmath.Spot // Spawn locations around the maze perimeter.
+	next   int             // Index of the next spawn point.
+}
+
+// newSpawner creates a spawner for the given floorplan that releases
+// waves of size sentinels every delay game ticks.
+func newSpawner(plan grid.Grid, size, delay int) *spawner {
+	sp := &spawner{size: size, delay: delay}
+	w, h := plan.Size()
+	sp.points = []gridmath.Spot{
+		{X: -1, Y: -1}, {X: w / 2, Y: h}, {X: w, Y: -1}, {X: -1, Y: h / 2},
+		{X: w, Y: h}, {X: w / 2, Y: -1}, {X: -1, Y: h}, {X: w, Y: h / 2},
+	}
+	return sp
+}
+
+// Sentinel spawn safety tuning.
+const (
+	spawnSafety = 3  // Fewest grid steps between the player and a spawning sentinel.
+	spawnGrace  = 50 // Game ticks before the first wave after entering a level.
+)
+
+// spawn activates the next wave of inactive sentinels when it is time.
+// The wave waits while every spawn point is close to the player at
+// grid location px, py. Returns the number of sentinels that were released.
+func (sp *spawner) spawn(sentries []*sentinel, px, py int) (released int) {
+	if sp.ticks > 0 {
+		sp.ticks--
+		return 0
+	}
+	if !sp.skipUnsafe(px, py) {
+		return 0
+	}
+	for _, sentry := range sentries {
+		if released >= sp.size {
+			break
+		}
+		if !sentry.active {
+			spot := sp.points[sp.next]
+			sentry.setGridAt(spot.X, spot.Y)
+			sentry.setActive(true)
+			released++
+		}
+	}
+	if released > 0 {
+		sp.next = (sp.next + 1) % len(sp.points)
+	}
+	sp.ticks = sp.delay
+	return released
+}
+
+// skipUnsafe moves on to the next spawn point that is at least
+// spawnSafety grid steps from the player. Return false if there is
+// no safe spawn point.
+func (sp *spawner) skipUnsafe(px, py int) bool {
+	for cnt := 0; cnt < len(sp.points); cnt++ {
+		spot := sp.points[sp.next]
+		if abs(spot.X-px) >= spawnSafety || abs(spot.Y-py) >= spawnSafety {
+			return true
+		}
+		sp.next = (sp.next + 1) % len(sp.points)
+	}
+	return false
+}
+
+// holdBack gives the player a short grace period before the next wave.
+func (sp *spawner) holdBack() {
+	if sp.ticks < spawnGrace {
+		sp.ticks = spawnGrace
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Blob shadows are soft dark patches on the floor under the player and
+// under the sentinels near the player. They ground the otherwise flat lit
+// models and make distances easier to judge. Only sentinels within the
+// level fade distance get a shadow, so a small pool of shadows covers
+// even the busiest levels.
+
+import (
+	"github.com/gazed/vu"
+)
+
+// Blob shadow tuning.
+const (
+	shadowPool   = 24    // Most sentinel shadows shown at once.
+	shadowLift   = 0.015 // Shadow height above the floor tiles.
+	shadowAlpha  = 0.45  // Shadow darkness.
+	shadowSize   = 0.5   // Sentinel shadow radius in game units.
+	playerShadow = 0.35  // Player shadow radius in game units.
+)
+
+// shadows are the blob shadow models for one level.
+type shadows struct {
+	player *vu.Ent   // Shadow under the player.
+	pool   []*vu.Ent // Shadows handed out to the nearby sentinels.
+}
+
+// newShadows creates the hidden blob shadows for the level.
+func (lvl *level) newShadows() *shadows {
+	sh := &shadows{}
+	sh.player = lvl.newShadow(playerShadow)
+	for cnt := 0; cnt < shadowPool; cnt++ {
+		sh.pool = append(sh.pool, lvl.newShadow(shadowSize))
+	}
+	return sh
+}
+
+// newShadow creates one hidden blob shadow with the given radius.
+func (lvl *level) newShadow(radius float64) *vu.Ent {
+	blob := lvl.scene.AddPart().SetScale(radius, 1, radius)
+	m := blob.MakeModel("uva", "msh:tile", "tex:blob")
+	trackAsset(m, "tex:blob")
+	m.SetAlpha(shadowAlpha).SetUniform("fd", lvl.fade)
+	blob.Cull(true)
+	return blob
+}
+
+// showShadows puts the blob shadows under the player and the active
+// enemies that are within the fade distance. Unused shadows are hidden.
+func (lvl *level) showShadows() {
+	sh := lvl.shadows
+	x, _, z := lvl.body.At()
+	sh.player.SetAt(x, shadowLift, z)
+	sh.player.Cull(false)
+	used, fadeSq := 0, lvl.fade*lvl.fade
+	for _, foe := range lvl.enemies {
+		if used == len(sh.pool) {
+			break
+		}
+		if foe.isActive() {
+			sx, _, sz := foe.location()
+			if (sx-x)*(sx-x)+(sz-z)*(sz-z) <= fadeSq {
+				sh.pool[used].SetAt(sx, shadowLift, sz)
+				sh.pool[used].Cull(false)
+				used++
+			}
+		}
+	}
+	for ; used < len(sh.pool); used++ {
+		sh.pool[used].Cull(true)
+	}
+}
@@ -0,0 +1,261 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Share codes let players race each other on the same mazes. A share code
+// is made at the end of each run and holds the maze seed, starting level,
+// daily challenge, mutators, and the run result. The code is shown on the
+// end and launch screens, where it can be copied to the clipboard, and
+// written to share.txt in the save directory for platforms without a
+// clipboard. Typing or pasting a code on the launch screen replays the
+// same setup. Custom maze and practice runs, and co-op
+// runs, can't be shared.
+
+import (
+	"errors"
+	"hash/crc32"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gazed/vu"
+)
+
+// Share code tuning.
+const (
+	shareVersion = "1"         // Changes whenever the code layout changes.
+	shareFile    = "share.txt" // Last share code, in the save directory.
+	maxShareSeed = 1 << 31     // Keeps the seed part of the code short.
+	maxShareCode = 40          // Longest share code that can be typed.
+)
+
+// Share code errors shown on the launch screen.
+var (
+	errShareCode  = errors.New("not a share code")
+	errShareCheck = errors.New("share code has a typo")
+	errShareDay   = errors.New("share code is for another daily challenge")
+)
+
+// shareCode is the setup and result of one run.
+type shareCode struct {
+	seed     int64 // Maze seed for the run.
+	start    int   // Starting level.
+	day      int64 // Daily challenge seed, eg: 20160102, zero for regular runs.
+	mutators int   // One bit for each active mutator in gameMutatorIDs.
+	secs     int   // Run time in whole seconds.
+	levels   int   // Levels completed.
+}
+
+// encode returns the share code as dash separated base 36 fields
+// followed by a checksum, eg: 1-K3J9A2-0-0-5-2F-4-1LQ8ZX.
+func (sc shareCode) encode() string {
+	fields := []int64{sc.seed, int64(sc.start), sc.day, int64(sc.mutators), int64(sc.secs), int64(sc.levels)}
+	parts := []string{shareVersion}
+	for _, field := range fields {
+		parts = append(parts, strconv.FormatInt(field, 36))
+	}
+	body := strings.Join(parts, "-")
+	return strings.ToUpper(body + "-" + shareChecksum(body))
+}
+
+// shareChecksum returns a short checksum of the lower case share code body.
+func shareChecksum(body string) string {
+	return strconv.FormatUint(uint64(crc32.ChecksumIEEE([]byte(body))), 36)
+}
+
+// decodeShare parses a share code. Codes are not case sensitive.
+func decodeShare(code string) (sc shareCode, err error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(code)), "-")
+	if len(parts) != 8 || parts[0] != shareVersion {
+		return sc, errShareCode
+	}
+	if shareChecksum(strings.Join(parts[:7], "-")) != parts[7] {
+		return sc, errShareCheck
+	}
+	fields := make([]int64, 6)
+	for cnt := range fields {
+		if fields[cnt], err = strconv.ParseInt(parts[cnt+1], 36, 64); err != nil || fields[cnt] < 0 {
+			return sc, errShareCode
+		}
+	}
+	sc = shareCode{seed: fields[0], start: int(fields[1]), day: fields[2],
+		mutators: int(fields[3]), secs: int(fields[4]), levels: int(fields[5])}
+	if sc.seed <= 0 || sc.seed > maxShareSeed || sc.start >= len(gameMuster) || sc.mutators >= 1<<uint(len(gameMutatorIDs)) {
+		return sc, errShareCode
+	}
+	return sc, nil
+}
+
+// mutatorBits packs the active mutators into share code bits.
+func mutatorBits(mutators map[string]bool) (bits int) {
+	for cnt, id := range gameMutatorIDs {
+		if mutators[id] {
+			bits |= 1 << uint(cnt)
+		}
+	}
+	return bits
+}
+
+// mutatorSet unpacks the share code mutator bits.
+func (sc shareCode) mutatorSet() map[string]bool {
+	mutators := map[string]bool{}
+	for cnt, id := range gameMutatorIDs {
+		if sc.mutators&(1<<uint(cnt)) != 0 {
+			mutators[id] = true
+		}
+	}
+	return mutators
+}
+
+// shareCode
+// ===========================================================================
+// game run sharing.
+
+// shareRun returns the share code for the run that just ended, or
+// the empty string if the run can't be shared. The code is also saved
+// to the share file so that it can be copied.
+func (g *game) shareRun() string {
+	if g.cl == nil || g.coop != nil || g.practice || (g.daily == nil && g.mp.launchMaze != "") {
+		return ""
+	}
+	sc := shareCode{seed: g.seed, start: g.mp.launchLevel, mutators: mutatorBits(gameMutators),
+		secs: int(g.elapsed + 0.5), levels: len(g.splits)}
+	if g.daily != nil {
+		sc.day, sc.start = g.daily.seed, 0
+	}
+	g.mp.shared = sc.encode()
+	file := path.Join(path.Dir(newSaver().File), shareFile)
+	if err := ioutil.WriteFile(file, []byte(g.mp.shared+"\n"), 0644); err != nil {
+		logf("game.shareRun: %s", err)
+	}
+	return g.mp.shared
+}
+
+// replayRun starts a new run on the mazes from a share code.
+// Daily challenge share codes are only accepted on the same day.
+func (g *game) replayRun(sc *shareCode) {
+	g.seed = sc.seed
+	g.newGame(sc.day != 0, sc.mutatorSet())
+	g.setLevel(sc.start)
+}
+
+// game run sharing
+// ===========================================================================
+// launch screen share codes.
+
+// checkShare returns the share code to replay for the typed code.
+func checkShare(code string, now time.Time) (*shareCode, error) {
+	sc, err := decodeShare(code)
+	if err != nil {
+		return nil, err
+	}
+	if sc.day != 0 && sc.day != newChallenge(now).seed {
+		return nil, errShareDay
+	}
+	return &sc, nil
+}
+
+// showShare shows the last share code, or the given note, in the
+// bottom right corner of the launch screen.
+func (l *launch) showShare(note string) {
+	l.copy.SetStr("Copy last share code")
+	l.copy.Cull(l.mp.shared == "")
+	switch {
+	case note != "":
+		l.share.SetStr(note + ". Click to try again.")
+	case l.mp.shared != "":
+		l.share.SetStr("Last run " + l.mp.shared + ". Click to enter a share code.")
+	default:
+		l.share.SetStr("Click to enter a share code.")
+	}
+	l.placeShare()
+}
+
+// placeShare keeps the share code in the bottom right corner
+// with the copy link above it.
+func (l *launch) placeShare() {
+	w, _ := labelSize(scaleLabel(l.share, 1))
+	l.share.SetAt(float64(l.w-20-w), 20, 0)
+	w, _ = labelSize(scaleLabel(l.copy, 1))
+	l.copy.SetAt(float64(l.w-20-w), 20+30*textScale, 0)
+}
+
+// copyCode starts copying the last share code to the clipboard.
+// The result is shown by copiedCode when the copy finishes.
+func (l *launch) copyCode() {
+	if l.copying == nil {
+		l.copying = copyLater(l.mp.clip, l.mp.shared)
+	}
+}
+
+// copiedCode shows whether the share code made it to the clipboard.
+// The share file is the fallback on platforms without a clipboard.
+func (l *launch) copiedCode(copied clipping) {
+	if copied.err != nil && copied.err != errNoClipboard {
+		logf("Failed to copy: %s", copied.err)
+	}
+	if copied.err == nil {
+		l.copy.SetStr("Copied " + copied.text)
+	} else {
+		l.copy.SetStr("No clipboard, see " + shareFile + " in the save folder")
+	}
+	l.placeShare()
+}
+
+// startCode begins typing a share code. The clipboard is read in
+// the background and pasted in by pasteCode when it arrives.
+func (l *launch) startCode() {
+	l.typing, l.code = true, ""
+	l.pasting = pasteLater(l.mp.clip)
+	l.share.SetStr("Share code: " + l.code + "_")
+	l.placeShare()
+}
+
+// pasteCode fills in a share code from the clipboard so that it only
+// needs to be confirmed. Nothing is pasted once typing has started.
+func (l *launch) pasteCode(pasted clipping) {
+	if pasted.err != nil {
+		logf("Failed to paste: %s", pasted.err)
+		return
+	}
+	if !l.typing || l.code != "" || len(pasted.text) > maxShareCode {
+		return
+	}
+	if _, err := decodeShare(pasted.text); err == nil {
+		l.code = strings.ToUpper(pasted.text)
+		l.share.SetStr("Share code: " + l.code + "_")
+		l.placeShare()
+	}
+}
+
+// typeCode adds pressed letters, digits, and dashes to the share code.
+// Returns the typed code and true when return is pressed. Escape cancels
+// typing and returns an empty code.
+func (l *launch) typeCode(ip *inputState) (code string, done bool) {
+	for _, press := range ip.pressedKeys() {
+		switch press {
+		case vu.KRet:
+			l.typing = false
+			return l.code, true
+		case vu.KEsc:
+			l.typing = false
+			return "", true
+		case vu.KDel:
+			if len(l.code) > 0 {
+				l.code = l.code[:len(l.code)-1]
+			}
+		default:
+			r := unicode.ToUpper(vu.Symbol(press))
+			if len(l.code) < maxShareCode && ((r >= 'A' && r <= 'Z') || unicode.IsDigit(r) || r == '-') {
+				l.code += string(r)
+			}
+		}
+	}
+	l.share.SetStr("Share code: " + l.code + "_")
+	l.placeShare()
+	return "", false
+}
@@ -0,0 +1,51 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShareCode(t *testing.T) {
+	sc := shareCode{seed: 123456789, start: 2, mutators: mutatorBits(map[string]bool{fragile: true}), secs: 305, levels: 3}
+	code := sc.encode()
+	got, err := decodeShare(strings.ToLower(code))
+	if err != nil || got != sc {
+		t.Fatalf("Expected %q to decode to %v, got %v %v", code, sc, got, err)
+	}
+	if !got.mutatorSet()[fragile] || len(got.mutatorSet()) != 1 {
+		t.Errorf("Unexpected mutators %v", got.mutatorSet())
+	}
+	typo := strings.Replace(code, "-2-", "-3-", 1)
+	if _, err := decodeShare(typo); err != errShareCheck {
+		t.Errorf("Expected %q to fail the checksum, got %v", typo, err)
+	}
+	if _, err := decodeShare("hello"); err != errShareCode {
+		t.Errorf("Expected a bad share code error, got %v", err)
+	}
+	now := time.Now()
+	sc.day = newChallenge(now.AddDate(0, 0, -1)).seed
+	if _, err := checkShare(sc.encode(), now); err != errShareDay {
+		t.Errorf("Expected yesterday's daily share code to be refused, got %v", err)
+	}
+}
+
+// fixedClipboard is a clipboard that always pastes the same text.
+type fixedClipboard string
+
+func (fc fixedClipboard) copy(text string) error { return nil }
+func (fc fixedClipboard) paste() (string, error) { return string(fc), nil }
+
+func TestPasteLater(t *testing.T) {
+	select {
+	case pasted := <-pasteLater(fixedClipboard("ABC-123")):
+		if pasted.text != "ABC-123" || pasted.err != nil {
+			t.Errorf("Expected the clipboard text, got %v", pasted)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected the clipboard to be read")
+	}
+}
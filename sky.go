@@ -0,0 +1,88 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// The sky dome is a large half sphere that follows the player. It is
+// drawn as stacked rings, each a flat colour, that blend from the
+// background mist colour at the horizon to the theme sky colour overhead.
+// Walls fade into the dome as they fade into the background.
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/gazed/vu"
+)
+
+// Sky dome tuning.
+const (
+	skyRadius   = 40 // Inside the far camera clip.
+	skyRings    = 8  // Colour bands from horizon to the top.
+	skySegments = 16 // Sides around each ring.
+	skyDip      = 10 // Degrees below the horizon covered by the lowest band.
+)
+
+// skyDome holds the dome rings. Rings are ordered from the horizon up.
+type skyDome struct {
+	root  *vu.Ent   // Follows the player.
+	rings []*vu.Ent // One model for each colour band.
+	top   [3]float32
+}
+
+// skyMeshes counts generated sky ring meshes so each has a unique name.
+var skyMeshes int
+
+// newSkyDome creates a sky dome blending to the given top colour.
+func newSkyDome(scene *vu.Ent, top [3]float32) *skyDome {
+	sd := &skyDome{top: top}
+	sd.root = scene.AddPart()
+	step := (90 + skyDip) / float64(skyRings)
+	for cnt := 0; cnt < skyRings; cnt++ {
+		low := -skyDip + float64(cnt)*step
+		skyMeshes++
+		ring := sd.root.AddPart()
+		ring.MakeModel("colored", "mat:white").GenMesh("sky" + strconv.Itoa(skyMeshes))
+		verts, faces := skyRing(low, low+step)
+		msh := ring.Mesh()
+		msh.InitData(0, 3, vu.StaticDraw, false).SetData(0, verts)
+		msh.InitFaces(vu.StaticDraw).SetFaces(faces)
+		sd.rings = append(sd.rings, ring)
+	}
+	return sd
+}
+
+// skyRing returns the vertexes and faces for the dome band between the
+// given elevations in degrees. Faces wind so they are seen from inside.
+func skyRing(low, high float64) (verts []float32, faces []uint16) {
+	lo, hi := low*math.Pi/180, high*math.Pi/180
+	for cnt := 0; cnt <= skySegments; cnt++ {
+		a := float64(cnt) * 2 * math.Pi / skySegments
+		verts = append(verts,
+			float32(skyRadius*math.Cos(lo)*math.Cos(a)), float32(skyRadius*math.Sin(lo)), float32(skyRadius*math.Cos(lo)*math.Sin(a)),
+			float32(skyRadius*math.Cos(hi)*math.Cos(a)), float32(skyRadius*math.Sin(hi)), float32(skyRadius*math.Cos(hi)*math.Sin(a)))
+	}
+	for cnt := uint16(0); cnt < skySegments; cnt++ {
+		bl, tl := cnt*2, cnt*2+1 // this side bottom and top.
+		br, tr := bl+2, tl+2     // next side bottom and top.
+		faces = append(faces, bl, br, tr, bl, tr, tl)
+	}
+	return verts, faces
+}
+
+// follow keeps the dome centered on the player.
+func (sd *skyDome) follow(x, z float64) { sd.root.SetAt(x, 0, z) }
+
+// tint colours the rings from the horizon colour to the top colour.
+func (sd *skyDome) tint(horizon [3]float32) {
+	for cnt, ring := range sd.rings {
+		r, g, b := skyBlend(horizon, sd.top, float32(cnt)/float32(skyRings-1))
+		ring.SetColor(float64(r), float64(g), float64(b))
+	}
+}
+
+// skyBlend mixes the from and to colours where t is 0 for all from
+// and 1 for all to.
+func skyBlend(from, to [3]float32, t float32) (r, g, b float32) {
+	return from[0] + (to[0]-from[0])*t, from[1] + (to[1]-from[1])*t, from[2] + (to[2]-from[2])*t
+}
@@ -0,0 +1,129 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Speech is an accessibility option that reads out important game events.
+// Events are published as speak events and passed to a platform specific
+// speaker. Platforms without a speaker use a quiet speaker.
+//    osx  : see speech_darwin.go
+//    win  : see speech_windows.go
+//    other: see speech_other.go
+
+import (
+	"container/list"
+	"os/exec"
+	"strconv"
+)
+
+// speaker is implemented by the platform text-to-speech adapters.
+type speaker interface {
+	speak(text string) // Read out the text without blocking.
+}
+
+// quietSpeaker is the speaker for platforms without text-to-speech.
+type quietSpeaker struct{}
+
+// speak implements speaker by ignoring the text.
+func (qs quietSpeaker) speak(text string) {}
+
+// spokenLines is the most text waiting to be read out. More text is
+// dropped while the speaker is this far behind.
+const spokenLines = 4
+
+// commandSpeaker reads out text by running a platform speech command for
+// each line. The commands run one at a time on a worker goroutine that
+// waits for each one to finish, so the game loop never blocks and every
+// command is reaped.
+type commandSpeaker struct {
+	lines chan string // Text waiting to be read out.
+	errs  chan error  // Failed commands, logged from the game loop.
+}
+
+// newCommandSpeaker starts the worker that runs the given speech commands.
+func newCommandSpeaker(command func(text string) *exec.Cmd) *commandSpeaker {
+	cs := &commandSpeaker{lines: make(chan string, spokenLines), errs: make(chan error, 1)}
+	go func() {
+		for text := range cs.lines {
+			if err := command(text).Run(); err != nil {
+				select {
+				case cs.errs <- err:
+				default:
+				}
+			}
+		}
+	}()
+	return cs
+}
+
+// speak implements speaker by queuing the text for the worker.
+func (cs *commandSpeaker) speak(text string) {
+	select {
+	case err := <-cs.errs:
+		logf("Failed to speak: %s", err)
+	default:
+	}
+	select {
+	case cs.lines <- text:
+	default:
+	}
+}
+
+// speech passes spoken events to the platform speaker when the
+// speech option is turned on.
+type speech struct {
+	on      bool    // True if events are spoken.
+	speaker speaker // Platform text-to-speech.
+}
+
+// newSpeech returns a disabled speech using the platform speaker.
+func newSpeech() *speech {
+	return &speech{speaker: newSpeaker()}
+}
+
+// say reads out the text if speech is turned on.
+func (s *speech) say(text string) {
+	if s.on && text != "" {
+		s.speaker.speak(text)
+	}
+}
+
+// speech
+// ===========================================================================
+// game announcements
+
+// announced remembers what has been announced for the current level.
+type announced struct {
+	level  int  // Level that was last announced.
+	cores  int  // Core milestones announced on the level.
+	low    bool // True if low cloak energy was announced.
+	alarms int  // Sentinel alarms already announced on the level.
+}
+
+// coreMilestone is the number of collected cores between announcements.
+const coreMilestone = 5
+
+// announce publishes speak events for the important changes since the
+// last call: a new level, core milestones, low cloak energy, and sentinel
+// alarms.
+func (g *game) announce(eventq *list.List) {
+	lvl, said := g.cl, &g.said
+	if said.level != lvl.num {
+		*said = announced{level: lvl.num}
+		publish(eventq, speak, "Level "+strconv.Itoa(lvl.num))
+	}
+	if cores := lvl.fetched / coreMilestone; cores > said.cores {
+		said.cores = cores
+		publish(eventq, speak, strconv.Itoa(lvl.fetched)+" cores")
+	}
+	if low := lvl.player.cloakLow(); low != said.low {
+		said.low = low
+		if low {
+			publish(eventq, speak, "Cloak low")
+		}
+	}
+	if lvl.alarms > said.alarms {
+		said.alarms = lvl.alarms
+		publish(eventq, speak, "Sentinel near")
+	}
+}
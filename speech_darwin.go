@@ -0,0 +1,14 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import "os/exec"
+
+// newSpeaker returns the text-to-speech adapter for OSX
+// using the built in say command.
+func newSpeaker() speaker {
+	return newCommandSpeaker(func(text string) *exec.Cmd {
+		return exec.Command("say", text)
+	})
+}
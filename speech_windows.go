@@ -0,0 +1,24 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// newSpeaker returns the text-to-speech adapter for Windows.
+func newSpeaker() speaker { return newCommandSpeaker(speechCommand) }
+
+// speechCommand reads out the text with the .NET speech synthesizer
+// through PowerShell without showing a console window.
+func speechCommand(text string) *exec.Cmd {
+	script := "Add-Type -AssemblyName System.Speech; " +
+		"(New-Object System.Speech.Synthesis.SpeechSynthesizer).Speak('" +
+		strings.Replace(text, "'", "''", -1) + "')"
+	cmd := exec.Command("powershell", "-NoProfile", "-Command", script)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	return cmd
+}
@@ -0,0 +1,119 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// The optional run timer shows the game time on the HUD along with the
+// split for the last completed level. Splits are compared to the best
+// regular run that started from the first level, and are exported to
+// the save directory when a run ends.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"time"
+)
+
+// split records the game time when a level was completed.
+func (g *game) split() {
+	lvl := g.cl.num
+	for len(g.splits) <= lvl {
+		g.splits = append(g.splits, 0)
+	}
+	g.splits[lvl] = g.elapsed
+	g.lastSplit = fmt.Sprintf("L%d %s", lvl, formatSplit(g.elapsed))
+	if best := g.bestSplits(); lvl < len(best) && best[lvl] > 0 {
+		g.lastSplit += fmt.Sprintf(" (%+.1f)", g.elapsed-best[lvl])
+	}
+}
+
+// comparable returns true for regular runs from the first level.
+// Practice runs are never compared.
+// Only these runs are compared to, and can become, the best run.
+func (g *game) comparable() bool {
+	return !g.practice && g.daily == nil && mutatorKey(gameMutators) == "" &&
+		g.mp.launchMaze == "" && g.mp.launchLevel == 0
+}
+
+// bestSplits returns the splits of the best run if the current run
+// can be compared to it.
+func (g *game) bestSplits() []float64 {
+	if !g.comparable() {
+		return nil
+	}
+	saver := newSaver()
+	saver.restore()
+	return saver.Splits
+}
+
+// finishSplits is called when a run ends. It saves the splits of a new
+// best run and, when the run timer is on, exports the run splits.
+func (g *game) finishSplits() {
+	best := g.bestSplits()
+	if g.comparable() {
+		newSaver().persistSplits(g.splits)
+	}
+	if g.mp.opts[timerOption] {
+		writeSplits(g.runSplits(best))
+	}
+}
+
+// runSplits describes a finished run for export.
+type runSplits struct {
+	Version  string       // Game version.
+	Date     string       // Time the run finished.
+	Daily    string       // Daily challenge date, empty for regular runs.
+	Mutators string       // Active mutators, empty for none.
+	Total    float64      // Run time in seconds.
+	Splits   []levelSplit // Level completion times.
+}
+
+// levelSplit is the export for one completed level.
+type levelSplit struct {
+	Level int     // Completed level.
+	Time  float64 // Run time in seconds when the level was completed.
+	Best  float64 // Same split in the best run, 0 if not compared.
+}
+
+// runSplits gathers the splits of the current run.
+func (g *game) runSplits(best []float64) *runSplits {
+	run := &runSplits{Version: version, Total: g.elapsed}
+	run.Date = time.Now().Format("2006-01-02 15:04:05")
+	run.Mutators = mutatorKey(gameMutators)
+	if g.daily != nil {
+		run.Daily = g.daily.day
+	}
+	for lvl, secs := range g.splits {
+		if secs > 0 {
+			ls := levelSplit{Level: lvl, Time: secs}
+			if lvl < len(best) {
+				ls.Best = best[lvl]
+			}
+			run.Splits = append(run.Splits, ls)
+		}
+	}
+	return run
+}
+
+// writeSplits saves the run splits as JSON to a new timestamped file in
+// the save directory.
+func writeSplits(run *runSplits) {
+	bites, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		logf("writeSplits: %s", err)
+		return
+	}
+	dir := path.Dir(newSaver().File)
+	file := path.Join(dir, time.Now().Format("splits-20060102-150405.json"))
+	if err := ioutil.WriteFile(file, bites, 0644); err != nil {
+		logf("writeSplits: %s", err)
+	}
+}
+
+// formatSplit formats seconds as minutes, seconds and tenths, eg: 2:05.3.
+func formatSplit(secs float64) string {
+	tenths := int(secs*10 + 0.5)
+	return fmt.Sprintf("%d:%02d.%d", tenths/600, tenths/10%60, tenths%10)
+}
@@ -0,0 +1,101 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Telemetry is an opt-in local record of how each level visit ends.
+// It is appended to a CSV file in the save directory for anyone curious
+// about the difficulty curve. Nothing identifies the player and nothing
+// is ever sent anywhere.
+
+import (
+	"encoding/csv"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/gazed/bampf/gridmath"
+)
+
+// Level visit outcomes.
+const (
+	evolvedOutcome   = "evolved"   // Completed the level.
+	diedOutcome      = "died"      // Ran out of health and dropped a level.
+	descendedOutcome = "descended" // Chose to drop a level from the center.
+	voidOutcome      = "void"      // Fell into a void and dropped a level.
+	quitOutcome      = "quit"      // Quit the game part way through the level.
+)
+
+// telemetryFile is the name of the telemetry file in the save directory.
+const telemetryFile = "telemetry.csv"
+
+// telemetryHeader names the telemetry columns.
+var telemetryHeader = []string{"version", "level", "outcome", "seconds",
+	"hits", "cores", "alarms", "gridx", "gridy", "mutators", "daily"}
+
+// visit is the telemetry record of one level visit.
+type visit struct {
+	level    int     // Level number.
+	outcome  string  // How the visit ended.
+	secs     float64 // Seconds spent on the level.
+	hits     int     // Sentinel collisions.
+	cores    int     // Cores collected.
+	alarms   int     // Sentinel proximity warnings.
+	gx, gy   int     // Player grid location when the visit ended.
+	mutators string  // Active mutators key.
+	daily    bool    // True for daily challenges.
+}
+
+// record returns the visit as a row of telemetry columns.
+func (v visit) record() []string {
+	return []string{version, strconv.Itoa(v.level), v.outcome,
+		strconv.FormatFloat(v.secs, 'f', 1, 64),
+		strconv.Itoa(v.hits), strconv.Itoa(v.cores), strconv.Itoa(v.alarms),
+		strconv.Itoa(v.gx), strconv.Itoa(v.gy), v.mutators,
+		strconv.FormatBool(v.daily)}
+}
+
+// appendVisit adds the visit to the given telemetry file. The column
+// names are written first when the file is new.
+func appendVisit(file string, v visit) error {
+	_, err := os.Stat(file)
+	isNew := os.IsNotExist(err)
+	out, err := os.OpenFile(file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(out)
+	if isNew {
+		w.Write(telemetryHeader)
+	}
+	w.Write(v.record())
+	w.Flush()
+	if err = w.Error(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// visit
+// ===========================================================================
+// game telemetry handling.
+
+// logOutcome records how the current level visit ended when telemetry
+// is turned on. Only the first outcome of each visit is recorded.
+func (g *game) logOutcome(outcome string) {
+	if g.cl == nil || g.logged || g.practice || !g.mp.opts[telemetryOption] {
+		return
+	}
+	g.logged = true
+	lvl := g.cl
+	x, y, z := lvl.cam.At()
+	v := visit{level: lvl.num, outcome: outcome, secs: g.elapsed - g.started,
+		hits: lvl.hits, cores: lvl.fetched, alarms: lvl.alarms,
+		mutators: mutatorKey(gameMutators), daily: g.daily != nil}
+	v.gx, v.gy = gridmath.ToGrid(x, y, z, float64(lvl.units))
+	file := path.Join(path.Dir(newSaver().File), telemetryFile)
+	if err := appendVisit(file, v); err != nil {
+		logf("game.logOutcome: %s", err)
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"testing"
+)
+
+func TestAppendVisit(t *testing.T) {
+	file := "telemetry-test.csv"
+	defer os.Remove(file)
+	appendVisit(file, visit{level: 1, outcome: evolvedOutcome, secs: 61.04, hits: 2})
+	appendVisit(file, visit{level: 2, outcome: quitOutcome, gx: -1, gy: 4, daily: true})
+
+	in, err := os.Open(file)
+	if err != nil {
+		t.Fatalf("Expected telemetry file: %s", err)
+	}
+	defer in.Close()
+	rows, err := csv.NewReader(in).ReadAll()
+	if err != nil || len(rows) != 3 {
+		t.Fatalf("Expected header and 2 visits, got %v %s", rows, err)
+	}
+	if rows[0][2] != "outcome" || rows[1][3] != "61.0" || rows[1][4] != "2" {
+		t.Errorf("Unexpected first visit %v", rows[1])
+	}
+	if rows[2][2] != quitOutcome || rows[2][7] != "-1" || rows[2][10] != "true" {
+		t.Errorf("Unexpected second visit %v", rows[2])
+	}
+}
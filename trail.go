@@ -0,0 +1,166 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// With the hazards mutator, sentinels on the later levels leave a short
+// trail of scorched floor tiles behind them. The tiles fade away as the
+// sentinel moves on and cost the player a cell if stepped on, so the
+// player has to watch where the sentinels have been as well as where
+// they are. Cloaked players float over the trails like they slip past
+// the sentinels.
+
+import (
+	"github.com/gazed/bampf/gridmath"
+	"github.com/gazed/vu"
+)
+
+// Sentinel trail tuning.
+const (
+	trailLoss  = 1   // Cells lost stepping on a trail tile.
+	trailAlpha = 0.6 // Transparency of a fresh trail tile.
+	trailStep  = 2   // Furthest grid step that leaves a trail. Longer jumps are teleports.
+)
+
+// trailTile is one scorched floor tile.
+type trailTile struct {
+	tile  *vu.Ent       // Tile model.
+	spot  gridmath.Spot // Tile grid location.
+	ticks int           // Game ticks until the tile fades away.
+}
+
+// trails tracks the trail tiles left by all the sentinels of a level.
+// Tile models are pooled since tiles come and go all the time.
+type trails struct {
+	scene *vu.Ent                     // Parent for the tile models.
+	life  int                         // Game ticks that a trail tile lasts.
+	fade  float64                     // Level fade distance.
+	units float64                     // Grid to game scale.
+	tiles []*trailTile                // Visible trail tiles, oldest first.
+	pool  []*vu.Ent                   // Hidden tile models ready for reuse.
+	last  map[*sentinel]gridmath.Spot // Sentinel grid locations on the previous tick.
+}
+
+// newTrails creates trails that last long enough to show the given
+// number of tiles behind each sentinel.
+func newTrails(scene *vu.Ent, length, units int, fade float64) *trails {
+	return &trails{scene: scene, life: length * sentinelSpeed, fade: fade,
+		units: float64(units), last: map[*sentinel]gridmath.Spot{}}
+}
+
+// update fades the trail tiles and lays a new tile wherever a sentinel
+// has just left. Return true if the trail tiles changed.
+func (tr *trails) update(sentries []*sentinel) (changed bool) {
+	for cnt := len(tr.tiles) - 1; cnt >= 0; cnt-- {
+		t := tr.tiles[cnt]
+		if t.ticks--; t.ticks <= 0 {
+			tr.remove(cnt)
+			changed = true
+			continue
+		}
+		t.tile.SetAlpha(trailAlpha * float64(t.ticks) / float64(tr.life))
+	}
+	for _, sentry := range sentries {
+		if !sentry.active {
+			delete(tr.last, sentry)
+			continue
+		}
+		x, y, z := sentry.location()
+		gx, gy := gridmath.ToGrid(x, y, z, tr.units)
+		at := gridmath.Spot{X: gx, Y: gy}
+		if prev, ok := tr.last[sentry]; ok && prev != at && abs(prev.X-gx)+abs(prev.Y-gy) <= trailStep {
+			tr.lay(prev)
+			changed = true
+		}
+		tr.last[sentry] = at
+	}
+	return changed
+}
+
+// lay puts a fresh trail tile at the given grid location, reusing
+// the existing tile if there is already one there.
+func (tr *trails) lay(spot gridmath.Spot) {
+	for cnt, t := range tr.tiles {
+		if t.spot == spot {
+			tr.tiles = append(tr.tiles[:cnt], tr.tiles[cnt+1:]...)
+			t.ticks = tr.life
+			tr.tiles = append(tr.tiles, t)
+			return
+		}
+	}
+	var tile *vu.Ent
+	if n := len(tr.pool); n > 0 {
+		tile, tr.pool = tr.pool[n-1], tr.pool[:n-1]
+	} else {
+		tile = tr.scene.AddPart()
+		m := tile.MakeModel("flata", "msh:tile", "mat:tred")
+		trackAsset(m, "mat:tred")
+		m.SetUniform("fd", tr.fade)
+	}
+	gamex, gamez := gridmath.ToGame(spot.X, spot.Y, tr.units)
+	tile.SetAt(gamex, voidLift, gamez).SetAlpha(trailAlpha)
+	tile.Cull(false)
+	tr.tiles = append(tr.tiles, &trailTile{tile: tile, spot: spot, ticks: tr.life})
+}
+
+// burn returns true if there is a trail tile at the given grid location.
+// The tile is used up so that it only burns the player once.
+func (tr *trails) burn(gridx, gridy int) bool {
+	for cnt, t := range tr.tiles {
+		if t.spot.X == gridx && t.spot.Y == gridy {
+			tr.remove(cnt)
+			return true
+		}
+	}
+	return false
+}
+
+// remove hides the indexed trail tile and returns it to the pool.
+func (tr *trails) remove(index int) {
+	t := tr.tiles[index]
+	t.tile.Cull(true)
+	tr.pool = append(tr.pool, t.tile)
+	tr.tiles = append(tr.tiles[:index], tr.tiles[index+1:]...)
+}
+
+// reset clears all the trail tiles. Expected to be called when the
+// level is deactivated.
+func (tr *trails) reset() {
+	for len(tr.tiles) > 0 {
+		tr.remove(len(tr.tiles) - 1)
+	}
+	tr.last = map[*sentinel]gridmath.Spot{}
+}
+
+// squares returns the minimap markers for the trail tiles.
+func (tr *trails) squares() (squares []square) {
+	for _, t := range tr.tiles {
+		gamex, gamez := gridmath.ToGame(t.spot.X, t.spot.Y, tr.units)
+		squares = append(squares, square{gamex, -gamez, 0.6})
+	}
+	return squares
+}
+
+// trails
+// ===========================================================================
+// level trail handling.
+
+// updateTrails lays and fades the sentinel trails and costs the player
+// a cell for stepping on one. Levels without trails are left alone.
+func (lvl *level) updateTrails() {
+	if lvl.trails == nil {
+		return
+	}
+	changed := lvl.trails.update(lvl.sentries)
+	if !lvl.player.cloaked && lvl.player.grace <= 0 && !lvl.practice {
+		x, y, z := lvl.cam.At()
+		if gx, gy := gridmath.ToGrid(x, y, z, float64(lvl.units)); lvl.trails.burn(gx, gy) {
+			changed = true
+			lvl.player.play(collideSound)
+			lvl.loseCells(trailLoss)
+		}
+	}
+	if changed {
+		lvl.hd.showTrails(lvl.trails.squares())
+	}
+}
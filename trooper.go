@@ -19,6 +19,7 @@ import (
 // trooper works with single cubes (cells) of size 2 centered at the origin.
 type trooper struct {
 	part   *vu.Ent // Graphics container.
+	detail *vu.Ent // Container for the detailed cells.
 	lvl    int     // Current game level of trooper.
 	neo    *vu.Ent // Un-injured trooper
 	bits   []box   // Injured troopers have panels and edge cubes.
@@ -26,10 +27,25 @@ type trooper struct {
 	center *vu.Ent // Center always represented as one piece
 	mid    int     // Level entry number of cells.
 
+	// the detailed cells are replaced by a single simplified cube when the
+	// trooper is shown small or its health is changing rapidly.
+	simplify bool    // True if the simplified cube can be used.
+	scale    float64 // Trooper size.
+	lod      *vu.Ent // Simplified cube, nil when the detailed cells are shown.
+	pending  int     // Cell changes not yet applied to the detailed cells.
+	churn    int     // Recent health changes, decays each tick.
+
 	// trooper special powers are cloaking and teleporting.
 	cloaked               bool // Is cloaking turned on.
 	cloakEnergy, cemax    int  // Energy available for cloaking.
 	teleportEnergy, temax int  // Energy available for teleporting.
+	grace                 int  // Ticks of sentinel immunity after a forced decloak or knockback.
+
+	// trooper configuration from upgrades earned between levels.
+	cloakBoost int     // Extra maximum cloak energy.
+	regen      int     // Teleport energy regained each tick.
+	gain       int     // Extra cells attached for each core.
+	reach      float64 // Extra core pickup distance.
 
 	// health and energy monitors.
 	hms map[string]healthMonitor // Health event monitors.
@@ -46,16 +62,18 @@ func newTrooper(part *vu.Ent, level int) *trooper {
 	tr := &trooper{}
 	tr.lvl = level
 	tr.part = part
+	tr.detail = part.AddPart()
 	tr.bits = []box{}
 	tr.ipos = []int{}
 	tr.mid = tr.lvl*tr.lvl*tr.lvl*8 - (tr.lvl-1)*(tr.lvl-1)*(tr.lvl-1)*8
 
 	// set max energies.
 	tr.cemax, tr.temax = 1000, 1000
+	tr.regen = 1
 
 	// special case for a level 0 (start screen) trooper.
 	if tr.lvl == 0 {
-		cube := newCube(tr.part, 0, 0, 0, 1)
+		cube := newCube(tr.detail, 0, 0, 0, 1)
 		cube.edgeSort(1)
 		tr.bits = append(tr.bits, cube)
 		return tr
@@ -65,12 +83,12 @@ func newTrooper(part *vu.Ent, level int) *trooper {
 	cubeSize := 1.0 / float64(tr.lvl+1)
 	centerOffset := cubeSize * 0.5
 	panelCenter := float64(tr.lvl) * centerOffset
-	tr.bits = append(tr.bits, newPanel(tr.part, panelCenter, 0.0, 0.0, tr.lvl))
-	tr.bits = append(tr.bits, newPanel(tr.part, -panelCenter, 0.0, 0.0, tr.lvl))
-	tr.bits = append(tr.bits, newPanel(tr.part, 0.0, panelCenter, 0.0, tr.lvl))
-	tr.bits = append(tr.bits, newPanel(tr.part, 0.0, -panelCenter, 0.0, tr.lvl))
-	tr.bits = append(tr.bits, newPanel(tr.part, 0.0, 0.0, panelCenter, tr.lvl))
-	tr.bits = append(tr.bits, newPanel(tr.part, 0.0, 0.0, -panelCenter, tr.lvl))
+	tr.bits = append(tr.bits, newPanel(tr.detail, panelCenter, 0.0, 0.0, tr.lvl))
+	tr.bits = append(tr.bits, newPanel(tr.detail, -panelCenter, 0.0, 0.0, tr.lvl))
+	tr.bits = append(tr.bits, newPanel(tr.detail, 0.0, panelCenter, 0.0, tr.lvl))
+	tr.bits = append(tr.bits, newPanel(tr.detail, 0.0, -panelCenter, 0.0, tr.lvl))
+	tr.bits = append(tr.bits, newPanel(tr.detail, 0.0, 0.0, panelCenter, tr.lvl))
+	tr.bits = append(tr.bits, newPanel(tr.detail, 0.0, 0.0, -panelCenter, tr.lvl))
 
 	// troopers are made out of cubes and panels.
 	mx := float64(-tr.lvl)
@@ -112,7 +130,7 @@ func newTrooper(part *vu.Ent, level int) *trooper {
 				}
 				if newCells > 0 {
 					x, y, z := mx*centerOffset, my*centerOffset, mz*centerOffset
-					cube := newCube(tr.part, x, y, z, float64(cubeSize))
+					cube := newCube(tr.detail, x, y, z, float64(cubeSize))
 					cube.edgeSort(newCells)
 					tr.bits = append(tr.bits, cube)
 				}
@@ -133,13 +151,19 @@ func newTrooper(part *vu.Ent, level int) *trooper {
 }
 
 // play the indicated sound.
-func (tr *trooper) play(sound uint32) { tr.part.PlaySound(sound) }
+func (tr *trooper) play(sound uint32) { sounds.play(tr.part, sound) }
 
 // fullHealth returns true if the player is at full health.
-func (tr *trooper) fullHealth() bool { return tr.neo != nil }
+func (tr *trooper) fullHealth() bool {
+	health, _, max := tr.health()
+	return health == max
+}
 
 // setScale changes the troopers size.
-func (tr *trooper) setScale(scale float64) { tr.part.SetScale(scale, scale, scale) }
+func (tr *trooper) setScale(scale float64) {
+	tr.scale = scale
+	tr.part.SetScale(scale, scale, scale)
+}
 
 // loc gets the troopers current location.
 func (tr *trooper) loc() (x, y, z float64) { return tr.part.At() }
@@ -151,9 +175,10 @@ func (tr *trooper) addCenter() {
 	if tr.lvl > 0 {
 		cubeSize := 1.0 / float64(tr.lvl+1)
 		scale := float64(tr.lvl-1) * cubeSize * 0.45 // leave a gap.
-		tr.center = tr.part.AddPart().SetScale(scale, scale, scale)
+		tr.center = tr.detail.AddPart().SetScale(scale, scale, scale)
 		m := tr.center.MakeModel("flata", "msh:cube", "mat:tred")
 		m.SetUniform("fd", 1000)
+		paint(m, trooperColours.core)
 	}
 }
 
@@ -161,6 +186,7 @@ func (tr *trooper) addCenter() {
 // (the starting number of cells for the level), and the maximum
 // possible cell count for this level.
 func (tr *trooper) health() (health, mid, max int) {
+	health = tr.pending
 	for _, b := range tr.bits {
 		health += b.box().ccnt
 	}
@@ -171,6 +197,8 @@ func (tr *trooper) health() (health, mid, max int) {
 
 // reset the troopers health to the level's minimum.
 func (tr *trooper) reset() {
+	tr.pending = 0
+	tr.showDetail()
 	tr.trash()
 	tr.addCenter()
 	for cnt, b := range tr.bits {
@@ -179,9 +207,18 @@ func (tr *trooper) reset() {
 	tr.healthChanged(tr.health())
 }
 
-// attach currently tries to attach new cells to the panels first.
-// Otherwise add to an edge.
+// attach adds a cell, deferring the change while the simplified cube
+// is shown.
 func (tr *trooper) attach() {
+	if tr.simplified(1) {
+		return
+	}
+	tr.attachCell()
+}
+
+// attachCell currently tries to attach new cells to the panels first.
+// Otherwise add to an edge.
+func (tr *trooper) attachCell() {
 	for _, b := range tr.bits {
 		if b.attach() {
 			health, mid, max := tr.health()
@@ -194,11 +231,20 @@ func (tr *trooper) attach() {
 	}
 }
 
-// detach currently tries to remove cells from edges first.
-// Otherwise remove from a panel.
+// detach removes a cell, deferring the change while the simplified cube
+// is shown.
 func (tr *trooper) detach() {
+	if tr.simplified(-1) {
+		return
+	}
+	tr.detachCell()
+}
+
+// detachCell currently tries to remove cells from edges first.
+// Otherwise remove from a panel.
+func (tr *trooper) detachCell() {
 	if tr.neo != nil {
-		tr.demerge() // will re-enter detach.
+		tr.demerge() // will re-enter detachCell.
 		return
 	}
 	for _, b := range tr.bits {
@@ -209,27 +255,30 @@ func (tr *trooper) detach() {
 	}
 }
 
-// detachCores removes the indicated number of cells.
-func (tr *trooper) detachCores(loss int) {
+// detachCores removes the indicated number of cells. Returns the share
+// of the maximum health that was lost, from 0 to 1.
+func (tr *trooper) detachCores(loss int) float64 {
 	if loss <= 0 {
-		return
+		return 0
 	}
-	h, _, _ := tr.health()
+	h, _, max := tr.health()
 	if loss > h {
 		loss = h
 	}
 	for cnt := loss; cnt > 0; cnt-- {
 		tr.detach()
 	}
+	return float64(loss) / float64(max)
 }
 
 // merge collapses all the troopers cubes into a single cube with an
 // optional center cube.  Called when the trooper reaches full health.
 func (tr *trooper) merge() {
 	tr.trash()
-	tr.neo = tr.part.AddPart().SetScale(0.5, 0.5, 0.5)
+	tr.neo = tr.detail.AddPart().SetScale(0.5, 0.5, 0.5)
 	m := tr.neo.MakeModel("flata", "msh:cube", "mat:tblue")
 	m.SetUniform("fd", 1000)
+	paint(m, trooperColours.panel)
 	tr.addCenter()
 }
 
@@ -241,7 +290,7 @@ func (tr *trooper) demerge() {
 	for _, b := range tr.bits {
 		b.reset(b.box().cmax)
 	}
-	tr.detach()
+	tr.detachCell()
 }
 
 // trash destroys all the troopers cells.
@@ -259,6 +308,84 @@ func (tr *trooper) trash() {
 	tr.neo = nil
 }
 
+// Simplified cube tuning.
+const (
+	lodPixels = 5  // Smallest on-screen cell size for the detailed cells.
+	lodChurn  = 20 // Recent health changes that switch to the simplified cube.
+	lodCost   = 5  // Churn added for each health change.
+)
+
+// simplified applies a health change, +1 or -1 cells, to the simplified
+// cube when the simplified cube is needed. Returns false if the change is
+// to be made to the detailed cells instead.
+func (tr *trooper) simplified(change int) bool {
+	if !tr.simplify || tr.lvl == 0 {
+		return false
+	}
+	tr.churn += lodCost
+	if tr.lod == nil && tr.churn <= lodChurn && !tr.tooSmall() {
+		return false
+	}
+	health, mid, max := tr.health()
+	if health+change < 0 || health+change > max {
+		return true // nothing to add or remove.
+	}
+	tr.pending += change
+	health += change
+	tr.showSimplified(health, max)
+	tr.healthChanged(health, mid, max)
+	return true
+}
+
+// tooSmall returns true if the detailed cells would be too small to see.
+// Cells are roughly 0.4 units across for a level 1 trooper of scale 1.
+func (tr *trooper) tooSmall() bool {
+	return tr.scale*0.4/float64(tr.lvl) < lodPixels
+}
+
+// showSimplified replaces the detailed cells with a single cube
+// that grows with the trooper health.
+func (tr *trooper) showSimplified(health, max int) {
+	if tr.lod == nil {
+		tr.detail.Cull(true)
+		tr.lod = tr.part.AddPart()
+		m := tr.lod.MakeModel("flata", "msh:cube", "mat:tgreen")
+		m.SetUniform("fd", 1000)
+		paint(m, trooperColours.cell)
+	}
+	size := 0.25 + 0.25*float64(health)/float64(max)
+	tr.lod.SetScale(size, size, size)
+}
+
+// showDetail applies any deferred health changes to the detailed cells
+// and shows them in place of the simplified cube.
+func (tr *trooper) showDetail() {
+	if tr.lod != nil {
+		tr.lod.Dispose()
+		tr.lod = nil
+	}
+	pending := tr.pending
+	tr.pending = 0
+	for ; pending > 0; pending-- {
+		tr.attachCell()
+	}
+	for ; pending < 0; pending++ {
+		tr.detachCell()
+	}
+	tr.detail.Cull(false)
+}
+
+// updateDetail is called each game tick to switch back to the
+// detailed cells once the health has stopped changing.
+func (tr *trooper) updateDetail() {
+	if tr.churn > 0 {
+		tr.churn--
+	}
+	if tr.lod != nil && tr.churn == 0 && !tr.tooSmall() {
+		tr.showDetail()
+	}
+}
+
 // addCloakEnergy is called to increase the amount of cloaking energy.
 func (tr *trooper) addCloakEnergy() {
 	tr.cloakEnergy += 100
@@ -274,9 +401,11 @@ func (tr *trooper) cloak(useCloak bool) {
 	if useCloak && tr.cloakEnergy > 0 {
 		tr.cloaked = true
 		tr.play(cloakSound)
+		sounds.startLoop(tr.part, humSounds)
 	} else if !useCloak {
 		tr.cloaked = false
 		tr.play(decloakSound)
+		sounds.stopLoop()
 	}
 }
 
@@ -292,6 +421,12 @@ func (tr *trooper) teleport() bool {
 	return false
 }
 
+// chargeTeleport fully charges the teleport energy.
+func (tr *trooper) chargeTeleport() {
+	tr.teleportEnergy = tr.temax
+	tr.energyChanged()
+}
+
 // energy returns the amount of energy available for cloaking and teleporting.
 func (tr *trooper) energy() (teng, tmax, ceng, cmax int) {
 	ce := tr.cloakEnergy
@@ -308,17 +443,30 @@ func (tr *trooper) updateEnergy() {
 
 	// teleport energy increases to max.
 	if tr.teleportEnergy < tr.temax {
-		tr.teleportEnergy++
+		tr.teleportEnergy += tr.regen
+		if tr.teleportEnergy > tr.temax {
+			tr.teleportEnergy = tr.temax
+		}
 		change = true
 	}
 
-	// cloak energy is used until gone.
+	// cloak energy is used until gone. The player gets a warning when
+	// the energy is low and a short grace period once it runs out.
+	if tr.grace > 0 {
+		tr.grace--
+	}
 	if tr.cloaked {
 		change = true
 		tr.cloakEnergy -= 4
 		if tr.cloakEnergy <= 0 {
 			tr.cloakEnergy = 0
 			tr.cloak(false)
+			tr.grace = cloakGrace
+		} else if tr.cloakLow() && (tr.cloakEnergy/4)%lowCloakBeep == 0 {
+			tr.play(lowCloakSound)
+		}
+		if tr.cloaked {
+			sounds.tickLoop(1 - float64(tr.cloakEnergy)/float64(tr.cemax)) // hum rises as energy drains.
 		}
 	}
 	if change {
@@ -326,14 +474,173 @@ func (tr *trooper) updateEnergy() {
 	}
 }
 
+// cloakLow returns true if the cloak is on and has less than
+// a fifth of its energy left.
+func (tr *trooper) cloakLow() bool { return tr.cloaked && tr.cloakEnergy*5 < tr.cemax }
+
+// Cloak warning and grace period timing in game ticks.
+const (
+	lowCloakBeep = 12 // Ticks between low cloak energy warnings.
+	cloakGrace   = 50 // One second of sentinel immunity after the cloak fails.
+)
+
+// configure sets the trooper upgrades. The extra cloak energy replaces
+// any previous extra so that troopers can be configured more than once.
+func (tr *trooper) configure(cloakBoost, regen, gain int, reach float64) {
+	tr.cemax += cloakBoost - tr.cloakBoost
+	tr.cloakBoost = cloakBoost
+	tr.regen = regen
+	tr.gain = gain
+	tr.reach = reach
+}
+
 // resetEnergy is called at the start of a level.
 func (tr *trooper) resetEnergy() {
 	tr.teleportEnergy = tr.temax
-	tr.cloakEnergy = 1000
+	tr.cloakEnergy = tr.cemax
+	tr.grace = 0
+}
+
+// setHealth attaches or detaches cells until the trooper has the given
+// number of cells. The cells are limited to the trooper's maximum.
+func (tr *trooper) setHealth(cells int) {
+	health, _, max := tr.health()
+	if cells > max {
+		cells = max
+	}
+	if cells < 0 {
+		cells = 0
+	}
+	for ; health < cells; health++ {
+		tr.attach()
+	}
+	for ; health > cells; health-- {
+		tr.detach()
+	}
 }
 
 // trooper
 // ===========================================================================
+// trooper saving
+
+// TrooperSave is the exact state of a trooper so that a saved run can be
+// continued with the same cells and energy. TrooperSave needs to be public
+// and visible for the encoding package.
+type TrooperSave struct {
+	Cells    []int // Cells in each cube, see trooper.snapshot.
+	Cloak    int   // Cloak energy.
+	Teleport int   // Teleport energy.
+	Cloaked  bool  // True if the cloak was on.
+}
+
+// snapshot returns the trooper state. There is one cell count for each
+// edge cube and one for each cube of each panel. The cubes of a merged
+// panel are counted as full. Deferred cell changes are applied first.
+func (tr *trooper) snapshot() *TrooperSave {
+	if tr.pending != 0 {
+		tr.showDetail()
+	}
+	save := &TrooperSave{Cloak: tr.cloakEnergy, Teleport: tr.teleportEnergy, Cloaked: tr.cloaked}
+	for _, b := range tr.bits {
+		if p, ok := b.(*panel); ok {
+			for _, c := range p.cubes {
+				if p.ccnt == p.cmax {
+					save.Cells = append(save.Cells, c.cmax)
+				} else {
+					save.Cells = append(save.Cells, c.ccnt)
+				}
+			}
+			continue
+		}
+		save.Cells = append(save.Cells, b.box().ccnt)
+	}
+	return save
+}
+
+// fits returns true if the saved state is for a trooper
+// with the same cubes as the given trooper.
+func (save *TrooperSave) fits(tr *trooper) bool {
+	slot := 0
+	check := func(max int) bool {
+		ok := slot < len(save.Cells) && save.Cells[slot] >= 0 && save.Cells[slot] <= max
+		slot++
+		return ok
+	}
+	for _, b := range tr.bits {
+		if p, ok := b.(*panel); ok {
+			for _, c := range p.cubes {
+				if !check(c.cmax) {
+					return false
+				}
+			}
+		} else if !check(b.box().cmax) {
+			return false
+		}
+	}
+	return slot == len(save.Cells)
+}
+
+// restore puts the trooper back to the saved state. Energy is limited
+// to the trooper's maximums. Return false, leaving the trooper unchanged,
+// if the saved state is for a different trooper.
+func (tr *trooper) restore(save *TrooperSave) bool {
+	if save == nil || !save.fits(tr) {
+		return false
+	}
+
+	// the saved cells replace any deferred changes and the merged trooper.
+	tr.pending, tr.churn = 0, 0
+	if tr.lod != nil {
+		tr.lod.Dispose()
+		tr.lod = nil
+		tr.detail.Cull(false)
+	}
+	if tr.neo != nil {
+		tr.neo.Dispose()
+		tr.neo = nil
+	}
+	slot := 0
+	for _, b := range tr.bits {
+		p, ok := b.(*panel)
+		if !ok {
+			b.reset(save.Cells[slot])
+			slot++
+			continue
+		}
+		cells := 0
+		for range p.cubes {
+			cells += save.Cells[slot]
+			slot++
+		}
+		if cells == p.cmax {
+			p.reset(p.cmax) // merges the panel.
+			continue
+		}
+		p.trash()
+		p.ccnt = 0
+		for cnt, c := range p.cubes {
+			c.reset(save.Cells[slot-len(p.cubes)+cnt])
+			p.ccnt += c.ccnt
+		}
+	}
+	health, mid, max := tr.health()
+	if health == max {
+		tr.merge()
+	}
+	tr.healthChanged(health, mid, max)
+
+	// energy and cloak.
+	tr.cloakEnergy = int(clamp(float64(save.Cloak), 0, float64(tr.cemax)))
+	tr.teleportEnergy = int(clamp(float64(save.Teleport), 0, float64(tr.temax)))
+	if save.Cloaked != tr.cloaked {
+		tr.cloak(save.Cloaked)
+	}
+	tr.energyChanged()
+	return true
+}
+
+// trooper saving
+// ===========================================================================
 // box & cbox
 
 // box defines common cell behaviours.
@@ -488,6 +795,7 @@ func (p *panel) merge() {
 	}
 	m := p.slab.MakeModel("flata", "msh:cube", "mat:tblue")
 	m.SetUniform("fd", 1000)
+	paint(m, trooperColours.panel)
 }
 
 // trash clears any visible parts from the panel. It is up to calling methods
@@ -568,6 +876,7 @@ func (c *cube) addCell() {
 	cell.SetScale(scale, scale, scale)
 	m := cell.MakeModel("flata", "msh:cube", "mat:tgreen")
 	m.SetUniform("fd", 1000)
+	paint(m, trooperColours.cell)
 	c.cells = append(c.cells, cell)
 }
 
@@ -587,6 +896,7 @@ func (c *cube) merge() {
 	cell := c.part.AddPart().SetAt(c.cx, c.cy, c.cz)
 	m := cell.MakeModel("flata", "msh:cube", "mat:tgreen")
 	m.SetUniform("fd", 1000)
+	paint(m, trooperColours.cell)
 	scale := (c.csize - (c.csize * 0.15)) * 0.5 // leave a gap (just c.csize for no gap)
 	cell.SetScale(scale, scale, scale)
 	c.cells = append(c.cells, cell)
@@ -703,3 +1013,52 @@ func (tr *trooper) energyChanged() {
 		}
 	}
 }
+
+// trooper
+// ===========================================================================
+// colours
+
+// colours is a trooper colour scheme. The cells are the small cubes,
+// the panels include the merged trooper cube, and the core is the
+// center cube.
+type colours struct {
+	name  string     // Scheme name shown on the launch screen.
+	cell  [3]float64 // Cell colour.
+	panel [3]float64 // Panel colour.
+	core  [3]float64 // Center cube colour.
+}
+
+// colourSchemes are the trooper colour choices. The first scheme
+// matches the trooper materials and is the default.
+var colourSchemes = []colours{
+	{name: "classic", cell: [3]float64{0.35, 0.43, 0.46}, panel: [3]float64{0.15, 0.55, 0.82}, core: [3]float64{0.86, 0.20, 0.18}},
+	{name: "ember", cell: [3]float64{0.55, 0.35, 0.25}, panel: [3]float64{0.90, 0.55, 0.15}, core: [3]float64{0.95, 0.85, 0.30}},
+	{name: "moss", cell: [3]float64{0.30, 0.45, 0.30}, panel: [3]float64{0.45, 0.75, 0.35}, core: [3]float64{0.75, 0.30, 0.65}},
+	{name: "ghost", cell: [3]float64{0.55, 0.55, 0.60}, panel: [3]float64{0.85, 0.85, 0.90}, core: [3]float64{0.20, 0.20, 0.25}},
+}
+
+// trooperColours is the colour scheme used for new trooper cells.
+var trooperColours = colourSchemes[0]
+
+// getColours returns the named colour scheme or the default scheme
+// if the name is unknown.
+func getColours(name string) colours {
+	for _, scheme := range colourSchemes {
+		if scheme.name == name {
+			return scheme
+		}
+	}
+	return colourSchemes[0]
+}
+
+// colourNames lists the colour schemes in launch screen order.
+func colourNames() []string {
+	names := []string{}
+	for _, scheme := range colourSchemes {
+		names = append(names, scheme.name)
+	}
+	return names
+}
+
+// paint colours a trooper cell model.
+func paint(m *vu.Ent, rgb [3]float64) { m.SetColor(rgb[0], rgb[1], rgb[2]) }
@@ -0,0 +1,79 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+)
+
+// plainBox is a box without any models so that
+// trooper cell counts can be tested without an engine.
+type plainBox struct{ cbox }
+
+func newPlainBox(cmax int) *plainBox {
+	b := &plainBox{}
+	b.cmax = cmax
+	b.trashc, b.mergec, b.addc, b.remc = func() {}, func() {}, func() {}, func() {}
+	return b
+}
+func (b *plainBox) trash() {}
+func (b *plainBox) merge() {}
+
+// plainTrooper returns a level 1 trooper, 64 cells at most,
+// made of plain boxes.
+func plainTrooper() *trooper {
+	tr := &trooper{lvl: 1, cemax: 1000, temax: 1000}
+	for cnt := 0; cnt < 8; cnt++ {
+		tr.bits = append(tr.bits, newPlainBox(8))
+	}
+	return tr
+}
+
+func TestTrooperSetHealth(t *testing.T) {
+	tr := plainTrooper()
+	tr.setHealth(20)
+	if health, _, _ := tr.health(); health != 20 {
+		t.Errorf("Expected 20 cells, got %d", health)
+	}
+	tr.setHealth(-5)
+	if health, _, _ := tr.health(); health != 0 {
+		t.Errorf("Expected no cells, got %d", health)
+	}
+}
+
+func TestTrooperRestore(t *testing.T) {
+	tr := plainTrooper()
+	tr.setHealth(30)
+	tr.cloakEnergy, tr.teleportEnergy = 400, 250
+	save := tr.snapshot()
+
+	// restore into a trooper with a different cell distribution.
+	other := plainTrooper()
+	other.bits[7].reset(5)
+	if !other.restore(save) {
+		t.Fatalf("Expected the snapshot to fit a matching trooper")
+	}
+	for cnt, b := range other.bits {
+		if b.box().ccnt != tr.bits[cnt].box().ccnt {
+			t.Errorf("Expected box %d to have %d cells, got %d", cnt, tr.bits[cnt].box().ccnt, b.box().ccnt)
+		}
+	}
+	health, _, max := other.health()
+	if health != 30 || health > max || other.neo != nil {
+		t.Errorf("Expected 30 cells without a merged trooper, got %d of %d", health, max)
+	}
+	if teng, _, ceng, _ := other.energy(); teng != 250 || ceng != 400 || other.cloaked {
+		t.Errorf("Expected the saved energy, got %d %d", teng, ceng)
+	}
+
+	// saves for other troopers, or with too many cells, are refused.
+	save.Cells[0] = 9
+	if other.restore(save) || other.bits[0].box().ccnt != tr.bits[0].box().ccnt {
+		t.Errorf("Expected an overfull cube to be refused")
+	}
+	save.Cells = save.Cells[1:]
+	if other.restore(save) {
+		t.Errorf("Expected a save for a different trooper to be refused")
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Turrets are stationary enemies placed at dead-ends on the later levels
+// when the hazards mutator is on. A turret watches down the corridor
+// leading out of its dead-end and fires a slow energy bolt whenever it
+// sees an uncloaked player. Bolts fly in a straight line, fizzle when they
+// reach a wall or gate, and cost cells like a sentinel hit when they reach
+// the player. Cloaked players can't be seen and absorb any bolts that
+// reach them. Turrets and their bolts are enemies so they show on the
+// minimap and the detector like the sentinels.
+
+import (
+	"github.com/gazed/bampf/gridmath"
+	"github.com/gazed/vu"
+	"github.com/gazed/vu/grid"
+)
+
+// Turret tuning.
+const (
+	turretClear  = 4    // Grid steps kept clear of turrets around the start and center.
+	turretReach  = 8    // Grid steps a turret can see down its corridor.
+	turretReload = 120  // Ticks between turret shots.
+	turretBolts  = 2    // Bolts in flight at once for each turret.
+	boltSpeed    = 0.06 // Grid spots a bolt moves each tick.
+)
+
+// turret is a stationary enemy that fires bolts down one corridor.
+type turret struct {
+	part   *vu.Ent       // Turret model.
+	at     gridmath.Spot // Dead-end grid location.
+	aim    gridmath.Spot // Grid direction of the corridor, one step.
+	units  float64       // Maze scale factor.
+	active bool          // Turrets without a dead-end stay hidden.
+	reload int           // Ticks until the turret can fire again.
+}
+
+// newTurret creates a hidden turret.
+func newTurret(part *vu.Ent, units int, fade float64) *turret {
+	t := &turret{part: part, units: float64(units)}
+	t.part.SetScale(0.3, 0.4, 0.3)
+	m := t.part.MakeModel("flata", "msh:cube", "mat:red")
+	trackAsset(m, "mat:red")
+	m.SetUniform("fd", fade)
+	t.part.Cull(true)
+	return t
+}
+
+// move reloads the turret. Turrets don't move.
+func (t *turret) move(plan grid.Grid, far bool) {
+	if t.reload > 0 {
+		t.reload--
+	}
+}
+
+// location gets the turrets location.
+func (t *turret) location() (x, y, z float64) { return t.part.At() }
+
+// setGridAt puts the turret down at the given grid location.
+func (t *turret) setGridAt(gridx, gridy int) {
+	t.at = gridmath.Spot{X: gridx, Y: gridy}
+	gamex, gamez := gridmath.ToGame(gridx, gridy, t.units)
+	t.part.SetAt(gamex, 0.4, gamez)
+}
+
+// isActive returns true for turrets placed in the maze.
+func (t *turret) isActive() bool { return t.active }
+
+// onPlayerCollide ignores the player. Only the bolts do damage.
+func (t *turret) onPlayerCollide(lvl *level) bool { return false }
+
+// minimapStyle shows turrets as fixed markers.
+func (t *turret) minimapStyle() markerStyle { return fixedMarker }
+
+// bolt is one slow moving turret projectile. Bolts are pooled
+// and reused once they hit something.
+type bolt struct {
+	part   *vu.Ent       // Bolt model.
+	dir    gridmath.Spot // Grid direction of travel, one step.
+	units  float64       // Maze scale factor.
+	flown  float64       // Grid spots travelled since launch.
+	active bool          // Bolts in flight.
+}
+
+// newBolt creates a hidden bolt.
+func newBolt(part *vu.Ent, units int, fade float64) *bolt {
+	b := &bolt{part: part, units: float64(units)}
+	b.part.SetScale(0.12, 0.12, 0.12)
+	m := b.part.MakeModel("flata", "msh:cube", "mat:orange")
+	trackAsset(m, "mat:orange")
+	m.SetUniform("fd", fade)
+	b.part.Cull(true)
+	return b
+}
+
+// launch fires the bolt from the given grid spot in the given direction.
+func (b *bolt) launch(from, dir gridmath.Spot) {
+	b.dir, b.flown = dir, 0
+	b.setGridAt(from.X, from.Y)
+	b.setActive(true)
+}
+
+// move flies the bolt a bit further. Bolts are stopped by anything
+// that isn't open floor, and fizzle once they fly out of turret reach.
+func (b *bolt) move(plan grid.Grid, far bool) {
+	x, y, z := b.part.At()
+	x += float64(b.dir.X) * boltSpeed * b.units
+	z -= float64(b.dir.Y) * boltSpeed * b.units
+	b.part.SetAt(x, y, z)
+	b.part.Spin(0, 6, 0)
+	b.flown += boltSpeed
+	gx, gy := gridmath.ToGrid(x, y, z, b.units)
+	if b.flown > turretReach+1 || !plan.IsOpen(gx, gy) {
+		b.setActive(false)
+	}
+}
+
+// location gets the bolts current location.
+func (b *bolt) location() (x, y, z float64) { return b.part.At() }
+
+// setGridAt puts the bolt at the given grid location.
+func (b *bolt) setGridAt(gridx, gridy int) {
+	gamex, gamez := gridmath.ToGame(gridx, gridy, b.units)
+	b.part.SetAt(gamex, 0.5, gamez)
+}
+
+// isActive returns true for bolts in flight.
+func (b *bolt) isActive() bool { return b.active }
+
+// setActive shows and enables the bolt or hides and disables it.
+func (b *bolt) setActive(active bool) {
+	b.active = active
+	b.part.Cull(!active)
+}
+
+// onPlayerCollide uses up the bolt.
+func (b *bolt) onPlayerCollide(lvl *level) bool {
+	b.setActive(false)
+	return true
+}
+
+// minimapStyle shows bolts like any other dangerous enemy.
+func (b *bolt) minimapStyle() markerStyle { return solidMarker }
+
+// inSight returns true if the target spot can be seen by looking from
+// the given spot in the given direction. Walls block the view and
+// nothing beyond reach grid steps is seen.
+func inSight(plan grid.Grid, from, dir, target gridmath.Spot, reach int) bool {
+	for step := 1; step <= reach; step++ {
+		x, y := from.X+dir.X*step, from.Y+dir.Y*step
+		if !plan.IsOpen(x, y) {
+			return false
+		}
+		if x == target.X && y == target.Y {
+			return true
+		}
+	}
+	return false
+}
+
+// turretEnemies is the number of turret and bolt enemies
+// created for the given level.
+func turretEnemies(levelNum int) int { return gameHazard(gameTurrets, levelNum) * (1 + turretBolts) }
+
+// inSight
+// ===========================================================================
+// level turret handling.
+
+// makeTurrets creates the hidden turrets and their bolts.
+// The turrets are placed once the floorplan is built.
+func (lvl *level) makeTurrets(scene *vu.Ent, levelNum int) {
+	turrets := gameHazard(gameTurrets, levelNum)
+	for cnt := 0; cnt < turrets; cnt++ {
+		t := newTurret(scene.AddPart(), lvl.units, lvl.fade)
+		lvl.turrets = append(lvl.turrets, t)
+		lvl.enemies = append(lvl.enemies, t)
+	}
+	for cnt := 0; cnt < turrets*turretBolts; cnt++ {
+		b := newBolt(scene.AddPart(), lvl.units, lvl.fade)
+		lvl.bolts = append(lvl.bolts, b)
+		lvl.enemies = append(lvl.enemies, b)
+	}
+}
+
+// buildTurrets places the turrets at dead-ends away from the start and
+// center. Expected to be called after the gates, voids, and interdiction
+// zone are placed so that turrets stay off them. Turrets without a
+// dead-end stay hidden.
+func (lvl *level) buildTurrets(plan grid.Grid) {
+	if len(lvl.turrets) == 0 {
+		return
+	}
+	sx, sy, sz := startSpot()
+	startx, starty := gridmath.ToGrid(sx, sy, sz, float64(lvl.units))
+	keep := []gridmath.Spot{{X: lvl.gcx, Y: lvl.gcy}, {X: startx, Y: starty}}
+	placed := 0
+	for _, spot := range deadEnds(plan, len(lvl.turrets)*2, turretClear, keep) {
+		if placed >= len(lvl.turrets) {
+			return
+		}
+		if lvl.voids[spot] || lvl.interdict[spot] || lvl.gates[spot] != nil {
+			continue
+		}
+		t := lvl.turrets[placed]
+		t.aim, _ = deadEnd(plan, spot.X, spot.Y)
+		t.setGridAt(spot.X, spot.Y)
+		t.active = true
+		t.part.Cull(false)
+		lvl.cc.remDropAt(spot.X, spot.Y)
+		placed++
+	}
+}
+
+// turretAt returns true if a turret was placed at the given grid spot.
+func (lvl *level) turretAt(spot gridmath.Spot) bool {
+	for _, t := range lvl.turrets {
+		if t.active && t.at == spot {
+			return true
+		}
+	}
+	return false
+}
+
+// resetTurrets clears any bolts in flight and reloads the turrets.
+func (lvl *level) resetTurrets() {
+	for _, b := range lvl.bolts {
+		b.setActive(false)
+	}
+	for _, t := range lvl.turrets {
+		t.reload = turretReload / 2
+	}
+}
+
+// updateTurrets fires the turrets that can see the player.
+// Cloaked players are not seen and absorb any bolts that reach them.
+// Expected to be called each game update after the enemies move.
+func (lvl *level) updateTurrets() {
+	if len(lvl.turrets) == 0 {
+		return
+	}
+	x, y, z := lvl.cam.At()
+	px, py := gridmath.ToGrid(x, y, z, float64(lvl.units))
+	player := gridmath.Spot{X: px, Y: py}
+	if lvl.player.cloaked {
+		for _, b := range lvl.bolts {
+			if b.active {
+				bx, by, bz := b.location()
+				if gx, gy := gridmath.ToGrid(bx, by, bz, float64(lvl.units)); gx == px && gy == py {
+					b.setActive(false)
+				}
+			}
+		}
+		return
+	}
+	for _, t := range lvl.turrets {
+		if !t.active || t.reload > 0 || !inSight(lvl.guards, t.at, t.aim, player, turretReach) {
+			continue
+		}
+		for _, b := range lvl.bolts {
+			if !b.active {
+				b.launch(t.at, t.aim)
+				t.reload = turretReload
+				break
+			}
+		}
+	}
+}
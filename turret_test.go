@@ -0,0 +1,59 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/gazed/bampf/gridmath"
+)
+
+// turretPlan is a corridor with three dead-ends.
+func turretPlan(t testing.TB) *customPlan {
+	return testPlan(t,
+		"#######",
+		"#.....#",
+		"###.###",
+		"###.###",
+		"###.###",
+		"###.###",
+		"#######",
+	)
+}
+
+func TestDeadEnds(t *testing.T) {
+	plan := turretPlan(t)
+	if spots := deadEnds(plan, 10, turretClear, nil); len(spots) != 3 {
+		t.Errorf("Expected 3 dead-ends, got %v", spots)
+	}
+	if dir, ok := deadEnd(plan, 3, 5); !ok || dir != (gridmath.Spot{X: 0, Y: -1}) {
+		t.Errorf("Expected the way out to lead up, got %v %t", dir, ok)
+	}
+	if _, ok := deadEnd(plan, 3, 1); ok {
+		t.Errorf("Expected a junction not to be a dead-end")
+	}
+	keep := []gridmath.Spot{{X: 3, Y: 4}}
+	for _, spot := range deadEnds(plan, 10, turretClear, keep) {
+		if spot.X == 3 && spot.Y == 5 {
+			t.Errorf("Expected dead-ends near the keep spots to be skipped")
+		}
+	}
+	if spots := deadEnds(plan, 1, turretClear, nil); len(spots) != 1 {
+		t.Errorf("Expected the dead-ends to be limited, got %v", spots)
+	}
+}
+
+func TestInSight(t *testing.T) {
+	plan := turretPlan(t)
+	from, up := gridmath.Spot{X: 3, Y: 5}, gridmath.Spot{X: 0, Y: -1}
+	if !inSight(plan, from, up, gridmath.Spot{X: 3, Y: 1}, turretReach) {
+		t.Errorf("Expected the end of the corridor to be seen")
+	}
+	if inSight(plan, from, up, gridmath.Spot{X: 3, Y: 1}, 3) {
+		t.Errorf("Expected spots beyond reach not to be seen")
+	}
+	if inSight(plan, from, up, gridmath.Spot{X: 1, Y: 1}, turretReach) {
+		t.Errorf("Expected walls to block the view")
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Completing a level earns an upgrade point. Points are spent on the
+// level summary shown before evolving, and the upgrades are applied to
+// the trooper of every following level for the rest of the game.
+
+import (
+	"fmt"
+
+	"github.com/gazed/vu"
+)
+
+// Trooper upgrades.
+const (
+	cloakUpgrade = iota // More maximum cloak energy.
+	regenUpgrade        // Faster teleport energy recharge.
+	gainUpgrade         // More cells for each collected core.
+	reachUpgrade        // Collect cores from further away.
+	upgradeKinds        // Number of upgrade kinds.
+)
+
+// Upgrade tuning for each point spent.
+const (
+	cloakBoost = 250 // Extra maximum cloak energy.
+	regenBoost = 1   // Extra teleport energy per tick.
+	gainBoost  = 1   // Extra cells attached per core.
+	reachBoost = 0.5 // Extra core pickup distance in game units.
+)
+
+// upgrades tracks the upgrade points earned and spent during one game.
+type upgrades struct {
+	points int               // Unspent upgrade points.
+	ranks  [upgradeKinds]int // Points spent on each upgrade.
+}
+
+// spend uses an upgrade point on the given upgrade.
+// Returns false if there are no points or the upgrade is unknown.
+func (u *upgrades) spend(kind int) bool {
+	if u.points <= 0 || kind < 0 || kind >= upgradeKinds {
+		return false
+	}
+	u.points--
+	u.ranks[kind]++
+	return true
+}
+
+// apply configures the trooper with the upgrades spent so far.
+func (u *upgrades) apply(tr *trooper) {
+	tr.configure(u.ranks[cloakUpgrade]*cloakBoost,
+		1+u.ranks[regenUpgrade]*regenBoost,
+		u.ranks[gainUpgrade]*gainBoost,
+		float64(u.ranks[reachUpgrade])*reachBoost)
+}
+
+// upgrades
+// ===========================================================================
+// level summary
+
+// showSummary grants an upgrade point for completing the current level
+// and shows the level summary. The game waits for the player to choose
+// before evolving.
+func (g *game) showSummary() {
+	g.ups.points++
+	ranks := g.ups.ranks
+	text := []string{
+		fmt.Sprintf("Level %d complete in %s", g.cl.num, formatTime(g.elapsed-g.started)),
+		"",
+		fmt.Sprintf("Upgrade points: %d", g.ups.points),
+		fmt.Sprintf("  1 - Cloak energy +%d (rank %d)", cloakBoost, ranks[cloakUpgrade]),
+		fmt.Sprintf("  2 - Teleport recharge +%d (rank %d)", regenBoost, ranks[regenUpgrade]),
+		fmt.Sprintf("  3 - Core gain +%d (rank %d)", gainBoost, ranks[gainUpgrade]),
+		fmt.Sprintf("  4 - Core reach +%.1f (rank %d)", reachBoost, ranks[reachUpgrade]),
+		"",
+		"Press Return to save the point for later.",
+	}
+	g.summary = newTextView(g.cl.hd.ui, text)
+	g.summary.resize(g.ww, g.wh)
+	g.summary.toggle()
+}
+
+// summaryInput spends an upgrade point or saves it for later.
+// The summary is closed and the player evolves once a choice is made.
+func (g *game) summaryInput(ip *inputState) {
+	chosen := false
+	for _, press := range ip.pressedKeys() {
+		switch press {
+		case vu.K1, vu.K2, vu.K3, vu.K4: // upgrades in the order shown.
+			chosen = g.ups.spend(press-vu.K1) || chosen
+		case vu.KRet:
+			chosen = true
+		}
+	}
+	if chosen {
+		g.summary.ui.Dispose()
+		g.summary = nil
+		g.mp.ani.addAnimation(g.newEvolveAnimation(1))
+	}
+}
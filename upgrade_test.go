@@ -0,0 +1,20 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestSpendUpgrades(t *testing.T) {
+	u := &upgrades{}
+	if u.spend(cloakUpgrade) {
+		t.Error("Expected no upgrade without points")
+	}
+	u.points = 2
+	if !u.spend(gainUpgrade) || u.points != 1 || u.ranks[gainUpgrade] != 1 {
+		t.Errorf("Expected gain upgrade, got %+v", u)
+	}
+	if u.spend(upgradeKinds) || u.points != 1 {
+		t.Errorf("Expected unknown upgrade to keep the point, got %+v", u)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Usage statistics track how the cloak and teleport abilities have been
+// used during the current run. They are shown as tooltips when the mouse
+// is over the cloak or teleport bars while the game is paused. The closest
+// sentinel distance only counts sentinels that were dodged, not ones that
+// hit the player or passed by while the player was cloaked.
+
+import (
+	"math"
+	"strconv"
+)
+
+// usage is the cloak and teleport statistics for the current run.
+type usage struct {
+	cloaked   float64 // Seconds spent cloaked.
+	teleports int     // Teleports used.
+	closest   float64 // Nearest dodged sentinel in maze tiles, zero if none.
+	hits      int     // Level sentinel hits at the last update.
+}
+
+// track adds the last dt seconds of play on the given level.
+// Expected to be called once each game update.
+func (u *usage) track(lvl *level, dt float64) {
+	if lvl.player.cloaked {
+		u.cloaked += dt
+		return
+	}
+	if lvl.hits != u.hits || lvl.player.grace > 0 {
+		u.hits = lvl.hits // close calls don't count on collisions.
+		return
+	}
+	x, _, z := lvl.body.At()
+	for _, foe := range lvl.enemies {
+		if foe.isActive() {
+			sx, _, sz := foe.location()
+			if dist := math.Hypot(sx-x, sz-z) / float64(lvl.units); u.closest == 0 || dist < u.closest {
+				u.closest = dist
+			}
+		}
+	}
+}
+
+// describeCloak is the cloak bar tooltip.
+func (u *usage) describeCloak() string {
+	text := "Cloaked for " + strconv.FormatFloat(u.cloaked, 'f', 1, 64) + " seconds"
+	if u.closest > 0 {
+		text += ", closest sentinel dodged " + strconv.FormatFloat(u.closest, 'f', 1, 64) + " tiles away"
+	}
+	return text
+}
+
+// describeTeleport is the teleport bar tooltip.
+func (u *usage) describeTeleport() string {
+	if u.teleports == 1 {
+		return "Teleported once this run"
+	}
+	return "Teleported " + strconv.Itoa(u.teleports) + " times this run"
+}
@@ -0,0 +1,79 @@
+// Copyright © 2013-2016 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package main
+
+// Voids are rare hazard tiles that drop the player down to the previous
+// level. Voids are only placed with the hazards mutator. They are placed in dead ends, well away from the player start
+// and the maze center, so they never block the only way through the maze.
+// There is no level below the first level, so a void there costs a few
+// cells and spits the player back out at the teleport spot.
+
+import (
+	"github.com/gazed/bampf/gridmath"
+	"github.com/gazed/vu"
+	"github.com/gazed/vu/grid"
+	"github.com/gazed/vu/math/lin"
+)
+
+// Void tuning.
+const (
+	voidClear = 4    // Grid steps kept clear of voids around the start and center.
+	voidLoss  = 2    // Cells lost falling into a void on the first level.
+	voidLift  = 0.02 // Warning ring height above the floor tiles.
+)
+
+// abs returns the absolute value of the given integer.
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+// abs
+// ===========================================================================
+// level void handling.
+
+// buildVoids adds the void pits and their warning rings to the level.
+// Expected to be called after the maze center is known.
+func (lvl *level) buildVoids(scene *vu.Ent, plan grid.Grid) {
+	sx, sy, sz := startSpot()
+	startx, starty := gridmath.ToGrid(sx, sy, sz, float64(lvl.units))
+	keep := []gridmath.Spot{{X: lvl.gcx, Y: lvl.gcy}, {X: startx, Y: starty}}
+	lvl.voids = map[gridmath.Spot]bool{}
+	for _, spot := range deadEnds(plan, gameHazard(gameVoids, lvl.num), voidClear, keep) {
+		gamex, gamez := gridmath.ToGame(spot.X, spot.Y, float64(lvl.units))
+		pit := scene.AddPart().SetAt(gamex, gateLift, gamez)
+		m := pit.MakeModel("uvra", "msh:tile", "tex:void")
+		trackAsset(m, "tex:void")
+		m.SetUniform("spin", -1.5).SetUniform("fd", lvl.fade)
+		ring := scene.AddPart().SetAt(gamex, voidLift, gamez)
+		m = ring.MakeModel("uvra", "msh:tile", "tex:voidring")
+		trackAsset(m, "tex:voidring")
+		m.SetAlpha(0.8).SetUniform("spin", 0.5).SetUniform("fd", lvl.fade)
+		lvl.voids[spot] = true
+		lvl.cc.remDropAt(spot.X, spot.Y) // don't lure players into voids.
+	}
+}
+
+// inVoid returns true if the player is standing on a void.
+func (lvl *level) inVoid() bool {
+	x, y, z := lvl.body.At()
+	gx, gy := gridmath.ToGrid(x, y, z, float64(lvl.units))
+	return lvl.voids[gridmath.Spot{X: gx, Y: gy}]
+}
+
+// climbOutOfVoid costs the player a few cells and puts them back at the
+// teleport spot. Used on the first level where there is nowhere to drop.
+func (lvl *level) climbOutOfVoid() {
+	lvl.loseCells(voidLoss)
+	x, y, z := teleportSpot()
+	lvl.body.DisposeBody()
+	lvl.body.SetAt(x, y, z)
+	lvl.body.SetView(lin.QI)
+	lvl.cam.SetAt(x, y, z)
+	lvl.body.MakeBody(vu.Sphere(0.25))
+	lvl.body.SetSolid(1, 0)
+	lvl.hd.showBanner("The void spits you back out, a little lighter")
+}